@@ -42,6 +42,10 @@ type Client struct {
 	mCalHandler handler // interface
 	mMsgChann   chan string
 	//
+	// the parsed calmessages received by the otel handler instead of mMsgChann
+	//
+	mOTelChann chan *jsonMessage
+	//
 	// key of the map is the ThreadId passed to each calmessage by the caller.
 	// each group behind a key has its own caltxn chain with pending msgbuf, flag etc.
 	// since golang does not recommend extracting goid, we need to generate a unique id
@@ -95,7 +99,7 @@ func GetCalClientInstance() *Client {
 	once.Do(func() {
 		gMapMtx = &sync.Mutex{}
 		sCalClientInstance = &Client{mAlreadyInit: false}
-		cfg, err := config.NewTxtConfig("cal_client.txt")
+		cfg, err := config.NewFileConfig("cal_client.txt")
 		if err != nil {
 			sCalClientInstance = nil
 			return
@@ -159,6 +163,9 @@ func (c *Client) init(cfg config.Config, vcfg config.Config) error {
 		// @TODO not working
 		//
 		c.mCalHandler = &fileHandler{}
+	} else if c.mCalConfig.isOTel() {
+		c.mOTelChann = make(chan *jsonMessage, bfMsgChannelSize)
+		c.mCalHandler = &otelHandler{mMsgReadChann: c.mOTelChann}
 	} else {
 		c.mCalHandler = &socketHandler{}
 	}
@@ -180,6 +187,16 @@ func (c *Client) WriteData(_msg string) error {
 	return nil
 }
 
+// writeOTelData sends a parsed calmessage to the otel handler
+func (c *Client) writeOTelData(_msg *jsonMessage) error {
+	select {
+	case c.mOTelChann <- _msg:
+	default:
+		return errors.New("write buffer full. no message written")
+	}
+	return nil
+}
+
 // getConfigInstance gets the config
 func (c *Client) getConfigInstance() *calConfig {
 	return c.mCalConfig
@@ -195,6 +212,15 @@ func (c *Client) IsInitialized() bool {
 	return c.mAlreadyInit
 }
 
+// IsOTelHandler says if the calmessages are sent through OpenTelemetry, the process must then set the
+// OpenTelemetry tracer provider
+func (c *Client) IsOTelHandler() bool {
+	if c.mCalConfig == nil {
+		return false
+	}
+	return c.mCalConfig.isOTel()
+}
+
 // IsPoolstackEnabled says if pool stack is enabled
 func (c *Client) IsPoolstackEnabled() bool {
 	if c.mCalConfig == nil {
@@ -259,7 +285,7 @@ func (c *Client) setRootCalTxn(_rootCalTxn *calTransaction, _tgname ...string) {
 	} else {
 		c.mRootCalTxn[DefaultTGName] = _rootCalTxn
 	}
-	if _rootCalTxn != nil {
+	if (_rootCalTxn != nil) && (c.mOTelChann == nil) {
 		c.WriteData(handlerCtrlMsgNewRoot)
 	}
 }
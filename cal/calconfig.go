@@ -21,6 +21,7 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/paypal/hera/config"
@@ -63,7 +64,7 @@ type calConfig struct {
 	//
 	msgBufferSize int
 	//
-	// socket or file (@TODO implement localfile)
+	// socket, file or otel
 	//
 	handlerType string
 	//
@@ -71,6 +72,13 @@ type calConfig struct {
 	//
 	logFileName string
 	//
+	// if handler type is file, the format of the localfile: text (calmessages) or json (one object per line).
+	// the localfile is rotated when it grows over logMaxSizeMB, keeping logMaxBackups old files
+	//
+	logFormat     string
+	logMaxSizeMB  int
+	logMaxBackups int
+	//
 	// if ture, calmsgs with different threadids will be put in different swimminglanes.
 	// otherwise, all calmsgs from a same process will be put in the same swimminglane
 	// even these calmsgs carry different threadids.
@@ -108,6 +116,9 @@ func (c *calConfig) initialize(cfg config.Config, vcfg config.Config, _labelAffi
 	c.msgBufferSize = cfg.GetOrDefaultInt("cal_message_buffer_size", 300)
 	c.handlerType = cfg.GetOrDefaultString("cal_handler", "socket")
 	c.logFileName = cfg.GetOrDefaultString("cal_log_file", "logCalClient.txt")
+	c.logFormat = strings.ToLower(cfg.GetOrDefaultString("cal_log_format", logFormatText))
+	c.logMaxSizeMB = cfg.GetOrDefaultInt("cal_log_max_size_mb", 0)
+	c.logMaxBackups = cfg.GetOrDefaultInt("cal_log_max_backups", 5)
 	c.enableTG = (cfg.GetOrDefaultString("cal_enable_threadgroup", "false") == "true")
 	c.poolstackEnabled = (cfg.GetOrDefaultString("cal_pool_stack_enable", "true") == "true")
 	c.poolStackSize = cfg.GetOrDefaultInt("cal_max_pool_stack_size", 2048)
@@ -177,6 +188,16 @@ func (c *calConfig) getLogFileName() string {
 	return c.logFileName
 }
 
+// isJSON tells if the calmessages are written to the localfile as JSON objects
+func (c *calConfig) isJSON() bool {
+	return strings.EqualFold(c.handlerType, "file") && (c.logFormat == logFormatJSON)
+}
+
+// isOTel tells if the calmessages are sent as spans through OpenTelemetry
+func (c *calConfig) isOTel() bool {
+	return strings.EqualFold(c.handlerType, handlerTypeOTel)
+}
+
 func (c *calConfig) getPoolstackEnabled() bool {
 	return c.poolstackEnabled
 }
@@ -20,8 +20,19 @@ package cal
 import (
 	"log"
 	"os"
+
+	"github.com/paypal/hera/utility/rotatefile"
 )
 
+// sLogFileOwner is true in the process rotating the localfile
+var sLogFileOwner bool
+
+// SetLogFileOwner makes the process the one rotating the localfile when "cal_log_max_size_mb" is set. It must be
+// called before the first calmessage, by only one of the processes sharing the localfile
+func SetLogFileOwner(_owner bool) {
+	sLogFileOwner = _owner
+}
+
 // fileHandler is for the implementation to write to a file
 type fileHandler struct {
 	//
@@ -40,6 +51,17 @@ func (c *fileHandler) init(_config *calConfig, _msgchann <-chan string) error {
 	if _config != nil {
 		filename = _config.getLogFileName()
 	}
+	if (_config != nil) && (_config.logMaxSizeMB > 0) {
+		//
+		// only the owner (mux) rotates the file, the workers re-open it after a rotation
+		//
+		file, err := rotatefile.Open(filename, int64(_config.logMaxSizeMB)*1024*1024, _config.logMaxBackups, sLogFileOwner)
+		if err != nil {
+			return err
+		}
+		c.mFileLogger = log.New(file, "", 0)
+		return nil
+	}
 	file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 	if err != nil {
 		return err
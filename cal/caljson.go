@@ -0,0 +1,162 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cal
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the values of "cal_log_format"
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// the kind of message for each calmessage class
+var jsonKinds = map[string]string{
+	calClassStartTransaction:  "transaction_start",
+	calClassEndTransaction:    "transaction_end",
+	calClassAtomicTransaction: "transaction",
+	calClassEvent:             "event",
+	calClassHeartbeat:         "heartbeat",
+}
+
+// jsonMessage is a calmessage written by the file handler when "cal_log_format" is json, and sent to the
+// otel handler
+type jsonMessage struct {
+	Time        string            `json:"time"`
+	Kind        string            `json:"kind"`
+	Pool        string            `json:"pool,omitempty"`
+	Pid         int               `json:"pid"`
+	ThreadGroup string            `json:"thread_group,omitempty"`
+	Type        string            `json:"type"`
+	Name        string            `json:"name"`
+	Status      string            `json:"status,omitempty"`
+	Duration    *float64          `json:"duration,omitempty"`
+	CorrID      string            `json:"corr_id,omitempty"`
+	Data        map[string]string `json:"data,omitempty"`
+}
+
+// toJSON converts a calmessage in the CAL format (class, timestamp and tab separated fields) to a JSON
+// object. It returns the message unchanged if it can't be parsed. now is the time the message is converted,
+// giving the date missing in the CAL timestamp
+func toJSON(_msg string, _pool string, _tgname string, _corrID string, _now time.Time) string {
+	msg := parseMessage(_msg, _pool, _tgname, _corrID, _now)
+	if msg == nil {
+		return _msg
+	}
+	buf, err := json.Marshal(msg)
+	if err != nil {
+		return _msg
+	}
+	return string(buf) + calEndOfLine
+}
+
+// parseMessage parses a calmessage in the CAL format, it returns nil if the message can't be parsed
+func parseMessage(_msg string, _pool string, _tgname string, _corrID string, _now time.Time) *jsonMessage {
+	line := strings.TrimRight(strings.TrimLeft(_msg, calEndOfLine), calEndOfLine)
+	// class, 11 bytes timestamp, tab
+	if len(line) < 13 {
+		return nil
+	}
+	msg := &jsonMessage{Kind: jsonKinds[line[:1]], Pool: _pool, Pid: os.Getpid(), CorrID: _corrID}
+	if msg.Kind == "" {
+		return nil
+	}
+	if _tgname != DefaultTGName {
+		msg.ThreadGroup = _tgname
+	}
+	msg.Time = jsonTime(line[1:12], _now)
+	var data string
+	switch line[:1] {
+	case calClassStartTransaction:
+		fields := strings.SplitN(line[13:], calTab, 2)
+		msg.Type = fields[0]
+		if len(fields) > 1 {
+			msg.Name = fields[1]
+		}
+	case calClassEndTransaction, calClassAtomicTransaction:
+		fields := strings.SplitN(line[13:], calTab, 5)
+		if len(fields) < 5 {
+			return nil
+		}
+		msg.Type, msg.Name, msg.Status, data = fields[0], fields[1], fields[2], fields[4]
+		duration, err := strconv.ParseFloat(fields[3], 64)
+		if err == nil {
+			msg.Duration = &duration
+		}
+	default:
+		fields := strings.SplitN(line[13:], calTab, 4)
+		if len(fields) < 4 {
+			return nil
+		}
+		msg.Type, msg.Name, msg.Status, data = fields[0], fields[1], fields[2], fields[3]
+	}
+	msg.Data = jsonData(data)
+	// the correlation id is part of the data of the root transactions
+	if corrID, ok := msg.Data["corr_id_"]; ok {
+		if msg.CorrID == "" {
+			msg.CorrID = corrID
+		}
+		delete(msg.Data, "corr_id_")
+		for _, key := range []string{"log_id_", "session_id_"} {
+			if msg.Data[key] == "" {
+				delete(msg.Data, key)
+			}
+		}
+	}
+	return msg
+}
+
+// jsonData splits the name=value pairs of the data
+func jsonData(_data string) map[string]string {
+	if len(_data) == 0 {
+		return nil
+	}
+	data := make(map[string]string)
+	for _, pair := range strings.Split(_data, calAmpersand) {
+		if len(pair) == 0 {
+			continue
+		}
+		eq := strings.Index(pair, calEquals)
+		if eq < 0 {
+			data[pair] = ""
+		} else {
+			data[pair[:eq]] = pair[eq+1:]
+		}
+	}
+	return data
+}
+
+// jsonTime returns the RFC3339 time of a CAL timestamp (hh:mm:ss.cc), which is at most a few seconds before now
+func jsonTime(_ts string, _now time.Time) string {
+	tod, err := time.ParseInLocation("15:04:05.00", _ts, _now.Location())
+	if err != nil {
+		return _now.Format(time.RFC3339Nano)
+	}
+	ts := time.Date(_now.Year(), _now.Month(), _now.Day(), tod.Hour(), tod.Minute(), tod.Second(), tod.Nanosecond(), _now.Location())
+	if ts.Sub(_now) > time.Hour {
+		// the message was created before midnight
+		ts = ts.AddDate(0, 0, -1)
+	}
+	return ts.Format(time.RFC3339Nano)
+}
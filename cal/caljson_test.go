@@ -0,0 +1,58 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cal
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestToJSON(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 1, 0, time.UTC)
+	msg := "T23:59:59.50\tAPI\tCLIENT_SESSION\t0\t12.5\tcorr_id_=abc&log_id_=&session_id_=&sql=42\r\n"
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(toJSON(msg, "pool", DefaultTGName, "", now)), &obj); err != nil {
+		t.Fatal(err)
+	}
+	if (obj["kind"] != "transaction_end") || (obj["type"] != "API") || (obj["name"] != "CLIENT_SESSION") || (obj["duration"] != 12.5) {
+		t.Errorf("unexpected transaction %v", obj)
+	}
+	if obj["corr_id"] != "abc" {
+		t.Errorf("expected the correlation id, got %v", obj["corr_id"])
+	}
+	if data := obj["data"].(map[string]interface{}); (len(data) != 1) || (data["sql"] != "42") {
+		t.Errorf("unexpected data %v", data)
+	}
+	// before midnight
+	if obj["time"] != "2024-02-29T23:59:59.5Z" {
+		t.Errorf("unexpected time %v", obj["time"])
+	}
+
+	msg = "\r\nt00:00:01.00\tURL\tstart\r\n"
+	if err := json.Unmarshal([]byte(toJSON(msg, "pool", "tg1", "xyz", now)), &obj); err != nil {
+		t.Fatal(err)
+	}
+	if (obj["kind"] != "transaction_start") || (obj["name"] != "start") || (obj["thread_group"] != "tg1") || (obj["corr_id"] != "xyz") {
+		t.Errorf("unexpected transaction start %v", obj)
+	}
+
+	if toJSON("garbage\r\n", "pool", DefaultTGName, "", now) != "garbage\r\n" {
+		t.Error("expected an unparsable message unchanged")
+	}
+}
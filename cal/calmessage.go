@@ -531,6 +531,12 @@ func (act *calActivity) writeMessageToHandler(_msg string) {
 	if cfg != nil {
 		enableTG = cfg.enableTG
 	}
+	if (cfg != nil) && cfg.isOTel() {
+		if msg := parseMessage(_msg, cfg.getPoolName(), act.mThreadGroupName, client.getCorrelationID(act.mThreadGroupName), time.Now()); msg != nil {
+			client.writeOTelData(msg)
+		}
+		return
+	}
 
 	//
 	// @TODO: performance. hash ctxkey into 4 bytes, mod by 100, add to the end of _msg.
@@ -542,6 +548,9 @@ func (act *calActivity) writeMessageToHandler(_msg string) {
 		//binary.LittleEndian.PutUint32(tid, h.Sum32())
 		binary.LittleEndian.PutUint32(tid, (h.Sum32() % CALMaxThreadNum))
 	}
+	if (cfg != nil) && cfg.isJSON() {
+		_msg = toJSON(_msg, cfg.getPoolName(), act.mThreadGroupName, client.getCorrelationID(act.mThreadGroupName), time.Now())
+	}
 	client.WriteData(_msg + string(tid))
 }
 
@@ -0,0 +1,182 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cal
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	handlerTypeOTel = "otel"
+	// the instrumentation scope of the spans
+	otelTracerName = "github.com/paypal/hera/cal"
+	// the transactions nested deeper in a thread group are not sent
+	otelMaxDepth = 64
+)
+
+// otelSpan is a transaction started and not completed yet
+type otelSpan struct {
+	ctx   context.Context
+	span  trace.Span
+	mType string
+	mName string
+}
+
+// otelHandler sends the calmessages through OpenTelemetry: the transactions become spans, nested as the
+// transactions of each thread group, and the events become events of the current span. The events outside
+// a transaction become spans without duration. The spans are exported by the tracer provider the process
+// registers with otel.SetTracerProvider, the messages sent before are dropped
+type otelHandler struct {
+	//
+	// readonly channel for arriving calmessages, parsed by the client
+	//
+	mMsgReadChann <-chan *jsonMessage
+	mConfig       *calConfig
+	mTracer       trace.Tracer
+	//
+	// the transactions not completed of each thread group, the innermost last
+	//
+	mOpen map[string][]*otelSpan
+}
+
+// init keeps the channel of the parsed calmessages set by the client, the channel of the calmessages in the
+// CAL format is not used
+func (c *otelHandler) init(_config *calConfig, _msgchann <-chan string) error {
+	c.mConfig = _config
+	if c.mTracer == nil {
+		c.mTracer = otel.Tracer(otelTracerName)
+	}
+	c.mOpen = make(map[string][]*otelSpan)
+	return nil
+}
+
+func (c *otelHandler) run() {
+	for {
+		msg, ok := <-c.mMsgReadChann
+		if !ok {
+			return
+		}
+		c.send(msg)
+	}
+}
+
+// send converts a calmessage to a span or a span event
+func (c *otelHandler) send(_msg *jsonMessage) {
+	ts, err := time.Parse(time.RFC3339Nano, _msg.Time)
+	if err != nil {
+		ts = time.Now()
+	}
+	open := c.mOpen[_msg.ThreadGroup]
+	parent := context.Background()
+	if len(open) > 0 {
+		parent = open[len(open)-1].ctx
+	}
+	switch _msg.Kind {
+	case "transaction_start":
+		if len(open) >= otelMaxDepth {
+			return
+		}
+		ctx, span := c.mTracer.Start(parent, spanName(_msg), trace.WithTimestamp(ts), trace.WithAttributes(spanAttributes(_msg)...))
+		c.mOpen[_msg.ThreadGroup] = append(open, &otelSpan{ctx: ctx, span: span, mType: _msg.Type, mName: _msg.Name})
+	case "transaction_end":
+		//
+		// the innermost transaction with this type and name. the transactions still open inside it are
+		// completed with it
+		//
+		for i := len(open) - 1; i >= 0; i-- {
+			if (open[i].mType != _msg.Type) || (open[i].mName != _msg.Name) {
+				continue
+			}
+			for j := len(open) - 1; j > i; j-- {
+				open[j].span.End(trace.WithTimestamp(ts))
+			}
+			open[i].span.SetAttributes(spanAttributes(_msg)...)
+			setSpanStatus(open[i].span, _msg.Status)
+			open[i].span.End(trace.WithTimestamp(ts))
+			if i == 0 {
+				delete(c.mOpen, _msg.ThreadGroup)
+			} else {
+				c.mOpen[_msg.ThreadGroup] = open[:i]
+			}
+			return
+		}
+		//
+		// the start was not sent, sent as an atomic transaction
+		//
+		c.sendSpan(parent, _msg, ts)
+	case "transaction":
+		c.sendSpan(parent, _msg, ts)
+	default:
+		if len(open) == 0 {
+			c.sendSpan(parent, _msg, ts)
+			return
+		}
+		open[len(open)-1].span.AddEvent(spanName(_msg), trace.WithTimestamp(ts), trace.WithAttributes(spanAttributes(_msg)...))
+	}
+}
+
+// sendSpan sends a complete span starting at ts, lasting the duration of the calmessage
+func (c *otelHandler) sendSpan(_parent context.Context, _msg *jsonMessage, _ts time.Time) {
+	end := _ts
+	if _msg.Duration != nil {
+		end = _ts.Add(time.Duration(*_msg.Duration * float64(time.Millisecond)))
+	}
+	_, span := c.mTracer.Start(_parent, spanName(_msg), trace.WithTimestamp(_ts), trace.WithAttributes(spanAttributes(_msg)...))
+	setSpanStatus(span, _msg.Status)
+	span.End(trace.WithTimestamp(end))
+}
+
+func spanName(_msg *jsonMessage) string {
+	return _msg.Type + " " + _msg.Name
+}
+
+func spanAttributes(_msg *jsonMessage) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("cal.type", _msg.Type),
+		attribute.String("cal.name", _msg.Name),
+		attribute.String("cal.pool", _msg.Pool),
+		attribute.Int("cal.pid", _msg.Pid),
+	}
+	if len(_msg.Status) > 0 {
+		attrs = append(attrs, attribute.String("cal.status", _msg.Status))
+	}
+	if len(_msg.ThreadGroup) > 0 {
+		attrs = append(attrs, attribute.String("cal.thread_group", _msg.ThreadGroup))
+	}
+	if len(_msg.CorrID) > 0 {
+		attrs = append(attrs, attribute.String("cal.corr_id", _msg.CorrID))
+	}
+	for key, value := range _msg.Data {
+		attrs = append(attrs, attribute.String("cal.data."+key, value))
+	}
+	return attrs
+}
+
+// setSpanStatus marks the span in error for the fatal and error CAL statuses
+func setSpanStatus(_span trace.Span, _status string) {
+	if strings.HasPrefix(_status, TransFatal) || strings.HasPrefix(_status, TransError) {
+		_span.SetStatus(codes.Error, _status)
+	}
+}
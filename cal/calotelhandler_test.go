@@ -0,0 +1,74 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cal
+
+import (
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestOTelHandler(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	handler := &otelHandler{mTracer: sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer(otelTracerName)}
+	handler.init(nil, nil)
+	now := time.Date(2024, 3, 1, 12, 0, 2, 0, time.UTC)
+	for _, msg := range []string{
+		"t12:00:00.00\tAPI\tCLIENT_SESSION\r\n",
+		"E12:00:00.10\tEXEC\tsql\t0\tsqlhash=42\r\n",
+		"A12:00:00.20\tFETCH\tsql\t2.0.0.0\t5.0\t\r\n",
+		"T12:00:01.00\tAPI\tCLIENT_SESSION\t0\t1000.0\tcorr_id_=abc&log_id_=&session_id_=\r\n",
+	} {
+		handler.send(parseMessage(msg, "pool", DefaultTGName, "", now))
+	}
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	fetch, session := spans[0], spans[1]
+	if (session.Name() != "API CLIENT_SESSION") || (fetch.Name() != "FETCH sql") {
+		t.Errorf("unexpected spans %s, %s", session.Name(), fetch.Name())
+	}
+	if fetch.Parent().SpanID() != session.SpanContext().SpanID() {
+		t.Error("expected the atomic transaction nested in the session")
+	}
+	if (fetch.Status().Code != codes.Error) || (session.Status().Code == codes.Error) {
+		t.Errorf("unexpected statuses %v, %v", fetch.Status(), session.Status())
+	}
+	if fetch.EndTime().Sub(fetch.StartTime()) != 5*time.Millisecond {
+		t.Errorf("unexpected duration %v", fetch.EndTime().Sub(fetch.StartTime()))
+	}
+	if (len(session.Events()) != 1) || (session.Events()[0].Name != "EXEC sql") {
+		t.Errorf("expected the event in the session, got %v", session.Events())
+	}
+	corrID := false
+	for _, attr := range session.Attributes() {
+		if (attr.Key == "cal.corr_id") && (attr.Value.AsString() == "abc") {
+			corrID = true
+		}
+	}
+	if !corrID {
+		t.Errorf("expected the correlation id in %v", session.Attributes())
+	}
+	if len(handler.mOpen) != 0 {
+		t.Errorf("expected no open transaction, got %v", handler.mOpen)
+	}
+}
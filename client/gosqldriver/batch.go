@@ -0,0 +1,185 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gosqldriver
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+
+	"github.com/paypal/hera/common"
+	"github.com/paypal/hera/utility/encoding/netstring"
+	"github.com/paypal/hera/utility/logger"
+)
+
+// the largest batch the workers accept in one request, larger batches are split
+const maxBatchRows = 100
+
+// BatchResult is the result of ExecBatch
+type BatchResult struct {
+	// the number of rows changed by each row of binds, -1 if the database does not tell it. If a row
+	// failed, it has the counts of the rows before it, which are rolled back if the batch is not run in a
+	// transaction
+	RowsAffected []int64
+	// the error of the row failing, the rows after it did not run
+	Err error
+}
+
+// ExecBatch implements the extension HeraConn interface. In a transaction, the batch runs in it. Else it is
+// committed if all the rows succeed, or rolled back if one fails
+func (c *heraConnection) ExecBatch(query string, rows [][]driver.Value) (*BatchResult, error) {
+	// the rows are checked before the first request, a bad row can't stop a batch split in several
+	// requests half way
+	if err := checkBatchRows(rows); err != nil {
+		return nil, err
+	}
+	res := &BatchResult{RowsAffected: make([]int64, 0, len(rows))}
+	autoCommit := !c.inTx
+	for start := 0; start < len(rows); start += maxBatchRows {
+		end := start + maxBatchRows
+		if end > len(rows) {
+			end = len(rows)
+		}
+		// the batch split in several requests is committed by the last one
+		commit := autoCommit && (end == len(rows))
+		err := c.execBatchChunk(newStmt(c, query), rows[start:end], commit, res)
+		if err != nil {
+			if autoCommit && (start > 0) {
+				// the previous requests left a transaction open on the worker
+				(&tx{hera: c}).Rollback()
+			}
+			return nil, err
+		}
+		if res.Err != nil {
+			if autoCommit && !commit {
+				// the previous requests are rolled back with it
+				err = (&tx{hera: c}).Rollback()
+				if err != nil {
+					return nil, err
+				}
+			}
+			break
+		}
+	}
+	return res, nil
+}
+
+// checkBatchRows returns an error if the rows don't have the same number of values, or if a value can't be bound
+func checkBatchRows(rows [][]driver.Value) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	cols := len(rows[0])
+	for i, row := range rows {
+		if len(row) != cols {
+			return fmt.Errorf("batch row %d has %d values instead of %d", i, len(row), cols)
+		}
+		for _, val := range row {
+			if _, err := bindValue(val); err != nil {
+				return fmt.Errorf("batch row %d: %s", i, err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+// batchRequest builds the request running the statement for the rows: each bind name is followed by the number
+// of rows, the size of the largest value then the value for each row. With commit, the worker commits the
+// transaction after the batch, or rolls it back if a row fails
+func (st *stmt) batchRequest(rows [][]driver.Value, commit bool) (*netstring.Netstring, error) {
+	cols := len(rows[0])
+	nss := make([]*netstring.Netstring, 0, 1 /*CmdClientCalCorrelationID*/ +1 /*CmdPrepare*/ +cols*(3+len(rows))+1 /*CmdShardKey*/ +1 /*CmdBatchCommit*/ +1 /*CmdExecute*/)
+	if st.hera.corrID != nil {
+		nss = append(nss, st.hera.corrID)
+	}
+	nss = append(nss, netstring.NewNetstringFrom(common.CmdPrepareV2, []byte(st.sql)))
+	values := make([]*netstring.Netstring, len(rows))
+	for col := 0; col < cols; col++ {
+		maxSize := 0
+		for i, row := range rows {
+			if len(row) != cols {
+				return nil, fmt.Errorf("batch row %d has %d values instead of %d", i, len(row), cols)
+			}
+			var err error
+			values[i], err = bindValue(row[col])
+			if err != nil {
+				return nil, err
+			}
+			if len(values[i].Payload) > maxSize {
+				maxSize = len(values[i].Payload)
+			}
+		}
+		nss = append(nss, netstring.NewNetstringFrom(common.CmdBindName, []byte(fmt.Sprintf("p%d", col+1))),
+			netstring.NewNetstringFrom(common.CmdBindNum, []byte(strconv.Itoa(len(rows)))),
+			netstring.NewNetstringFrom(common.CmdBindValueMaxSize, []byte(strconv.Itoa(maxSize))))
+		nss = append(nss, values...)
+	}
+	if len(st.hera.shardKeyPayload) > 0 {
+		nss = append(nss, netstring.NewNetstringFrom(common.CmdShardKey, st.hera.shardKeyPayload))
+	}
+	if commit {
+		nss = append(nss, netstring.NewNetstringFrom(common.CmdBatchCommit, nil))
+	}
+	nss = append(nss, netstring.NewNetstringFrom(common.CmdExecute, nil))
+	st.hera.corrID = nil
+	return netstring.NewNetstringEmbedded(nss), nil
+}
+
+// execBatchChunk runs up to maxBatchRows rows, adding their result to res
+func (c *heraConnection) execBatchChunk(st *stmt, rows [][]driver.Value, commit bool, res *BatchResult) error {
+	cmd, err := st.batchRequest(rows, commit)
+	if err != nil {
+		return err
+	}
+	err = c.execNs(cmd)
+	if err != nil {
+		return err
+	}
+	_, total, err := st.execResponse()
+	if err != nil {
+		if (err == errReadResponse) || (err == driver.ErrBadConn) {
+			return err
+		}
+		// the whole batch failed
+		res.Err = err
+		return nil
+	}
+	for range rows {
+		ns, err := c.getResponse()
+		if err != nil {
+			return err
+		}
+		switch ns.Cmd {
+		case common.RcValue:
+			cnt, err := strconv.ParseInt(string(ns.Payload), 10, 64)
+			if err != nil {
+				return err
+			}
+			res.RowsAffected = append(res.RowsAffected, cnt)
+		case common.RcSQLError:
+			res.Err = fmt.Errorf("SQL error: %s", string(ns.Payload))
+			return nil
+		default:
+			return fmt.Errorf("Unknown code: %d, data: %s", ns.Cmd, string(ns.Payload))
+		}
+	}
+	if logger.GetLogger().V(logger.Debug) {
+		logger.GetLogger().Log(logger.Debug, c.id, "batch of", len(rows), "rows successfull, rows affected:", total)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package gosqldriver
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/paypal/hera/common"
+	"github.com/paypal/hera/utility/encoding/netstring"
+)
+
+func TestExecBatch(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		reader := netstring.NewNetstringReader(server)
+		var bindNum, values int
+		var commit bool
+		for {
+			ns, err := reader.ReadNext()
+			if err != nil {
+				return
+			}
+			switch ns.Cmd {
+			case common.CmdBindNum:
+				bindNum = int(ns.Payload[0] - '0')
+			case common.CmdBindValue:
+				values++
+			case common.CmdBatchCommit:
+				commit = true
+			case common.CmdExecute:
+				if (bindNum != 3) || (values != 6) {
+					t.Errorf("expected 2 array binds of 3 rows, got %d values for %d rows", values, bindNum)
+				}
+				if !commit {
+					t.Error("expected the batch outside of a transaction to be committed by the worker")
+				}
+				// the third row fails
+				resp := append(append(value("0"), value("2")...), value("1")...)
+				resp = append(append(resp, value("1")...), netstring.NewNetstringFrom(common.RcSQLError, []byte("ORA-00001")).Serialized...)
+				server.Write(resp)
+			}
+		}
+	}()
+	hera := NewHeraConnection(client).(*heraConnection)
+	res, err := hera.ExecBatch("insert into t (id, name) values (?, ?)",
+		[][]driver.Value{{1, "a"}, {2, "b"}, {2, "c"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (len(res.RowsAffected) != 2) || (res.RowsAffected[0] != 1) || (res.RowsAffected[1] != 1) {
+		t.Errorf("expected 1 row changed by each of the first 2 rows, got %v", res.RowsAffected)
+	}
+	if res.Err == nil {
+		t.Error("expected the error of the third row")
+	}
+}
+
+// batchRows returns count rows of 2 values
+func batchRows(count int) [][]driver.Value {
+	rows := make([][]driver.Value, count)
+	for i := range rows {
+		rows[i] = []driver.Value{i, "a"}
+	}
+	return rows
+}
+
+func TestExecBatchBadRow(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		// nothing is sent for a batch with a bad row
+		reader := netstring.NewNetstringReader(server)
+		if ns, err := reader.ReadNext(); err == nil {
+			t.Errorf("unexpected request %d", ns.Cmd)
+		}
+	}()
+	hera := NewHeraConnection(client).(*heraConnection)
+	for _, bad := range [][]driver.Value{{1}, {1, 2.5}} {
+		rows := batchRows(2*maxBatchRows + 1)
+		rows[maxBatchRows+1] = bad
+		if _, err := hera.ExecBatch("insert into t (id, name) values (?, ?)", rows); err == nil {
+			t.Errorf("batch with the row %v in the second request accepted", bad)
+		}
+	}
+	client.Close()
+}
+
+func TestExecBatchRollback(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	rolledBack := make(chan bool, 1)
+	go func() {
+		reader := netstring.NewNetstringReader(server)
+		requests := 0
+		for {
+			ns, err := reader.ReadNext()
+			if err != nil {
+				rolledBack <- false
+				return
+			}
+			switch ns.Cmd {
+			case common.CmdExecute:
+				requests++
+				resp := append(value("0"), value(fmt.Sprintf("%d", maxBatchRows))...)
+				if requests == 1 {
+					for i := 0; i < maxBatchRows; i++ {
+						resp = append(resp, value("1")...)
+					}
+				} else {
+					// the second request gets a broken response
+					resp = append(resp, netstring.NewNetstringFrom(common.RcNoMoreData, nil).Serialized...)
+				}
+				server.Write(resp)
+			case common.CmdRollback:
+				server.Write(netstring.NewNetstringFrom(common.RcOK, nil).Serialized)
+				rolledBack <- true
+				return
+			}
+		}
+	}()
+	hera := NewHeraConnection(client).(*heraConnection)
+	if _, err := hera.ExecBatch("insert into t (id, name) values (?, ?)", batchRows(2*maxBatchRows)); err == nil {
+		t.Error("expected the error of the second request")
+	}
+	client.Close()
+	if !<-rolledBack {
+		t.Error("expected the first request rolled back")
+	}
+}
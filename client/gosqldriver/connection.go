@@ -24,12 +24,18 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/paypal/hera/common"
+	"github.com/paypal/hera/utility/encoding/compression"
 	"github.com/paypal/hera/utility/encoding/netstring"
 	"github.com/paypal/hera/utility/logger"
 )
 
+// errReadResponse is returned when the connection is broken, the following responses are lost
+var errReadResponse = errors.New("Failed to read response")
+
 var corrIDUnsetCmd = netstring.NewNetstringFrom(common.CmdClientCalCorrelationID, []byte("CorrId=NotSet"))
 
 type heraConnection struct {
@@ -41,11 +47,29 @@ type heraConnection struct {
 	// correlation id
 	corrID *netstring.Netstring
 	clientinfo *netstring.Netstring
+	// the requests are sent as binary frames
+	binaryFraming bool
+	// codec of the compressed responses
+	compression string
+	// the responses unpacked from a compressed response, not returned yet
+	pending []*netstring.Netstring
+	// a transaction was started and not committed or rolled back yet
+	inTx bool
 }
 
 // NewHeraConnection creates a structure implementing a driver.Con interface
 func NewHeraConnection(conn net.Conn) driver.Conn {
-	hera := &heraConnection{conn: conn, id: conn.RemoteAddr().String(), reader: netstring.NewNetstringReader(conn), corrID: corrIDUnsetCmd}
+	return NewHeraConnectionOptions(conn, ConnOptions{})
+}
+
+// NewHeraConnectionOptions creates a structure implementing a driver.Con interface, using the protocol
+// options the server agreed to during the handshake
+func NewHeraConnectionOptions(conn net.Conn, opts ConnOptions) driver.Conn {
+	hera := &heraConnection{conn: conn, id: conn.RemoteAddr().String(), reader: netstring.NewNetstringReader(conn), corrID: corrIDUnsetCmd,
+		binaryFraming: opts.BinaryFraming, compression: opts.Compression}
+	if opts.BinaryFraming {
+		hera.reader = netstring.NewFrameReader(conn)
+	}
 	if logger.GetLogger().V(logger.Info) {
 		logger.GetLogger().Log(logger.Info, hera.id, "create driver connection")
 	}
@@ -82,6 +106,7 @@ func (c *heraConnection) Begin() (driver.Tx, error) {
 	if logger.GetLogger().V(logger.Debug) {
 		logger.GetLogger().Log(logger.Debug, c.id, "begin txn")
 	}
+	c.inTx = true
 	return &tx{hera: c}, nil
 }
 
@@ -99,18 +124,46 @@ func (c *heraConnection) execNs(ns *netstring.Netstring) error {
 		}
 		logger.GetLogger().Log(logger.Verbose, c.id, "send command:", ns.Cmd, ", payload:", payload)
 	}
-	_, err := c.conn.Write(ns.Serialized)
+	return c.write(ns)
+}
+
+// write sends the netstrings in one write, as binary frames if the binary framing was negotiated
+func (c *heraConnection) write(nss ...*netstring.Netstring) error {
+	if (len(nss) == 1) && !c.binaryFraming {
+		_, err := c.conn.Write(nss[0].Serialized)
+		return err
+	}
+	var data []byte
+	for _, ns := range nss {
+		if !c.binaryFraming {
+			data = append(data, ns.Serialized...)
+			continue
+		}
+		var err error
+		data, err = netstring.AppendBinary(data, ns)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := c.conn.Write(data)
 	return err
 }
 
 // returns the next message from the connection
 func (c *heraConnection) getResponse() (*netstring.Netstring, error) {
-	ns, err := c.reader.ReadNext()
+	ns, err := c.readNext()
 	if err != nil {
 		if logger.GetLogger().V(logger.Warning) {
 			logger.GetLogger().Log(logger.Warning, c.id, "Failed to read response")
 		}
-		return nil, errors.New("Failed to read response")
+		return nil, errReadResponse
+	}
+	if ns.Cmd == common.CmdServerDraining {
+		// the server is going down for maintenance, database/sql retries on a new connection
+		if logger.GetLogger().V(logger.Info) {
+			logger.GetLogger().Log(logger.Info, c.id, "server draining")
+		}
+		return nil, driver.ErrBadConn
 	}
 	if logger.GetLogger().V(logger.Verbose) {
 		payload := string(ns.Payload)
@@ -122,6 +175,41 @@ func (c *heraConnection) getResponse() (*netstring.Netstring, error) {
 	return ns, nil
 }
 
+// maximum size of a decompressed response
+const maxDecompressedLen = 1 << 30
+
+// readNext returns the next netstring from the connection, unpacking the compressed responses
+func (c *heraConnection) readNext() (*netstring.Netstring, error) {
+	if len(c.pending) > 0 {
+		ns := c.pending[0]
+		c.pending = c.pending[1:]
+		return ns, nil
+	}
+	ns, err := c.reader.ReadNext()
+	if (err != nil) || (ns.Cmd != common.RcCompressed) {
+		return ns, err
+	}
+	data, err := compression.Decompress(c.compression, ns.Payload, maxDecompressedLen)
+	if err != nil {
+		return nil, err
+	}
+	parse := netstring.ParseNext
+	if c.binaryFraming {
+		parse = netstring.ParseAny
+	}
+	for len(data) > 0 {
+		ns, data, err = parse(data)
+		if err != nil {
+			return nil, err
+		}
+		c.pending = append(c.pending, ns)
+	}
+	if len(c.pending) == 0 {
+		return nil, errors.New("empty compressed response")
+	}
+	return c.readNext()
+}
+
 // implementing the extension HeraConn interface
 func (c *heraConnection) SetShardID(shard int) error {
 	c.exec(common.CmdSetShardID, []byte(fmt.Sprintf("%d", shard)))
@@ -161,6 +249,34 @@ func (c *heraConnection) GetNumShards() (int, error) {
 	return num, nil
 }
 
+// implementing the extension HeraConn interface
+func (c *heraConnection) SetSessionVars(vars map[string]string) error {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		if strings.ContainsAny(name, "=\n") || strings.Contains(vars[name], "\n") {
+			return fmt.Errorf("bad session variable %s", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lines := make([]string, len(names))
+	for i, name := range names {
+		lines[i] = name + "=" + vars[name]
+	}
+	c.exec(common.CmdSetSessionVars, []byte(strings.Join(lines, "\n")))
+	ns, err := c.getResponse()
+	if err != nil {
+		return err
+	}
+	if ns.Cmd == common.RcError {
+		return errors.New(string(ns.Payload))
+	}
+	if ns.Cmd != common.RcOK {
+		return fmt.Errorf("Unknown error, cmd=%d, payload size=%d", ns.Cmd, len(ns.Payload))
+	}
+	return nil
+}
+
 // implementing the extension HeraConn interface
 func (c *heraConnection) SetShardKeyPayload(payload string) {
 	c.shardKeyPayload = []byte(payload)
@@ -189,7 +305,7 @@ func (c *heraConnection) SetClientInfo(poolName string, host string)(error){
                         logger.GetLogger().Log(logger.Verbose, "SetClientInfo", c.clientinfo.Serialized)
                 }
 
-        _, err := c.conn.Write(c.clientinfo.Serialized)
+        err := c.write(c.clientinfo)
         if err != nil {
                 if logger.GetLogger().V(logger.Warning) {
                         logger.GetLogger().Log(logger.Warning, "Failed to send client info")
@@ -221,7 +337,7 @@ func (c *heraConnection) SetClientInfoWithPoolStack(poolName string, host string
                         logger.GetLogger().Log(logger.Verbose, "SetClientInfo", c.clientinfo.Serialized)
                 }
 
-        _, err := c.conn.Write(c.clientinfo.Serialized)
+        err := c.write(c.clientinfo)
         if err != nil {
                 if logger.GetLogger().V(logger.Warning) {
                         logger.GetLogger().Log(logger.Warning, "Failed to send client info")
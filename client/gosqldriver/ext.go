@@ -36,6 +36,11 @@ type HeraConn interface {
 	// returns the number os shards
 	GetNumShards() (int, error)
 
+	// SetSessionVars sets session variables like time_zone, sql_mode, search_path or NLS settings for the SQLs
+	// on this connection, replacing the ones set before. Nil or an empty map clears them. The server must have
+	// enable_session_variables set. The variables set during a transaction are used after it ends
+	SetSessionVars(vars map[string]string) error
+
 	// This is used for queries which don't have shard key. The format is "<key>=<value1>;<value2>;...<valuen>"
 	SetShardKeyPayload(payload string)
 	// Reset the state set via SetShardKeyPayload
@@ -47,6 +52,14 @@ type HeraConn interface {
 	SetClientInfo(poolname string, host string) error
 
 	SetClientInfoWithPoolStack(poolName string, host string, poolStack string) error
+
+	// NewPipeline returns a Pipeline sending several statements back to back on this connection, without waiting
+	// for the result of each statement. The connection must not run other statements until the results are read
+	NewPipeline() *Pipeline
+
+	// ExecBatch runs a DML once for each row of binds, sending the rows as array binds. The rows run in the
+	// transaction of the connection, it stops at the first row failing
+	ExecBatch(query string, rows [][]driver.Value) (*BatchResult, error)
 }
 
 // HeraStmt is an API extension for *sql.Stmt
@@ -0,0 +1,119 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gosqldriver
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/paypal/hera/common"
+	"github.com/paypal/hera/utility/encoding/compression"
+	"github.com/paypal/hera/utility/encoding/netstring"
+)
+
+// ProtocolVersionCmd asks the server for the binary framing. It is sent in the same write as the client
+// info, a server not supporting it ignores it
+var ProtocolVersionCmd = netstring.NewNetstringFrom(common.CmdProtocolVersion, []byte(common.ProtocolVersionBinaryFraming))
+
+// ErrServerDraining is returned by ReadServerInfo when the server bounces the connection because it is
+// draining for maintenance. It wraps driver.ErrBadConn, for database/sql to retry on a new connection
+var ErrServerDraining = fmt.Errorf("server draining: %w", driver.ErrBadConn)
+
+// ConnOptions are the protocol options asked in the data source name, then the options agreed by the server
+type ConnOptions struct {
+	// the messages are sent as binary frames
+	BinaryFraming bool
+	// codec of the large responses, empty if they are not compressed
+	Compression string
+}
+
+// ParseDSN splits the data source name into the server address and the options following '?', e.g.
+// "127.0.0.1:10101?framing=binary&compression=deflate". "framing=binary" asks for the binary framing,
+// "compression=deflate" or "compression=snappy" asks the server to compress the large responses
+func ParseDSN(dsn string) (addr string, opts ConnOptions, err error) {
+	pos := strings.IndexByte(dsn, '?')
+	if pos == -1 {
+		return dsn, opts, nil
+	}
+	options, err := url.ParseQuery(dsn[pos+1:])
+	if err != nil {
+		return "", opts, err
+	}
+	for name, values := range options {
+		value := values[len(values)-1]
+		switch name {
+		case "framing":
+			switch value {
+			case "binary":
+				opts.BinaryFraming = true
+			case "text":
+				opts.BinaryFraming = false
+			default:
+				return "", opts, fmt.Errorf("invalid framing %s", value)
+			}
+		case "compression":
+			switch value {
+			case compression.Deflate, compression.Snappy:
+				opts.Compression = value
+			case "none":
+				opts.Compression = ""
+			default:
+				return "", opts, fmt.Errorf("invalid compression %s", value)
+			}
+		default:
+			return "", opts, fmt.Errorf("unknown option %s", name)
+		}
+	}
+	return dsn[:pos], opts, nil
+}
+
+// HandshakeCmds returns the commands asking for the options, to send before the client info
+func (opts ConnOptions) HandshakeCmds() []byte {
+	var cmds []byte
+	if opts.BinaryFraming {
+		cmds = append(cmds, ProtocolVersionCmd.Serialized...)
+	}
+	if len(opts.Compression) > 0 {
+		cmds = append(cmds, netstring.NewNetstringFrom(common.CmdClientCompression, []byte(opts.Compression)).Serialized...)
+	}
+	return cmds
+}
+
+// ReadServerInfo reads the answer to the client info sent during the handshake. It is preceded by the answers
+// to the options asked, a server not supporting an option does not answer it. A draining server answers
+// with CmdServerDraining instead, ErrServerDraining is returned
+func ReadServerInfo(reader *netstring.Reader) (ns *netstring.Netstring, agreed ConnOptions, err error) {
+	for {
+		ns, err = reader.ReadNext()
+		if err != nil {
+			return nil, agreed, err
+		}
+		switch ns.Cmd {
+		case common.CmdProtocolVersion:
+			agreed.BinaryFraming = (string(ns.Payload) == common.ProtocolVersionBinaryFraming)
+		case common.CmdClientCompression:
+			agreed.Compression = string(ns.Payload)
+		case common.CmdServerDraining:
+			return nil, agreed, ErrServerDraining
+		default:
+			return ns, agreed, nil
+		}
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gosqldriver
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"io"
+
+	"github.com/paypal/hera/common"
+	"github.com/paypal/hera/utility/encoding/netstring"
+	"github.com/paypal/hera/utility/logger"
+)
+
+// PipelineResult is the result of one statement of a Pipeline
+type PipelineResult struct {
+	// the number of rows changed by a DML
+	RowsAffected int64
+	// the rows returned by a query, with one value per column
+	Rows [][]driver.Value
+	// the error of the statement, it does not stop the statements following it
+	Err error
+}
+
+type pipelineStmt struct {
+	st    *stmt
+	args  []driver.NamedValue
+	query bool
+	// the request, nil once sent
+	cmd *netstring.Netstring
+}
+
+// pipelineWindow is the maximum number of statements sent whose result was not read. The server stops
+// reading the connection when it queued 64 requests, and the client must read the responses before
+// the socket buffers are full
+const pipelineWindow = 32
+
+// Pipeline sends independent statements back to back, the server runs them in order on the same worker.
+// The results are read in the same order, with Next or Results. The queries return all their rows. At most
+// pipelineWindow statements are in flight, Send reads the results ahead to send more.
+//
+//	p := gosqldriver.InnerConn(conn).NewPipeline()
+//	p.Exec("INSERT INTO t (id, name) VALUES (?, ?)", 1, "a")
+//	p.Query("SELECT name FROM t WHERE id = ?", 2)
+//	results, err := p.Results()
+type Pipeline struct {
+	hera *heraConnection
+	// the statements not sent yet
+	queued []pipelineStmt
+	// the statements sent whose result was not read yet
+	inFlight []pipelineStmt
+	// the results read ahead by Send, not returned yet
+	done []*PipelineResult
+	// set when the connection broke, the results not read are lost
+	err error
+}
+
+// NewPipeline implements the extension HeraConn interface
+func (c *heraConnection) NewPipeline() *Pipeline {
+	return &Pipeline{hera: c}
+}
+
+// Exec adds a statement that doesn't return rows, such as an INSERT or UPDATE
+func (p *Pipeline) Exec(query string, args ...driver.Value) error {
+	return p.add(query, args, false)
+}
+
+// Query adds a query returning rows
+func (p *Pipeline) Query(query string, args ...driver.Value) error {
+	return p.add(query, args, true)
+}
+
+func (p *Pipeline) add(query string, args []driver.Value, isQuery bool) error {
+	st := newStmt(p.hera, query)
+	named := namedValues(args)
+	cmd, err := st.request(named, isQuery)
+	if err != nil {
+		return err
+	}
+	p.queued = append(p.queued, pipelineStmt{st: st, args: named, query: isQuery, cmd: cmd})
+	return nil
+}
+
+// Send sends the statements added since the last Send. It does not wait for the results, unless more than
+// pipelineWindow statements are in flight: the results are then read ahead, and returned by Next
+func (p *Pipeline) Send() error {
+	for {
+		if err := p.sendWindow(); err != nil {
+			return err
+		}
+		if len(p.queued) == 0 {
+			return nil
+		}
+		res, err := p.readResult()
+		if err != nil {
+			return err
+		}
+		p.done = append(p.done, res)
+	}
+}
+
+// sendWindow sends the queued statements in one write, as many as the window allows
+func (p *Pipeline) sendWindow() error {
+	if p.err != nil {
+		return p.err
+	}
+	cnt := pipelineWindow - len(p.inFlight)
+	if cnt > len(p.queued) {
+		cnt = len(p.queued)
+	}
+	if cnt <= 0 {
+		return nil
+	}
+	if logger.GetLogger().V(logger.Debug) {
+		logger.GetLogger().Log(logger.Debug, p.hera.id, "pipeline: sending", cnt, "statements")
+	}
+	cmds := make([]*netstring.Netstring, cnt)
+	for i := range cmds {
+		cmds[i] = p.queued[i].cmd
+		p.queued[i].cmd = nil
+	}
+	p.inFlight = append(p.inFlight, p.queued[:cnt]...)
+	p.queued = p.queued[cnt:]
+	err := p.hera.write(cmds...)
+	if err != nil {
+		p.err = err
+	}
+	return err
+}
+
+// Next returns the result of the next statement, sending the statements not sent yet. It returns io.EOF
+// when all the results were read
+func (p *Pipeline) Next() (*PipelineResult, error) {
+	if len(p.done) > 0 {
+		res := p.done[0]
+		p.done = p.done[1:]
+		return res, nil
+	}
+	if err := p.sendWindow(); err != nil {
+		return nil, err
+	}
+	if len(p.inFlight) == 0 {
+		return nil, io.EOF
+	}
+	return p.readResult()
+}
+
+// readResult reads the result of the first statement in flight, the error returned is for a broken
+// connection
+func (p *Pipeline) readResult() (*PipelineResult, error) {
+	ps := p.inFlight[0]
+	p.inFlight = p.inFlight[1:]
+	cols, nRows, err := ps.st.execResponse()
+	if err != nil {
+		if (err == errReadResponse) || (err == driver.ErrBadConn) {
+			p.err = err
+			return nil, err
+		}
+		return &PipelineResult{Err: err}, nil
+	}
+	if !ps.query {
+		if err = ps.st.readOuts(ps.args); err != nil {
+			p.err = err
+			return nil, err
+		}
+		return &PipelineResult{RowsAffected: int64(nRows)}, nil
+	}
+	return p.readRows(cols)
+}
+
+// readRows reads all the rows of a query
+func (p *Pipeline) readRows(cols int) (*PipelineResult, error) {
+	res := &PipelineResult{}
+	var row []driver.Value
+	for {
+		ns, err := p.hera.getResponse()
+		if err != nil {
+			p.err = err
+			return nil, err
+		}
+		switch ns.Cmd {
+		case common.RcValue:
+			row = append(row, ns.Payload)
+			if len(row) == cols {
+				res.Rows = append(res.Rows, row)
+				row = nil
+			}
+		case common.RcOK, common.RcNoMoreData:
+			return res, nil
+		case common.RcSQLError:
+			res.Err = fmt.Errorf("SQL error: %s", string(ns.Payload))
+			return res, nil
+		case common.RcError:
+			res.Err = fmt.Errorf("Internal hera error: %s", string(ns.Payload))
+			return res, nil
+		}
+	}
+}
+
+// Results sends the statements not sent yet and returns the results not read yet
+func (p *Pipeline) Results() ([]*PipelineResult, error) {
+	results := make([]*PipelineResult, 0, len(p.done)+len(p.inFlight)+len(p.queued))
+	for {
+		res, err := p.Next()
+		if err == io.EOF {
+			return results, nil
+		}
+		if err != nil {
+			return results, err
+		}
+		results = append(results, res)
+	}
+}
@@ -0,0 +1,142 @@
+package gosqldriver
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paypal/hera/common"
+	"github.com/paypal/hera/utility/encoding/netstring"
+)
+
+func value(val string) []byte {
+	return netstring.NewNetstringFrom(common.RcValue, []byte(val)).Serialized
+}
+
+// fakeServer answers the statements once it read count of them, the responses of a pipeline can't
+// be needed before all its requests are sent
+func fakeServer(t *testing.T, conn net.Conn, count int) {
+	reader := netstring.NewNetstringReader(conn)
+	var resp []byte
+	var sql string
+	for done := 0; done < count; {
+		ns, err := reader.ReadNext()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		switch ns.Cmd {
+		case common.CmdPrepareV2:
+			sql = string(ns.Payload)
+		case common.CmdExecute:
+			switch {
+			case strings.HasPrefix(sql, "bad"):
+				resp = append(resp, netstring.NewNetstringFrom(common.RcSQLError, []byte("ORA-00942")).Serialized...)
+				done++
+			case strings.HasPrefix(sql, "select"):
+				resp = append(append(resp, value("2")...), value("0")...)
+			default:
+				resp = append(append(resp, value("0")...), value("1")...)
+				done++
+			}
+		case common.CmdFetch:
+			for _, val := range []string{"a", "1", "b", "2"} {
+				resp = append(resp, value(val)...)
+			}
+			resp = append(resp, "1:6,"...)
+			done++
+		}
+	}
+	conn.Write(resp)
+}
+
+func TestPipeline(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go fakeServer(t, server, 3)
+	hera := NewHeraConnection(client).(*heraConnection)
+	p := hera.NewPipeline()
+	p.Exec("insert into t (id) values (?)", 1)
+	p.Exec("bad sql")
+	p.Query("select name, id from t where id > ?", 0)
+	results, err := p.Results()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	if (results[0].Err != nil) || (results[0].RowsAffected != 1) {
+		t.Errorf("insert: %v %d", results[0].Err, results[0].RowsAffected)
+	}
+	if (results[1].Err == nil) || !strings.Contains(results[1].Err.Error(), "ORA-00942") {
+		t.Errorf("bad sql: expected a SQL error, got %v", results[1].Err)
+	}
+	if (results[2].Err != nil) || (len(results[2].Rows) != 2) || (string(results[2].Rows[1][0].([]byte)) != "b") {
+		t.Errorf("select: %v %v", results[2].Err, results[2].Rows)
+	}
+	if _, err = p.Next(); err == nil {
+		t.Error("expected io.EOF after the last result")
+	}
+}
+
+// queueingServer answers the DMLs like the mux: it queues up to 64 requests while it writes the response of
+// the previous one, and stops reading the connection when the queue is full
+func queueingServer(conn net.Conn) {
+	queue := make(chan []byte, 64)
+	go func() {
+		for resp := range queue {
+			if _, err := conn.Write(resp); err != nil {
+				return
+			}
+		}
+	}()
+	reader := netstring.NewNetstringReader(conn)
+	defer close(queue)
+	for {
+		ns, err := reader.ReadNext()
+		if err != nil {
+			return
+		}
+		if ns.Cmd == common.CmdExecute {
+			queue <- append(value("0"), value("1")...)
+		}
+	}
+}
+
+func TestPipelineWindow(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go queueingServer(server)
+	hera := NewHeraConnection(client).(*heraConnection)
+	p := hera.NewPipeline()
+	const count = 5 * pipelineWindow
+	for i := 0; i < count; i++ {
+		p.Exec("insert into t (id) values (?)", i)
+	}
+	done := make(chan error, 1)
+	go func() {
+		// more statements than the server queues, sent all at once they would block the client and the server
+		if err := p.Send(); err != nil {
+			done <- err
+			return
+		}
+		results, err := p.Results()
+		if (err == nil) && (len(results) != count) {
+			t.Errorf("expected %d results, got %d", count, len(results))
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("pipeline blocked")
+	}
+	if len(p.inFlight) != 0 {
+		t.Errorf("%d statements in flight", len(p.inFlight))
+	}
+}
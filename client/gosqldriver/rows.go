@@ -19,9 +19,9 @@ package gosqldriver
 
 import (
 	"database/sql/driver"
-	"errors"
 	"fmt"
 	"io"
+	"strconv"
 
 	"github.com/paypal/hera/common"
 	"github.com/paypal/hera/utility/encoding/netstring"
@@ -38,11 +38,13 @@ type rows struct {
 	currentRow     int
 	fetchChunkSize []byte
 	completed      bool
+	// the number of columns of the next result set, -1 if there is none
+	nextCols int
 }
 
 // TODO: fetch chunk size
 func newRows(hera *heraConnection, cols int, fetchChunkSize []byte) (*rows, error) {
-	rs := &rows{hera: hera, cols: cols, currentRow: 0, fetchChunkSize: fetchChunkSize}
+	rs := &rows{hera: hera, cols: cols, currentRow: 0, fetchChunkSize: fetchChunkSize, nextCols: -1}
 	err := rs.fetchResults()
 	if err != nil {
 		return nil, err
@@ -67,10 +69,37 @@ func (r *rows) fetchResults() error {
 			}
 			r.completed = true
 			return nil
+		case common.RcMoreResults:
+			// the rows of a stored procedure returning several result sets
+			cols, err := strconv.Atoi(string(ns.Payload))
+			if err != nil {
+				return err
+			}
+			r.nextCols = cols
+			r.completed = true
+			return nil
 		}
 	}
 }
 
+// HasNextResultSet implements driver.RowsNextResultSet, it is called at the end of the current result set
+func (r *rows) HasNextResultSet() bool {
+	return r.nextCols >= 0
+}
+
+// NextResultSet implements driver.RowsNextResultSet, the rows of the next result set are fetched by Next
+func (r *rows) NextResultSet() error {
+	if r.nextCols < 0 {
+		return io.EOF
+	}
+	r.cols = r.nextCols
+	r.nextCols = -1
+	r.vals = r.vals[:0]
+	r.currentRow = 0
+	r.completed = false
+	return nil
+}
+
 // Columns returns the names of the columns. The number of
 // columns of the result is inferred from the length of the
 // slice. If a particular column name isn't known, an empty
@@ -80,9 +109,33 @@ func (r *rows) Columns() []string {
 	return make([]string, r.cols)
 }
 
-// Close closes the rows iterator.
+// Close closes the rows iterator. The rows not read yet, including the ones of the next result sets, are
+// fetched and dropped, the worker is freed only after sending all of them
 func (r *rows) Close() error {
-	return errors.New("Rows.Close() not yet implemented")
+	for {
+		for !r.completed {
+			err := r.fetch()
+			if err != nil {
+				return err
+			}
+		}
+		if r.NextResultSet() == io.EOF {
+			r.vals = nil
+			return nil
+		}
+	}
+}
+
+// fetch sends CmdFetch and reads the next rows of the result set
+func (r *rows) fetch() error {
+	ns := netstring.NewNetstringFrom(common.CmdFetch, r.fetchChunkSize)
+	err := r.hera.execNs(ns)
+	if err != nil {
+		return err
+	}
+	r.vals = r.vals[:0]
+	r.currentRow = 0
+	return r.fetchResults()
 }
 
 // Next is called to populate the next row of data into
@@ -99,17 +152,10 @@ func (r *rows) Next(dest []driver.Value) error {
 			return io.EOF
 		}
 		// fetch the next rows
-		ns := netstring.NewNetstringFrom(common.CmdFetch, r.fetchChunkSize)
-		err := r.hera.execNs(ns)
-		if err != nil {
-			return err
-		}
-		r.vals = r.vals[:0]
-		err = r.fetchResults()
+		err := r.fetch()
 		if err != nil {
 			return err
 		}
-		r.currentRow = 0
 		if len(r.vals) == 0 {
 			return io.EOF
 		}
@@ -20,6 +20,7 @@ package gosqldriver
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"database/sql/driver"
 	"errors"
 	"fmt"
@@ -79,239 +80,94 @@ func (st *stmt) NumInput() int {
 	return -1
 }
 
-// Implements driver.Stmt.
-// Exec executes a query that doesn't return rows, such as an INSERT or UPDATE.
-func (st *stmt) Exec(args []driver.Value) (driver.Result, error) {
-	sk := 0
-	if len(st.hera.shardKeyPayload) > 0 {
-		sk = 1
-	}
-	crid := 0
-	if st.hera.corrID != nil {
-		crid = 1
-	}
-	binds := len(args)
-	nss := make([]*netstring.Netstring, crid /*CmdClientCorrelationID*/ +1 /*CmdPrepare*/ +2*binds /* CmdBindName and CmdBindValue */ +sk /*CmdShardKey*/ +1 /*CmdExecute*/)
-	idx := 0
-	if crid == 1 {
-		nss[0] = st.hera.corrID
-		st.hera.corrID = nil
-		idx++
-	}
-	nss[idx] = netstring.NewNetstringFrom(common.CmdPrepareV2, []byte(st.sql))
-	idx++
-	for _, val := range args {
-		nss[idx] = netstring.NewNetstringFrom(common.CmdBindName, []byte(fmt.Sprintf("p%d", (idx-crid)/2+1)))
-		idx++
-		switch val := val.(type) {
-		default:
-			return nil, fmt.Errorf("unexpected parameter type %T, only int,string and []byte supported", val)
-		case int:
-			nss[idx] = netstring.NewNetstringFrom(common.CmdBindValue, []byte(fmt.Sprintf("%d", int(val))))
-		case int64:
-			nss[idx] = netstring.NewNetstringFrom(common.CmdBindValue, []byte(fmt.Sprintf("%d", int(val))))
-		case []byte:
-			nss[idx] = netstring.NewNetstringFrom(common.CmdBindValue, val)
-		case string:
-			nss[idx] = netstring.NewNetstringFrom(common.CmdBindValue, []byte(val))
-		}
-		if logger.GetLogger().V(logger.Verbose) {
-			logger.GetLogger().Log(logger.Verbose, st.hera.id, "Bind name =", string(nss[idx-1].Payload), ", value=", string(nss[idx].Payload))
-		}
-		idx++
-	}
-	if sk == 1 {
-		nss[idx] = netstring.NewNetstringFrom(common.CmdShardKey, st.hera.shardKeyPayload)
-		idx++
-	}
-	nss[idx] = netstring.NewNetstringFrom(common.CmdExecute, nil)
-	cmd := netstring.NewNetstringEmbedded(nss)
-	err := st.hera.execNs(cmd)
-	if err != nil {
-		return nil, err
-	}
-	ns, err := st.hera.getResponse()
-	if err != nil {
-		return nil, err
+// namedValues converts the arguments of Exec and Query, they are bound by position
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, val := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: val}
 	}
-	if ns.Cmd != common.RcValue {
-		switch ns.Cmd {
-		case common.RcSQLError:
-			return nil, fmt.Errorf("SQL error: %s", string(ns.Payload))
-		case common.RcError:
-			return nil, fmt.Errorf("Internal hera error: %s", string(ns.Payload))
-		default:
-			return nil, fmt.Errorf("Unknown code: %d, data: %s", ns.Cmd, string(ns.Payload))
-		}
-	}
-	// it was columns number, irelevant for DML
-	ns, err = st.hera.getResponse()
-	if err != nil {
-		return nil, err
-	}
-	if ns.Cmd != common.RcValue {
-		return nil, fmt.Errorf("Unknown code2: %d, data: %s", ns.Cmd, string(ns.Payload))
-	}
-	res := &result{}
-	res.nRows, err = strconv.Atoi(string(ns.Payload))
-	if err != nil {
-		return nil, err
-	}
-	if logger.GetLogger().V(logger.Debug) {
-		logger.GetLogger().Log(logger.Debug, st.hera.id, "DML successfull, rows affected:", res.nRows)
-	}
-	return res, nil
+	return named
 }
 
-// Implement driver.StmtExecContext method to execute a DML
-func (st *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
-	//TODO: refactor ExecContext / Exec to reuse code
-	//TODO: honor the context timeout and return when it is canceled
-	sk := 0
-	if len(st.hera.shardKeyPayload) > 0 {
-		sk = 1
-	}
-	crid := 0
-	if st.hera.corrID != nil {
-		crid = 1
-	}
-	binds := len(args)
-	nss := make([]*netstring.Netstring, crid /*CmdClientCalCorrelationID*/ +1 /*CmdPrepare*/ +2*binds /* CmdBindName and BindValue */ +sk /*CmdShardKey*/ +1 /*CmdExecute*/)
-	idx := 0
-	if crid == 1 {
-		nss[0] = st.hera.corrID
-		st.hera.corrID = nil
-		idx++
-	}
-	nss[idx] = netstring.NewNetstringFrom(common.CmdPrepareV2, []byte(st.sql))
-	idx++
-	for _, val := range args {
-		if len(val.Name) > 0 {
-			nss[idx] = netstring.NewNetstringFrom(common.CmdBindName, []byte(val.Name))
-		} else {
-			nss[idx] = netstring.NewNetstringFrom(common.CmdBindName, []byte(fmt.Sprintf("p%d", (idx-crid)/2+1)))
+// CheckNamedValue implements driver.NamedValueChecker, accepting the sql.Out parameters. The other
+// parameters are converted by the default converter
+func (st *stmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if out, ok := nv.Value.(sql.Out); ok {
+		if out.In {
+			return errors.New("in/out parameters are not supported")
 		}
-		idx++
-		switch val := val.Value.(type) {
-		default:
-			return nil, fmt.Errorf("unexpected parameter type %T, only int,string and []byte supported", val)
-		case int:
-			nss[idx] = netstring.NewNetstringFrom(common.CmdBindValue, []byte(fmt.Sprintf("%d", int(val))))
-		case int64:
-			nss[idx] = netstring.NewNetstringFrom(common.CmdBindValue, []byte(fmt.Sprintf("%d", int(val))))
-		case []byte:
-			nss[idx] = netstring.NewNetstringFrom(common.CmdBindValue, val)
-		case string:
-			nss[idx] = netstring.NewNetstringFrom(common.CmdBindValue, []byte(val))
-		}
-		if logger.GetLogger().V(logger.Verbose) {
-			logger.GetLogger().Log(logger.Verbose, st.hera.id, "Bind name =", string(nss[idx-1].Payload), ", value=", string(nss[idx].Payload))
-		}
-		idx++
-	}
-	if sk == 1 {
-		nss[idx] = netstring.NewNetstringFrom(common.CmdShardKey, st.hera.shardKeyPayload)
-		idx++
-	}
-	nss[idx] = netstring.NewNetstringFrom(common.CmdExecute, nil)
-	cmd := netstring.NewNetstringEmbedded(nss)
-	err := st.hera.execNs(cmd)
-	if err != nil {
-		return nil, err
-	}
-	ns, err := st.hera.getResponse()
-	if err != nil {
-		return nil, err
-	}
-	if ns.Cmd != common.RcValue {
-		switch ns.Cmd {
-		case common.RcSQLError:
-			return nil, fmt.Errorf("SQL error: %s", string(ns.Payload))
-		case common.RcError:
-			return nil, fmt.Errorf("Internal hera error: %s", string(ns.Payload))
-		default:
-			return nil, fmt.Errorf("Unknown code: %d, data: %s", ns.Cmd, string(ns.Payload))
-		}
-	}
-	// it was columns number, irelevant for DML
-	ns, err = st.hera.getResponse()
-	if err != nil {
-		return nil, err
-	}
-	if ns.Cmd != common.RcValue {
-		return nil, fmt.Errorf("Unknown code2: %d, data: %s", ns.Cmd, string(ns.Payload))
+		return nil
 	}
-	res := &result{}
-	res.nRows, err = strconv.Atoi(string(ns.Payload))
-	if err != nil {
-		return nil, err
-	}
-	if logger.GetLogger().V(logger.Debug) {
-		logger.GetLogger().Log(logger.Debug, st.hera.id, "DML successfull, rows affected:", res.nRows)
-	}
-	return res, nil
+	return driver.ErrSkip
 }
 
-// Implements driver.Stmt.
-// Query executes a query that may return rows, such as a SELECT.
-func (st *stmt) Query(args []driver.Value) (driver.Rows, error) {
-	sk := 0
-	if len(st.hera.shardKeyPayload) > 0 {
-		sk = 1
-	}
-	crid := 0
+// bindValue returns the CmdBindValue of a parameter
+func bindValue(val driver.Value) (*netstring.Netstring, error) {
+	switch val := val.(type) {
+	case int:
+		return netstring.NewNetstringFrom(common.CmdBindValue, []byte(fmt.Sprintf("%d", val))), nil
+	case int64:
+		return netstring.NewNetstringFrom(common.CmdBindValue, []byte(fmt.Sprintf("%d", val))), nil
+	case []byte:
+		return netstring.NewNetstringFrom(common.CmdBindValue, val), nil
+	case string:
+		return netstring.NewNetstringFrom(common.CmdBindValue, []byte(val)), nil
+	}
+	return nil, fmt.Errorf("unexpected parameter type %T, only int,string and []byte supported", val)
+}
+
+// errOutQuery is returned for out parameters in a query
+var errOutQuery = errors.New("out parameters are only supported by Exec")
+
+// request builds the request executing the statement, followed by the fetch of the first rows for a query
+func (st *stmt) request(args []driver.NamedValue, query bool) (*netstring.Netstring, error) {
+	nss := make([]*netstring.Netstring, 0, 1 /*CmdClientCalCorrelationID*/ +1 /*CmdPrepare*/ +2*len(args) /* CmdBindName and CmdBindValue */ +1 /*CmdShardKey*/ +1 /*CmdExecute*/ +1 /* CmdFetch */)
 	if st.hera.corrID != nil {
-		crid = 1
-	}
-	binds := len(args)
-	nss := make([]*netstring.Netstring, crid /*CmdClientCorrelationID*/ +1 /*CmdPrepare*/ +2*binds /* CmdBindName and BindValue */ +sk /*CmdShardKey*/ +1 /*CmdExecute*/ +1 /* CmdFetch */)
-	idx := 0
-	if crid == 1 {
-		nss[0] = st.hera.corrID
-		st.hera.corrID = nil
-		idx++
+		nss = append(nss, st.hera.corrID)
 	}
-	nss[idx] = netstring.NewNetstringFrom(common.CmdPrepareV2, []byte(st.sql))
-	idx++
-	for _, val := range args {
-		nss[idx] = netstring.NewNetstringFrom(common.CmdBindName, []byte(fmt.Sprintf("p%d", (idx-crid)/2+1)))
-		idx++
-		switch val := val.(type) {
-		default:
-			return nil, fmt.Errorf("unexpected parameter type %T, only int,string and []byte supported", val)
-		case int:
-			nss[idx] = netstring.NewNetstringFrom(common.CmdBindValue, []byte(fmt.Sprintf("%d", val)))
-		case int64:
-			nss[idx] = netstring.NewNetstringFrom(common.CmdBindValue, []byte(fmt.Sprintf("%d", val)))
-		case []byte:
-			nss[idx] = netstring.NewNetstringFrom(common.CmdBindValue, val)
-		case string:
-			nss[idx] = netstring.NewNetstringFrom(common.CmdBindValue, []byte(val))
+	nss = append(nss, netstring.NewNetstringFrom(common.CmdPrepareV2, []byte(st.sql)))
+	for i, val := range args {
+		name := val.Name
+		if len(name) == 0 {
+			name = fmt.Sprintf("p%d", i+1)
+		}
+		if _, ok := val.Value.(sql.Out); ok {
+			if query {
+				return nil, errOutQuery
+			}
+			nss = append(nss, netstring.NewNetstringFrom(common.CmdBindOutName, []byte(name)))
+			continue
 		}
+		nss = append(nss, netstring.NewNetstringFrom(common.CmdBindName, []byte(name)))
+		value, err := bindValue(val.Value)
+		if err != nil {
+			return nil, err
+		}
+		nss = append(nss, value)
 		if logger.GetLogger().V(logger.Verbose) {
-			logger.GetLogger().Log(logger.Verbose, st.hera.id, "Bind name =", string(nss[idx-1].Payload), ", value=", string(nss[idx].Payload))
+			logger.GetLogger().Log(logger.Verbose, st.hera.id, "Bind name =", string(nss[len(nss)-2].Payload), ", value=", string(nss[len(nss)-1].Payload))
 		}
-		idx++
 	}
-	if sk == 1 {
-		nss[idx] = netstring.NewNetstringFrom(common.CmdShardKey, st.hera.shardKeyPayload)
-		idx++
+	if len(st.hera.shardKeyPayload) > 0 {
+		nss = append(nss, netstring.NewNetstringFrom(common.CmdShardKey, st.hera.shardKeyPayload))
 	}
-	nss[idx] = netstring.NewNetstringFrom(common.CmdExecute, nil)
-	idx++
-	nss[idx] = netstring.NewNetstringFrom(common.CmdFetch, st.fetchChunkSize)
-	cmd := netstring.NewNetstringEmbedded(nss)
-	err := st.hera.execNs(cmd)
-	if err != nil {
-		return nil, err
+	nss = append(nss, netstring.NewNetstringFrom(common.CmdExecute, nil))
+	if query {
+		nss = append(nss, netstring.NewNetstringFrom(common.CmdFetch, st.fetchChunkSize))
 	}
+	// the correlation id is sent once
+	st.hera.corrID = nil
+	return netstring.NewNetstringEmbedded(nss), nil
+}
 
+// execResponse reads the response to the execute: the number of columns, then the number of rows
+func (st *stmt) execResponse() (cols int, nRows int, err error) {
 	var ns *netstring.Netstring
 Loop:
 	for {
 		ns, err = st.hera.getResponse()
 		if err != nil {
-			return nil, err
+			return 0, 0, err
 		}
 		if ns.Cmd != common.RcValue {
 			switch ns.Cmd {
@@ -321,138 +177,155 @@ Loop:
 				}
 				// continues the loop
 			case common.RcSQLError:
-				return nil, fmt.Errorf("SQL error: %s", string(ns.Payload))
+				return 0, 0, fmt.Errorf("SQL error: %s", string(ns.Payload))
 			case common.RcError:
-				return nil, fmt.Errorf("Internal hera error: %s", string(ns.Payload))
+				return 0, 0, fmt.Errorf("Internal hera error: %s", string(ns.Payload))
 			default:
-				return nil, fmt.Errorf("Unknown code: %d, data: %s", ns.Cmd, string(ns.Payload))
+				return 0, 0, fmt.Errorf("Unknown code: %d, data: %s", ns.Cmd, string(ns.Payload))
 			}
 		} else {
 			break Loop
 		}
 	}
-	cols, err := strconv.Atoi(string(ns.Payload))
+	cols, err = strconv.Atoi(string(ns.Payload))
 	if err != nil {
-		return nil, err
+		return 0, 0, err
 	}
-
 	ns, err = st.hera.getResponse()
 	if err != nil {
-		return nil, err
+		return 0, 0, err
 	}
 	if ns.Cmd != common.RcValue {
-		return nil, fmt.Errorf("Unknown code2: %d, data: %s", ns.Cmd, string(ns.Payload))
+		return 0, 0, fmt.Errorf("Unknown code2: %d, data: %s", ns.Cmd, string(ns.Payload))
 	}
-	// number of rows is ignored
-	_, err = strconv.Atoi(string(ns.Payload))
+	nRows, err = strconv.Atoi(string(ns.Payload))
+	if err != nil {
+		return 0, 0, err
+	}
+	return cols, nRows, nil
+}
+
+// Implements driver.Stmt.
+// Exec executes a query that doesn't return rows, such as an INSERT or UPDATE.
+func (st *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return st.ExecContext(context.Background(), namedValues(args))
+}
+
+// Implement driver.StmtExecContext method to execute a DML
+func (st *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	//TODO: honor the context timeout and return when it is canceled
+	cmd, err := st.request(args, false)
+	if err != nil {
+		return nil, err
+	}
+	err = st.hera.execNs(cmd)
+	if err != nil {
+		return nil, err
+	}
+	// the columns number is irelevant for DML
+	_, nRows, err := st.execResponse()
+	if err != nil {
+		return nil, err
+	}
+	err = st.readOuts(args)
 	if err != nil {
 		return nil, err
 	}
 	if logger.GetLogger().V(logger.Debug) {
-		logger.GetLogger().Log(logger.Debug, st.hera.id, "Query successfull, num columns:", cols)
+		logger.GetLogger().Log(logger.Debug, st.hera.id, "DML successfull, rows affected:", nRows)
 	}
-	return newRows(st.hera, cols, st.fetchChunkSize)
+	return &result{nRows: nRows}, nil
 }
 
-// Implements driver.StmtQueryContextx
-// QueryContext executes a query that may return rows, such as a SELECT
-func (st *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
-	// TODO: refactor Query/QueryContext to reuse code
-	// TODO: honor the context timeout and return when it is canceled
-	sk := 0
-	if len(st.hera.shardKeyPayload) > 0 {
-		sk = 1
+// readOuts reads the values of the out parameters, following the number of rows changed
+func (st *stmt) readOuts(args []driver.NamedValue) error {
+	var outs []sql.Out
+	for _, arg := range args {
+		if out, ok := arg.Value.(sql.Out); ok {
+			outs = append(outs, out)
+		}
 	}
-	crid := 0
-	if st.hera.corrID != nil {
-		crid = 1
+	if len(outs) == 0 {
+		return nil
 	}
-	binds := len(args)
-	nss := make([]*netstring.Netstring, crid /*ClientCalCorrelationID*/ +1 /*CmdPrepare*/ +2*binds /* CmdBindName and BindValue */ +sk /*ShardKey*/ +1 /*Execute*/ +1 /* Fetch */)
-	idx := 0
-	if crid == 1 {
-		nss[0] = st.hera.corrID
-		st.hera.corrID = nil
-		idx++
+	ns, err := st.hera.getResponse()
+	if err != nil {
+		return err
 	}
-	nss[idx] = netstring.NewNetstringFrom(common.CmdPrepareV2, []byte(st.sql))
-	idx++
-	for _, val := range args {
-		if len(val.Name) > 0 {
-			nss[idx] = netstring.NewNetstringFrom(common.CmdBindName, []byte(val.Name))
-		} else {
-			nss[idx] = netstring.NewNetstringFrom(common.CmdBindName, []byte(fmt.Sprintf("p%d", (idx-crid)/2+1)))
+	if (ns.Cmd != common.RcValue) || (string(ns.Payload) != "1") {
+		return fmt.Errorf("Unknown code3: %d, data: %s", ns.Cmd, string(ns.Payload))
+	}
+	for _, out := range outs {
+		ns, err = st.hera.getResponse()
+		if err != nil {
+			return err
 		}
-		idx++
-		switch val := val.Value.(type) {
-		default:
-			return nil, fmt.Errorf("unexpected parameter type %T, only int,string and []byte supported", val)
-		case int:
-			nss[idx] = netstring.NewNetstringFrom(common.CmdBindValue, []byte(fmt.Sprintf("%d", val)))
-		case int64:
-			nss[idx] = netstring.NewNetstringFrom(common.CmdBindValue, []byte(fmt.Sprintf("%d", val)))
-		case []byte:
-			nss[idx] = netstring.NewNetstringFrom(common.CmdBindValue, val)
-		case string:
-			nss[idx] = netstring.NewNetstringFrom(common.CmdBindValue, []byte(val))
+		if ns.Cmd != common.RcValue {
+			return fmt.Errorf("Unknown code4: %d, data: %s", ns.Cmd, string(ns.Payload))
 		}
-		if logger.GetLogger().V(logger.Verbose) {
-			logger.GetLogger().Log(logger.Verbose, st.hera.id, "Bind name =", string(nss[idx-1].Payload), ", value=", string(nss[idx].Payload))
+		err = setOut(out.Dest, ns.Payload)
+		if err != nil {
+			return err
 		}
-		idx++
-	}
-	if sk == 1 {
-		nss[idx] = netstring.NewNetstringFrom(common.CmdShardKey, st.hera.shardKeyPayload)
-		idx++
-	}
-	nss[idx] = netstring.NewNetstringFrom(common.CmdExecute, nil)
-	idx++
-	nss[idx] = netstring.NewNetstringFrom(common.CmdFetch, st.fetchChunkSize)
-	cmd := netstring.NewNetstringEmbedded(nss)
-	err := st.hera.execNs(cmd)
-	if err != nil {
-		return nil, err
 	}
+	return nil
+}
 
-	var ns *netstring.Netstring
-Loop:
-	for {
-		ns, err = st.hera.getResponse()
+// setOut sets the destination of an out parameter, an empty value is NULL
+func setOut(dest interface{}, val []byte) error {
+	switch dest := dest.(type) {
+	case *string:
+		*dest = string(val)
+	case *[]byte:
+		*dest = append([]byte(nil), val...)
+	case *sql.NullString:
+		*dest = sql.NullString{String: string(val), Valid: len(val) > 0}
+	case *int64:
+		if len(val) == 0 {
+			*dest = 0
+			return nil
+		}
+		num, err := strconv.ParseInt(string(val), 10, 64)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		if ns.Cmd != common.RcValue {
-			switch ns.Cmd {
-			case common.RcStillExecuting:
-				if logger.GetLogger().V(logger.Info) {
-					logger.GetLogger().Log(logger.Info, st.hera.id, " Still executing ...")
-				}
-				// continues the loop
-			case common.RcSQLError:
-				return nil, fmt.Errorf("SQL error: %s", string(ns.Payload))
-			case common.RcError:
-				return nil, fmt.Errorf("Internal hera error: %s", string(ns.Payload))
-			default:
-				return nil, fmt.Errorf("Unknown code: %d, data: %s", ns.Cmd, string(ns.Payload))
-			}
-		} else {
-			break Loop
+		*dest = num
+	case *int:
+		if len(val) == 0 {
+			*dest = 0
+			return nil
 		}
+		num, err := strconv.Atoi(string(val))
+		if err != nil {
+			return err
+		}
+		*dest = num
+	default:
+		return fmt.Errorf("unexpected out parameter type %T, only *string, *[]byte, *sql.NullString, *int64 and *int supported", dest)
 	}
-	cols, err := strconv.Atoi(string(ns.Payload))
+	return nil
+}
+
+// Implements driver.Stmt.
+// Query executes a query that may return rows, such as a SELECT.
+func (st *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return st.QueryContext(context.Background(), namedValues(args))
+}
+
+// Implements driver.StmtQueryContextx
+// QueryContext executes a query that may return rows, such as a SELECT
+func (st *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	// TODO: honor the context timeout and return when it is canceled
+	cmd, err := st.request(args, true)
 	if err != nil {
 		return nil, err
 	}
-
-	ns, err = st.hera.getResponse()
+	err = st.hera.execNs(cmd)
 	if err != nil {
 		return nil, err
 	}
-	if ns.Cmd != common.RcValue {
-		return nil, fmt.Errorf("Unknown code2: %d, data: %s", ns.Cmd, string(ns.Payload))
-	}
 	// number of rows is ignored
-	_, err = strconv.Atoi(string(ns.Payload))
+	cols, _, err := st.execResponse()
 	if err != nil {
 		return nil, err
 	}
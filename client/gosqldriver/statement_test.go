@@ -0,0 +1,172 @@
+package gosqldriver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/paypal/hera/common"
+	"github.com/paypal/hera/utility/encoding/netstring"
+)
+
+func TestExecOutParams(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		reader := netstring.NewNetstringReader(server)
+		var outs int
+		for {
+			ns, err := reader.ReadNext()
+			if err != nil {
+				return
+			}
+			switch ns.Cmd {
+			case common.CmdBindOutName:
+				outs++
+			case common.CmdExecute:
+				if outs != 2 {
+					t.Errorf("expected 2 out binds, got %d", outs)
+				}
+				resp := append(append(value("0"), value("1")...), value("1")...)
+				resp = append(append(resp, value("alice")...), value("42")...)
+				server.Write(resp)
+			}
+		}
+	}()
+	hera := NewHeraConnection(client).(*heraConnection)
+	st, err := hera.Prepare("call get_user(?, ?, ?)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var name string
+	var age int64
+	args := []driver.NamedValue{{Ordinal: 1, Value: int64(7)}, {Ordinal: 2, Value: sql.Out{Dest: &name}}, {Ordinal: 3, Value: sql.Out{Dest: &age}}}
+	if _, err = st.(driver.StmtExecContext).ExecContext(context.Background(), args); err != nil {
+		t.Fatal(err)
+	}
+	if (name != "alice") || (age != 42) {
+		t.Errorf("expected alice, 42, got %s, %d", name, age)
+	}
+	if _, err = st.(driver.StmtQueryContext).QueryContext(context.Background(), args); err != errOutQuery {
+		t.Errorf("expected %v for out parameters in a query, got %v", errOutQuery, err)
+	}
+}
+
+func TestNextResultSet(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		reader := netstring.NewNetstringReader(server)
+		fetches := 0
+		for {
+			ns, err := reader.ReadNext()
+			if err != nil {
+				return
+			}
+			switch ns.Cmd {
+			case common.CmdExecute:
+				server.Write(append(value("1"), value("0")...))
+			case common.CmdFetch:
+				fetches++
+				if fetches == 1 {
+					// the first result set has one column, the second one two
+					resp := append(append(value("a"), value("b")...), netstring.NewNetstringFrom(common.RcMoreResults, []byte("2")).Serialized...)
+					server.Write(resp)
+				} else {
+					resp := append(append(value("c"), value("3")...), netstring.NewNetstringFrom(common.RcNoMoreData, nil).Serialized...)
+					server.Write(resp)
+				}
+			}
+		}
+	}()
+	hera := NewHeraConnection(client).(*heraConnection)
+	st, err := hera.Prepare("call list_users()")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := st.(driver.StmtQueryContext).QueryContext(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := rs.(*rows)
+	dest := make([]driver.Value, 1)
+	for i := 0; i < 2; i++ {
+		if err = r.Next(dest); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = r.Next(dest); err != io.EOF {
+		t.Fatalf("expected the end of the first result set, got %v", err)
+	}
+	if !r.HasNextResultSet() {
+		t.Fatal("expected a second result set")
+	}
+	if err = r.NextResultSet(); err != nil {
+		t.Fatal(err)
+	}
+	dest = make([]driver.Value, len(r.Columns()))
+	if err = r.Next(dest); err != nil {
+		t.Fatal(err)
+	}
+	if (len(dest) != 2) || (string(dest[0].([]byte)) != "c") {
+		t.Errorf("unexpected row of the second result set %v", dest)
+	}
+	if r.HasNextResultSet() {
+		t.Error("expected no third result set")
+	}
+}
+
+func TestRowsCloseDrainsResultSets(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	fetched := make(chan int, 1)
+	go func() {
+		reader := netstring.NewNetstringReader(server)
+		fetches := 0
+		for {
+			ns, err := reader.ReadNext()
+			if err != nil {
+				fetched <- fetches
+				return
+			}
+			switch ns.Cmd {
+			case common.CmdExecute:
+				server.Write(append(value("1"), value("0")...))
+			case common.CmdFetch:
+				fetches++
+				switch fetches {
+				case 1:
+					server.Write(append(value("a"), netstring.NewNetstringFrom(common.RcOK, nil).Serialized...))
+				case 2:
+					server.Write(append(value("b"), netstring.NewNetstringFrom(common.RcMoreResults, []byte("1")).Serialized...))
+				default:
+					server.Write(append(value("c"), netstring.NewNetstringFrom(common.RcNoMoreData, nil).Serialized...))
+				}
+			}
+		}
+	}()
+	hera := NewHeraConnection(client).(*heraConnection)
+	st, err := hera.Prepare("call list_users()")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := st.(driver.StmtQueryContext).QueryContext(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dest := make([]driver.Value, 1)
+	if err = rs.Next(dest); err != nil {
+		t.Fatal(err)
+	}
+	// the rest of the first result set and the second one are fetched
+	if err = rs.Close(); err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+	if fetches := <-fetched; fetches != 3 {
+		t.Errorf("expected 3 fetches, got %d", fetches)
+	}
+}
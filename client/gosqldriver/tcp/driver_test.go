@@ -0,0 +1,79 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcp
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"github.com/paypal/hera/common"
+	"github.com/paypal/hera/utility/encoding/netstring"
+)
+
+// drainingServer bounces every connection like a draining mux, it returns its address and the number of
+// connections accepted
+func drainingServer(t *testing.T) (string, *int32) {
+	lsn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { lsn.Close() })
+	var accepted int32
+	go func() {
+		for {
+			conn, err := lsn.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&accepted, 1)
+			// the mux answers the client info with CmdServerDraining and closes the connection
+			reader := netstring.NewNetstringReader(conn)
+			if _, err = reader.ReadNext(); err == nil {
+				conn.Write(netstring.NewNetstringFrom(common.CmdServerDraining, []byte("draining")).Serialized)
+			}
+			conn.Close()
+		}
+	}()
+	return lsn.Addr().String(), &accepted
+}
+
+func TestOpenServerDraining(t *testing.T) {
+	addr, accepted := drainingServer(t)
+	conn, err := drv.Open(addr)
+	if (conn != nil) || !errors.Is(err, driver.ErrBadConn) {
+		t.Fatalf("expected a bad connection from a draining server, got %v", err)
+	}
+
+	// database/sql dials again on a bad connection
+	db, err := sql.Open("hera", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	before := atomic.LoadInt32(accepted)
+	if err = db.Ping(); !errors.Is(err, driver.ErrBadConn) {
+		t.Errorf("expected a bad connection, got %v", err)
+	}
+	if dials := atomic.LoadInt32(accepted) - before; dials < 2 {
+		t.Errorf("expected database/sql to dial again, dialed %d times", dials)
+	}
+}
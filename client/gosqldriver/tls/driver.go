@@ -23,6 +23,9 @@
 //  import _ "github.com/paypal/hera/client/gosqldriver/tls"
 //
 //  db, err := sql.Open("hera", "1:<ip>:<port>")
+//
+// Adding "?framing=binary" to the data source name asks the server for the binary framing, "?compression=deflate"
+// asks it to compress the large responses. The options are combined with '&'.
 package tls
 
 import (
@@ -58,7 +61,11 @@ func (driver *heraDriver) Open(url string) (driver.Conn, error) {
 	if logger.GetLogger().V(logger.Info) {
 		logger.GetLogger().Log(logger.Info, "Dialing to hera server:", ipport)
 	}
-	conn, err := tls.Dial("tcp", ipport, HeraTLSDrv.TLSCfg)
+	addr, opts, err := gosqldriver.ParseDSN(ipport)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := tls.Dial("tcp", addr, HeraTLSDrv.TLSCfg)
 
 	if err != nil {
 		if logger.GetLogger().V(logger.Warning) {
@@ -71,20 +78,32 @@ func (driver *heraDriver) Open(url string) (driver.Conn, error) {
 	}
 
 	reader := netstring.NewNetstringReader(conn)
+	if opts.BinaryFraming {
+		// the answers following the protocol version are binary frames
+		reader = netstring.NewFrameReader(conn)
+	}
 
 	// send client info
 	pid := os.Getpid()
 	host, _ := os.Hostname()
 	helloCmd := netstring.NewNetstringFrom(common.CmdClientInfo, []byte(fmt.Sprintf("PID: %d,HOST: %s, EXEC: %d@%s, Poolname: unset, Command: init, null, Name: GO_driver", pid, host, pid, host)))
 
-	_, err = conn.Write(helloCmd.Serialized)
+	hello := append(opts.HandshakeCmds(), helloCmd.Serialized...)
+	_, err = conn.Write(hello)
 	if err != nil {
 		if logger.GetLogger().V(logger.Warning) {
 			logger.GetLogger().Log(logger.Warning, "Failed to send client info")
 		}
 		return nil, errors.New("Failed custom auth, failed to send client info")
 	}
-	ns, err := reader.ReadNext()
+	ns, opts, err := gosqldriver.ReadServerInfo(reader)
+	if errors.Is(err, gosqldriver.ErrServerDraining) {
+		if logger.GetLogger().V(logger.Info) {
+			logger.GetLogger().Log(logger.Info, "Server draining, connection bounced:", url)
+		}
+		conn.Close()
+		return nil, err
+	}
 	if err != nil {
 		if logger.GetLogger().V(logger.Warning) {
 			logger.GetLogger().Log(logger.Warning, "Failed to read server info")
@@ -95,5 +114,5 @@ func (driver *heraDriver) Open(url string) (driver.Conn, error) {
 		logger.GetLogger().Log(logger.Debug, "Server info:", string(ns.Payload))
 	}
 
-	return gosqldriver.NewHeraConnection(conn), nil
+	return gosqldriver.NewHeraConnectionOptions(conn, opts), nil
 }
@@ -35,6 +35,7 @@ func (t *tx) cmd(cmd int) error {
 	}
 	hera := t.hera
 	t.hera = nil
+	hera.inTx = false
 	err := hera.exec(cmd, nil)
 	if err != nil {
 		return err
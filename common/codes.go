@@ -28,6 +28,8 @@ const (
 	RcOK             = 5
 	RcNoMoreData     = 6
 	RcStillExecuting = 7
+	RcCompressed     = 8 // the payload is the compressed responses, with the codec negotiated by CmdClientCompression
+	RcMoreResults    = 9 // ends the rows of a result set, the payload is the number of columns of the next one
 )
 
 // Commands
@@ -53,6 +55,8 @@ const (
 	CmdShardKey         = 27
 	CmdGetNumShards     = 28
 	CmdSetShardID       = 29
+	CmdSetSessionVars   = 30 // the payload is one "name=value" per line, empty to clear
+	CmdBatchCommit      = 31 // before CmdExecute of a batch run outside of a transaction: it is committed, or rolled back if a row fails
 )
 
 // DataType defines Bind data types
@@ -84,6 +88,7 @@ const (
 	CmdServerConnectionRejectedClientTime  = 1010
 	CmdServerInfo                          = 1011
 	CmdServerIntInfo                       = 1012
+	CmdServerDraining                      = 1013 // the server is draining, the client should reconnect
 
 	CmdClientProtocolNameNoAuth = 2001
 	CmdClientProtocolName       = 2002
@@ -95,4 +100,14 @@ const (
 	CmdClientCalCorrelationID = 2006
 
 	CmdProtocolVersion = 2008
+	// the client lists the compression codecs it supports, the server answers with the codec it uses
+	CmdClientCompression = 2009
+)
+
+// CmdProtocolVersion payloads. The client asks for a version and the server answers with the version it uses
+const (
+	// ProtocolVersionText is the text netstring protocol
+	ProtocolVersionText = "1"
+	// ProtocolVersionBinaryFraming uses binary frames on the client connection once the server answered
+	ProtocolVersionBinaryFraming = "2"
 )
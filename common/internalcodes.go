@@ -33,9 +33,13 @@ const (
 	EORMoreIncomingRequests     = 4 /* worker would be free, but it is not because there are more requests on the incomming buffer because
 	they were pipelined by the client */
 	EORBusyOther = 5 /* not used yet */
-	EORRestart   = 6
+	EORRestart   = 6 /* the request is done, the worker is free for the client but exits instead of serving another one */
 )
 
+// WorkerExitReadOnly is the exit code of a worker whose database became read-only, e.g. after a failover
+// promoted another database. The mux recycles the other workers of the pool
+const WorkerExitReadOnly = 96
+
 // Reasons for stranded child
 const (
 	StrandedClientClose       = 4
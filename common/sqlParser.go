@@ -25,16 +25,22 @@ import (
 //
 // IsRead tells is the SQL is doing a read, basically a SELECT but not a SELECT ... FOR UPDATE or nextval.
 // Parse tells if the SQL is a select and if the SQL starts a transaction
+// AltersSession tells if the SQL changes the state of the session beyond the transaction: SET, temporary tables,
+// prepared statements, session locks
 type SQLParser interface {
 	IsRead(string) bool
 	Parse(sql string) (isSelect bool, transaction bool)
 	MustExecInsteadOfPrepare(sql string) (bool)
+	AltersSession(sql string) bool
 }
 
 type regexSQLParser struct {
 	matcher          *regexp.Regexp
 	matcherForUpdate *regexp.Regexp
 	matcherStartTransaction *regexp.Regexp
+	matcherSession          *regexp.Regexp
+	matcherSessionLock      *regexp.Regexp
+	matcherTransactionSet   *regexp.Regexp
 }
 
 type dummyParser struct {
@@ -56,6 +62,20 @@ func NewRegexSQLParser() (SQLParser, error) {
 	if err != nil {
 		return nil, err
 	}
+	parser.matcherSession, err = regexp.Compile("(?i)^\\s*(/\\*.*\\*/)*\\s*(set\\s|alter\\s+session\\s|create\\s+(global\\s+|local\\s+)?temp(orary)?\\s|prepare\\s|use\\s|lock\\s+tables?\\s|listen\\s)")
+	if err != nil {
+		return nil, err
+	}
+	// the session locks, the transaction ones (pg_advisory_xact_lock) are released at the end of the transaction
+	parser.matcherSessionLock, err = regexp.Compile("(?i)(pg_(try_)?advisory_lock|get_lock\\s*\\(|dbms_lock\\.|dbms_session\\.)")
+	if err != nil {
+		return nil, err
+	}
+	// the SETs limited to the transaction
+	parser.matcherTransactionSet, err = regexp.Compile("(?i)^\\s*(/\\*.*\\*/)*\\s*set\\s+(local|transaction)\\s")
+	if err != nil {
+		return nil, err
+	}
 	return parser, nil
 }
 
@@ -90,6 +110,15 @@ func (parser *regexSQLParser) Parse(sql string) (bool, bool) {
 	return false, true
 }
 
+// AltersSession tells if the SQL changes the state of the session beyond the transaction, so that the session
+// must be reset before another client uses it
+func (parser *regexSQLParser) AltersSession(sql string) bool {
+	if parser.matcherSession.MatchString(sql) {
+		return !parser.matcherTransactionSet.MatchString(sql)
+	}
+	return parser.matcherSessionLock.MatchString(sql)
+}
+
 // NewDummyParser crestes a parser that always returns false
 func NewDummyParser() SQLParser {
 	return &dummyParser{}
@@ -99,6 +128,10 @@ func (parser *dummyParser) MustExecInsteadOfPrepare(sql string) bool {
 	return false
 }
 
+func (parser *dummyParser) AltersSession(sql string) bool {
+	return false
+}
+
 func (parser *dummyParser) IsRead(sql string) bool {
 	return false
 }
@@ -75,3 +75,35 @@ func TestDumySQLParser(t *testing.T) {
 	}
 	t.Log("----Done TestSQLParser")
 }
+
+func TestSQLParserAltersSession(t *testing.T) {
+	parser, err := NewRegexSQLParser()
+	if err != nil {
+		t.Fatal("Fail to create the parser: " + err.Error())
+	}
+	for sql, expected := range map[string]bool{
+		"SET search_path TO app":                              true,
+		"/* hera */ set @x = 1":                               true,
+		"set session sql_mode='ANSI'":                         true,
+		"SET LOCAL statement_timeout = 1000":                  false,
+		"set transaction isolation level serializable":        false,
+		"CREATE TEMPORARY TABLE t (id int)":                   true,
+		"create global temporary table t (id number)":         true,
+		"create table t (id int)":                             false,
+		"PREPARE stmt1 FROM 'select 1'":                       true,
+		"alter session set nls_date_format='YYYY-MM-DD'":      true,
+		"select pg_advisory_lock(1)":                          true,
+		"select pg_advisory_xact_lock(1)":                     false,
+		"SELECT GET_LOCK('app', 10)":                          true,
+		"begin dbms_session.set_context('ctx', 'a', 1); end;": true,
+		"update foo set bar = 1":                              false,
+		"select foo from bar":                                 false,
+	} {
+		if parser.AltersSession(sql) != expected {
+			t.Errorf("%q alters the session: %v, expected %v", sql, !expected, expected)
+		}
+	}
+	if NewDummyParser().AltersSession("set @x = 1") {
+		t.Error("dummy parser detects a session change")
+	}
+}
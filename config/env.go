@@ -0,0 +1,60 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+// EnvPrefix is the prefix of the environment variables overriding the config files
+const EnvPrefix = "HERA_"
+
+type envConfig struct {
+	data map[string][]byte
+}
+
+// NewEnvConfig returns a RawConfig with the environment variables starting with prefix. The key of a variable
+// is its name without the prefix, in lower case, with "__" standing for ".": HERA_BIND_PORT is "bind_port"
+// and HERA_OPSCFG__HERA__SERVER__LOG_LEVEL is "opscfg.hera.server.log_level". The environment is read once
+func NewEnvConfig(prefix string) RawConfig {
+	cfg := &envConfig{data: make(map[string][]byte)}
+	for _, env := range os.Environ() {
+		eq := strings.Index(env, "=")
+		if (eq < 0) || !strings.HasPrefix(env[:eq], prefix) || (eq == len(prefix)) {
+			continue
+		}
+		key := strings.ToLower(strings.ReplaceAll(env[len(prefix):eq], "__", "."))
+		cfg.data[key] = []byte(env[eq+1:])
+	}
+	return cfg
+}
+
+// implements rawConfig
+func (cfg *envConfig) GetAllValues() map[string][]byte {
+	return cfg.data
+}
+
+// implements rawConfig
+func (cfg *envConfig) GetValue(key string) ([]byte, error) {
+	val, ok := cfg.data[key]
+	if ok {
+		return val, nil
+	}
+	return nil, ErrNotFound
+}
@@ -0,0 +1,128 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Layer is a RawConfig of a LayeredConfig
+type Layer struct {
+	// Name is the source of the values of the layer, reported by LayeredConfig.Source
+	Name   string
+	Config RawConfig
+}
+
+// LayeredConfig is a RawConfig where a key takes its value from the first layer having it
+type LayeredConfig struct {
+	layers []Layer
+	all    map[string][]byte
+}
+
+// NewLayeredConfig creates a LayeredConfig, the layers are given from the highest precedence to the lowest
+func NewLayeredConfig(layers ...Layer) *LayeredConfig {
+	cfg := &LayeredConfig{layers: layers, all: make(map[string][]byte)}
+	for i := len(layers) - 1; i >= 0; i-- {
+		for key, val := range layers[i].Config.GetAllValues() {
+			cfg.all[key] = val
+		}
+	}
+	return cfg
+}
+
+// implements rawConfig
+func (cfg *LayeredConfig) GetAllValues() map[string][]byte {
+	return cfg.all
+}
+
+// implements rawConfig
+func (cfg *LayeredConfig) GetValue(key string) ([]byte, error) {
+	val, ok := cfg.all[key]
+	if ok {
+		return val, nil
+	}
+	return nil, ErrNotFound
+}
+
+// Source returns the name of the layer giving the value of the key, empty if no layer has it
+func (cfg *LayeredConfig) Source(key string) string {
+	for _, layer := range cfg.layers {
+		if _, err := layer.Config.GetValue(key); err == nil {
+			return layer.Name
+		}
+	}
+	return ""
+}
+
+// Layers returns the names of the layers, from the highest precedence to the lowest
+func (cfg *LayeredConfig) Layers() []string {
+	names := make([]string, len(cfg.layers))
+	for i, layer := range cfg.layers {
+		names[i] = layer.Name
+	}
+	return names
+}
+
+// layerFiles returns the files layered over a txt config file, from the highest precedence to the lowest:
+// the YAML and JSON files with the same name, then the file itself
+func layerFiles(filename string) []string {
+	base := strings.TrimSuffix(filename, filepath.Ext(filename))
+	return []string{base + ".yaml", base + ".yml", base + ".json", filename}
+}
+
+// NewFileConfig creates a Config for a txt config file (see NewTxtConfig) overridden by the YAML or JSON
+// file with the same name (hera.yaml, hera.yml or hera.json for hera.txt), themselves overridden by the
+// HERA_* environment variables (see NewEnvConfig). The files which don't exist are skipped, it returns an
+// error if none exists
+func NewFileConfig(filename string) (Config, error) {
+	cfg, err := newFileConfig(filename)
+	if err != nil {
+		return nil, err
+	}
+	return NewConfig(cfg), nil
+}
+
+func newFileConfig(filename string) (*LayeredConfig, error) {
+	layers := []Layer{{Name: SourceEnv, Config: NewEnvConfig(EnvPrefix)}}
+	var err error
+	for _, file := range layerFiles(filename) {
+		var raw RawConfig
+		switch filepath.Ext(file) {
+		case ".yaml", ".yml":
+			raw, err = NewYAMLConfig(file)
+		case ".json":
+			raw, err = NewJSONConfig(file)
+		default:
+			raw, err = newTxtConfig(file)
+		}
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		layers = append(layers, Layer{Name: filepath.Base(file), Config: raw})
+	}
+	if len(layers) == 1 {
+		// the error of the txt file
+		return nil, err
+	}
+	return NewLayeredConfig(layers...), nil
+}
@@ -0,0 +1,131 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, dir string, name string, content string) string {
+	filename := filepath.Join(dir, name)
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestYAMLConfig(t *testing.T) {
+	dir := t.TempDir()
+	cfg, err := NewYAMLConfig(writeFile(t, dir, "hera.yaml", `
+bind_port: 10101
+child.executable: mysqlworker
+enable_taf: true
+bind_eviction_decr_per_sec: 2.5
+hostname_prefix: [a, b]
+opscfg:
+  hera:
+    server:
+      log_level: 3
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, expected := range map[string]string{
+		"bind_port":                    "10101",
+		"child.executable":             "mysqlworker",
+		"enable_taf":                   "true",
+		"bind_eviction_decr_per_sec":   "2.5",
+		"hostname_prefix":              "a,b",
+		"opscfg.hera.server.log_level": "3",
+	} {
+		val, err := cfg.GetValue(key)
+		if (err != nil) || (string(val) != expected) {
+			t.Errorf("%s = %s %v, expected %s", key, val, err, expected)
+		}
+	}
+	if _, err = NewYAMLConfig(writeFile(t, dir, "bad.yaml", "- a\n- b\n")); err == nil {
+		t.Error("YAML list accepted as config")
+	}
+}
+
+func TestJSONConfig(t *testing.T) {
+	cfg, err := NewJSONConfig(writeFile(t, t.TempDir(), "hera.json",
+		`{"bind_port": 10101, "max_scuttle": 12345678901, "opscfg": {"default": {"server": {"max_connections": 4}}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewConfig(cfg)
+	if c.GetOrDefaultInt("bind_port", 0) != 10101 || c.GetOrDefaultString("max_scuttle", "") != "12345678901" ||
+		c.GetOrDefaultInt("opscfg.default.server.max_connections", 0) != 4 {
+		t.Errorf("unexpected values:\n%s", c.Dump())
+	}
+}
+
+func TestEnvConfig(t *testing.T) {
+	t.Setenv("HERA_BIND_PORT", "10102")
+	t.Setenv("HERA_OPSCFG__HERA__SERVER__LOG_LEVEL", "4")
+	t.Setenv("HERA_", "ignored")
+	cfg := NewConfig(NewEnvConfig(EnvPrefix))
+	if cfg.GetOrDefaultInt("bind_port", 0) != 10102 || cfg.GetOrDefaultInt("opscfg.hera.server.log_level", 0) != 4 {
+		t.Errorf("unexpected values:\n%s", cfg.Dump())
+	}
+	if _, err := cfg.GetString(""); err != ErrNotFound {
+		t.Error("empty key set")
+	}
+}
+
+func TestFileConfig(t *testing.T) {
+	dir := t.TempDir()
+	txt := writeFile(t, dir, "hera.txt", "bind_port=10101\nlog_level=2\nmax_scuttle=16\n")
+	writeFile(t, dir, "hera.yaml", "log_level: 3\nnum_shards: 4\n")
+	t.Setenv("HERA_MAX_SCUTTLE", "32")
+	t.Setenv("HERA_NUM_SHARDS", "8")
+
+	raw, err := newFileConfig(txt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, expected := range map[string]string{
+		"bind_port":   "10101/hera.txt",
+		"log_level":   "3/hera.yaml",
+		"max_scuttle": "32/env",
+		"num_shards":  "8/env",
+	} {
+		val, _ := raw.GetValue(key)
+		if got := string(val) + "/" + raw.Source(key); got != expected {
+			t.Errorf("%s = %s, expected %s", key, got, expected)
+		}
+	}
+
+	// the YAML file is enough
+	cfg, err := NewFileConfig(filepath.Join(dir, "missing.txt"))
+	if err == nil {
+		t.Errorf("config without file: %s", cfg.Dump())
+	}
+	os.Remove(txt)
+	cfg, err = NewFileConfig(txt)
+	if (err != nil) || (cfg.GetOrDefaultInt("log_level", 0) != 3) {
+		t.Errorf("config with only YAML: %v", err)
+	}
+	writeFile(t, dir, "hera.json", "{")
+	if _, err = NewFileConfig(txt); err == nil {
+		t.Error("invalid JSON accepted")
+	}
+}
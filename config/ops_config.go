@@ -74,7 +74,7 @@ func InitOpsConfigWithName(name string) error {
 }
 
 func (cfg *opsConfig) Load() error {
-	cfg.cfg, cfg.err = NewTxtConfig(cfg.cfgName)
+	cfg.cfg, cfg.err = NewFileConfig(cfg.cfgName)
 	return cfg.err
 }
 
@@ -82,14 +82,26 @@ func (cfg *opsConfig) Changed() bool {
 	if len(cfg.cfgName) == 0 {
 		return false
 	}
-	stat, err := os.Stat(cfg.cfgName)
-	if err != nil {
+	// the latest change of the file and of its YAML and JSON layers
+	var modTime time.Time
+	var err error
+	for _, file := range layerFiles(cfg.cfgName) {
+		stat, statErr := os.Stat(file)
+		if statErr != nil {
+			err = statErr
+			continue
+		}
+		if stat.ModTime().After(modTime) {
+			modTime = stat.ModTime()
+		}
+	}
+	if modTime.IsZero() {
 		cfg.err = err
 		return false
 	}
 
-	if stat.ModTime() != cfg.lastModTime {
-		cfg.lastModTime = stat.ModTime()
+	if modTime != cfg.lastModTime {
+		cfg.lastModTime = modTime
 		return true
 	}
 	return false
@@ -190,7 +202,7 @@ func (cfg *opsConfig) Dump() string {
 // returns the file name where the ops config is
 func getCalPoolName() (string, error) {
 	// TODO: use CalClient::get_poolName()
-	calCdb, err := NewTxtConfig("cal_client.txt")
+	calCdb, err := NewFileConfig("cal_client.txt")
 	if err != nil {
 		return "", err
 	}
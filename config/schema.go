@@ -0,0 +1,478 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Errors
+var (
+	ErrUnknownKey  = errors.New("unknown config key")
+	ErrNotInSchema = errors.New("config key not in schema")
+	ErrMissingKey  = errors.New("required config key missing")
+)
+
+// Type is the type of the value of a config entry
+type Type int
+
+// The types of the config values
+const (
+	TypeString Type = iota
+	TypeInt
+	TypeBool
+)
+
+func (t Type) String() string {
+	switch t {
+	case TypeInt:
+		return "int"
+	case TypeBool:
+		return "bool"
+	}
+	return "string"
+}
+
+// The sources of the effective values. The values of a LayeredConfig have the name of their layer as source
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+)
+
+// the keys of the tools sharing the config file, which are not checked
+const extensionKeyPrefix = "x-"
+
+var (
+	trueValues  = []string{"1", "on", "true", "yes", "enable", "enabled"}
+	falseValues = []string{"0", "off", "false", "no", "disable", "disabled"}
+)
+
+// Entry describes a config key
+type Entry struct {
+	Key  string
+	Type Type
+	// Default is the value used when the key is not set, in the config file format. Empty means the
+	// default is computed by the code reading the key
+	Default string
+	// Min and Max are the range of the int values, checked if Min < Max
+	Min int
+	Max int
+	// Values are the allowed values of a string entry (case insensitive), any value is allowed if empty
+	Values []string
+	// Reloadable entries are read from the ops config, and reloaded when the ops config changes
+	Reloadable bool
+	// Group is the feature the entry belongs to
+	Group string
+	// Prefix entries match all the keys starting with Key, like "slow_query_threshold_ms_" for
+	// "slow_query_threshold_ms_rw"
+	Prefix bool
+	// Required entries must be in the config file
+	Required bool
+	// Deprecated entries are accepted with a warning, they are not used
+	Deprecated bool
+}
+
+// Schema is the set of the known config keys
+type Schema struct {
+	entries  []*Entry
+	byKey    map[string]*Entry
+	prefixes []*Entry
+}
+
+// NewSchema creates a schema from its entries. It returns an error if a key is defined twice or if a
+// default is not valid
+func NewSchema(entries []Entry) (*Schema, error) {
+	schema := &Schema{byKey: make(map[string]*Entry)}
+	for i := range entries {
+		entry := &entries[i]
+		if _, ok := schema.byKey[entry.Key]; ok {
+			return nil, fmt.Errorf("config key %s defined twice in the schema", entry.Key)
+		}
+		if len(entry.Default) > 0 {
+			if err := entry.Check(entry.Default); err != nil {
+				return nil, fmt.Errorf("default of %s: %v", entry.Key, err)
+			}
+		}
+		schema.byKey[entry.Key] = entry
+		schema.entries = append(schema.entries, entry)
+		if entry.Prefix {
+			schema.prefixes = append(schema.prefixes, entry)
+		}
+	}
+	return schema, nil
+}
+
+// DefaultInt returns the default of an int key, 0 if the key has no default. It is the default of the keys
+// read from a Config not checked against the schema, as the ops config
+func (s *Schema) DefaultInt(key string) int {
+	val := 0
+	if entry := s.Lookup(key); entry != nil {
+		val, _ = strconv.Atoi(entry.Default)
+	}
+	return val
+}
+
+// Entries returns the entries, sorted by group and key
+func (s *Schema) Entries() []*Entry {
+	entries := make([]*Entry, len(s.entries))
+	copy(entries, s.entries)
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Group != entries[j].Group {
+			return entries[i].Group < entries[j].Group
+		}
+		return entries[i].Key < entries[j].Key
+	})
+	return entries
+}
+
+// Lookup returns the entry of a key, nil if the key is not in the schema. The ops config keys
+// "opscfg.<name>.server.<key>" match the reloadable entry of <key>
+func (s *Schema) Lookup(key string) *Entry {
+	if strings.HasPrefix(key, "opscfg.") {
+		idx := strings.Index(key, ".server.")
+		if idx < 0 {
+			return nil
+		}
+		entry := s.Lookup(key[idx+len(".server."):])
+		if (entry == nil) || !entry.Reloadable {
+			return nil
+		}
+		return entry
+	}
+	if entry, ok := s.byKey[key]; ok && !entry.Prefix {
+		return entry
+	}
+	for _, entry := range s.prefixes {
+		if strings.HasPrefix(key, entry.Key) && (len(key) > len(entry.Key)) {
+			return entry
+		}
+	}
+	return nil
+}
+
+// suggest returns the entry key closest to an unknown key, empty if none is close enough
+func (s *Schema) suggest(key string) string {
+	best := ""
+	bestDist := 3
+	for _, entry := range s.entries {
+		dist := editDistance(key, entry.Key)
+		if dist < bestDist {
+			best, bestDist = entry.Key, dist
+		}
+	}
+	return best
+}
+
+// Check returns an error if the value is not valid for the entry
+func (e *Entry) Check(value string) error {
+	switch e.Type {
+	case TypeInt:
+		val, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not an int", value)
+		}
+		if (e.Min < e.Max) && ((val < e.Min) || (val > e.Max)) {
+			return fmt.Errorf("%d is not between %d and %d", val, e.Min, e.Max)
+		}
+	case TypeBool:
+		if !containsFold(trueValues, value) && !containsFold(falseValues, value) {
+			return fmt.Errorf("%q is not a bool", value)
+		}
+	default:
+		if (len(e.Values) > 0) && !containsFold(e.Values, value) {
+			return fmt.Errorf("%q is not one of %s", value, strings.Join(e.Values, ", "))
+		}
+	}
+	return nil
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// editDistance is the Levenshtein distance of a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1
+			}
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// Value is the effective value of a config key
+type Value struct {
+	Entry  *Entry
+	Key    string
+	Value  string
+	Source string
+}
+
+// CheckedConfig is a Config validated against a schema: the unknown keys, the values having the wrong
+// type or out of range and the missing required keys are reported by Errors. The keys not set take the
+// default of the schema. The unknown keys coming from the environment are ignored, the environment being
+// shared by all the config files. It is not thread safe
+type CheckedConfig struct {
+	raw      RawConfig
+	cfg      Config
+	schema   *Schema
+	errs     []error
+	warnings []string
+}
+
+// NewCheckedFileConfig creates a CheckedConfig for a txt config file and its YAML, JSON and environment
+// layers, see NewFileConfig
+func NewCheckedFileConfig(filename string, schema *Schema) (*CheckedConfig, error) {
+	raw, err := newFileConfig(filename)
+	if err != nil {
+		return nil, err
+	}
+	return NewCheckedConfig(raw, schema), nil
+}
+
+// NewCheckedConfig validates a RawConfig against the schema
+func NewCheckedConfig(raw RawConfig, schema *Schema) *CheckedConfig {
+	cfg := &CheckedConfig{raw: raw, cfg: NewConfig(raw), schema: schema}
+	values := raw.GetAllValues()
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if strings.HasPrefix(key, extensionKeyPrefix) {
+			continue
+		}
+		entry := schema.Lookup(key)
+		if (entry == nil) && (cfg.source(key) == SourceEnv) {
+			continue
+		}
+		if entry == nil {
+			if suggestion := schema.suggest(key); len(suggestion) > 0 {
+				cfg.errs = append(cfg.errs, fmt.Errorf("%v: %s, did you mean %s?", ErrUnknownKey, key, suggestion))
+			} else {
+				cfg.errs = append(cfg.errs, fmt.Errorf("%v: %s", ErrUnknownKey, key))
+			}
+			continue
+		}
+		if entry.Deprecated {
+			cfg.warnings = append(cfg.warnings, fmt.Sprintf("config key %s is deprecated and ignored", key))
+			continue
+		}
+		if err := entry.Check(string(values[key])); err != nil {
+			cfg.errs = append(cfg.errs, fmt.Errorf("%v: %s: %v", ErrInvalidConfigValue, key, err))
+		}
+	}
+	for _, entry := range schema.entries {
+		if entry.Required && !entry.Prefix {
+			if _, ok := values[entry.Key]; !ok {
+				cfg.errs = append(cfg.errs, fmt.Errorf("%v: %s", ErrMissingKey, entry.Key))
+			}
+		}
+	}
+	return cfg
+}
+
+// Sources returns the sources of the values, from the highest precedence to the lowest
+func (cfg *CheckedConfig) Sources() []string {
+	if layered, ok := cfg.raw.(*LayeredConfig); ok {
+		return layered.Layers()
+	}
+	return []string{SourceFile}
+}
+
+// source returns the source of the value of the key in the config
+func (cfg *CheckedConfig) source(key string) string {
+	if layered, ok := cfg.raw.(*LayeredConfig); ok {
+		return layered.Source(key)
+	}
+	return SourceFile
+}
+
+// Errors returns the problems found in the config, and the keys read which are not in the schema
+func (cfg *CheckedConfig) Errors() []error {
+	return cfg.errs
+}
+
+// Warnings returns the deprecated keys found in the config
+func (cfg *CheckedConfig) Warnings() []string {
+	return cfg.warnings
+}
+
+// Effective returns the value of every entry of the schema, followed by the keys in the file matching
+// the entry: the keys starting with a prefix entry and the ops config keys of a reloadable entry
+func (cfg *CheckedConfig) Effective() []Value {
+	var values []Value
+	all := cfg.raw.GetAllValues()
+	for _, entry := range cfg.schema.Entries() {
+		if entry.Deprecated {
+			continue
+		}
+		if !entry.Prefix {
+			values = append(values, cfg.effective(entry, entry.Key))
+		}
+		var keys []string
+		for key := range all {
+			if (key != entry.Key) && (cfg.schema.Lookup(key) == entry) {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			values = append(values, cfg.effective(entry, key))
+		}
+	}
+	return values
+}
+
+// effective returns the value of the key in the file if valid, the default otherwise
+func (cfg *CheckedConfig) effective(entry *Entry, key string) Value {
+	val, err := cfg.raw.GetValue(key)
+	if (err == nil) && (entry.Check(string(val)) == nil) {
+		return Value{Entry: entry, Key: key, Value: string(val), Source: cfg.source(key)}
+	}
+	return Value{Entry: entry, Key: key, Value: entry.Default, Source: SourceDefault}
+}
+
+// value returns the valid value of key in the file, or the default of the schema. ok is false if
+// neither is set, the caller's default is used then
+func (cfg *CheckedConfig) value(key string, typ Type) (val string, ok bool) {
+	entry := cfg.schema.Lookup(key)
+	if entry == nil {
+		cfg.errs = append(cfg.errs, fmt.Errorf("%v: %s", ErrNotInSchema, key))
+		return "", false
+	}
+	if entry.Type != typ {
+		cfg.errs = append(cfg.errs, fmt.Errorf("config key %s is read as %v, its schema type is %v", key, typ, entry.Type))
+		return "", false
+	}
+	value := cfg.effective(entry, key)
+	if (value.Source == SourceDefault) && (len(value.Value) == 0) {
+		return "", false
+	}
+	return value.Value, true
+}
+
+// GetInt implements Config interface
+func (cfg *CheckedConfig) GetInt(key string) (int, error) {
+	val, ok := cfg.value(key, TypeInt)
+	if !ok {
+		return 0, ErrNotFound
+	}
+	return strconv.Atoi(val)
+}
+
+// GetOrDefaultInt implements Config interface
+func (cfg *CheckedConfig) GetOrDefaultInt(key string, defaultVal int) int {
+	val, err := cfg.GetInt(key)
+	if err == nil {
+		return val
+	}
+	return defaultVal
+}
+
+// GetString implements Config interface
+func (cfg *CheckedConfig) GetString(key string) (string, error) {
+	val, ok := cfg.value(key, TypeString)
+	if !ok {
+		return "", ErrNotFound
+	}
+	return val, nil
+}
+
+// GetOrDefaultString implements Config interface
+func (cfg *CheckedConfig) GetOrDefaultString(key string, def string) string {
+	val, err := cfg.GetString(key)
+	if err == nil {
+		return val
+	}
+	return def
+}
+
+// GetBool implements Config interface
+func (cfg *CheckedConfig) GetBool(key string) (bool, error) {
+	val, ok := cfg.value(key, TypeBool)
+	if !ok {
+		return false, ErrNotFound
+	}
+	return containsFold(trueValues, val), nil
+}
+
+// GetOrDefaultBool implements Config interface
+func (cfg *CheckedConfig) GetOrDefaultBool(key string, def bool) bool {
+	val, err := cfg.GetBool(key)
+	if err == nil {
+		return val
+	}
+	return def
+}
+
+// Int returns the value of an int key, or its default in the schema, 0 if neither is set
+func (cfg *CheckedConfig) Int(key string) int {
+	val, _ := cfg.GetInt(key)
+	return val
+}
+
+// String returns the value of a string key, or its default in the schema, "" if neither is set
+func (cfg *CheckedConfig) String(key string) string {
+	val, _ := cfg.GetString(key)
+	return val
+}
+
+// Bool returns the value of a bool key, or its default in the schema, false if neither is set
+func (cfg *CheckedConfig) Bool(key string) bool {
+	val, _ := cfg.GetBool(key)
+	return val
+}
+
+// IsSwitchEnabled implements Config interface
+func (cfg *CheckedConfig) IsSwitchEnabled(key string) bool {
+	return cfg.GetOrDefaultBool(key, false)
+}
+
+// Dump implements Config interface
+func (cfg *CheckedConfig) Dump() string {
+	return cfg.cfg.Dump()
+}
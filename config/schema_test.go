@@ -0,0 +1,177 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+type mapConfig map[string]string
+
+func (m mapConfig) GetValue(key string) ([]byte, error) {
+	val, ok := m[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return []byte(val), nil
+}
+
+func (m mapConfig) GetAllValues() map[string][]byte {
+	values := make(map[string][]byte)
+	for key, val := range m {
+		values[key] = []byte(val)
+	}
+	return values
+}
+
+func testSchema(t *testing.T) *Schema {
+	schema, err := NewSchema([]Entry{
+		{Key: "port", Type: TypeInt, Min: 1, Max: 65535, Required: true, Group: "A"},
+		{Key: "timeout_ms", Type: TypeInt, Default: "100", Reloadable: true, Group: "A"},
+		{Key: "mode", Type: TypeString, Default: "off", Values: []string{"off", "on"}, Group: "B"},
+		{Key: "enabled", Type: TypeBool, Default: "true", Group: "B"},
+		{Key: "threshold_", Type: TypeInt, Prefix: true, Reloadable: true, Group: "B"},
+		{Key: "old_key", Type: TypeBool, Deprecated: true, Group: "C"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+func TestNewSchema(t *testing.T) {
+	_, err := NewSchema([]Entry{{Key: "a", Type: TypeInt}, {Key: "a", Type: TypeString}})
+	if err == nil {
+		t.Error("duplicate key accepted")
+	}
+	_, err = NewSchema([]Entry{{Key: "a", Type: TypeInt, Default: "x"}})
+	if err == nil {
+		t.Error("invalid default accepted")
+	}
+}
+
+func TestSchemaLookup(t *testing.T) {
+	schema := testSchema(t)
+	for key, expected := range map[string]string{
+		"port":                          "port",
+		"threshold_rw":                  "threshold_",
+		"threshold_":                    "",
+		"opscfg.hera.server.timeout_ms": "timeout_ms",
+		"opscfg.default.server.port":    "",
+		"unknown":                       "",
+	} {
+		entry := schema.Lookup(key)
+		if (expected == "") != (entry == nil) || ((entry != nil) && (entry.Key != expected)) {
+			t.Errorf("Lookup(%s) = %v, expected %s", key, entry, expected)
+		}
+	}
+}
+
+func TestCheckedConfig(t *testing.T) {
+	cfg := NewCheckedConfig(mapConfig{
+		"port":                          "abc",
+		"mode":                          "ON",
+		"enabeld":                       "true",
+		"threshold_ro":                  "5",
+		"opscfg.hera.server.timeout_ms": "20",
+		"old_key":                       "true",
+		"x-tool":                        "anything",
+	}, testSchema(t))
+	var msgs []string
+	for _, err := range cfg.Errors() {
+		msgs = append(msgs, err.Error())
+	}
+	all := strings.Join(msgs, "\n")
+	if len(msgs) != 2 || !strings.Contains(all, "enabeld, did you mean enabled?") || !strings.Contains(all, "port: \"abc\" is not an int") {
+		t.Errorf("unexpected errors:\n%s", all)
+	}
+	if len(cfg.Warnings()) != 1 {
+		t.Errorf("expected a warning for old_key, got %v", cfg.Warnings())
+	}
+
+	if cfg.GetOrDefaultInt("port", 8) != 8 {
+		t.Error("invalid value not replaced by the default")
+	}
+	if cfg.GetOrDefaultInt("timeout_ms", 5) != 100 {
+		t.Error("schema default not used")
+	}
+	if cfg.GetOrDefaultString("mode", "") != "ON" {
+		t.Error("file value not used")
+	}
+	if !cfg.GetOrDefaultBool("enabled", false) {
+		t.Error("schema default not used")
+	}
+	if cfg.GetOrDefaultInt("threshold_ro", 0) != 5 {
+		t.Error("prefix value not used")
+	}
+	if len(cfg.Errors()) != 2 {
+		t.Errorf("unexpected errors reading known keys: %v", cfg.Errors())
+	}
+	cfg.GetOrDefaultInt("not_in_schema", 0)
+	cfg.GetOrDefaultString("timeout_ms", "")
+	if len(cfg.Errors()) != 4 || !strings.Contains(cfg.Errors()[2].Error(), ErrNotInSchema.Error()) {
+		t.Errorf("reading keys not in the schema not reported: %v", cfg.Errors())
+	}
+
+	sources := make(map[string]string)
+	for _, val := range cfg.Effective() {
+		sources[val.Key] = val.Value + "/" + val.Source
+	}
+	for key, expected := range map[string]string{
+		"port":                          "/default",
+		"timeout_ms":                    "100/default",
+		"opscfg.hera.server.timeout_ms": "20/file",
+		"mode":                          "ON/file",
+		"threshold_ro":                  "5/file",
+	} {
+		if sources[key] != expected {
+			t.Errorf("effective %s = %s, expected %s", key, sources[key], expected)
+		}
+	}
+	if _, ok := sources["old_key"]; ok {
+		t.Error("deprecated key reported")
+	}
+}
+
+func TestCheckedConfigMissing(t *testing.T) {
+	cfg := NewCheckedConfig(mapConfig{}, testSchema(t))
+	if len(cfg.Errors()) != 1 || !strings.Contains(cfg.Errors()[0].Error(), ErrMissingKey.Error()) {
+		t.Errorf("missing required key not reported: %v", cfg.Errors())
+	}
+	if _, err := cfg.GetInt("port"); err != ErrNotFound {
+		t.Errorf("GetInt of a missing key without default: %v", err)
+	}
+}
+
+func TestSchemaDefaults(t *testing.T) {
+	schema := testSchema(t)
+	cfg := NewCheckedConfig(mapConfig{"port": "80", "mode": "on"}, schema)
+	if (cfg.Int("port") != 80) || (cfg.Int("timeout_ms") != 100) || (cfg.Int("threshold_ro") != 0) {
+		t.Errorf("unexpected ints %d %d %d", cfg.Int("port"), cfg.Int("timeout_ms"), cfg.Int("threshold_ro"))
+	}
+	if (cfg.String("mode") != "on") || !cfg.Bool("enabled") {
+		t.Errorf("unexpected values %s %v", cfg.String("mode"), cfg.Bool("enabled"))
+	}
+	if (schema.DefaultInt("timeout_ms") != 100) || (schema.DefaultInt("opscfg.hera.server.timeout_ms") != 100) || (schema.DefaultInt("port") != 0) {
+		t.Error("unexpected schema defaults")
+	}
+	if len(cfg.Errors()) != 0 {
+		t.Errorf("unexpected errors %v", cfg.Errors())
+	}
+}
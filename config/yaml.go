@@ -0,0 +1,165 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// structuredConfig is a RawConfig read from a YAML or JSON document. The nested objects are flattened,
+// their keys joined with ".", so that
+//
+//	opscfg:
+//	  hera:
+//	    server:
+//	      log_level: 3
+//
+// gives "opscfg.hera.server.log_level". The lists are joined with ","
+type structuredConfig struct {
+	data map[string][]byte
+}
+
+// NewYAMLConfig returns a RawConfig reading a YAML file
+func NewYAMLConfig(filename string) (RawConfig, error) {
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err = yaml.Unmarshal(buf, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return newStructuredConfig(filename, doc)
+}
+
+// NewJSONConfig returns a RawConfig reading a JSON file
+func NewJSONConfig(filename string) (RawConfig, error) {
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.UseNumber()
+	if err = dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return newStructuredConfig(filename, doc)
+}
+
+func newStructuredConfig(filename string, doc interface{}) (RawConfig, error) {
+	cfg := &structuredConfig{data: make(map[string][]byte)}
+	if doc == nil {
+		// empty document
+		return cfg, nil
+	}
+	switch doc.(type) {
+	case map[string]interface{}, map[interface{}]interface{}:
+	default:
+		return nil, fmt.Errorf("%s: %v, the document must be an object", filename, ErrInvalidConfig)
+	}
+	if err := cfg.flatten("", doc); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return cfg, nil
+}
+
+func (cfg *structuredConfig) flatten(key string, val interface{}) error {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if err := cfg.flatten(joinKey(key, k), child); err != nil {
+				return err
+			}
+		}
+	case map[interface{}]interface{}:
+		for k, child := range v {
+			if err := cfg.flatten(joinKey(key, fmt.Sprint(k)), child); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			str, ok := scalarString(item)
+			if !ok {
+				return fmt.Errorf("%v: %s, the lists must have scalar values", ErrInvalidConfigValue, key)
+			}
+			items[i] = str
+		}
+		cfg.data[key] = []byte(strings.Join(items, ","))
+	default:
+		str, ok := scalarString(v)
+		if !ok {
+			return fmt.Errorf("%v: %s", ErrInvalidConfigValue, key)
+		}
+		cfg.data[key] = []byte(str)
+	}
+	return nil
+}
+
+func joinKey(prefix string, key string) string {
+	if len(prefix) == 0 {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// scalarString returns the value as it would be written in a txt config
+func scalarString(val interface{}) (string, bool) {
+	switch v := val.(type) {
+	case nil:
+		return "", true
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case int:
+		return strconv.Itoa(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case json.Number:
+		return v.String(), true
+	}
+	return "", false
+}
+
+// implements rawConfig
+func (cfg *structuredConfig) GetAllValues() map[string][]byte {
+	return cfg.data
+}
+
+// implements rawConfig
+func (cfg *structuredConfig) GetValue(key string) ([]byte, error) {
+	val, ok := cfg.data[key]
+	if ok {
+		return val, nil
+	}
+	return nil, ErrNotFound
+}
@@ -64,14 +64,6 @@ type adaptiveQueueManager struct {
 	//
 	lastEmptyTimeMs int64
 	//
-	// long timeout in adaptive queue (200 ms by default)
-	//
-	longTimeoutMs int
-	//
-	// short timeout in adaptive queue (5 ms by default)
-	//
-	shortTimeoutMs int
-	//
 	// collection of evicted sqlhash. value is eviction time (epoch in millisecond)
 	//
 	evictedSqlhash map[int32]int64
@@ -89,9 +81,6 @@ func (mgr *adaptiveQueueManager) init(wpool *WorkerPool) error {
 	}
 	mgr.wpool = wpool
 
-	mgr.longTimeoutMs = GetConfig().BacklogTimeoutMsec
-	mgr.shortTimeoutMs = GetConfig().ShortBacklogTimeoutMsec
-
 	mgr.lastEmptyTimeMs = (time.Now().UnixNano() / int64(time.Millisecond))
 	mgr.evictedSqlhash = make(map[int32]int64)
 	mgr.dispatchedWorkers = make(map[*WorkerClient]string)
@@ -146,7 +135,7 @@ func (mgr *adaptiveQueueManager) doBindEviction() int {
 		throttleCount += len(keyValues)
 	}
 	GetBindEvict().lock.Unlock()
-	if throttleCount > GetConfig().BindEvictionMaxThrottle {
+	if throttleCount > GetReloadableConfig().BindEvictionMaxThrottle {
 		if logger.GetLogger().V(logger.Info) {
 			logger.GetLogger().Log(logger.Info, "already too many bind throttles, skipping bind eviction and throttle")
 		}
@@ -230,7 +219,7 @@ func (mgr *adaptiveQueueManager) doBindEviction() int {
 		bindName := entry.Name
 		bindValue := entry.Value
 
-		if len(entry.Workers) < int(float64(GetConfig().BindEvictionThresholdPct)/100.*float64(numDispatchedWorkers)) {
+		if len(entry.Workers) < int(float64(GetReloadableConfig().BindEvictionThresholdPct)/100.*float64(numDispatchedWorkers)) {
 			continue
 		}
 		// evict sqlhash, bindvalue
@@ -519,7 +508,7 @@ func (mgr *adaptiveQueueManager) shouldSoftEvict(sqlhash int32) bool {
 	//
 	// random threshold.
 	//
-	probability := GetConfig().SoftEvictionProbability
+	probability := GetReloadableConfig().SoftEvictionProbability
 	if probability == 0 {
 		return false
 	}
@@ -545,7 +534,7 @@ func (mgr *adaptiveQueueManager) shouldSoftEvict(sqlhash int32) bool {
 	//
 	// sqlhash has been on blacklist for too long.
 	//
-	jailtime := int64(GetConfig().SoftEvictionEffectiveTimeMs)
+	jailtime := int64(GetReloadableConfig().SoftEvictionEffectiveTimeMs)
 	var now = time.Now().UnixNano() / int64(time.Millisecond)
 	if logger.GetLogger().V(logger.Verbose) {
 		logger.GetLogger().Log(logger.Verbose, "shouldsoftevict jailtime >-", lastEvictTime, now, jailtime)
@@ -625,9 +614,11 @@ func (mgr *adaptiveQueueManager) getBacklogTimeout() (int, bool) {
 	//
 	// if backlog is empty, return long timeout.
 	//
+	// long and short timeouts in adaptive queue, they can be changed in the ops config
+	cfg := GetReloadableConfig()
 	blgsize := atomic.LoadInt32(&(mgr.wpool.backlogCnt))
 	if blgsize == 0 {
-		return mgr.longTimeoutMs, true
+		return cfg.BacklogTimeoutMsec, true
 	}
 	var now = time.Now().UnixNano() / int64(time.Millisecond)
 	//
@@ -636,8 +627,8 @@ func (mgr *adaptiveQueueManager) getBacklogTimeout() (int, bool) {
 	if logger.GetLogger().V(logger.Debug) {
 		logger.GetLogger().Log(logger.Debug, "adaptiveQueueManager getBacklogTimeout. lastEmptyTimeMs", mgr.lastEmptyTimeMs, " now", now)
 	}
-	if mgr.lastEmptyTimeMs < (now - int64(mgr.longTimeoutMs)) {
-		return mgr.shortTimeoutMs, false
+	if mgr.lastEmptyTimeMs < (now - int64(cfg.BacklogTimeoutMsec)) {
+		return cfg.ShortBacklogTimeoutMsec, false
 	}
-	return mgr.longTimeoutMs, true
+	return cfg.BacklogTimeoutMsec, true
 }
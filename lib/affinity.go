@@ -0,0 +1,235 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lib
+
+import (
+	"container/list"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/paypal/hera/cal"
+	"github.com/paypal/hera/common"
+	"github.com/paypal/hera/utility/encoding/netstring"
+	"github.com/paypal/hera/utility/logger"
+)
+
+// Sqlhash affinity: among the free workers, GetWorker prefers one which recently ran the same SQL, having the
+// statement prepared (see "enable_cache") and warm database session caches. When the pool is under pressure,
+// i.e. requests are in the backlog or too few workers are free, the workers are selected as usual.
+
+// affinityEnabled tells if GetWorker looks for a worker which ran the SQL
+func affinityEnabled() bool {
+	cfg := GetConfig()
+	if cfg == nil {
+		return false
+	}
+	return cfg.EnableSQLHashAffinity || (cfg.EnableCache && (cfg.MaxCacheSize > 0))
+}
+
+// affinitySize returns the number of sqlhashes remembered for each worker, at least the size of the statement
+// cache of the worker
+func affinitySize() int {
+	size := GetConfig().SQLHashAffinitySize
+	if GetConfig().EnableCache && (GetConfig().MaxCacheSize > size) {
+		size = GetConfig().MaxCacheSize
+	}
+	return size
+}
+
+// recentHashes holds the sqlhashes of the last statements a worker ran. It is changed by the coordinator
+// owning the worker and read by the pool while the worker is free
+type recentHashes struct {
+	size  int
+	lru   *list.List // front is the most recently used
+	items map[int32]*list.Element
+	// a statement altered the session, the worker resets it when freed and drops its statement cache
+	reset bool
+}
+
+// newRecentHashes returns nil if the sqlhash affinity is disabled
+func newRecentHashes() *recentHashes {
+	if !affinityEnabled() || (affinitySize() <= 0) {
+		return nil
+	}
+	return &recentHashes{size: affinitySize(), lru: list.New(), items: make(map[int32]*list.Element)}
+}
+
+// add records that the worker ran the statement
+func (rh *recentHashes) add(sqlhash int32) {
+	if el, ok := rh.items[sqlhash]; ok {
+		rh.lru.MoveToFront(el)
+		return
+	}
+	rh.items[sqlhash] = rh.lru.PushFront(sqlhash)
+	if rh.lru.Len() > rh.size {
+		oldest := rh.lru.Back()
+		rh.lru.Remove(oldest)
+		delete(rh.items, oldest.Value.(int32))
+	}
+}
+
+// recordSQL records the statement of the request in the recent sqlhashes of the worker, in a transaction or not
+func (crd *Coordinator) recordSQL(worker *WorkerClient, request *netstring.Netstring) {
+	if (worker.recent == nil) || (crd.sqlhash == 0) {
+		return
+	}
+	worker.recent.add(crd.sqlhash)
+	if !GetConfig().EnableSessionReset || (crd.sqlParser == nil) {
+		return
+	}
+	nss := crd.nss
+	if !request.IsComposite() {
+		nss = []*netstring.Netstring{request}
+	}
+	for _, ns := range nss {
+		if (ns.Cmd == common.CmdPrepare) || (ns.Cmd == common.CmdPrepareV2) || (ns.Cmd == common.CmdPrepareSpecial) {
+			if crd.sqlParser.AltersSession(string(ns.Payload)) {
+				worker.recent.reset = true
+			}
+		}
+	}
+}
+
+// has tells if the worker recently ran the statement
+func (rh *recentHashes) has(sqlhash int32) bool {
+	_, ok := rh.items[sqlhash]
+	return ok
+}
+
+// affinityStats counts the worker selections of a pool, protected by the pool lock
+type affinityStats struct {
+	// a free worker recently ran the SQL
+	hits int64
+	// no free worker ran the SQL
+	misses int64
+	// the pool was under pressure, the worker was selected as usual
+	fallbacks  int64
+	lastReport time.Time
+}
+
+// underPressure tells if requests wait in the backlog or if less than "sqlhash_affinity_min_idle_pct" of the
+// workers are free. The caller holds the pool lock
+func (pool *WorkerPool) underPressure() bool {
+	if atomic.LoadInt32(&pool.backlogCnt) > 0 {
+		return true
+	}
+	return pool.activeQ.Len()*100 < pool.currentSize*GetConfig().SQLHashAffinityMinIdlePct
+}
+
+// indexIdle adds the worker just pushed to the free workers to the index of the free workers by sqlhash. The
+// sqlhashes of a worker change only while a coordinator owns it, so they are the same when it is unindexed. The
+// caller holds the pool lock
+func (pool *WorkerPool) indexIdle(worker *WorkerClient) {
+	if worker.recent == nil {
+		return
+	}
+	if pool.idleByHash == nil {
+		pool.idleByHash = make(map[int32][]*WorkerClient)
+	}
+	for sqlhash := range worker.recent.items {
+		pool.idleByHash[sqlhash] = append(pool.idleByHash[sqlhash], worker)
+	}
+}
+
+// unindexIdle removes the worker taken from the free workers from the index by sqlhash. The caller holds the pool
+// lock
+func (pool *WorkerPool) unindexIdle(worker *WorkerClient) {
+	if worker.recent == nil {
+		return
+	}
+	for sqlhash := range worker.recent.items {
+		idle := pool.idleByHash[sqlhash]
+		for i := range idle {
+			if idle[i] == worker {
+				idle = append(idle[:i], idle[i+1:]...)
+				break
+			}
+		}
+		if len(idle) == 0 {
+			delete(pool.idleByHash, sqlhash)
+		} else {
+			pool.idleByHash[sqlhash] = idle
+		}
+	}
+}
+
+// getAffinityWorker removes from the free workers and returns the one which most recently became free among those
+// which recently ran the SQL, nil if there is none or if the pool is under pressure. The caller holds the pool lock
+func (pool *WorkerPool) getAffinityWorker(sqlhash int32) *WorkerClient {
+	defer pool.reportAffinity()
+	if pool.underPressure() {
+		pool.affinity.fallbacks++
+		return nil
+	}
+	var found *WorkerClient
+	idle := pool.idleByHash[sqlhash]
+	for i := len(idle) - 1; i >= 0; i-- {
+		if idle[i].Status > wsInit {
+			found = idle[i]
+			break
+		}
+	}
+	if found == nil {
+		pool.affinity.misses++
+		return nil
+	}
+	pool.activeQ.Remove(found)
+	pool.unindexIdle(found)
+	pool.affinity.hits++
+	if logger.GetLogger().V(logger.Debug) {
+		logger.GetLogger().Log(logger.Debug, "Pool::SelectWorker affinity", found.pid, found.Type, pool.InstID, uint32(sqlhash))
+	}
+	return found
+}
+
+// reportAffinity sends the hit rate to CAL every "sqlhash_affinity_report_interval" seconds. The caller holds
+// the pool lock
+func (pool *WorkerPool) reportAffinity() {
+	now := time.Now()
+	if pool.affinity.lastReport.IsZero() {
+		pool.affinity.lastReport = now
+		return
+	}
+	if now.Sub(pool.affinity.lastReport) < time.Duration(GetConfig().SQLHashAffinityReportInterval)*time.Second {
+		return
+	}
+	pool.affinity.lastReport = now
+	stats := pool.affinity
+	total := stats.hits + stats.misses + stats.fallbacks
+	if total == 0 {
+		return
+	}
+	pct := fmt.Sprintf("%.2f", 100*float64(stats.hits)/float64(total))
+	name := fmt.Sprintf("%s%d", poolNamePrefix[pool.Type], pool.InstID)
+	if GetConfig().EnableSharding {
+		name = fmt.Sprintf("%s%d_shd%d", poolNamePrefix[pool.Type], pool.InstID, pool.ShardID)
+	}
+	if logger.GetLogger().V(logger.Info) {
+		logger.GetLogger().Log(logger.Info, "sqlhash affinity", name, "hits:", stats.hits, "misses:", stats.misses, "fallbacks:", stats.fallbacks, "hit rate:", pct)
+	}
+	evt := cal.NewCalEvent(EvtTypeMux, "sqlhash_affinity_"+name, cal.TransOK, "")
+	evt.AddDataInt("hits", stats.hits)
+	evt.AddDataInt("misses", stats.misses)
+	evt.AddDataInt("fallbacks", stats.fallbacks)
+	evt.AddDataStr("pct", pct)
+	evt.Completed()
+	pool.affinity.hits = 0
+	pool.affinity.misses = 0
+	pool.affinity.fallbacks = 0
+}
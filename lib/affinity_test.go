@@ -0,0 +1,79 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/paypal/hera/common"
+	"github.com/paypal/hera/utility/encoding/netstring"
+)
+
+func TestAffinityWorker(t *testing.T) {
+	savedCfg := gAppConfig
+	gAppConfig = &Config{EnableSQLHashAffinity: true, SQLHashAffinitySize: 2, SQLHashAffinityMinIdlePct: 50, SQLHashAffinityReportInterval: 60}
+	defer func() { gAppConfig = savedCfg }()
+
+	pool := &WorkerPool{activeQ: NewQueue(), currentSize: 4}
+	workers := make([]*WorkerClient, 4)
+	for i := range workers {
+		workers[i] = &WorkerClient{ID: i, Status: wsAcpt, recent: newRecentHashes()}
+	}
+	workers[1].recent.add(10)
+	workers[2].recent.add(20)
+	workers[2].recent.add(30)
+	workers[2].recent.add(10)
+	// 20 is the least recently used of the 2 statements
+	if workers[2].recent.has(20) || !workers[2].recent.has(30) {
+		t.Error("expected 20 to be dropped from the recent sqlhashes of worker 2")
+	}
+	for _, worker := range workers {
+		pool.activeQ.Push(worker)
+		pool.indexIdle(worker)
+	}
+
+	// the last freed worker first
+	if w := pool.getActiveWorker(10); w != workers[2] {
+		t.Errorf("expected worker 2 having sqlhash 10, got %v", w)
+	}
+	if w := pool.getActiveWorker(10); w != workers[1] {
+		t.Errorf("expected worker 1 having sqlhash 10, got %v", w)
+	}
+	if len(pool.idleByHash) != 0 {
+		t.Errorf("expected the index to be empty, got %v", pool.idleByHash)
+	}
+	// no free worker ran the SQL
+	if w := pool.getActiveWorker(30); w != workers[0] {
+		t.Errorf("expected worker 0, got %v", w)
+	}
+	if (pool.affinity.hits != 2) || (pool.affinity.misses != 1) {
+		t.Errorf("expected 2 hits and 1 miss, got %+v", pool.affinity)
+	}
+
+	// 1 free worker out of 4 is under the 50% minimum, the worker is selected as usual
+	if w := pool.getActiveWorker(40); w != workers[3] {
+		t.Errorf("expected worker 3, got %v", w)
+	}
+	if pool.affinity.fallbacks != 1 {
+		t.Errorf("expected 1 fallback, got %+v", pool.affinity)
+	}
+}
+
+func TestRecordSQL(t *testing.T) {
+	savedCfg := gAppConfig
+	gAppConfig = &Config{EnableSQLHashAffinity: true, SQLHashAffinitySize: 4, EnableSessionReset: true}
+	defer func() { gAppConfig = savedCfg }()
+
+	parser, _ := common.NewRegexSQLParser()
+	crd := &Coordinator{sqlParser: parser}
+	worker := &WorkerClient{recent: newRecentHashes()}
+	for i, sql := range []string{"select 1 from dual", "update t set a = 1", "set @a = 1"} {
+		crd.sqlhash = int32(i + 1)
+		crd.nss = []*netstring.Netstring{netstring.NewNetstringFrom(common.CmdPrepareV2, []byte(sql)), netstring.NewNetstringFrom(common.CmdExecute, nil)}
+		crd.recordSQL(worker, netstring.NewNetstringEmbedded(crd.nss))
+		if !worker.recent.has(crd.sqlhash) {
+			t.Errorf("%s not recorded", sql)
+		}
+		if worker.recent.reset != (i == 2) {
+			t.Errorf("%s: session reset %v", sql, worker.recent.reset)
+		}
+	}
+}
@@ -140,7 +140,7 @@ func (be *BindEvict) ShouldBlock(sqlhash uint32, bindKV map[string]string, heavy
 		// check if not used in a while
 		now := time.Now()
 		recent := entry.RecentAttempt.Load().(*time.Time)
-		gap := now.Sub(*recent).Seconds() * GetConfig().BindEvictionDecrPerSec
+		gap := now.Sub(*recent).Seconds() * GetReloadableConfig().BindEvictionDecrPerSec
 		entry.decrAllowEveryX(int(gap))
 		if entry.AllowEveryX == 0 {
 			return false, nil
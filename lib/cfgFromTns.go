@@ -87,11 +87,11 @@ func CfgFromTns(name string) {
 		logErr(fmt.Sprintf("numShards=%d taf:%d rw:%d",numShards,tafShards,rwShards))
 
 		if numShards == tafShards {
-			GetConfig().EnableTAF=true
+			setEnableTAF(true)
 			logErr("sh taf=true")
 		}
 		if numShards == rwShards {
-			GetConfig().ReadonlyPct=50
+			setReadonlyPct(50)
 			logErr("sh rw=true")
 		}
 
@@ -101,7 +101,7 @@ func CfgFromTns(name string) {
 		dbName := baseName[:len(baseName)-1]+"2"+twoTaskSuffix
 		_,ok = tnsEntries[dbName] // taf
 		if ok && baseName[len(baseName)-2] == 'R' {
-			GetConfig().EnableTAF=true
+			setEnableTAF(true)
 			logErr("taf=true")
 			os.Setenv("TWO_TASK_STANDBY0", dbName)
 		}
@@ -110,7 +110,7 @@ func CfgFromTns(name string) {
 		_,ok = tnsEntries[dbName]
 		if ok {
 			logErr("rw-split=true")
-			GetConfig().ReadonlyPct=50
+			setReadonlyPct(50)
 			os.Setenv("TWO_TASK_READ", dbName)
 		}
 
@@ -126,12 +126,12 @@ func CfgFromTns(name string) {
 		GetConfig().NumOfShards = GetConfig().CfgFromTnsOverrideNumShards
 	}
 	if GetConfig().CfgFromTnsOverrideTaf != -1 {
-		GetConfig().EnableTAF = (GetConfig().CfgFromTnsOverrideTaf == 1)
+		setEnableTAF(GetConfig().CfgFromTnsOverrideTaf == 1)
 	}
 	if GetConfig().CfgFromTnsOverrideRWSplit != -1 {
-		GetConfig().ReadonlyPct = GetConfig().CfgFromTnsOverrideRWSplit
+		setReadonlyPct(GetConfig().CfgFromTnsOverrideRWSplit)
 	}
-	if GetConfig().EnableTAF {
+	if GetReloadableConfig().EnableTAF {
 		InitTAF(GetConfig().NumOfShards)
 	}
 }
@@ -25,7 +25,6 @@ import (
 	"github.com/paypal/hera/utility/logger"
 	otelconfig "github.com/paypal/hera/utility/logger/otel/config"
 
-	"os"
 	"path/filepath"
 	"strings"
 	"sync/atomic"
@@ -47,24 +46,15 @@ type Config struct {
 	//
 	NumStdbyDbs        int
 	InitialMaxChildren int
-	ReadonlyPct        int
 	TafChildrenPct 	   int
 	//
-	// backlog
+	// bind eviction, the thresholds are in ReloadableConfig
 	//
-	BacklogPct                  int
-	BacklogTimeoutMsec          int
-	BacklogTimeoutUnit          int64
-	ShortBacklogTimeoutMsec     int
-	SoftEvictionEffectiveTimeMs int
-	SoftEvictionProbability     int
-	BindEvictionTargetConnPct   int
-	BindEvictionThresholdPct    int
-	BindEvictionDecrPerSec      float64
-	BindEvictionNames           string
-	BindEvictionMaxThrottle     int
-	SkipEvictRegex              string
-	EvictRegex                  string
+	BindEvictionNames string
+	SkipEvictRegex    string
+	EvictRegex        string
+	// the reloadable settings of hera.txt, a key missing in the ops config takes this value
+	reloadableBase ReloadableConfig
 	//
 	//
 	//
@@ -134,11 +124,6 @@ type Config struct {
 	// flag to enable CLIENT_INFO to worker
 	EnableCmdClientInfoToWorker bool
 
-	// if TAF is enabled
-	EnableTAF bool
-	// Timeout for a query to run on the primary, before fallback to secondary
-	TAFTimeoutMs uint32
-
 	// for adaptive timeouts, how long a window to try to keep
 	TAFBinDuration       int
 	TAFAllowSlowEveryX   int
@@ -150,6 +135,52 @@ type Config struct {
 	ProfileTelnetPort string
 	// to use OpenSSL (for testing) or crypto/tls
 	UseOpenSSL bool
+	// if the clients can switch to the binary framing with CmdProtocolVersion
+	EnableBinaryFraming bool
+	// the worker responses over this size are compressed for the clients asking for it, 0 disables the compression
+	CompressionThreshold int
+
+	// port for the read-only diagnostic http endpoints, disabled if empty
+	IntrospectHTTPPort string
+	// per connection worker pinning statistics
+	EnablePinStats bool
+	// pinning report interval (in sec)
+	PinStatsInterval int
+	// how many client host/pool entries are reported each interval
+	PinStatsTopN int
+
+	// slow query log, the thresholds are in ops config
+	EnableSlowQueryLog    bool
+	SlowQueryLogFile      string
+	SlowQueryLogMaxSizeMB int
+	SlowQueryLogBackups   int
+	// if false the bind values are redacted
+	SlowQueryLogBinds bool
+
+	// binary upgrade: how long to wait for the new mux to be ready, the percentage of its workers to be healthy
+	// before it takes traffic and how long the old mux drains the client connections
+	UpgradeTimeoutSec      int
+	UpgradeWarmPct         int
+	UpgradeDrainTimeoutSec int
+	// maintenance drain: how long mux waits for the client connections to close before exiting
+	DrainTimeoutSec int
+
+	// per sqlhash latency statistics and the periodic top SQL report
+	EnableSQLStats bool
+	// top SQL report interval (in sec)
+	SQLStatsInterval int
+	// how many sqlhashes are reported each interval, also the sqlhashes exported to OTEL
+	SQLStatsTopN int
+	// bounds the number of sqlhashes tracked in one interval
+	SQLStatsMaxHashes int
+
+	// sqlhash affinity: GetWorker prefers a free worker which ran the SQL among its last SQLHashAffinitySize
+	// statements, unless less than SQLHashAffinityMinIdlePct of the workers are free or requests wait in backlog
+	EnableSQLHashAffinity     bool
+	SQLHashAffinitySize       int
+	SQLHashAffinityMinIdlePct int
+	// hit rate report interval (in sec)
+	SQLHashAffinityReportInterval int
 
 	ErrorCodePrefix       string
 	StateLogPrefix        string
@@ -158,19 +189,36 @@ type Config struct {
 	RacMaintReloadInterval int
 	// worker restarts are spread over this window
 	RacRestartWindow int
+	// maintenances scheduled from a file, and from POST /hera/maint
+	MaintFile         string
+	MaintAdminEnabled bool
+	// the workers of a pool whose database became read-only are recycled over this window
+	FailoverRestartWindow int
 
 	// worker lifespan check interval
 	lifeSpanCheckInterval int
 
 	MuxPidFile string
 
+	// module name in v$session table
+	moduleName string
+
 	// when numWorkers changes, it will write to this channel, for worker manager to update
 	numWorkersCh chan int
 
 	EnableConnLimitCheck         bool
-	EnableQueryBindBlocker       bool
 	QueryBindBlockerMinSqlPrefix int
 
+	// one of off, enforce, dry_run, learn
+	SQLAllowlistMode string
+	// if empty the allowlist is loaded from the management table
+	SQLAllowlistFile string
+	// where the learn mode records the statements seen
+	SQLAllowlistLearnFile string
+
+	// if set, the shards and their data sources are read from this file instead of tnsnames.ora
+	TopologyFile string
+
 	// taf testing
 	TestingEnableDMLTaf bool
 
@@ -195,6 +243,15 @@ type Config struct {
 	EnableBindHashLogging  bool
 	EnableSessionVariables bool
 	UseNonBlocking         bool
+	// the names of the session variables a client can set, a name ending with "*" is a prefix
+	SessionVarsAllowed []string
+	// the workers reset the session altered by a client before serving another one, with SessionResetSQL
+	// or the reset of the database
+	EnableSessionReset bool
+	SessionResetSQL    string
+	// the number of statements each worker keeps prepared when enable_cache is set, mux prefers the
+	// workers having the statement
+	MaxCacheSize int
 }
 
 // The OpsConfig contains the configuration that can be modified during run time
@@ -207,6 +264,7 @@ type OpsConfig struct {
 	maxLifespanPerChild    uint32
 	satRecoverThresholdMs  uint32
 	satRecoverThrottleRate uint32
+	slowQueryThresholdMs   [wtypeTotalCount]uint32
 }
 
 var gAppConfig *Config
@@ -239,40 +297,47 @@ func parseMapStrStr(encoded string) map[string]string {
 
 // InitConfig initializes the configuration, both the static configuration (from hera.txt) and the dynamic configuration
 func InitConfig(poolName string) error {
-	currentDir, abserr := filepath.Abs(filepath.Dir(os.Args[0]))
+	currentDir, filename := configFile()
 
-	if abserr != nil {
-		currentDir = "./"
-	} else {
-		currentDir = currentDir + "/"
-	}
-	filename := currentDir + "hera.txt"
-
-	cdb, err := config.NewTxtConfig(filename)
+	// hera.txt, overridden by hera.yaml or hera.json and by the HERA_* environment variables, is validated
+	// against the schema, the keys not set take the default of the schema
+	cdb, err := config.NewCheckedFileConfig(filename, configSchema())
 	if err != nil {
 		return err
 	}
 
-	gAppConfig = &Config{numWorkersCh: make(chan int, 1)}
+	gAppConfig = &Config{numWorkersCh: make(chan int, 1), moduleName: poolName}
 
-	logFile := cdb.GetOrDefaultString("log_file", "hera.log")
+	logFile := cdb.String("log_file")
 	logFile = currentDir + logFile
-	logLevel := cdb.GetOrDefaultInt("log_level", logger.Info)
+	logLevel := cdb.Int("log_level")
 
-	err = logger.CreateLogger(logFile, "PROXY", int32(logLevel))
+	// mux rotates the log file shared with the workers
+	err = logger.CreateLoggerWithOptions(logFile, "PROXY", int32(logLevel), logger.OptionsFromConfig(cdb, true))
 	if err != nil {
 		FullShutdown()
 	}
+	for _, warning := range cdb.Warnings() {
+		if logger.GetLogger().V(logger.Warning) {
+			logger.GetLogger().Log(logger.Warning, warning)
+		}
+	}
+	err = configErrors(cdb)
+	if err != nil {
+		return err
+	}
 
-	gAppConfig.ChildExecutable = cdb.GetOrDefaultString("child.executable", "")
+	gAppConfig.ChildExecutable = cdb.String("child.executable")
 	gAppConfig.Port, err = cdb.GetInt("bind_port")
 	if err != nil {
 		return errors.New("Config error: bind_port undefined")
 	}
-	gAppConfig.CertChainFile = cdb.GetOrDefaultString("cert_chain_file", "")
-	gAppConfig.KeyFile = cdb.GetOrDefaultString("key_file", "")
+	gAppConfig.CertChainFile = cdb.String("cert_chain_file")
+	gAppConfig.KeyFile = cdb.String("key_file")
+	gAppConfig.EnableBinaryFraming = cdb.Bool("enable_binary_framing")
+	gAppConfig.CompressionThreshold = cdb.Int("compression_threshold")
 
-	gAppConfig.LifoScheduler = cdb.GetOrDefaultBool("lifo_scheduler_enabled", true)
+	gAppConfig.LifoScheduler = cdb.Bool("lifo_scheduler_enabled")
 
 	gAppConfig.NumStdbyDbs, err = cdb.GetInt("num_standby_dbs")
 	if err != nil {
@@ -285,23 +350,23 @@ func InitConfig(poolName string) error {
 		gAppConfig.NumStdbyDbs = 0
 	}
 
-	gAppConfig.ConfigReloadTimeMs = cdb.GetOrDefaultInt("config_reload_time_ms", 30*1000)
-	gAppConfig.ConfigLoggingReloadTimeHours = cdb.GetOrDefaultInt("config_logging_reload_time_hours", 24)
-	gAppConfig.CustomAuthTimeoutMs = cdb.GetOrDefaultInt("custom_auth_timeout", 1000)
-	gAppConfig.TimeSkewThresholdWarnSec = cdb.GetOrDefaultInt("time_skew_threshold_warn", 2)
-	gAppConfig.TimeSkewThresholdErrorSec = cdb.GetOrDefaultInt("time_skew_threshold_error", 15)
-	gAppConfig.StrandedWorkerTimeoutMs = cdb.GetOrDefaultInt("max_stranded_time_interval", 2000)
-	gAppConfig.HighLoadStrandedWorkerTimeoutMs = cdb.GetOrDefaultInt("high_load_max_stranded_time_interval", 600111)
-	gAppConfig.HighLoadSkipInitiateRecoverPct = cdb.GetOrDefaultInt("high_load_skip_initiate_recover_pct", 80)
-	gAppConfig.HighLoadPct = cdb.GetOrDefaultInt("high_load_pct", 130)   // >100 disabled
-	gAppConfig.InitLimitPct = cdb.GetOrDefaultInt("init_limit_pct", 125) // >100 disabled
-
-	gAppConfig.StateLogInterval = cdb.GetOrDefaultInt("state_log_interval", 1)
+	gAppConfig.ConfigReloadTimeMs = cdb.Int("config_reload_time_ms")
+	gAppConfig.ConfigLoggingReloadTimeHours = cdb.Int("config_logging_reload_time_hours")
+	gAppConfig.CustomAuthTimeoutMs = cdb.Int("custom_auth_timeout")
+	gAppConfig.TimeSkewThresholdWarnSec = cdb.Int("time_skew_threshold_warn")
+	gAppConfig.TimeSkewThresholdErrorSec = cdb.Int("time_skew_threshold_error")
+	gAppConfig.StrandedWorkerTimeoutMs = cdb.Int("max_stranded_time_interval")
+	gAppConfig.HighLoadStrandedWorkerTimeoutMs = cdb.Int("high_load_max_stranded_time_interval")
+	gAppConfig.HighLoadSkipInitiateRecoverPct = cdb.Int("high_load_skip_initiate_recover_pct")
+	gAppConfig.HighLoadPct = cdb.Int("high_load_pct")   // >100 disabled
+	gAppConfig.InitLimitPct = cdb.Int("init_limit_pct") // >100 disabled
+
+	gAppConfig.StateLogInterval = cdb.Int("state_log_interval")
 	if gAppConfig.StateLogInterval <= 0 {
 		gAppConfig.StateLogInterval = 1
 	}
 
-	databaseType := cdb.GetOrDefaultString(ConfigDatabaseType, "oracle")
+	databaseType := cdb.String(ConfigDatabaseType)
 	if strings.EqualFold(databaseType, "oracle") {
 		gAppConfig.DatabaseType = Oracle
 		if gAppConfig.ChildExecutable == "" {
@@ -322,26 +387,26 @@ func InitConfig(poolName string) error {
 		return errors.New("database type must be either Oracle or MySQL")
 	}
 
-	gAppConfig.EnableSharding = cdb.GetOrDefaultBool("enable_sharding", false)
+	gAppConfig.EnableSharding = cdb.Bool("enable_sharding")
 
-	gAppConfig.UseShardMap = cdb.GetOrDefaultBool("use_shardmap", true)
-	gAppConfig.NumOfShards = cdb.GetOrDefaultInt("num_shards", 1)
+	gAppConfig.UseShardMap = cdb.Bool("use_shardmap")
+	gAppConfig.NumOfShards = cdb.Int("num_shards")
 	if gAppConfig.EnableSharding == false || gAppConfig.UseShardMap == false {
 		gAppConfig.NumOfShards = 1
 	}
 	if (gAppConfig.NumOfShards < 1) || (gAppConfig.NumOfShards > 48) {
 		return errors.New("num_shards must be between 1 and 48")
 	}
-	gAppConfig.ShardKeyName = strings.ToLower(cdb.GetOrDefaultString("shard_key_name", ""))
-	gAppConfig.MaxScuttleBuckets = cdb.GetOrDefaultInt("max_scuttle", 1024)
+	gAppConfig.ShardKeyName = strings.ToLower(cdb.String("shard_key_name"))
+	gAppConfig.MaxScuttleBuckets = cdb.Int("max_scuttle")
 	if (gAppConfig.MaxScuttleBuckets < 1) || (gAppConfig.MaxScuttleBuckets > 1024) {
 		return errors.New("max_scuttle must be between 1 and 1024")
 	}
-	gAppConfig.ScuttleColName = cdb.GetOrDefaultString("scuttle_col_name", "scuttle_id")
+	gAppConfig.ScuttleColName = cdb.String("scuttle_col_name")
 	if len(gAppConfig.ScuttleColName) == 0 {
 		return errors.New("scuttle_col_name is empty string")
 	}
-	algo := cdb.GetOrDefaultString("sharding_algo", "hash")
+	algo := cdb.String("sharding_algo")
 	algo = strings.ToUpper(algo)
 	if algo == "HASH" {
 		gAppConfig.ShardingAlgoHash = true
@@ -352,43 +417,45 @@ func InitConfig(poolName string) error {
 			return errors.New("sharding_algo must be either hash or mod")
 		}
 	}
-	gAppConfig.ShardingPostfix = cdb.GetOrDefaultString("sharding_postfix", "")
-	gAppConfig.EnableWhitelistTest = cdb.GetOrDefaultBool("enable_whitelist_test", false)
+	gAppConfig.ShardingPostfix = cdb.String("sharding_postfix")
+	gAppConfig.EnableWhitelistTest = cdb.Bool("enable_whitelist_test")
 	if gAppConfig.EnableWhitelistTest {
-		gAppConfig.NumWhitelistChildren = cdb.GetOrDefaultInt("whitelist_children", 5)
+		gAppConfig.NumWhitelistChildren = cdb.Int("whitelist_children")
 	}
-	gAppConfig.ShardingCfgReloadInterval = cdb.GetOrDefaultInt("sharding_cfg_reload_interval", 2)
-	gAppConfig.ShardingCrossKeysErr = cdb.GetOrDefaultBool("sharding_cross_keys_err", false)
-	gAppConfig.ShardKeyValueTypeIsString = cdb.GetOrDefaultBool("shard_key_value_type_is_string", false)
+	gAppConfig.ShardingCfgReloadInterval = cdb.Int("sharding_cfg_reload_interval")
+	gAppConfig.ShardingCrossKeysErr = cdb.Bool("sharding_cross_keys_err")
+	gAppConfig.ShardKeyValueTypeIsString = cdb.Bool("shard_key_value_type_is_string")
 
-	gAppConfig.HostnamePrefix = parseMapStrStr(cdb.GetOrDefaultString("hostname_prefix", ""))
+	gAppConfig.HostnamePrefix = parseMapStrStr(cdb.String("hostname_prefix"))
 
-	gAppConfig.EnableCmdClientInfoToWorker = cdb.GetOrDefaultBool("enable_client_info_to_worker", false)
+	gAppConfig.EnableCmdClientInfoToWorker = cdb.Bool("enable_client_info_to_worker")
 
-	gAppConfig.CfgFromTns = cdb.GetOrDefaultBool("cfg_from_tns", true)
-	gAppConfig.CfgFromTnsOverrideNumShards = cdb.GetOrDefaultInt("cfg_from_tns_override_num_shards", -1)
-	gAppConfig.CfgFromTnsOverrideTaf = cdb.GetOrDefaultInt("cfg_from_tns_override_taf", -1)
-	gAppConfig.CfgFromTnsOverrideRWSplit = cdb.GetOrDefaultInt("cfg_from_tns_override_rw_split", -1)
+	gAppConfig.CfgFromTns = cdb.Bool("cfg_from_tns")
+	gAppConfig.CfgFromTnsOverrideNumShards = cdb.Int("cfg_from_tns_override_num_shards")
+	gAppConfig.CfgFromTnsOverrideTaf = cdb.Int("cfg_from_tns_override_taf")
+	gAppConfig.CfgFromTnsOverrideRWSplit = cdb.Int("cfg_from_tns_override_rw_split")
 
 	// TAF stuff
-	gAppConfig.EnableTAF = cdb.GetOrDefaultBool("enable_taf", false)
-	gAppConfig.TAFTimeoutMs = uint32(cdb.GetOrDefaultInt("taf_timeout_ms", 200))
-	gAppConfig.TAFBinDuration = cdb.GetOrDefaultInt("taf_bin_duration", 3600*24)
-	gAppConfig.TAFAllowSlowEveryX = cdb.GetOrDefaultInt("taf_allow_slow_every_x", 100)
-	gAppConfig.TAFNormallySlowCount = cdb.GetOrDefaultInt("taf_normally_slow_count", 5)
-	if gAppConfig.EnableTAF {
+	gAppConfig.TAFBinDuration = cdb.Int("taf_bin_duration")
+	gAppConfig.TAFAllowSlowEveryX = cdb.Int("taf_allow_slow_every_x")
+	gAppConfig.TAFNormallySlowCount = cdb.Int("taf_normally_slow_count")
+	if cdb.Bool("enable_taf") {
 		InitTAF(gAppConfig.NumOfShards)
 	}
 	// TODO:
 	gAppConfig.NumStdbyDbs = 1
 
 	// Fetch Oracle worker configurations.. The defaults must be same between oracle worker and here for accurate logging.
-	gAppConfig.EnableCache = cdb.GetOrDefaultBool("enable_cache", false)
-	gAppConfig.EnableHeartBeat = cdb.GetOrDefaultBool("enable_heart_beat", false)
-	gAppConfig.EnableQueryReplaceNL = cdb.GetOrDefaultBool("enable_query_replace_nl", true)
-	gAppConfig.EnableBindHashLogging = cdb.GetOrDefaultBool("enable_bind_hash_logging", false)
-	gAppConfig.EnableSessionVariables = cdb.GetOrDefaultBool("enable_session_variables", false)
-	gAppConfig.UseNonBlocking = cdb.GetOrDefaultBool("use_non_blocking", false)
+	gAppConfig.EnableCache = cdb.Bool("enable_cache")
+	gAppConfig.MaxCacheSize = cdb.Int("max_cache_size")
+	gAppConfig.EnableHeartBeat = cdb.Bool("enable_heart_beat")
+	gAppConfig.EnableQueryReplaceNL = cdb.Bool("enable_query_replace_nl")
+	gAppConfig.EnableBindHashLogging = cdb.Bool("enable_bind_hash_logging")
+	gAppConfig.EnableSessionVariables = cdb.Bool("enable_session_variables")
+	gAppConfig.SessionVarsAllowed = parseSessionVarsAllowed(cdb.String("session_variables_allowed"))
+	gAppConfig.EnableSessionReset = cdb.Bool("enable_session_reset")
+	gAppConfig.SessionResetSQL = cdb.String("session_reset_sql")
+	gAppConfig.UseNonBlocking = cdb.Bool("use_non_blocking")
 
 	var numWorkers int
 	numWorkers = 6
@@ -407,19 +474,19 @@ func InitConfig(poolName string) error {
 		gOpsConfig = &OpsConfig{
 			logLevel:               cfg.GetOrDefaultInt("log_level", logLevel),
 			numWorkers:             uint32(numWorkers),
-			idleTimeoutMs:          uint32(cfg.GetOrDefaultInt("idle_timeout_ms", 600000)),
-			trIdleTimeoutMs:        uint32(cfg.GetOrDefaultInt("transaction_idle_timeout_ms", 900000)),
-			maxLifespanPerChild:    uint32(cfg.GetOrDefaultInt("max_lifespan_per_child", 0)),
-			maxRequestsPerChild:    uint32(cfg.GetOrDefaultInt("max_requests_per_child", 0)),
-			satRecoverThresholdMs:  uint32(cfg.GetOrDefaultInt("saturation_recover_threshold", 200)),
-			satRecoverThrottleRate: uint32(cfg.GetOrDefaultInt("saturation_recover_throttle_rate", 0)),
+			idleTimeoutMs:          uint32(cfg.GetOrDefaultInt("idle_timeout_ms", configSchema().DefaultInt("idle_timeout_ms"))),
+			trIdleTimeoutMs:        uint32(cfg.GetOrDefaultInt("transaction_idle_timeout_ms", configSchema().DefaultInt("transaction_idle_timeout_ms"))),
+			maxLifespanPerChild:    uint32(cfg.GetOrDefaultInt("max_lifespan_per_child", configSchema().DefaultInt("max_lifespan_per_child"))),
+			maxRequestsPerChild:    uint32(cfg.GetOrDefaultInt("max_requests_per_child", configSchema().DefaultInt("max_requests_per_child"))),
+			satRecoverThresholdMs:  uint32(cfg.GetOrDefaultInt("saturation_recover_threshold", configSchema().DefaultInt("saturation_recover_threshold"))),
+			satRecoverThrottleRate: uint32(cfg.GetOrDefaultInt("saturation_recover_throttle_rate", configSchema().DefaultInt("saturation_recover_throttle_rate"))),
 		}
+		loadSlowQueryThresholds(cfg)
 		logger.SetLogVerbosity(int32(gOpsConfig.logLevel))
 		gAppConfig.numWorkersCh <- numWorkers
 	}
 
-	gAppConfig.ReadonlyPct = cdb.GetOrDefaultInt("readonly_children_pct", 0)
-	gAppConfig.TafChildrenPct = cdb.GetOrDefaultInt("taf_children_pct", 100)
+	gAppConfig.TafChildrenPct = cdb.Int("taf_children_pct")
 	gAppConfig.InitialMaxChildren = numWorkers
 	if gAppConfig.EnableWhitelistTest {
 		if gAppConfig.NumWhitelistChildren < 2 {
@@ -436,56 +503,93 @@ func InitConfig(poolName string) error {
 		}
 	}
 
-	gAppConfig.BacklogPct = cdb.GetOrDefaultInt("backlog_pct", 30)
-	gAppConfig.BacklogTimeoutMsec = cdb.GetOrDefaultInt("request_backlog_timeout", 1000)
-	gAppConfig.ShortBacklogTimeoutMsec = cdb.GetOrDefaultInt("short_backlog_timeout", 30)
-	gAppConfig.BacklogTimeoutUnit = int64(gAppConfig.BacklogTimeoutMsec) / 5
-	if gAppConfig.BacklogTimeoutMsec < 5 {
-		gAppConfig.BacklogTimeoutUnit = 1
-	}
-	gAppConfig.SoftEvictionEffectiveTimeMs = cdb.GetOrDefaultInt("soft_eviction_effective_time", 10000)
-	gAppConfig.SoftEvictionProbability = cdb.GetOrDefaultInt("soft_eviction_probability", 50)
-	gAppConfig.BindEvictionTargetConnPct = cdb.GetOrDefaultInt("bind_eviction_target_conn_pct", 50)
-	gAppConfig.BindEvictionMaxThrottle = cdb.GetOrDefaultInt("bind_eviction_max_throttle", 20)
-	default_evict_names := fmt.Sprintf("id,num,%s", SrcPrefixAppKey)
-	gAppConfig.BindEvictionNames = cdb.GetOrDefaultString("bind_eviction_names", default_evict_names)
-	gAppConfig.BindEvictionThresholdPct = cdb.GetOrDefaultInt("bind_eviction_threshold_pct", 60)
-	fmt.Sscanf(cdb.GetOrDefaultString("bind_eviction_decr_per_sec", "10.0"),
-		"%f", &gAppConfig.BindEvictionDecrPerSec)
-
-	gAppConfig.SkipEvictRegex = cdb.GetOrDefaultString("skip_eviction_host_prefix", "")
-	gAppConfig.EvictRegex = cdb.GetOrDefaultString("eviction_host_prefix", "")
-
-	gAppConfig.BouncerEnabled = cdb.GetOrDefaultBool("bouncer_enabled", true)
-	gAppConfig.BouncerStartupDelay = cdb.GetOrDefaultInt("bouncer_startup_delay", 10)
-	gAppConfig.BouncerPollInterval = cdb.GetOrDefaultInt("bouncer_poll_interval_ms", 100)
-	gAppConfig.EnableProfile = cdb.GetOrDefaultBool("enable_profile", false)
-	gAppConfig.ProfileHTTPPort = cdb.GetOrDefaultString("profile_http_port", "6060")
-	gAppConfig.ProfileTelnetPort = cdb.GetOrDefaultString("profile_telnet_port", "3030")
-	gAppConfig.UseOpenSSL = cdb.GetOrDefaultBool("openssl", false)
-	gAppConfig.MuxPidFile = cdb.GetOrDefaultString("mux_pid_file", "mux.pid")
-
-	gAppConfig.ErrorCodePrefix = cdb.GetOrDefaultString("error_code_prefix", "HERA")
-	gAppConfig.StateLogPrefix = cdb.GetOrDefaultString("state_log_prefix", "hera")
-	gAppConfig.ManagementTablePrefix = cdb.GetOrDefaultString("management_table_prefix", "hera")
-	gAppConfig.RacMaintReloadInterval = cdb.GetOrDefaultInt("rac_sql_interval", 10)
-	gAppConfig.RacRestartWindow = cdb.GetOrDefaultInt("rac_restart_window", 240)
-	gAppConfig.lifeSpanCheckInterval = cdb.GetOrDefaultInt("lifespan_check_interval", 10)
-
-	gAppConfig.EnableConnLimitCheck = cdb.GetOrDefaultBool("enable_connlimit_check", false)
-	gAppConfig.EnableQueryBindBlocker = cdb.GetOrDefaultBool("enable_query_bind_blocker", false)
-	gAppConfig.QueryBindBlockerMinSqlPrefix = cdb.GetOrDefaultInt("query_bind_blocker_min_sql_prefix", 20)
-	gAppConfig.TestingEnableDMLTaf = cdb.GetOrDefaultBool("testing_enable_dml_taf", false)
-	gAppConfig.EnableDanglingWorkerRecovery = cdb.GetOrDefaultBool("enable_danglingworker_recovery", false)
-
-	gAppConfig.GoStatsInterval = cdb.GetOrDefaultInt("go_stats_interval", 10)
+	// backlog, eviction, TAF timeout, R-W split and rate limiter settings can be changed in the ops config
+	initReloadableConfig(cdb)
+	gAppConfig.BindEvictionNames = cdb.String("bind_eviction_names")
+
+	gAppConfig.SkipEvictRegex = cdb.String("skip_eviction_host_prefix")
+	gAppConfig.EvictRegex = cdb.String("eviction_host_prefix")
+
+	gAppConfig.BouncerEnabled = cdb.Bool("bouncer_enabled")
+	gAppConfig.BouncerStartupDelay = cdb.Int("bouncer_startup_delay")
+	gAppConfig.BouncerPollInterval = cdb.Int("bouncer_poll_interval_ms")
+	gAppConfig.EnableProfile = cdb.Bool("enable_profile")
+	gAppConfig.ProfileHTTPPort = cdb.String("profile_http_port")
+	gAppConfig.ProfileTelnetPort = cdb.String("profile_telnet_port")
+	gAppConfig.UseOpenSSL = cdb.Bool("openssl")
+	gAppConfig.IntrospectHTTPPort = cdb.String("introspect_http_port")
+	gAppConfig.EnablePinStats = cdb.Bool("enable_pin_stats")
+	gAppConfig.PinStatsInterval = cdb.Int("pin_stats_interval")
+	if gAppConfig.PinStatsInterval <= 0 {
+		gAppConfig.PinStatsInterval = 60
+	}
+	gAppConfig.PinStatsTopN = cdb.Int("pin_stats_top_n")
+	gAppConfig.EnableSlowQueryLog = cdb.Bool("enable_slow_query_log")
+	gAppConfig.SlowQueryLogFile = currentDir + cdb.String("slow_query_log_file")
+	gAppConfig.SlowQueryLogMaxSizeMB = cdb.Int("slow_query_log_max_size_mb")
+	gAppConfig.SlowQueryLogBackups = cdb.Int("slow_query_log_backups")
+	gAppConfig.SlowQueryLogBinds = cdb.Bool("slow_query_log_binds")
+	gAppConfig.UpgradeTimeoutSec = cdb.Int("upgrade_timeout_sec")
+	gAppConfig.UpgradeWarmPct = cdb.Int("upgrade_warm_pct")
+	gAppConfig.UpgradeDrainTimeoutSec = cdb.Int("upgrade_drain_timeout_sec")
+	gAppConfig.DrainTimeoutSec = cdb.Int("drain_timeout_sec")
+	gAppConfig.EnableSQLStats = cdb.Bool("enable_sql_stats")
+	gAppConfig.SQLStatsInterval = cdb.Int("sql_stats_interval")
+	if gAppConfig.SQLStatsInterval <= 0 {
+		gAppConfig.SQLStatsInterval = 60
+	}
+	gAppConfig.SQLStatsTopN = cdb.Int("sql_stats_top_n")
+	gAppConfig.SQLStatsMaxHashes = cdb.Int("sql_stats_max_hashes")
+	gAppConfig.EnableSQLHashAffinity = cdb.Bool("enable_sqlhash_affinity")
+	gAppConfig.SQLHashAffinitySize = cdb.Int("sqlhash_affinity_size")
+	gAppConfig.SQLHashAffinityMinIdlePct = cdb.Int("sqlhash_affinity_min_idle_pct")
+	gAppConfig.SQLHashAffinityReportInterval = cdb.Int("sqlhash_affinity_report_interval")
+	if gAppConfig.SQLHashAffinityReportInterval <= 0 {
+		gAppConfig.SQLHashAffinityReportInterval = 60
+	}
+	gAppConfig.MuxPidFile = cdb.String("mux_pid_file")
+
+	gAppConfig.ErrorCodePrefix = cdb.String("error_code_prefix")
+	gAppConfig.StateLogPrefix = cdb.String("state_log_prefix")
+	gAppConfig.ManagementTablePrefix = cdb.String("management_table_prefix")
+	gAppConfig.RacMaintReloadInterval = cdb.Int("rac_sql_interval")
+	gAppConfig.RacRestartWindow = cdb.Int("rac_restart_window")
+	gAppConfig.lifeSpanCheckInterval = cdb.Int("lifespan_check_interval")
+
+	gAppConfig.EnableConnLimitCheck = cdb.Bool("enable_connlimit_check")
+	gAppConfig.QueryBindBlockerMinSqlPrefix = cdb.Int("query_bind_blocker_min_sql_prefix")
+	gAppConfig.SQLAllowlistMode = strings.ToLower(cdb.String("sql_allowlist_mode"))
+	switch gAppConfig.SQLAllowlistMode {
+	case SQLAllowlistOff, SQLAllowlistEnforce, SQLAllowlistDryRun, SQLAllowlistLearn:
+	default:
+		return fmt.Errorf("invalid sql_allowlist_mode: %s", gAppConfig.SQLAllowlistMode)
+	}
+	gAppConfig.SQLAllowlistFile = cdb.String("sql_allowlist_file")
+	if (len(gAppConfig.SQLAllowlistFile) > 0) && !filepath.IsAbs(gAppConfig.SQLAllowlistFile) {
+		gAppConfig.SQLAllowlistFile = currentDir + gAppConfig.SQLAllowlistFile
+	}
+	gAppConfig.SQLAllowlistLearnFile = currentDir + cdb.String("sql_allowlist_learn_file")
+	gAppConfig.MaintFile = cdb.String("maint_file")
+	if (len(gAppConfig.MaintFile) > 0) && !filepath.IsAbs(gAppConfig.MaintFile) {
+		gAppConfig.MaintFile = currentDir + gAppConfig.MaintFile
+	}
+	gAppConfig.MaintAdminEnabled = cdb.Bool("maint_admin_enabled")
+	gAppConfig.FailoverRestartWindow = cdb.Int("failover_restart_window")
+	gAppConfig.TopologyFile = cdb.String("topology_file")
+	if (len(gAppConfig.TopologyFile) > 0) && !filepath.IsAbs(gAppConfig.TopologyFile) {
+		gAppConfig.TopologyFile = currentDir + gAppConfig.TopologyFile
+	}
+	gAppConfig.TestingEnableDMLTaf = cdb.Bool("testing_enable_dml_taf")
+	gAppConfig.EnableDanglingWorkerRecovery = cdb.Bool("enable_danglingworker_recovery")
+
+	gAppConfig.GoStatsInterval = cdb.Int("go_stats_interval")
 	defaultConns := 10000 // disable by default
-	if gAppConfig.EnableTAF {
+	if cdb.Bool("enable_taf") {
 		defaultConns = 5
 	}
-	gAppConfig.RandomStartMs = cdb.GetOrDefaultInt("random_start_ms", 20000)
+	gAppConfig.RandomStartMs = cdb.Int("random_start_ms")
 	gAppConfig.MaxDbConnectsPerSec = cdb.GetOrDefaultInt("max_db_connects_per_sec", defaultConns)
-	gAppConfig.MaxDesiredHealthyWorkerPct = cdb.GetOrDefaultInt("max_desire_healthy_worker_pct", 90)
+	gAppConfig.MaxDesiredHealthyWorkerPct = cdb.Int("max_desire_healthy_worker_pct")
 	if gAppConfig.MaxDesiredHealthyWorkerPct > 100 {
 		gAppConfig.MaxDesiredHealthyWorkerPct = 90
 	}
@@ -495,40 +599,21 @@ func InitConfig(poolName string) error {
 	if logger.GetLogger().V(logger.Info) {
 		otelconfig.OTelConfigData.Dump()
 	}
-	return nil
+	// the keys read above and missing in the schema
+	return configErrors(cdb)
 }
 
 // This function takes care of initialize OTEL configuration
 func initializeOTELConfigs(cdb config.Config, poolName string) {
-	otelconfig.OTelConfigData = &otelconfig.OTelConfig{}
-	//TODO initialize the values
-	otelconfig.OTelConfigData.Enabled = cdb.GetOrDefaultBool("enable_otel", false)
-	otelconfig.OTelConfigData.SkipCalStateLog = cdb.GetOrDefaultBool("skip_cal_statelog", false)
-	otelconfig.OTelConfigData.MetricNamePrefix = cdb.GetOrDefaultString("otel_metric_prefix", "pp.occ")
-	otelconfig.OTelConfigData.Host = cdb.GetOrDefaultString("otel_agent_host", "localhost")
-	otelconfig.OTelConfigData.MetricsPort = cdb.GetOrDefaultInt("otel_agent_metrics_port", 4318)
-	otelconfig.OTelConfigData.TracePort = cdb.GetOrDefaultInt("otel_agent_trace_port", 4318)
-	otelconfig.OTelConfigData.OtelMetricGRPC = cdb.GetOrDefaultBool("otel_agent_use_grpc_metric", false)
-	otelconfig.OTelConfigData.OtelTraceGRPC = cdb.GetOrDefaultBool("otel_agent_use_grpc_trace", false)
-	otelconfig.OTelConfigData.MetricsURLPath = cdb.GetOrDefaultString("otel_agent_metrics_uri", "")
-	otelconfig.OTelConfigData.TraceURLPath = cdb.GetOrDefaultString("otel_agent_trace_uri", "")
-	otelconfig.OTelConfigData.PoolName = poolName
-	otelconfig.OTelConfigData.UseTls = cdb.GetOrDefaultBool("otel_use_tls", false)
-	otelconfig.OTelConfigData.TLSCertPath = cdb.GetOrDefaultString("otel_tls_cert_path", "")
-	otelconfig.OTelConfigData.ResolutionTimeInSec = cdb.GetOrDefaultInt("otel_resolution_time_in_sec", 1)
-	otelconfig.OTelConfigData.ExporterTimeout = cdb.GetOrDefaultInt("otel_exporter_time_in_sec", 30)
-	otelconfig.OTelConfigData.EnableRetry = cdb.GetOrDefaultBool("otel_enable_exporter_retry", false)
-	otelconfig.OTelConfigData.ResourceType = gAppConfig.StateLogPrefix
-	otelconfig.OTelConfigData.OTelErrorReportingInterval = cdb.GetOrDefaultInt("otel_error_reporting_interval_in_sec", 60)
-	otelconfig.SetOTelIngestToken(cdb.GetOrDefaultString("otel_ingest_token", ""))
+	otelconfig.InitFromConfig(cdb, poolName, gAppConfig.StateLogPrefix)
 }
 
 func LogOccConfigs() {
 	whiteListConfigs := map[string]map[string]interface{}{
 		"BACKLOG": {
-			"backlog_pct":             gAppConfig.BacklogPct,
-			"request_backlog_timeout": gAppConfig.BacklogTimeoutMsec,
-			"short_backlog_timeout":   gAppConfig.ShortBacklogTimeoutMsec,
+			"backlog_pct":             GetReloadableConfig().BacklogPct,
+			"request_backlog_timeout": GetReloadableConfig().BacklogTimeoutMsec,
+			"short_backlog_timeout":   GetReloadableConfig().ShortBacklogTimeoutMsec,
 		},
 		"BOUNCER": {
 			"bouncer_enabled":          gAppConfig.BouncerEnabled,
@@ -552,6 +637,39 @@ func LogOccConfigs() {
 			"profile_http_port":   gAppConfig.ProfileHTTPPort,
 			"profile_telnet_port": gAppConfig.ProfileTelnetPort,
 		},
+		"PIN-STATS": {
+			"enable_pin_stats":     gAppConfig.EnablePinStats,
+			"pin_stats_interval":   gAppConfig.PinStatsInterval,
+			"pin_stats_top_n":      gAppConfig.PinStatsTopN,
+			"introspect_http_port": gAppConfig.IntrospectHTTPPort,
+		},
+		"SLOW-QUERY-LOG": {
+			"enable_slow_query_log":      gAppConfig.EnableSlowQueryLog,
+			"slow_query_log_file":        gAppConfig.SlowQueryLogFile,
+			"slow_query_log_max_size_mb": gAppConfig.SlowQueryLogMaxSizeMB,
+			"slow_query_log_backups":     gAppConfig.SlowQueryLogBackups,
+			"slow_query_log_binds":       gAppConfig.SlowQueryLogBinds,
+			"slow_query_threshold_ms_rw": GetSlowQueryThresholdMs(wtypeRW),
+			"slow_query_threshold_ms_ro": GetSlowQueryThresholdMs(wtypeRO),
+		},
+		"UPGRADE": {
+			"upgrade_timeout_sec":       gAppConfig.UpgradeTimeoutSec,
+			"upgrade_warm_pct":          gAppConfig.UpgradeWarmPct,
+			"upgrade_drain_timeout_sec": gAppConfig.UpgradeDrainTimeoutSec,
+			"drain_timeout_sec":         gAppConfig.DrainTimeoutSec,
+		},
+		"SQL-STATS": {
+			"enable_sql_stats":     gAppConfig.EnableSQLStats,
+			"sql_stats_interval":   gAppConfig.SQLStatsInterval,
+			"sql_stats_top_n":      gAppConfig.SQLStatsTopN,
+			"sql_stats_max_hashes": gAppConfig.SQLStatsMaxHashes,
+		},
+		"SQLHASH-AFFINITY": {
+			"enable_sqlhash_affinity":          gAppConfig.EnableSQLHashAffinity,
+			"sqlhash_affinity_size":            gAppConfig.SQLHashAffinitySize,
+			"sqlhash_affinity_min_idle_pct":    gAppConfig.SQLHashAffinityMinIdlePct,
+			"sqlhash_affinity_report_interval": gAppConfig.SQLHashAffinityReportInterval,
+		},
 		"SHARDING": {
 			"enable_sharding":                gAppConfig.EnableSharding,
 			"use_shardmap":                   gAppConfig.UseShardMap,
@@ -570,9 +688,9 @@ func LogOccConfigs() {
 			"sharding_algo": gAppConfig.ShardingAlgoHash,
 		},
 		"TAF": {
-			"enable_taf":              gAppConfig.EnableTAF,
+			"enable_taf":              GetReloadableConfig().EnableTAF,
 			"testing_enable_dml_taf":  gAppConfig.TestingEnableDMLTaf,
-			"taf_timeout_ms":          gAppConfig.TAFTimeoutMs,
+			"taf_timeout_ms":          GetReloadableConfig().TAFTimeoutMs,
 			"taf_bin_duration":        gAppConfig.TAFBinDuration,
 			"taf_allow_slow_every_x":  gAppConfig.TAFAllowSlowEveryX,
 			"taf_normally_slow_count": gAppConfig.TAFNormallySlowCount,
@@ -580,26 +698,31 @@ func LogOccConfigs() {
 		"BIND-EVICTION": {
 			"child.executable": gAppConfig.ChildExecutable,
 			//"enable_bind_hash_logging" FOUND FOR SOME OCCs ONLY IN occ.def
-			"bind_eviction_threshold_pct":       gAppConfig.BindEvictionThresholdPct,
-			"bind_eviction_decr_per_sec":        gAppConfig.BindEvictionDecrPerSec,
-			"bind_eviction_target_conn_pct":     gAppConfig.BindEvictionTargetConnPct,
-			"bind_eviction_max_throttle":        gAppConfig.BindEvictionMaxThrottle,
+			"bind_eviction_threshold_pct":       GetReloadableConfig().BindEvictionThresholdPct,
+			"bind_eviction_decr_per_sec":        GetReloadableConfig().BindEvictionDecrPerSec,
+			"bind_eviction_target_conn_pct":     GetReloadableConfig().BindEvictionTargetConnPct,
+			"bind_eviction_max_throttle":        GetReloadableConfig().BindEvictionMaxThrottle,
 			"bind_eviction_names":               gAppConfig.BindEvictionNames,
 			"skip_eviction_host_prefix":         gAppConfig.SkipEvictRegex,
 			"eviction_host_prefix":              gAppConfig.EvictRegex,
 			"query_bind_blocker_min_sql_prefix": gAppConfig.QueryBindBlockerMinSqlPrefix,
 			"enable_connlimit_check":            gAppConfig.EnableConnLimitCheck,
 		},
+		"SQL-ALLOWLIST": {
+			"sql_allowlist_mode":       gAppConfig.SQLAllowlistMode,
+			"sql_allowlist_file":       gAppConfig.SQLAllowlistFile,
+			"sql_allowlist_learn_file": gAppConfig.SQLAllowlistLearnFile,
+		},
 		"MANUAL-RATE-LIMITER": {
-			"enable_query_bind_blocker": gAppConfig.EnableQueryBindBlocker,
+			"enable_query_bind_blocker": GetReloadableConfig().EnableQueryBindBlocker,
 		},
 		"SATURATION-RECOVERY": {
 			"saturation_recover_threshold":     GetSatRecoverThresholdMs(),
 			"saturation_recover_throttle_rate": GetSatRecoverThrottleRate(),
 		},
 		"SOFT-EVICTION": {
-			"soft_eviction_effective_time": gAppConfig.SoftEvictionEffectiveTimeMs,
-			"soft_eviction_probability":    gAppConfig.SoftEvictionProbability,
+			"soft_eviction_effective_time": GetReloadableConfig().SoftEvictionEffectiveTimeMs,
+			"soft_eviction_probability":    GetReloadableConfig().SoftEvictionProbability,
 		},
 		"WORKER-CONFIGURATIONS": {
 			"lifespan_check_interval": gAppConfig.lifeSpanCheckInterval,
@@ -616,13 +739,20 @@ func LogOccConfigs() {
 			"max_desire_healthy_worker_pct":        gAppConfig.MaxDesiredHealthyWorkerPct,
 		},
 		"R-W-SPLIT": {
-			"readonly_children_pct": gAppConfig.ReadonlyPct,
+			"readonly_children_pct": GetReloadableConfig().ReadonlyPct,
 		},
 		"RAC": {
 			"management_table_prefix": gAppConfig.ManagementTablePrefix,
 			"rac_sql_interval":        gAppConfig.RacMaintReloadInterval,
 			"rac_restart_window":      gAppConfig.RacRestartWindow,
 		},
+		"MAINTENANCE": {
+			"maint_file":          gAppConfig.MaintFile,
+			"maint_admin_enabled": gAppConfig.MaintAdminEnabled,
+		},
+		"FAILOVER": {
+			"failover_restart_window": gAppConfig.FailoverRestartWindow,
+		},
 		"GENERAL-CONFIGURATIONS": {
 			"database_type":   gAppConfig.DatabaseType, //	Oracle = 0; MySQL=1; POSTGRES=2
 			"log_level":       gOpsConfig.logLevel,
@@ -630,6 +760,9 @@ func LogOccConfigs() {
 			"init_limit_pct":  gAppConfig.InitLimitPct,
 			"num_standby_dbs": gAppConfig.NumStdbyDbs,
 		},
+		"TOPOLOGY": {
+			"topology_file": gAppConfig.TopologyFile,
+		},
 		"ENABLE_CFG_FROM_TNS": {
 			"cfg_from_tns":                     gAppConfig.CfgFromTns,
 			"cfg_from_tns_override_num_shards": gAppConfig.CfgFromTnsOverrideNumShards,
@@ -638,15 +771,25 @@ func LogOccConfigs() {
 		},
 		"STATEMENT-CACHE": {
 			"enable_cache":            gAppConfig.EnableCache,
+			"max_cache_size":          gAppConfig.MaxCacheSize,
 			"enable_heart_beat":       gAppConfig.EnableHeartBeat,
 			"enable_query_replace_nl": gAppConfig.EnableQueryReplaceNL,
 		},
 		"SESSION-VARIABLES": {
-			"enable_session_variables": gAppConfig.EnableSessionVariables,
+			"enable_session_variables":  gAppConfig.EnableSessionVariables,
+			"session_variables_allowed": strings.Join(gAppConfig.SessionVarsAllowed, ","),
+		},
+		"SESSION-RESET": {
+			"enable_session_reset": gAppConfig.EnableSessionReset,
+			"session_reset_sql":    gAppConfig.SessionResetSQL,
 		},
 		"BIND-HASH-LOGGING": {
 			"enable_bind_hash_logging": gAppConfig.EnableBindHashLogging,
 		},
+		"PROTOCOL": {
+			"enable_binary_framing": gAppConfig.EnableBinaryFraming,
+			"compression_threshold": gAppConfig.CompressionThreshold,
+		},
 		"KEEP-ALIVE": {
 			"use_non_blocking": gAppConfig.UseNonBlocking,
 		},
@@ -655,7 +798,7 @@ func LogOccConfigs() {
 	for feature, configs := range whiteListConfigs {
 		switch feature {
 		case "BACKLOG":
-			if gAppConfig.BacklogPct == 0 {
+			if GetReloadableConfig().BacklogPct == 0 {
 				continue
 			}
 		case "BOUNCER":
@@ -670,16 +813,32 @@ func LogOccConfigs() {
 			if !gAppConfig.EnableProfile {
 				continue
 			}
+		case "PIN-STATS":
+			if !gAppConfig.EnablePinStats {
+				continue
+			}
+		case "SLOW-QUERY-LOG":
+			if !gAppConfig.EnableSlowQueryLog {
+				continue
+			}
+		case "SQL-STATS":
+			if !gAppConfig.EnableSQLStats {
+				continue
+			}
+		case "SQLHASH-AFFINITY":
+			if !gAppConfig.EnableSQLHashAffinity {
+				continue
+			}
 		case "SHARDING":
 			if !gAppConfig.EnableSharding {
 				continue
 			}
 		case "TAF":
-			if !gAppConfig.EnableTAF {
+			if !GetReloadableConfig().EnableTAF {
 				continue
 			}
 		case "R-W-SPLIT":
-			if gAppConfig.ReadonlyPct == 0 {
+			if GetReloadableConfig().ReadonlyPct == 0 {
 				continue
 			}
 		case "SATURATION-RECOVERY", "BIND-EVICTION":
@@ -687,15 +846,32 @@ func LogOccConfigs() {
 				continue
 			}
 		case "SOFT-EVICTION":
-			if GetSatRecoverThrottleRate() <= 0 && gAppConfig.SoftEvictionProbability <= 0 {
+			if GetSatRecoverThrottleRate() <= 0 && GetReloadableConfig().SoftEvictionProbability <= 0 {
 				continue
 			}
 		case "MANUAL-RATE-LIMITER":
-			if !gAppConfig.EnableQueryBindBlocker {
+			if !GetReloadableConfig().EnableQueryBindBlocker {
+				continue
+			}
+		case "SQL-ALLOWLIST":
+			if gAppConfig.SQLAllowlistMode == SQLAllowlistOff {
+				continue
+			}
+		case "MAINTENANCE":
+			if (len(gAppConfig.MaintFile) == 0) && !gAppConfig.MaintAdminEnabled {
+				continue
+			}
+		case "FAILOVER":
+			// only the mysql and postgres workers report their database read-only
+			if gAppConfig.DatabaseType == Oracle {
+				continue
+			}
+		case "TOPOLOGY":
+			if len(gAppConfig.TopologyFile) == 0 {
 				continue
 			}
 		case "ENABLE_CFG_FROM_TNS":
-			if !gAppConfig.CfgFromTns {
+			if !gAppConfig.CfgFromTns || (len(gAppConfig.TopologyFile) > 0) {
 				continue
 			}
 		case "STATEMENT-CACHE":
@@ -708,6 +884,11 @@ func LogOccConfigs() {
 				continue
 			}
 			calName = oracle_worker_config_cal_name
+		case "SESSION-RESET":
+			if !gAppConfig.EnableSessionReset {
+				continue
+			}
+			calName = oracle_worker_config_cal_name
 		case "BIND-HASH-LOGGING":
 			if !gAppConfig.EnableBindHashLogging {
 				continue
@@ -745,36 +926,46 @@ func CheckOpsConfigChange() {
 			}
 			logLevel, err := cfg.GetInt("log_level")
 			if (err == nil) && (logLevel != gOpsConfig.logLevel) {
+				logConfigChange("log_level", gOpsConfig.logLevel, logLevel)
 				logger.SetLogVerbosity(int32(logLevel))
 				gOpsConfig.logLevel = logLevel
 			}
 
-			idleTimeoutMs := uint32(cfg.GetOrDefaultInt("idle_timeout_ms", 600000))
-			trIdleTimeoutMs := uint32(cfg.GetOrDefaultInt("transaction_idle_timeout_ms", 900000))
+			idleTimeoutMs := uint32(cfg.GetOrDefaultInt("idle_timeout_ms", configSchema().DefaultInt("idle_timeout_ms")))
+			trIdleTimeoutMs := uint32(cfg.GetOrDefaultInt("transaction_idle_timeout_ms", configSchema().DefaultInt("transaction_idle_timeout_ms")))
 			if idleTimeoutMs != gOpsConfig.idleTimeoutMs {
+				logConfigChange("idle_timeout_ms", gOpsConfig.idleTimeoutMs, idleTimeoutMs)
 				atomic.StoreUint32(&(gOpsConfig.idleTimeoutMs), idleTimeoutMs)
 			}
 			if trIdleTimeoutMs != gOpsConfig.trIdleTimeoutMs {
+				logConfigChange("transaction_idle_timeout_ms", gOpsConfig.trIdleTimeoutMs, trIdleTimeoutMs)
 				atomic.StoreUint32(&(gOpsConfig.trIdleTimeoutMs), trIdleTimeoutMs)
 			}
 
-			maxLifespanPerChild := uint32(cfg.GetOrDefaultInt("max_lifespan_per_child", 0))
-			maxRequestsPerChild := uint32(cfg.GetOrDefaultInt("max_requests_per_child", 0))
+			maxLifespanPerChild := uint32(cfg.GetOrDefaultInt("max_lifespan_per_child", configSchema().DefaultInt("max_lifespan_per_child")))
+			maxRequestsPerChild := uint32(cfg.GetOrDefaultInt("max_requests_per_child", configSchema().DefaultInt("max_requests_per_child")))
 			if maxLifespanPerChild != gOpsConfig.maxLifespanPerChild {
+				logConfigChange("max_lifespan_per_child", gOpsConfig.maxLifespanPerChild, maxLifespanPerChild)
 				atomic.StoreUint32(&(gOpsConfig.maxLifespanPerChild), maxLifespanPerChild)
 			}
 			if maxRequestsPerChild != gOpsConfig.maxRequestsPerChild {
+				logConfigChange("max_requests_per_child", gOpsConfig.maxRequestsPerChild, maxRequestsPerChild)
 				atomic.StoreUint32(&(gOpsConfig.maxRequestsPerChild), maxRequestsPerChild)
 			}
 
-			satRecoverThresholdMs := uint32(cfg.GetOrDefaultInt("saturation_recover_threshold", 200))
+			satRecoverThresholdMs := uint32(cfg.GetOrDefaultInt("saturation_recover_threshold", configSchema().DefaultInt("saturation_recover_threshold")))
 			if satRecoverThresholdMs != gOpsConfig.satRecoverThresholdMs {
+				logConfigChange("saturation_recover_threshold", gOpsConfig.satRecoverThresholdMs, satRecoverThresholdMs)
 				atomic.StoreUint32(&(gOpsConfig.satRecoverThresholdMs), satRecoverThresholdMs)
 			}
-			satRecoverThrottleRate := uint32(cfg.GetOrDefaultInt("saturation_recover_throttle_rate", 0))
+			loadSlowQueryThresholds(cfg)
+
+			satRecoverThrottleRate := uint32(cfg.GetOrDefaultInt("saturation_recover_throttle_rate", configSchema().DefaultInt("saturation_recover_throttle_rate")))
 			if satRecoverThrottleRate != gOpsConfig.satRecoverThrottleRate {
+				logConfigChange("saturation_recover_throttle_rate", gOpsConfig.satRecoverThrottleRate, satRecoverThrottleRate)
 				atomic.StoreUint32(&(gOpsConfig.satRecoverThrottleRate), satRecoverThrottleRate)
 			}
+			reloadConfig(cfg)
 
 			numWorkers, err := cfg.GetInt(ConfigMaxWorkers)
 			if err != nil {
@@ -783,9 +974,7 @@ func CheckOpsConfigChange() {
 				}
 			} else {
 				if uint32(numWorkers) != gOpsConfig.numWorkers {
-					if logger.GetLogger().V(logger.Info) {
-						logger.GetLogger().Log(logger.Info, "Changing max_connections from", gOpsConfig.numWorkers, "to", numWorkers)
-					}
+					logConfigChange(ConfigMaxWorkers, gOpsConfig.numWorkers, numWorkers)
 					atomic.StoreUint32(&(gOpsConfig.numWorkers), uint32(numWorkers))
 					gAppConfig.numWorkersCh <- numWorkers
 				} else {
@@ -826,11 +1015,11 @@ func (cfg *Config) NumWorkersCh() <-chan int {
 // GetBacklogLimit returns the limit for the number of backlogged workers for a certain pool and shard.
 func (cfg *Config) GetBacklogLimit(wtype HeraWorkerType, shard int) int {
 	if wtype == wtypeRO {
-		return gAppConfig.BacklogPct * GetNumRWorkers(shard) / 100
+		return GetReloadableConfig().BacklogPct * GetNumRWorkers(shard) / 100
 	} else if wtype == wtypeStdBy {
-		return gAppConfig.BacklogPct * GetNumStdByWorkers(shard) / 100
+		return GetReloadableConfig().BacklogPct * GetNumStdByWorkers(shard) / 100
 	}
-	return gAppConfig.BacklogPct * GetNumWWorkers(shard) / 100
+	return GetReloadableConfig().BacklogPct * GetNumWWorkers(shard) / 100
 }
 
 // GetSatRecoverThresholdMs gets the saturation recover threshold in milliseconds from ops config
@@ -888,14 +1077,19 @@ func GetNumWorkers(shard int) int {
 
 // GetNumRWorkers gets the number of workers for the "Read" pool
 func GetNumRWorkers(shard int) int {
+	return numRWorkers(shard, GetReloadableConfig().ReadonlyPct)
+}
+
+// numRWorkers gets the number of workers for the "Read" pool with the given readonly_children_pct
+func numRWorkers(shard int, readonlyPct int) int {
 	numWhiteList := GetWhiteListChildCount(shard)
-	if (numWhiteList > 0) && (gAppConfig.ReadonlyPct > 0) {
+	if (numWhiteList > 0) && (readonlyPct > 0) {
 		// ReadonlyPct is not applied
 		return numWhiteList
 	}
 	num := 0
-	if gAppConfig.ReadonlyPct > 0 {
-		num = GetNumWorkers(shard) * gAppConfig.ReadonlyPct / 100
+	if readonlyPct > 0 {
+		num = GetNumWorkers(shard) * readonlyPct / 100
 		if num == 0 {
 			num = 1
 		}
@@ -905,12 +1099,17 @@ func GetNumRWorkers(shard int) int {
 
 // GetNumStdByWorkers gets the number of workers for the "StdBy" pool
 func GetNumStdByWorkers(shard int) int {
+	return numStdByWorkers(shard, GetReloadableConfig().EnableTAF)
+}
+
+// numStdByWorkers gets the number of workers for the "StdBy" pool, taf_children_pct applies if taf is set
+func numStdByWorkers(shard int, taf bool) int {
 	num := GetNumWWorkers(shard)
 	// TafChildrenPct should not be greater than 100.
 	if gAppConfig.TafChildrenPct > 100 {
 		return num
 	}
-	if gAppConfig.EnableTAF && gAppConfig.TafChildrenPct < 100 {
+	if taf && gAppConfig.TafChildrenPct < 100 {
 		if gAppConfig.TafChildrenPct < 0 {
 			num = 1
 		} else {
@@ -930,8 +1129,8 @@ func GetNumWWorkers(shard int) int {
 		return numWhiteList
 	}
 	num := GetNumWorkers(shard)
-	if gAppConfig.ReadonlyPct > 0 {
-		num = num - num*(gAppConfig.ReadonlyPct)/100
+	if readonlyPct := GetReloadableConfig().ReadonlyPct; readonlyPct > 0 {
+		num = num - num*readonlyPct/100
 		if num == 0 {
 			num = 1
 		}
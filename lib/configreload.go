@@ -0,0 +1,267 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lib
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/paypal/hera/cal"
+	"github.com/paypal/hera/config"
+	"github.com/paypal/hera/utility/logger"
+)
+
+// ReloadableConfig holds the settings of hera.txt which can be overridden in the ops config while the mux runs.
+// A reload replaces the whole snapshot, so the readers always see consistent values
+type ReloadableConfig struct {
+	// R-W split
+	ReadonlyPct int
+	// backlog
+	BacklogPct              int
+	BacklogTimeoutMsec      int
+	BacklogTimeoutUnit      int64
+	ShortBacklogTimeoutMsec int
+	// soft and bind eviction
+	SoftEvictionEffectiveTimeMs int
+	SoftEvictionProbability     int
+	BindEvictionTargetConnPct   int
+	BindEvictionThresholdPct    int
+	BindEvictionDecrPerSec      float64
+	BindEvictionMaxThrottle     int
+	// TAF
+	TAFTimeoutMs int
+	// TAF is enabled by hera.txt, tnsnames.ora or the topology, not by the ops config
+	EnableTAF bool
+	// manual rate limiter
+	EnableQueryBindBlocker bool
+}
+
+var gReloadableConfig atomic.Value
+
+// gReloadableLock serializes the changes of the snapshot and of reloadableBase: the reloads and the settings
+// changed by tnsnames.ora or the topology
+var gReloadableLock sync.Mutex
+
+// GetReloadableConfig returns the current values of the reloadable settings
+func GetReloadableConfig() *ReloadableConfig {
+	cfg := gReloadableConfig.Load()
+	if cfg == nil {
+		return &ReloadableConfig{}
+	}
+	return cfg.(*ReloadableConfig)
+}
+
+// UpdateReloadableConfig stores a copy of the current reloadable settings changed by update. The settings
+// returned before by GetReloadableConfig are not modified. It is meant for the tests changing a setting
+// without an ops config reload
+func UpdateReloadableConfig(update func(cfg *ReloadableConfig)) {
+	gReloadableLock.Lock()
+	defer gReloadableLock.Unlock()
+	cfg := *GetReloadableConfig()
+	update(&cfg)
+	gReloadableConfig.Store(&cfg)
+}
+
+// reloadableSetting is a key of hera.txt reloaded from the ops config into ReloadableConfig. Its schema
+// entry must be Reloadable
+type reloadableSetting struct {
+	key string
+	// value formats the setting for the CAL events
+	value func(cfg *ReloadableConfig) string
+	// set reads the setting from the config into cfg
+	set func(cfg *ReloadableConfig, cdb config.Config) error
+}
+
+func intSetting(key string, field func(cfg *ReloadableConfig) *int) reloadableSetting {
+	return reloadableSetting{
+		key:   key,
+		value: func(cfg *ReloadableConfig) string { return strconv.Itoa(*field(cfg)) },
+		set: func(cfg *ReloadableConfig, cdb config.Config) (err error) {
+			*field(cfg), err = cdb.GetInt(key)
+			return err
+		},
+	}
+}
+
+var reloadableSettings = []reloadableSetting{
+	intSetting("readonly_children_pct", func(cfg *ReloadableConfig) *int { return &cfg.ReadonlyPct }),
+	intSetting("backlog_pct", func(cfg *ReloadableConfig) *int { return &cfg.BacklogPct }),
+	intSetting("request_backlog_timeout", func(cfg *ReloadableConfig) *int { return &cfg.BacklogTimeoutMsec }),
+	intSetting("short_backlog_timeout", func(cfg *ReloadableConfig) *int { return &cfg.ShortBacklogTimeoutMsec }),
+	intSetting("soft_eviction_effective_time", func(cfg *ReloadableConfig) *int { return &cfg.SoftEvictionEffectiveTimeMs }),
+	intSetting("soft_eviction_probability", func(cfg *ReloadableConfig) *int { return &cfg.SoftEvictionProbability }),
+	intSetting("bind_eviction_target_conn_pct", func(cfg *ReloadableConfig) *int { return &cfg.BindEvictionTargetConnPct }),
+	intSetting("bind_eviction_threshold_pct", func(cfg *ReloadableConfig) *int { return &cfg.BindEvictionThresholdPct }),
+	intSetting("bind_eviction_max_throttle", func(cfg *ReloadableConfig) *int { return &cfg.BindEvictionMaxThrottle }),
+	{
+		key: "bind_eviction_decr_per_sec",
+		value: func(cfg *ReloadableConfig) string {
+			return strconv.FormatFloat(cfg.BindEvictionDecrPerSec, 'f', -1, 64)
+		},
+		set: func(cfg *ReloadableConfig, cdb config.Config) error {
+			val, err := cdb.GetString("bind_eviction_decr_per_sec")
+			if err == nil {
+				cfg.BindEvictionDecrPerSec, err = strconv.ParseFloat(strings.TrimSpace(val), 64)
+				if (err == nil) && (cfg.BindEvictionDecrPerSec < 0) {
+					err = fmt.Errorf("%s is negative", val)
+				}
+			}
+			return err
+		},
+	},
+	intSetting("taf_timeout_ms", func(cfg *ReloadableConfig) *int { return &cfg.TAFTimeoutMs }),
+	{
+		key:   "enable_query_bind_blocker",
+		value: func(cfg *ReloadableConfig) string { return strconv.FormatBool(cfg.EnableQueryBindBlocker) },
+		set: func(cfg *ReloadableConfig, cdb config.Config) (err error) {
+			cfg.EnableQueryBindBlocker, err = cdb.GetBool("enable_query_bind_blocker")
+			return err
+		},
+	},
+}
+
+// initReloadableConfig reads the reloadable settings from hera.txt, they are the values used until the ops
+// config overrides them
+func initReloadableConfig(cdb *config.CheckedConfig) {
+	cfg := &ReloadableConfig{
+		ReadonlyPct:                 cdb.Int("readonly_children_pct"),
+		BacklogPct:                  cdb.Int("backlog_pct"),
+		BacklogTimeoutMsec:          cdb.Int("request_backlog_timeout"),
+		ShortBacklogTimeoutMsec:     cdb.Int("short_backlog_timeout"),
+		SoftEvictionEffectiveTimeMs: cdb.Int("soft_eviction_effective_time"),
+		SoftEvictionProbability:     cdb.Int("soft_eviction_probability"),
+		BindEvictionTargetConnPct:   cdb.Int("bind_eviction_target_conn_pct"),
+		BindEvictionThresholdPct:    cdb.Int("bind_eviction_threshold_pct"),
+		BindEvictionMaxThrottle:     cdb.Int("bind_eviction_max_throttle"),
+		TAFTimeoutMs:                cdb.Int("taf_timeout_ms"),
+		EnableQueryBindBlocker:      cdb.Bool("enable_query_bind_blocker"),
+		EnableTAF:                   cdb.Bool("enable_taf"),
+	}
+	fmt.Sscanf(cdb.String("bind_eviction_decr_per_sec"), "%f", &cfg.BindEvictionDecrPerSec)
+	cfg.setBacklogTimeoutUnit()
+	gAppConfig.reloadableBase = *cfg
+	gReloadableConfig.Store(cfg)
+}
+
+// setReadonlyPct changes readonly_children_pct, when it is derived from tnsnames.ora or the topology
+func setReadonlyPct(pct int) {
+	UpdateReloadableConfig(func(cfg *ReloadableConfig) {
+		cfg.ReadonlyPct = pct
+		gAppConfig.reloadableBase.ReadonlyPct = pct
+	})
+}
+
+// setEnableTAF enables or disables TAF, when it is derived from tnsnames.ora or the topology
+func setEnableTAF(enable bool) {
+	UpdateReloadableConfig(func(cfg *ReloadableConfig) {
+		cfg.EnableTAF = enable
+		gAppConfig.reloadableBase.EnableTAF = enable
+	})
+}
+
+func (cfg *ReloadableConfig) setBacklogTimeoutUnit() {
+	cfg.BacklogTimeoutUnit = int64(cfg.BacklogTimeoutMsec) / 5
+	if cfg.BacklogTimeoutMsec < 5 {
+		cfg.BacklogTimeoutUnit = 1
+	}
+}
+
+// loadReloadableConfig returns the reloadable settings of the ops config. The keys not in the ops config
+// take their value from hera.txt. Invalid values are returned as errors, old is the current config
+func loadReloadableConfig(cdb config.Config, old *ReloadableConfig) (*ReloadableConfig, []error) {
+	cfg := gAppConfig.reloadableBase
+	var errs []error
+	for _, setting := range reloadableSettings {
+		val, err := cdb.GetString(setting.key)
+		if err != nil {
+			continue
+		}
+		err = configSchema().Lookup(setting.key).Check(val)
+		if err == nil {
+			err = setting.set(&cfg, cdb)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s", setting.key, err.Error()))
+		}
+	}
+	// the RO pools are created at startup or by the topology, they can be resized but not added or removed
+	if (old.ReadonlyPct == 0) != (cfg.ReadonlyPct == 0) {
+		errs = append(errs, fmt.Errorf("readonly_children_pct: can't change from %d to %d without a restart", old.ReadonlyPct, cfg.ReadonlyPct))
+	}
+	cfg.setBacklogTimeoutUnit()
+	return &cfg, errs
+}
+
+// reloadConfig applies the reloadable settings of the ops config. They are applied together or, if any
+// value is invalid, not at all. Each change is logged in CAL with the old and the new values
+func reloadConfig(cdb config.Config) {
+	gReloadableLock.Lock()
+	old := GetReloadableConfig()
+	cfg, errs := loadReloadableConfig(cdb, old)
+	if len(errs) == 0 {
+		gReloadableConfig.Store(cfg)
+	}
+	gReloadableLock.Unlock()
+	if len(errs) > 0 {
+		evt := cal.NewCalEvent(EvtTypeMux, "config_reload_rejected", cal.TransWarning, "")
+		for _, err := range errs {
+			if logger.GetLogger().V(logger.Alert) {
+				logger.GetLogger().Log(logger.Alert, "Rejecting ops config reload:", err.Error())
+			}
+			evt.AddDataStr("error", err.Error())
+		}
+		evt.Completed()
+		return
+	}
+
+	for _, setting := range reloadableSettings {
+		oldVal, newVal := setting.value(old), setting.value(cfg)
+		if oldVal != newVal {
+			logConfigChange(setting.key, oldVal, newVal)
+		}
+	}
+
+	if cfg.ReadonlyPct != old.ReadonlyPct {
+		resizeWorkerPools()
+	}
+	if cfg.EnableQueryBindBlocker && !old.EnableQueryBindBlocker {
+		InitQueryBindBlocker(gAppConfig.moduleName)
+	}
+}
+
+// resizeWorkerPools makes the worker broker resize the pools, a pending max_connections change already does it
+func resizeWorkerPools() {
+	select {
+	case gAppConfig.numWorkersCh <- GetNumWorkers(0):
+	default:
+	}
+}
+
+// logConfigChange logs a setting changed by an ops config reload, with its old and new values
+func logConfigChange(key string, oldVal interface{}, newVal interface{}) {
+	if logger.GetLogger().V(logger.Info) {
+		logger.GetLogger().Log(logger.Info, "Changing", key, "from", oldVal, "to", newVal)
+	}
+	evt := cal.NewCalEvent(EvtTypeMux, "config_reload_"+key, cal.TransOK, "")
+	evt.AddDataStr("old", fmt.Sprint(oldVal))
+	evt.AddDataStr("new", fmt.Sprint(newVal))
+	evt.Completed()
+}
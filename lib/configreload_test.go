@@ -0,0 +1,123 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paypal/hera/config"
+)
+
+func txtConfig(t *testing.T, content string) config.Config {
+	filename := filepath.Join(t.TempDir(), "hera.txt")
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.NewTxtConfig(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+// checkedConfig returns hera.txt having the content, checked against the schema
+func checkedConfig(t *testing.T, content string) *config.CheckedConfig {
+	filename := filepath.Join(t.TempDir(), "hera.txt")
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.NewCheckedFileConfig(filename, configSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestReloadableSettingsInSchema(t *testing.T) {
+	for _, setting := range reloadableSettings {
+		entry := configSchema().Lookup(setting.key)
+		if (entry == nil) || !entry.Reloadable {
+			t.Errorf("%s is not reloadable in the config schema", setting.key)
+		}
+	}
+}
+
+func TestReloadConfig(t *testing.T) {
+	savedCfg, savedOpsCfg, savedReloadable := gAppConfig, gOpsConfig, GetReloadableConfig()
+	defer func() {
+		gAppConfig, gOpsConfig = savedCfg, savedOpsCfg
+		gReloadableConfig.Store(savedReloadable)
+	}()
+	gAppConfig = &Config{numWorkersCh: make(chan int, 1)}
+	gOpsConfig = &OpsConfig{numWorkers: 10}
+	initReloadableConfig(checkedConfig(t, "readonly_children_pct=20\nbacklog_pct=20\n"))
+	if (GetNumRWorkers(0) != 2) || (GetNumWWorkers(0) != 8) {
+		t.Fatalf("wrong pool sizes %d %d", GetNumRWorkers(0), GetNumWWorkers(0))
+	}
+
+	reloadConfig(txtConfig(t, "readonly_children_pct=40\nrequest_backlog_timeout=2000\nbind_eviction_decr_per_sec=2.5\n"))
+	cfg := GetReloadableConfig()
+	if (cfg.ReadonlyPct != 40) || (cfg.BacklogTimeoutUnit != 400) || (cfg.BindEvictionDecrPerSec != 2.5) || (cfg.BacklogPct != 20) {
+		t.Errorf("reload not applied: %+v", cfg)
+	}
+	if (GetNumRWorkers(0) != 4) || (GetNumWWorkers(0) != 6) {
+		t.Errorf("wrong pool sizes after reload %d %d", GetNumRWorkers(0), GetNumWWorkers(0))
+	}
+	select {
+	case <-gAppConfig.NumWorkersCh():
+	default:
+		t.Error("pools not resized")
+	}
+
+	for _, ops := range []string{
+		"backlog_pct=50\nsoft_eviction_probability=200\n",
+		"backlog_pct=50\nbind_eviction_decr_per_sec=fast\n",
+		"backlog_pct=50\nenable_query_bind_blocker=maybe\n",
+		"backlog_pct=50\nreadonly_children_pct=0\n",
+	} {
+		reloadConfig(txtConfig(t, ops))
+		if GetReloadableConfig() != cfg {
+			t.Errorf("invalid reload applied: %q", ops)
+		}
+	}
+
+	// the keys removed from the ops config are back to their hera.txt value
+	reloadConfig(txtConfig(t, ""))
+	cfg = GetReloadableConfig()
+	if (cfg.ReadonlyPct != 20) || (cfg.BacklogTimeoutMsec != 1000) || (cfg.BindEvictionDecrPerSec != 10) {
+		t.Errorf("hera.txt values not restored: %+v", cfg)
+	}
+}
+
+func TestUpdateReloadableConfig(t *testing.T) {
+	saved := GetReloadableConfig()
+	defer gReloadableConfig.Store(saved)
+	gReloadableConfig.Store(&ReloadableConfig{BacklogPct: 30, BindEvictionDecrPerSec: 10})
+
+	old := GetReloadableConfig()
+	UpdateReloadableConfig(func(cfg *ReloadableConfig) { cfg.BindEvictionDecrPerSec = 2.5 })
+	cfg := GetReloadableConfig()
+	if (cfg.BindEvictionDecrPerSec != 2.5) || (cfg.BacklogPct != 30) {
+		t.Errorf("update not applied: %+v", cfg)
+	}
+	if old.BindEvictionDecrPerSec != 10 {
+		t.Error("the previous settings were modified")
+	}
+}
@@ -0,0 +1,306 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lib
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/paypal/hera/config"
+)
+
+const (
+	cfgInt    = config.TypeInt
+	cfgString = config.TypeString
+	cfgBool   = config.TypeBool
+	// upper bound of the int entries having only a lower bound
+	cfgNoMax = 1 << 30
+)
+
+// configEntries are the keys of hera.txt, read by the mux, the workers, the watchdog and the loggers. The
+// reloadable entries are read from the ops config. An empty default is computed where the key is read
+var configEntries = []config.Entry{
+	// GENERAL
+	{Key: "bind_port", Type: cfgInt, Min: 1, Max: 65535, Required: true, Group: "GENERAL"},
+	{Key: "bind_ip", Type: cfgString, Group: "GENERAL"},
+	{Key: "child.executable", Type: cfgString, Group: "GENERAL"},
+	{Key: ConfigDatabaseType, Type: cfgString, Default: "oracle", Values: []string{"oracle", "mysql", "postgres"}, Group: "GENERAL"},
+	{Key: "num_standby_dbs", Type: cfgInt, Default: "0", Min: 0, Max: cfgNoMax, Group: "GENERAL"},
+	{Key: "cert_chain_file", Type: cfgString, Group: "GENERAL"},
+	{Key: "key_file", Type: cfgString, Group: "GENERAL"},
+	{Key: "openssl", Type: cfgBool, Default: "false", Group: "GENERAL"},
+	{Key: "enable_binary_framing", Type: cfgBool, Default: "true", Group: "GENERAL"},
+	{Key: "compression_threshold", Type: cfgInt, Default: "16384", Group: "GENERAL"},
+	{Key: "config_reload_time_ms", Type: cfgInt, Default: "30000", Min: 1, Max: cfgNoMax, Group: "GENERAL"},
+	{Key: "config_logging_reload_time_hours", Type: cfgInt, Default: "24", Group: "GENERAL"},
+	{Key: "custom_auth_timeout", Type: cfgInt, Default: "1000", Group: "GENERAL"},
+	{Key: "time_skew_threshold_warn", Type: cfgInt, Default: "2", Group: "GENERAL"},
+	{Key: "time_skew_threshold_error", Type: cfgInt, Default: "15", Group: "GENERAL"},
+	{Key: "high_load_pct", Type: cfgInt, Default: "130", Group: "GENERAL"},
+	{Key: "init_limit_pct", Type: cfgInt, Default: "125", Group: "GENERAL"},
+	{Key: "state_log_interval", Type: cfgInt, Default: "1", Group: "GENERAL"},
+	{Key: "state_log_prefix", Type: cfgString, Default: "hera", Group: "GENERAL"},
+	{Key: "error_code_prefix", Type: cfgString, Default: "HERA", Group: "GENERAL"},
+	{Key: "mux_pid_file", Type: cfgString, Default: "mux.pid", Group: "GENERAL"},
+	{Key: "enable_client_info_to_worker", Type: cfgBool, Default: "false", Group: "GENERAL"},
+	{Key: "go_stats_interval", Type: cfgInt, Default: "10", Group: "GENERAL"},
+	{Key: "use_non_blocking", Type: cfgBool, Default: "false", Group: "GENERAL"},
+	// LOG
+	{Key: "log_file", Type: cfgString, Default: "hera.log", Group: "LOG"},
+	{Key: "log_level", Type: cfgInt, Default: "2", Reloadable: true, Group: "LOG"},
+	{Key: "log_format", Type: cfgString, Default: "text", Values: []string{"text", "json"}, Group: "LOG"},
+	{Key: "log_max_size_mb", Type: cfgInt, Default: "0", Min: 0, Max: cfgNoMax, Group: "LOG"},
+	{Key: "log_max_backups", Type: cfgInt, Default: "5", Min: 0, Max: cfgNoMax, Group: "LOG"},
+	// WORKER-CONFIGURATIONS
+	{Key: ConfigMaxWorkers, Type: cfgInt, Min: 0, Max: cfgNoMax, Reloadable: true, Group: "WORKER-CONFIGURATIONS"},
+	{Key: "idle_timeout_ms", Type: cfgInt, Default: "600000", Reloadable: true, Group: "WORKER-CONFIGURATIONS"},
+	{Key: "transaction_idle_timeout_ms", Type: cfgInt, Default: "900000", Reloadable: true, Group: "WORKER-CONFIGURATIONS"},
+	{Key: "max_lifespan_per_child", Type: cfgInt, Default: "0", Reloadable: true, Group: "WORKER-CONFIGURATIONS"},
+	{Key: "max_requests_per_child", Type: cfgInt, Default: "0", Reloadable: true, Group: "WORKER-CONFIGURATIONS"},
+	{Key: "lifespan_check_interval", Type: cfgInt, Default: "10", Group: "WORKER-CONFIGURATIONS"},
+	{Key: "lifo_scheduler_enabled", Type: cfgBool, Default: "true", Group: "WORKER-CONFIGURATIONS"},
+	{Key: "max_stranded_time_interval", Type: cfgInt, Default: "2000", Group: "WORKER-CONFIGURATIONS"},
+	{Key: "high_load_max_stranded_time_interval", Type: cfgInt, Default: "600111", Group: "WORKER-CONFIGURATIONS"},
+	{Key: "high_load_skip_initiate_recover_pct", Type: cfgInt, Default: "80", Group: "WORKER-CONFIGURATIONS"},
+	{Key: "enable_danglingworker_recovery", Type: cfgBool, Default: "false", Group: "WORKER-CONFIGURATIONS"},
+	// the default is 5 when TAF is enabled, 10000 otherwise
+	{Key: "max_db_connects_per_sec", Type: cfgInt, Group: "WORKER-CONFIGURATIONS"},
+	{Key: "max_desire_healthy_worker_pct", Type: cfgInt, Default: "90", Group: "WORKER-CONFIGURATIONS"},
+	{Key: "random_start_ms", Type: cfgInt, Default: "20000", Min: 0, Max: cfgNoMax, Group: "WORKER-CONFIGURATIONS"},
+	{Key: "db_heartbeat_interval", Type: cfgInt, Default: "120", Group: "WORKER-CONFIGURATIONS"},
+	// STATEMENT-CACHE and the worker settings reported in LogOccConfigs
+	{Key: "enable_cache", Type: cfgBool, Default: "false", Group: "STATEMENT-CACHE"},
+	{Key: "max_cache_size", Type: cfgInt, Default: "0", Min: 0, Max: cfgNoMax, Group: "STATEMENT-CACHE"},
+	{Key: "enable_heart_beat", Type: cfgBool, Default: "false", Group: "STATEMENT-CACHE"},
+	{Key: "enable_query_replace_nl", Type: cfgBool, Default: "true", Group: "STATEMENT-CACHE"},
+	{Key: "enable_bind_hash_logging", Type: cfgBool, Default: "false", Group: "STATEMENT-CACHE"},
+	{Key: "enable_session_variables", Type: cfgBool, Default: "false", Group: "SESSION-VARIABLES"},
+	{Key: "session_variables_allowed", Type: cfgString, Default: "time_zone,sql_mode,search_path,nls_*", Group: "SESSION-VARIABLES"},
+	{Key: "enable_session_reset", Type: cfgBool, Default: "false", Group: "SESSION-RESET"},
+	{Key: "session_reset_sql", Type: cfgString, Group: "SESSION-RESET"},
+	// SHARDING
+	{Key: "enable_sharding", Type: cfgBool, Default: "false", Group: "SHARDING"},
+	{Key: "use_shardmap", Type: cfgBool, Default: "true", Group: "SHARDING"},
+	{Key: "num_shards", Type: cfgInt, Default: "1", Min: 1, Max: 48, Group: "SHARDING"},
+	{Key: "shard_key_name", Type: cfgString, Group: "SHARDING"},
+	{Key: "max_scuttle", Type: cfgInt, Default: "1024", Min: 1, Max: 1024, Group: "SHARDING"},
+	{Key: "scuttle_col_name", Type: cfgString, Default: "scuttle_id", Group: "SHARDING"},
+	{Key: "sharding_algo", Type: cfgString, Default: "hash", Values: []string{"hash", "mod"}, Group: "SHARDING"},
+	{Key: "sharding_postfix", Type: cfgString, Group: "SHARDING"},
+	{Key: "enable_whitelist_test", Type: cfgBool, Default: "false", Group: "SHARDING"},
+	{Key: "whitelist_children", Type: cfgInt, Default: "5", Group: "SHARDING"},
+	{Key: "sharding_cfg_reload_interval", Type: cfgInt, Default: "2", Group: "SHARDING"},
+	{Key: "sharding_cross_keys_err", Type: cfgBool, Default: "false", Group: "SHARDING"},
+	{Key: "shard_key_value_type_is_string", Type: cfgBool, Default: "false", Group: "SHARDING"},
+	{Key: "hostname_prefix", Type: cfgString, Group: "SHARDING"},
+	// TOPOLOGY
+	{Key: "topology_file", Type: cfgString, Group: "TOPOLOGY"},
+	// ENABLE_CFG_FROM_TNS
+	{Key: "cfg_from_tns", Type: cfgBool, Default: "true", Group: "ENABLE_CFG_FROM_TNS"},
+	{Key: "cfg_from_tns_override_num_shards", Type: cfgInt, Default: "-1", Group: "ENABLE_CFG_FROM_TNS"},
+	{Key: "cfg_from_tns_override_taf", Type: cfgInt, Default: "-1", Group: "ENABLE_CFG_FROM_TNS"},
+	{Key: "cfg_from_tns_override_rw_split", Type: cfgInt, Default: "-1", Group: "ENABLE_CFG_FROM_TNS"},
+	// TAF
+	{Key: "enable_taf", Type: cfgBool, Default: "false", Group: "TAF"},
+	{Key: "testing_enable_dml_taf", Type: cfgBool, Default: "false", Group: "TAF"},
+	{Key: "taf_timeout_ms", Type: cfgInt, Default: "200", Min: 0, Max: cfgNoMax, Reloadable: true, Group: "TAF"},
+	{Key: "taf_bin_duration", Type: cfgInt, Default: "86400", Group: "TAF"},
+	{Key: "taf_allow_slow_every_x", Type: cfgInt, Default: "100", Group: "TAF"},
+	{Key: "taf_normally_slow_count", Type: cfgInt, Default: "5", Group: "TAF"},
+	{Key: "taf_children_pct", Type: cfgInt, Default: "100", Min: 0, Max: 100, Group: "TAF"},
+	// R-W-SPLIT
+	{Key: "readonly_children_pct", Type: cfgInt, Default: "0", Min: 0, Max: 100, Reloadable: true, Group: "R-W-SPLIT"},
+	// BACKLOG
+	{Key: "backlog_pct", Type: cfgInt, Default: "30", Reloadable: true, Group: "BACKLOG"},
+	{Key: "request_backlog_timeout", Type: cfgInt, Default: "1000", Reloadable: true, Group: "BACKLOG"},
+	{Key: "short_backlog_timeout", Type: cfgInt, Default: "30", Reloadable: true, Group: "BACKLOG"},
+	// SOFT-EVICTION
+	{Key: "soft_eviction_effective_time", Type: cfgInt, Default: "10000", Reloadable: true, Group: "SOFT-EVICTION"},
+	{Key: "soft_eviction_probability", Type: cfgInt, Default: "50", Min: 0, Max: 100, Reloadable: true, Group: "SOFT-EVICTION"},
+	// BIND-EVICTION
+	{Key: "bind_eviction_target_conn_pct", Type: cfgInt, Default: "50", Reloadable: true, Group: "BIND-EVICTION"},
+	{Key: "bind_eviction_max_throttle", Type: cfgInt, Default: "20", Reloadable: true, Group: "BIND-EVICTION"},
+	{Key: "bind_eviction_names", Type: cfgString, Default: "id,num," + SrcPrefixAppKey, Group: "BIND-EVICTION"},
+	{Key: "bind_eviction_threshold_pct", Type: cfgInt, Default: "60", Reloadable: true, Group: "BIND-EVICTION"},
+	{Key: "bind_eviction_decr_per_sec", Type: cfgString, Default: "10.0", Reloadable: true, Group: "BIND-EVICTION"},
+	{Key: "skip_eviction_host_prefix", Type: cfgString, Group: "BIND-EVICTION"},
+	{Key: "eviction_host_prefix", Type: cfgString, Group: "BIND-EVICTION"},
+	{Key: "enable_connlimit_check", Type: cfgBool, Default: "false", Group: "BIND-EVICTION"},
+	{Key: "query_bind_blocker_min_sql_prefix", Type: cfgInt, Default: "20", Group: "BIND-EVICTION"},
+	// MANUAL-RATE-LIMITER
+	{Key: "enable_query_bind_blocker", Type: cfgBool, Default: "false", Reloadable: true, Group: "MANUAL-RATE-LIMITER"},
+	// SATURATION-RECOVERY
+	{Key: "saturation_recover_threshold", Type: cfgInt, Default: "200", Reloadable: true, Group: "SATURATION-RECOVERY"},
+	{Key: "saturation_recover_throttle_rate", Type: cfgInt, Default: "0", Reloadable: true, Group: "SATURATION-RECOVERY"},
+	// BOUNCER
+	{Key: "bouncer_enabled", Type: cfgBool, Default: "true", Group: "BOUNCER"},
+	{Key: "bouncer_startup_delay", Type: cfgInt, Default: "10", Group: "BOUNCER"},
+	{Key: "bouncer_poll_interval_ms", Type: cfgInt, Default: "100", Group: "BOUNCER"},
+	// PROFILE
+	{Key: "enable_profile", Type: cfgBool, Default: "false", Group: "PROFILE"},
+	{Key: "profile_http_port", Type: cfgString, Default: "6060", Group: "PROFILE"},
+	{Key: "profile_telnet_port", Type: cfgString, Default: "3030", Group: "PROFILE"},
+	// PIN-STATS
+	{Key: "introspect_http_port", Type: cfgString, Group: "PIN-STATS"},
+	{Key: "enable_pin_stats", Type: cfgBool, Default: "false", Group: "PIN-STATS"},
+	{Key: "pin_stats_interval", Type: cfgInt, Default: "60", Group: "PIN-STATS"},
+	{Key: "pin_stats_top_n", Type: cfgInt, Default: "10", Group: "PIN-STATS"},
+	// SLOW-QUERY-LOG
+	{Key: "enable_slow_query_log", Type: cfgBool, Default: "false", Group: "SLOW-QUERY-LOG"},
+	{Key: "slow_query_log_file", Type: cfgString, Default: "slow_query.log", Group: "SLOW-QUERY-LOG"},
+	{Key: "slow_query_log_max_size_mb", Type: cfgInt, Default: "100", Min: 0, Max: cfgNoMax, Group: "SLOW-QUERY-LOG"},
+	{Key: "slow_query_log_backups", Type: cfgInt, Default: "5", Min: 0, Max: cfgNoMax, Group: "SLOW-QUERY-LOG"},
+	{Key: "slow_query_log_binds", Type: cfgBool, Default: "false", Group: "SLOW-QUERY-LOG"},
+	{Key: "slow_query_threshold_ms", Type: cfgInt, Default: "1000", Reloadable: true, Group: "SLOW-QUERY-LOG"},
+	{Key: "slow_query_threshold_ms_", Type: cfgInt, Prefix: true, Reloadable: true, Group: "SLOW-QUERY-LOG"},
+	// UPGRADE
+	{Key: "upgrade_timeout_sec", Type: cfgInt, Default: "120", Group: "UPGRADE"},
+	{Key: "upgrade_warm_pct", Type: cfgInt, Default: "90", Min: 0, Max: 100, Group: "UPGRADE"},
+	{Key: "upgrade_drain_timeout_sec", Type: cfgInt, Default: "60", Group: "UPGRADE"},
+	{Key: "drain_timeout_sec", Type: cfgInt, Default: "60", Group: "UPGRADE"},
+	// SQL-STATS
+	{Key: "enable_sql_stats", Type: cfgBool, Default: "false", Group: "SQL-STATS"},
+	{Key: "sql_stats_interval", Type: cfgInt, Default: "60", Group: "SQL-STATS"},
+	{Key: "sql_stats_top_n", Type: cfgInt, Default: "20", Group: "SQL-STATS"},
+	{Key: "sql_stats_max_hashes", Type: cfgInt, Default: "5000", Group: "SQL-STATS"},
+	// SQLHASH-AFFINITY
+	{Key: "enable_sqlhash_affinity", Type: cfgBool, Default: "false", Group: "SQLHASH-AFFINITY"},
+	{Key: "sqlhash_affinity_size", Type: cfgInt, Default: "32", Min: 0, Max: cfgNoMax, Group: "SQLHASH-AFFINITY"},
+	{Key: "sqlhash_affinity_min_idle_pct", Type: cfgInt, Default: "10", Min: 0, Max: 100, Group: "SQLHASH-AFFINITY"},
+	{Key: "sqlhash_affinity_report_interval", Type: cfgInt, Default: "60", Group: "SQLHASH-AFFINITY"},
+	// SQL-ALLOWLIST
+	{Key: "sql_allowlist_mode", Type: cfgString, Default: SQLAllowlistOff, Values: []string{SQLAllowlistOff, SQLAllowlistEnforce, SQLAllowlistDryRun, SQLAllowlistLearn}, Group: "SQL-ALLOWLIST"},
+	{Key: "sql_allowlist_file", Type: cfgString, Group: "SQL-ALLOWLIST"},
+	{Key: "sql_allowlist_learn_file", Type: cfgString, Default: "sql_allowlist_learned.txt", Group: "SQL-ALLOWLIST"},
+	// RAC
+	{Key: "management_table_prefix", Type: cfgString, Default: "hera", Group: "RAC"},
+	{Key: "rac_sql_interval", Type: cfgInt, Default: "10", Group: "RAC"},
+	{Key: "rac_restart_window", Type: cfgInt, Default: "240", Group: "RAC"},
+	// MAINTENANCE
+	{Key: "maint_file", Type: cfgString, Group: "MAINTENANCE"},
+	{Key: "maint_admin_enabled", Type: cfgBool, Default: "false", Group: "MAINTENANCE"},
+	{Key: "failover_restart_window", Type: cfgInt, Default: "10", Min: 1, Group: "FAILOVER"},
+	// OTEL
+	{Key: "enable_otel", Type: cfgBool, Default: "false", Group: "OTEL"},
+	{Key: "skip_cal_statelog", Type: cfgBool, Default: "false", Group: "OTEL"},
+	{Key: "otel_metric_prefix", Type: cfgString, Default: "pp.occ", Group: "OTEL"},
+	{Key: "otel_agent_host", Type: cfgString, Default: "localhost", Group: "OTEL"},
+	{Key: "otel_agent_metrics_port", Type: cfgInt, Default: "4318", Min: 1, Max: 65535, Group: "OTEL"},
+	{Key: "otel_agent_trace_port", Type: cfgInt, Default: "4318", Min: 1, Max: 65535, Group: "OTEL"},
+	{Key: "otel_agent_use_grpc_metric", Type: cfgBool, Default: "false", Group: "OTEL"},
+	{Key: "otel_agent_use_grpc_trace", Type: cfgBool, Default: "false", Group: "OTEL"},
+	{Key: "otel_agent_metrics_uri", Type: cfgString, Group: "OTEL"},
+	{Key: "otel_agent_trace_uri", Type: cfgString, Group: "OTEL"},
+	{Key: "otel_use_tls", Type: cfgBool, Default: "false", Group: "OTEL"},
+	{Key: "otel_tls_cert_path", Type: cfgString, Group: "OTEL"},
+	{Key: "otel_resolution_time_in_sec", Type: cfgInt, Default: "1", Group: "OTEL"},
+	{Key: "otel_exporter_time_in_sec", Type: cfgInt, Default: "30", Group: "OTEL"},
+	{Key: "otel_enable_exporter_retry", Type: cfgBool, Default: "false", Group: "OTEL"},
+	{Key: "otel_error_reporting_interval_in_sec", Type: cfgInt, Default: "60", Group: "OTEL"},
+	{Key: "otel_ingest_token", Type: cfgString, Group: "OTEL"},
+	// WATCHDOG
+	{Key: "pid_file", Type: cfgString, Default: "occ.pid", Group: "WATCHDOG"},
+	{Key: "watchdog_backoff_min_ms", Type: cfgInt, Default: "1000", Group: "WATCHDOG"},
+	{Key: "watchdog_backoff_max_ms", Type: cfgInt, Default: "60000", Group: "WATCHDOG"},
+	{Key: "watchdog_max_restarts", Type: cfgInt, Default: "0", Group: "WATCHDOG"},
+	{Key: "watchdog_restart_window_sec", Type: cfgInt, Default: "600", Group: "WATCHDOG"},
+	{Key: "watchdog_probe_interval_sec", Type: cfgInt, Default: "0", Group: "WATCHDOG"},
+	{Key: "watchdog_probe_start_delay_sec", Type: cfgInt, Default: "60", Group: "WATCHDOG"},
+	{Key: "watchdog_probe_failures", Type: cfgInt, Default: "3", Group: "WATCHDOG"},
+	{Key: "watchdog_probe_timeout_ms", Type: cfgInt, Default: "2000", Group: "WATCHDOG"},
+	// keys of older releases, still found in some hera.txt
+	{Key: "enable_heartbeat_fix", Type: cfgBool, Deprecated: true, Group: "DEPRECATED"},
+	{Key: "num_polls_before_activation", Type: cfgInt, Deprecated: true, Group: "DEPRECATED"},
+}
+
+var gConfigSchema *config.Schema
+
+// configSchema returns the schema of hera.txt
+func configSchema() *config.Schema {
+	if gConfigSchema == nil {
+		var err error
+		gConfigSchema, err = config.NewSchema(configEntries)
+		if err != nil {
+			// the table above is wrong
+			panic(err)
+		}
+	}
+	return gConfigSchema
+}
+
+// configFile returns the path of hera.txt, in the directory of the executable
+func configFile() (currentDir string, filename string) {
+	currentDir, abserr := filepath.Abs(filepath.Dir(os.Args[0]))
+	if abserr != nil {
+		currentDir = "./"
+	} else {
+		currentDir = currentDir + "/"
+	}
+	return currentDir, currentDir + "hera.txt"
+}
+
+// configErrors returns an error listing the problems found in hera.txt, nil if there are none
+func configErrors(cdb *config.CheckedConfig) error {
+	errs := cdb.Errors()
+	if len(errs) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return fmt.Errorf("Config error: %s", strings.Join(msgs, "; "))
+}
+
+// CheckConfig validates hera.txt, with its YAML, JSON and environment layers, against the schema and writes
+// the effective configuration, with the source of each value, followed by the problems found. It returns
+// false if the config is invalid
+func CheckConfig(out io.Writer) bool {
+	_, filename := configFile()
+	cdb, err := config.NewCheckedFileConfig(filename, configSchema())
+	if err != nil {
+		fmt.Fprintln(out, "failed to read", filename, err.Error())
+		return false
+	}
+	fmt.Fprintln(out, "#", filename, "sources:", strings.Join(cdb.Sources(), ", "))
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "GROUP\tKEY\tVALUE\tSOURCE\tTYPE\tRELOADABLE")
+	for _, val := range cdb.Effective() {
+		value := val.Value
+		if len(value) == 0 {
+			value = `""`
+		}
+		reloadable := "no"
+		if val.Entry.Reloadable {
+			reloadable = "yes"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%v\t%s\n", val.Entry.Group, val.Key, value, val.Source, val.Entry.Type, reloadable)
+	}
+	w.Flush()
+	for _, warning := range cdb.Warnings() {
+		fmt.Fprintln(out, "warning:", warning)
+	}
+	for _, err := range cdb.Errors() {
+		fmt.Fprintln(out, "error:", err.Error())
+	}
+	return len(cdb.Errors()) == 0
+}
@@ -0,0 +1,145 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lib
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestConfigSchemaKeys checks that the keys of hera.txt read by the mux, the workers, the watchdog and the
+// loggers are in the schema, with the same default. The keys read with the default of the schema are checked
+// for their type
+func TestConfigSchemaKeys(t *testing.T) {
+	files := []string{"config.go", "configreload.go", "slowquery.go", "../watchdog/watchdog.go", "../utility/logger/logger.go",
+		"../utility/logger/otel/config/otelconfig.go"}
+	shared, _ := filepath.Glob("../worker/shared/*.go")
+	files = append(files, shared...)
+	re := regexp.MustCompile(`GetOrDefault(Int|String|Bool)\("([^"]+)", ("[^"]*"|-?[0-9]+|true|false)\)`)
+	schema := configSchema()
+	count := 0
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, match := range re.FindAllStringSubmatch(string(src), -1) {
+			count++
+			entry := schema.Lookup(match[2])
+			if entry == nil {
+				t.Errorf("%s: %s not in the config schema", file, match[2])
+				continue
+			}
+			if !strings.EqualFold(entry.Type.String(), match[1]) {
+				t.Errorf("%s: %s read as %s, schema type is %v", file, match[2], match[1], entry.Type)
+			}
+			if def := strings.Trim(match[3], `"`); def != entry.Default {
+				t.Errorf("%s: %s default is %s, schema default is %s", file, match[2], def, entry.Default)
+			}
+		}
+	}
+	// the keys read with the default of the schema, or required
+	reSchema := regexp.MustCompile(`(?:cdb|cfg)\.(?:Get)?(Int|String|Bool)\(("[^"]+"|ConfigDatabaseType)\)|DefaultInt\("([^"]+)"\)`)
+	for _, file := range []string{"config.go", "configreload.go"} {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, match := range reSchema.FindAllStringSubmatch(string(src), -1) {
+			count++
+			key, typ := strings.Trim(match[2], `"`), match[1]
+			if key == "ConfigDatabaseType" {
+				key = ConfigDatabaseType
+			}
+			if len(match[3]) > 0 {
+				key, typ = match[3], "Int"
+			}
+			entry := schema.Lookup(key)
+			if entry == nil {
+				t.Errorf("%s: %s not in the config schema", file, key)
+			} else if !strings.EqualFold(entry.Type.String(), typ) {
+				t.Errorf("%s: %s read as %s, schema type is %v", file, key, typ, entry.Type)
+			}
+		}
+	}
+	if count < 100 {
+		t.Errorf("only %d keys found", count)
+	}
+}
+
+// TestCheckShippedConfigs checks that the hera.txt files of the repository are valid
+func TestCheckShippedConfigs(t *testing.T) {
+	_, filename := configFile()
+	for _, shipped := range []string{"../docker_build_and_run/srv/hera.txt", "../docker_build_and_run/srv/pg_hera.txt",
+		"../tests/devdocker/srv/hera.txt", "../tests/devdocker/srv/pg_hera.txt", "../tests/e2e/srvmysql/hera.txt",
+		"../tests/e2e/srvoracle/hera.txt"} {
+		src, err := os.ReadFile(shipped)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(filename, src, 0644); err != nil {
+			t.Skip("can't write", filename, err)
+		}
+		var out bytes.Buffer
+		if !CheckConfig(&out) {
+			t.Errorf("%s rejected:\n%s", shipped, out.String())
+		}
+	}
+	os.Remove(filename)
+}
+
+func TestCheckConfig(t *testing.T) {
+	_, filename := configFile()
+	err := os.WriteFile(filename, []byte("bind_port=10101\nbacklog_pct=40\nenable_taff=true\nmax_scuttle=2000\nx-mysql=manual\n"), 0644)
+	if err != nil {
+		t.Skip("can't write", filename, err)
+	}
+	defer os.Remove(filename)
+	t.Setenv("HERA_SHORT_BACKLOG_TIMEOUT", "40")
+	// keys of the other config files are ignored in the environment
+	t.Setenv("HERA_CAL_POOL_NAME", "pool")
+	var out bytes.Buffer
+	if CheckConfig(&out) {
+		t.Error("invalid config accepted")
+	}
+	report := out.String()
+	for _, expected := range []string{
+		"backlog_pct  40",
+		"request_backlog_timeout  1000",
+		"error: unknown config key: enable_taff, did you mean enable_taf?",
+		"error: Config entry invalid format: max_scuttle: 2000 is not between 1 and 1024",
+	} {
+		if !regexp.MustCompile(strings.ReplaceAll(regexp.QuoteMeta(expected), "  ", `\s+`)).MatchString(report) {
+			t.Errorf("%q not in the report:\n%s", expected, report)
+		}
+	}
+	if !regexp.MustCompile(`\sbacklog_pct\s+40\s+hera.txt`).MatchString(report) || !regexp.MustCompile(`short_backlog_timeout\s+40\s+env`).MatchString(report) ||
+		!regexp.MustCompile(`request_backlog_timeout\s+1000\s+default`).MatchString(report) {
+		t.Errorf("wrong sources in the report:\n%s", report)
+	}
+	if strings.Contains(report, "x-mysql") || strings.Contains(report, "cal_pool_name") {
+		t.Errorf("extension key reported:\n%s", report)
+	}
+}
@@ -19,6 +19,7 @@ package lib
 
 import (
 	"context"
+	"errors"
 	"io"
 	"net"
 	"strconv"
@@ -28,12 +29,18 @@ import (
 	"github.com/paypal/hera/utility/logger"
 )
 
+var errBinaryNotNegotiated = errors.New("binary frame without negotiation")
+
 // Spawns a goroutine which blocks waiting for a message on conn. When a message is received it writes
-// to the channel and exit. It basically wrapps the net.Conn in a channel
-func wrapNewNetstring(conn net.Conn) <-chan *netstring.Netstring {
+// to the channel and exit. It basically wrapps the net.Conn in a channel. The binary frames are accepted
+// only if the client negotiated them, they are forwarded as is to the workers
+func wrapNewNetstring(conn net.Conn, binaryFraming func() bool) <-chan *netstring.Netstring {
 	ch := make(chan *netstring.Netstring, 1)
 	go func() {
-		ns, err := netstring.NewNetstring(conn)
+		ns, err := netstring.ReadFrame(conn)
+		if (err == nil) && ns.IsBinary() && !binaryFraming() {
+			err = errBinaryNotNegotiated
+		}
 		if err != nil {
 			if err == io.EOF {
 				if logger.GetLogger().V(logger.Debug) {
@@ -93,7 +100,7 @@ func HandleConnection(conn net.Conn) {
 	for {
 		var ns *netstring.Netstring
 		select {
-		case ns = <-wrapNewNetstring(conn):
+		case ns = <-wrapNewNetstring(conn, crd.isBinaryFraming):
 		case timeout := <-crd.Done():
 			if logger.GetLogger().V(logger.Info) {
 				logger.GetLogger().Log(logger.Info, "Connection handler idle timeout", addr)
@@ -77,6 +77,9 @@ var (
 	ErrNoScuttleIdPredicate,
 	ErrCrossKeysDML,
 	ErrQueryBindBlocker,
+	ErrSQLNotAllowed,
+	ErrSessionVarsDisabled,
+	ErrBadSessionVar,
 	ErrOther,
 	ErrReqParseFail error
 )
@@ -106,6 +109,9 @@ func MkErr(prefix string) {
 	ErrNoShardValue = errors.New(prefix + "-375: no shard value or wrong sharKey array binding")
 	ErrCrossKeysDML = errors.New(prefix + "-206: cross key dml")
 	ErrQueryBindBlocker = errors.New(prefix + "-207: dba query bind blocker")
+	ErrSQLNotAllowed = errors.New(prefix + "-208: sql not in allowlist")
+	ErrSessionVarsDisabled = errors.New(prefix + "-209: session variables not enabled")
+	ErrBadSessionVar = errors.New(prefix + "-210: bad session variable")
 	ErrOther = errors.New(prefix + "-1000: unknown error")
 	ErrReqParseFail = errors.New("Request error")
 }
@@ -132,6 +138,8 @@ const (
 	envLogPrefix        = "logger.LOG_PREFIX"
 	envHeraName         = "HERA_NAME"
 	envTwoTask          = "TWO_TASK"
+	envWorkerType       = "HERA_WORKER_TYPE"
+	envShardID          = "HERA_SHARD_ID"
 )
 
 const (
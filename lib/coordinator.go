@@ -72,11 +72,30 @@ type Coordinator struct {
 
 	// if this handles an internal client like rac maintenance config or shard config
 	isInternal bool
+
+	// accounting of the worker pinned across client requests
+	pin connPinStats
+	// timestamps of the current worker request, for the slow query log
+	timing rqTiming
+	// number of columns of the last query executed, to count the fetched rows
+	resultCols int
+	// the session variables set by the client, in the CmdSetSessionVars format sorted by name
+	sessionVars string
+	// set to 1 once the client switched to the binary framing, read by the connection handler
+	binaryFraming int32
+	// the requests a pipelining client sent while the worker was running the previous one. They are
+	// processed in order after it, like the requests read from clientchannel
+	pipelined chan *netstring.Netstring
 }
 
+// maxPipelinedRequests is the number of requests queued while the worker runs a request, the client
+// connection is not read while the queue is full
+const maxPipelinedRequests = 64
+
 // NewCoordinator creates a coordinator, clientchannel is used to read the requests, conn is used to write responses
 func NewCoordinator(ctx context.Context, clientchannel <-chan *netstring.Netstring, conn net.Conn) *Coordinator {
-	coordinator := &Coordinator{clientchannel: clientchannel, conn: conn, ctx: ctx, done: make(chan int, 1), id: conn.RemoteAddr().String(), shard: &shardInfo{sessionShardID: -1}, prevShard: &shardInfo{sessionShardID: -1}}
+	coordinator := &Coordinator{clientchannel: clientchannel, conn: conn, ctx: ctx, done: make(chan int, 1), id: conn.RemoteAddr().String(), shard: &shardInfo{sessionShardID: -1}, prevShard: &shardInfo{sessionShardID: -1},
+		pipelined: make(chan *netstring.Netstring, maxPipelinedRequests)}
 	var err error
 	coordinator.sqlParser, err = common.NewRegexSQLParser()
 	if err != nil {
@@ -121,8 +140,12 @@ func (crd *Coordinator) Run() {
 	var workerCtrlChan <-chan *workerMsg
 	running := true
 	for running {
+		clientChannel := crd.clientchannel
+		if len(crd.pipelined) > 0 {
+			clientChannel = crd.pipelined
+		}
 		select {
-		case ns, ok := <-crd.clientchannel:
+		case ns, ok := <-clientChannel:
 			if !ok {
 				if logger.GetLogger().V(logger.Debug) {
 					logger.GetLogger().Log(logger.Debug, crd.id, "Coordinator exiting (closed channel) ...")
@@ -142,6 +165,17 @@ func (crd *Coordinator) Run() {
 			}
 			// new session
 			crd.nss = nil
+			if (crd.worker == nil) && isDrainRequested() {
+				// session boundary, the client reconnects to another mux
+				if idleTimer != nil {
+					idleTimer.Stop()
+				}
+				crd.respond(drainingNs.Serialized)
+				if logger.GetLogger().V(logger.Info) {
+					logger.GetLogger().Log(logger.Info, crd.id, "draining, closing the connection")
+				}
+				return
+			}
 			handle, _ := crd.handleMux(ns)
 			if !handle {
 				// not handled by mux, it means it is a worker command
@@ -163,7 +197,9 @@ func (crd *Coordinator) Run() {
 					workerCtrlChan = nil
 				}
 
+				crd.pinRequestStart()
 				running = crd.dispatch(ns)
+				crd.pinRequestDone()
 				if crd.worker != nil {
 					workerChan = crd.worker.channel()
 					workerCtrlChan = crd.worker.ctrlCh
@@ -316,7 +352,7 @@ func (crd *Coordinator) Run() {
 }
 
 func (crd *Coordinator) dispatch(request *netstring.Netstring) bool {
-	if GetConfig().EnableTAF && (crd.worker == nil) {
+	if GetReloadableConfig().EnableTAF && (crd.worker == nil) {
 		taferr := crd.DispatchTAFSession(request)
 		crd.processError(taferr)
 		return (taferr == nil)
@@ -388,18 +424,26 @@ func (crd *Coordinator) handleMux(request *netstring.Netstring) (bool, error) {
 			return false, err
 		}
 		crd.nss = nss
-		if GetConfig().EnableQueryBindBlocker {
-			block, _ := crd.PreprocessQueryBindBlocker(nss)
+		if GetReloadableConfig().EnableQueryBindBlocker {
+			block, remarks := crd.PreprocessQueryBindBlocker(nss)
 			if block {
-				ns := netstring.NewNetstringFrom(common.RcError, []byte(ErrQueryBindBlocker.Error()))
+				errMsg := ErrQueryBindBlocker.Error()
+				if len(remarks) > 0 {
+					errMsg += ": " + remarks
+				}
+				ns := netstring.NewNetstringFrom(common.RcError, []byte(errMsg))
 				crd.respond(ns.Serialized)
 				crd.conn.Close()
 				return true /*handled*/, nil
 			}
-		} // end GetConfig().EnableQueryBindBlocker
+		} // end GetReloadableConfig().EnableQueryBindBlocker
 		for _, ns := range nss {
 			if (ns.Cmd == common.CmdPrepare) || (ns.Cmd == common.CmdPrepareV2) || (ns.Cmd == common.CmdPrepareSpecial) {
 				crd.sqlhash = int32(utility.GetSQLHash(string(ns.Payload)))
+				if !crd.checkSQLAllowlist(string(ns.Payload)) {
+					crd.respond(netstring.NewNetstringFrom(common.RcError, []byte(ErrSQLNotAllowed.Error())).Serialized)
+					return true /*handled*/, nil
+				}
 				crd.isRead = crd.sqlParser.IsRead(string(ns.Payload))
 				handled := false
 				if GetConfig().EnableSharding {
@@ -429,6 +473,10 @@ func (crd *Coordinator) handleMux(request *netstring.Netstring) (bool, error) {
 	crd.nss = nil
 	// an individual request
 	if (request.Cmd == common.CmdPrepare) || (request.Cmd == common.CmdPrepareV2) || (request.Cmd == common.CmdPrepareSpecial) {
+		if !crd.checkSQLAllowlist(string(request.Payload)) {
+			crd.respond(netstring.NewNetstringFrom(common.RcError, []byte(ErrSQLNotAllowed.Error())).Serialized)
+			return true /*handled*/, nil
+		}
 		crd.isRead = crd.sqlParser.IsRead(string(request.Payload))
 		return false, nil
 	}
@@ -448,6 +496,10 @@ func (crd *Coordinator) processMuxCommand(request *netstring.Netstring) (bool, e
 		crd.corrID = request
 	case common.CmdServerPingCommand:
 		crd.respond([]byte("4:1009,"))
+	case common.CmdProtocolVersion:
+		crd.processProtocolVersion(string(request.Payload))
+	case common.CmdClientCompression:
+		crd.processCompression(string(request.Payload))
 	case common.CmdBacktrace: // TODO passing command to worker
 	case common.CmdClientInfo:
 		crd.processClientInfoMuxCommand(string(request.Payload))
@@ -482,6 +534,14 @@ func (crd *Coordinator) processMuxCommand(request *netstring.Netstring) (bool, e
 			crd.conn.Close()
 			return true, err
 		}
+	case common.CmdSetSessionVars:
+		err := crd.processSetSessionVars(request.Payload)
+		if err == nil {
+			crd.respond([]byte("1:5,"))
+		} else {
+			ns := netstring.NewNetstringFrom(common.RcError, []byte(err.Error()))
+			crd.respond(ns.Serialized)
+		}
 	case common.CmdGetNumShards:
 		numShards := fmt.Sprintf("%d", GetConfig().NumOfShards)
 		ns := netstring.NewNetstringFrom(common.RcOK, []byte(numShards))
@@ -623,6 +683,7 @@ func (crd *Coordinator) processClientInfoMuxCommand(clientInfo string) {
 }
 
 func (crd *Coordinator) resetWorkerInfo() {
+	crd.pinRelease()
 	crd.worker = nil
 	crd.workerpool = nil
 	crd.ticket = ""
@@ -658,23 +719,24 @@ func (crd *Coordinator) dispatchRequest(request *netstring.Netstring) error {
 	if ok {
 		wType := wtypeRW
 		cfg := GetNumWorkers(crd.shard.shardID)
-		if GetConfig().ReadonlyPct > 0 {
+		rcfg := GetReloadableConfig()
+		if rcfg.ReadonlyPct > 0 {
 			if crd.isRead {
 				wType = wtypeRO
-				cfg = int(float64(cfg) * float64(GetConfig().ReadonlyPct) / 100.0)
+				cfg = int(float64(cfg) * float64(rcfg.ReadonlyPct) / 100.0)
 			} else {
-				cfg = int(float64(cfg) * float64(100-GetConfig().ReadonlyPct) / 100.0)
+				cfg = int(float64(cfg) * float64(100-rcfg.ReadonlyPct) / 100.0)
 			}
 		}
 		numFree := GetStateLog().numFreeWorker(crd.shard.shardID, wType)
 		heavyUsage := false
-		thres := float64(GetConfig().BindEvictionTargetConnPct) / 100.0 * float64(cfg)
+		thres := float64(rcfg.BindEvictionTargetConnPct) / 100.0 * float64(cfg)
 		if numFree < int(thres) {
 			heavyUsage = true
 		}
 		if logger.GetLogger().V(logger.Verbose) {
 			msg := fmt.Sprintf("bind throttle heavyUsage?%t free:%d cfg:%d pct:%d thres:%f", heavyUsage,
-				numFree, cfg, GetConfig().BindEvictionTargetConnPct, thres)
+				numFree, cfg, rcfg.BindEvictionTargetConnPct, thres)
 			logger.GetLogger().Log(logger.Verbose, msg)
 		}
 		bindkv := parseBinds(request)
@@ -708,8 +770,9 @@ func (crd *Coordinator) dispatchRequest(request *netstring.Netstring) error {
 
 	}
 
+	getWorkerStart := time.Now()
 	if worker == nil {
-		if crd.isRead && (GetConfig().ReadonlyPct != 0) {
+		if crd.isRead && (GetReloadableConfig().ReadonlyPct != 0) {
 			workerpool, err = GetWorkerBrokerInstance().GetWorkerPool(wtypeRO, 0, crd.shard.shardID)
 			if err != nil {
 				return err
@@ -747,7 +810,7 @@ func (crd *Coordinator) dispatchRequest(request *netstring.Netstring) error {
 			if crd.shard.shardID != worker.shardID {
 				// we allow this but we need to have a different worker since it is a different shard
 				wType := wtypeRO
-				if GetConfig().ReadonlyPct == 0 {
+				if GetReloadableConfig().ReadonlyPct == 0 {
 					wType = wtypeRW
 				}
 
@@ -778,7 +841,14 @@ func (crd *Coordinator) dispatchRequest(request *netstring.Netstring) error {
 		}
 	}
 
+	var backlogWait time.Duration
+	if worker != crd.worker {
+		backlogWait = time.Since(getWorkerStart)
+	}
+	crd.timing = rqTiming{}
 	wait, err := crd.doRequest(crd.ctx, worker, request, crd.conn, nil)
+	crd.logSlowQuery(worker, request, backlogWait, err)
+	crd.recordSQLStats(worker, backlogWait, err)
 
 	if !xShardRead {
 		if wait {
@@ -914,7 +984,7 @@ func (crd *Coordinator) doRequest(ctx context.Context, worker *WorkerClient, req
 	atomic.StoreUint32(&(worker.sqlStartTimeMs), timesincestart)
 
 	if request != nil {
-		_ /*isPrepare*/, isCommit, isRollback, parseErr := crd.parseCmd(request)
+		isPrepare, isCommit, isRollback, parseErr := crd.parseCmd(request)
 		if parseErr != nil {
 			if logger.GetLogger().V(logger.Warning) {
 				logger.GetLogger().Log(logger.Warning, "doRequest: can't parse the client request", parseErr)
@@ -987,6 +1057,12 @@ func (crd *Coordinator) doRequest(ctx context.Context, worker *WorkerClient, req
 			plusAnyCorrId = netstring.NewNetstringEmbedded(ns)
 
 		}
+		if (worker != crd.worker) && (worker.sessionVars != crd.sessionVars) {
+			// the worker was just allocated, it is not in transaction
+			plusAnyCorrId, cnt = crd.prependSessionVars(worker, plusAnyCorrId, cnt)
+		}
+		crd.timing = rqTiming{start: time.Now()}
+		crd.initRowCount(request)
 		err := worker.Write(plusAnyCorrId, uint16(cnt))
 		if err != nil {
 			if logger.GetLogger().V(logger.Debug) {
@@ -994,6 +1070,9 @@ func (crd *Coordinator) doRequest(ctx context.Context, worker *WorkerClient, req
 			}
 			return false, ErrWorkerFail
 		}
+		if isPrepare {
+			crd.recordSQL(worker, request)
+		}
 		timesincestart := uint32(0)
 		if isCommit || isRollback { // set the sqlStartTimeMs to 0 to avoid recover routine to pick during saturation for OCC_COMMIT and OCC_ROLLBACK
 			atomic.StoreUint32(&(worker.sqlStartTimeMs), 0)
@@ -1034,6 +1113,9 @@ func (crd *Coordinator) doRequest(ctx context.Context, worker *WorkerClient, req
 	//
 	var reqStr string
 	clientChannel := crd.clientchannel
+	if len(crd.pipelined) == cap(crd.pipelined) {
+		clientChannel = nil
+	}
 	done := ctx.Done()
 	for {
 		select {
@@ -1051,7 +1133,8 @@ func (crd *Coordinator) doRequest(ctx context.Context, worker *WorkerClient, req
 				evt.Completed()
 				return false, ErrClientFail
 			}
-			if (ns != nil) && (ns.Cmd != common.CmdFetch) && (ns.Cmd != common.CmdCols) && (ns.Cmd != common.CmdColsInfo) {
+			if (len(crd.pipelined) > 0) || ((ns.Cmd != common.CmdFetch) && (ns.Cmd != common.CmdCols) && (ns.Cmd != common.CmdColsInfo)) {
+				// a pipelined request, it is processed after this one, through handleMux like any request
 				//
 				// if one dorequest gets multiple multiple_client_req, do this once.
 				//
@@ -1072,6 +1155,15 @@ func (crd *Coordinator) doRequest(ctx context.Context, worker *WorkerClient, req
 				}
 				evt := cal.NewCalEvent(EvtTypeMux, "multiple_client_req", cal.TransOK, logmsg+fmt.Sprintf(", cmd=%s %s", DebugString(ns.Serialized), reqStr))
 				evt.Completed()
+				if logger.GetLogger().V(logger.Debug) {
+					logger.GetLogger().Log(logger.Debug, crd.id, "doRequest: pipelined request queued", len(crd.pipelined)+1)
+				}
+				crd.pipelined <- ns
+				if len(crd.pipelined) == cap(crd.pipelined) {
+					// the connection handler waits until the queued requests are processed
+					clientChannel = nil
+				}
+				continue
 			}
 			if logger.GetLogger().V(logger.Debug) {
 				logger.GetLogger().Log(logger.Debug, crd.id, "coordinator dorequest got client request")
@@ -1128,6 +1220,10 @@ func (crd *Coordinator) doRequest(ctx context.Context, worker *WorkerClient, req
 			if msglen > 0 {
 				// disable timeout once response was sent to the client
 				timeout = nil
+				if crd.timing.firstResp.IsZero() {
+					crd.timing.firstResp = time.Now()
+				}
+				crd.countRows(msg.data)
 
 				_, err := clientWriter.Write(msg.data)
 				if err != nil {
@@ -1136,6 +1232,7 @@ func (crd *Coordinator) doRequest(ctx context.Context, worker *WorkerClient, req
 					}
 					return false, ErrClientFail
 				}
+				crd.timing.lastResp = time.Now()
 
 				if msglen >= 64*1024 {
 					evt := cal.NewCalEvent(EvtTypeMux, "large_payload_out", cal.TransOK, "")
@@ -1144,6 +1241,9 @@ func (crd *Coordinator) doRequest(ctx context.Context, worker *WorkerClient, req
 				}
 			}
 
+			if msg.eor {
+				crd.timing.eorRqID = msg.rqId
+			}
 			if msg.free {
 				if msg.rqId != worker.rqId {
 					evname := "crqId"
@@ -27,6 +27,7 @@ import (
 )
 
 
+// PreprocessQueryBindBlocker returns if the request is blocked and the remarks of the blocker entry
 func (crd *Coordinator) PreprocessQueryBindBlocker(requests []*netstring.Netstring) (bool, string) {
 	qbb := GetQueryBindBlockerCfg()
 	if qbb == nil {
@@ -59,13 +60,16 @@ func (crd *Coordinator) PreprocessQueryBindBlocker(requests []*netstring.Netstri
 			}
 		} // end if bind name
 	}
-	rv, reason := qbb.IsBlocked(sqltext, bindPairs)
+	rv, val, remarks := qbb.IsBlocked(sqltext, bindPairs)
 	if rv {
 		sqlhashStr := fmt.Sprintf("%d",uint32(utility.GetSQLHash(sqltext)))
 		caltxn := cal.NewCalTransaction("DBA_QUERY_BIND_BLOCKER", sqlhashStr, /*status*/"1.DB.MANUAL.1", "", cal.DefaultTGName)
-		caltxn.AddDataStr("val", reason)
+		caltxn.AddDataStr("val", val)
+		if len(remarks) > 0 {
+			caltxn.AddDataStr("remarks", remarks)
+		}
 		caltxn.AddDataStr("raddr", crd.conn.RemoteAddr().String())
 		caltxn.Completed()
 	}
-	return rv, reason
+	return rv, remarks
 }
@@ -58,7 +58,7 @@ func (p *tafResponsePreproc) Write(bf []byte) (int, error) {
 	if p.replyTime == 0 {
 		p.replyTime = time.Now().UnixNano()
 	}
-	ns, err := netstring.NewNetstring(bytes.NewReader(bf))
+	ns, err := netstring.ReadFrame(bytes.NewReader(bf))
 	if err == nil {
 		if !p.dataSent /*if prior to this some response was alredy sent - then disable this check*/ {
 			// look inside for SQLError
@@ -182,7 +182,7 @@ func (crd *Coordinator) DispatchTAFSession(request *netstring.Netstring) error {
 				}
 				respProcessor := &tafResponsePreproc{conn: crd.conn, ok: true, dataSent: false}
 
-				timeout := time.Duration(GetConfig().TAFTimeoutMs) * time.Millisecond
+				timeout := time.Duration(GetReloadableConfig().TAFTimeoutMs) * time.Millisecond
 				if queryNormallySlow {
 					timeout = 3600 * time.Second
 					if logger.GetLogger().V(logger.Verbose) {
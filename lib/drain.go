@@ -0,0 +1,128 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lib
+
+import (
+	"net"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/paypal/hera/cal"
+	"github.com/paypal/hera/common"
+	"github.com/paypal/hera/utility/encoding/netstring"
+	"github.com/paypal/hera/utility/logger"
+	"github.com/paypal/hera/watchdoglib"
+)
+
+// Drain for planned maintenance, started with SIGUSR1. New connections are bounced with CmdServerDraining,
+// the open connections get CmdServerDraining at their next session boundary, i.e. the next request when no
+// worker is attached, and are closed. Open transactions finish normally. Mux exits with watchdoglib.ExitDrained
+// when there are no more connections or when "drain_timeout_sec" passes.
+
+// how often the drain progress is reported
+const drainReportInterval = 5 * time.Second
+
+// when the drain started (unix nano), 0 if not draining
+var gDrainStart int64
+
+var drainingNs = netstring.NewNetstringFrom(common.CmdServerDraining, []byte("draining"))
+
+// isDrainRequested returns true if the connections are being drained, for maintenance or for an upgrade
+func isDrainRequested() bool {
+	return atomic.LoadInt64(&gDrainStart) != 0
+}
+
+// startDrain returns false if the drain already started
+func startDrain() bool {
+	return atomic.CompareAndSwapInt64(&gDrainStart, 0, time.Now().UnixNano())
+}
+
+// bounceDraining tells the client of a new connection to connect to another mux
+func bounceDraining(conn net.Conn) {
+	e := cal.NewCalEvent(cal.EventTypeWarning, "Bounce", cal.TxnStatus(cal.TransWarning, "SERVER", "DRAINING", "-1"), "")
+	e.AddDataStr("raddr", conn.RemoteAddr().String())
+	e.Completed()
+	conn.SetWriteDeadline(time.Now().Add(time.Second))
+	conn.Write(drainingNs.Serialized)
+}
+
+// drainConnections waits for the client connections to close or for the timeout. It returns the
+// connections left
+func drainConnections(timeout time.Duration) int {
+	deadline := time.Now().Add(timeout)
+	lastReport := time.Now()
+	for {
+		conns := GetStateLog().GetTotalConnections()
+		if (conns == 0) || time.Now().After(deadline) {
+			return conns
+		}
+		if time.Since(lastReport) >= drainReportInterval {
+			lastReport = time.Now()
+			evt := cal.NewCalEvent(EvtTypeMux, "drain_progress", cal.TransOK, "")
+			evt.AddDataInt("conns", int64(conns))
+			evt.AddDataInt("left_sec", int64(time.Until(deadline)/time.Second))
+			evt.Completed()
+			if logger.GetLogger().V(logger.Info) {
+				logger.GetLogger().Log(logger.Info, "drain: connections left", conns, "deadline in", time.Until(deadline).Round(time.Second))
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// exitMux terminates the workers, in the mux process group, and exits
+func exitMux(exitCode int) {
+	conns := GetStateLog().GetTotalConnections()
+	evt := cal.NewCalEvent(EvtTypeMux, "drain_exit", cal.TransOK, "")
+	evt.AddDataInt("conns", int64(conns))
+	evt.AddDataInt("exit_code", int64(exitCode))
+	evt.Completed()
+	if logger.GetLogger().V(logger.Alert) {
+		logger.GetLogger().Log(logger.Alert, "drained, exiting with", exitCode, ". Connections left:", conns)
+	}
+	signal.Ignore(syscall.SIGTERM)
+	syscall.Kill(-os.Getpid(), syscall.SIGTERM)
+	cal.ReleaseCxtResource()
+	os.Exit(exitCode)
+}
+
+// InitDrain handles SIGUSR1 which starts the drain
+func InitDrain() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGUSR1)
+	go func() {
+		for range sigs {
+			if !startDrain() {
+				continue
+			}
+			if logger.GetLogger().V(logger.Alert) {
+				logger.GetLogger().Log(logger.Alert, "drain: requested, timeout", GetConfig().DrainTimeoutSec, "sec")
+			}
+			evt := cal.NewCalEvent(EvtTypeMux, "drain_start", cal.TransOK, "")
+			evt.AddDataInt("conns", int64(GetStateLog().GetTotalConnections()))
+			evt.Completed()
+			go func() {
+				drainConnections(time.Duration(GetConfig().DrainTimeoutSec) * time.Second)
+				exitMux(watchdoglib.ExitDrained)
+			}()
+		}
+	}()
+}
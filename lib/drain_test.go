@@ -0,0 +1,54 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lib
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"github.com/paypal/hera/common"
+	"github.com/paypal/hera/utility/encoding/netstring"
+)
+
+func TestDrainBounce(t *testing.T) {
+	defer atomic.StoreInt64(&gDrainStart, 0)
+	if isDrainRequested() {
+		t.Fatal("draining before the drain started")
+	}
+	if !startDrain() || startDrain() {
+		t.Error("the drain must start only once")
+	}
+	if !isDrainRequested() {
+		t.Error("drain not started")
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		bounceDraining(server)
+		server.Close()
+	}()
+	ns, err := netstring.NewNetstring(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns.Cmd != common.CmdServerDraining {
+		t.Errorf("expected %d, got %d", common.CmdServerDraining, ns.Cmd)
+	}
+}
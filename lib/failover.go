@@ -0,0 +1,96 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lib
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/paypal/hera/cal"
+	"github.com/paypal/hera/utility/logger"
+)
+
+// failoverMaxBackoff is the longest time the failovers of a pool are ignored after a recycle, when the new
+// workers keep finding their database read-only, e.g. while no data source is writable yet
+const failoverMaxBackoff = 5 * time.Minute
+
+// failover is called when a worker exited with common.WorkerExitReadOnly: its heartbeat or a write found the
+// database read-only, it is not the primary anymore. The workers of the pool are recycled over
+// failover_restart_window, the new workers connect to the first writable data source of their TWO_TASK.
+// A new failover within the backoff of the last one only restarts its worker, the backoff starts at
+// failover_restart_window and doubles, up to failoverMaxBackoff, while the failovers follow each other.
+// It returns false if no recycle was scheduled
+func (pool *WorkerPool) failover(worker *WorkerClient) bool {
+	now := time.Now()
+	name := fmt.Sprintf("%s%d", poolNamePrefix[pool.Type], pool.InstID)
+
+	pool.poolCond.L.Lock()
+	last := time.Unix(0, pool.failoverTime)
+	// the workers started before the last failover are being recycled, their errors are not a new failover
+	if worker.startTimeNs <= pool.failoverTime {
+		pool.poolCond.L.Unlock()
+		if logger.GetLogger().V(logger.Debug) {
+			logger.GetLogger().Log(logger.Debug, "failover: worker", worker.pid, "already recycled, shard", pool.ShardID)
+		}
+		return false
+	}
+	if now.Before(last.Add(pool.failoverBackoff)) {
+		backoff := pool.failoverBackoff
+		pool.poolCond.L.Unlock()
+		if logger.GetLogger().V(logger.Info) {
+			logger.GetLogger().Log(logger.Info, "failover: worker", worker.pid, "found the database read-only, pool", name, "of shard", pool.ShardID, "recycled", now.Sub(last), "ago, backoff", backoff)
+		}
+		evt := cal.NewCalEvent(EvtTypeMux, "failover_backoff", cal.TransWarning, "")
+		evt.AddDataStr("pool", name)
+		evt.AddDataInt("shard", int64(pool.ShardID))
+		evt.AddDataInt("pid", int64(worker.pid))
+		evt.AddDataInt("backoff_ms", backoff.Milliseconds())
+		evt.Completed()
+		return false
+	}
+	window := time.Duration(GetConfig().FailoverRestartWindow) * time.Second
+	if now.Sub(last) < 2*pool.failoverBackoff {
+		// the last recycle did not find a writable database
+		pool.failoverBackoff *= 2
+		if pool.failoverBackoff > failoverMaxBackoff {
+			pool.failoverBackoff = failoverMaxBackoff
+		}
+	} else {
+		pool.failoverBackoff = window
+	}
+	pool.failoverTime = now.UnixNano()
+	backoff := pool.failoverBackoff
+	pool.poolCond.L.Unlock()
+
+	if logger.GetLogger().V(logger.Warning) {
+		logger.GetLogger().Log(logger.Warning, "failover: worker", worker.pid, "found the database read-only, recycling pool", name, "of shard", pool.ShardID)
+	}
+	evt := cal.NewCalEvent(EvtTypeMux, "failover_detected", cal.TransWarning, "")
+	evt.AddDataStr("pool", name)
+	evt.AddDataInt("shard", int64(pool.ShardID))
+	evt.AddDataInt("pid", int64(worker.pid))
+	evt.AddDataStr("db_uname", worker.dbUname)
+	evt.AddDataInt("backoff_ms", backoff.Milliseconds())
+	evt.Completed()
+
+	shard, inst := pool.ShardID, pool.InstID
+	req := MaintRequest{ID: fmt.Sprintf("failover-%s_%d-%d", name, shard, now.Unix()), Source: maintSourceFailover, Action: MaintRecycle,
+		Time: now, WindowSec: GetConfig().FailoverRestartWindow, Shard: &shard, Type: workerTypeNames[pool.Type], Inst: &inst}
+	startMaint(req, []*WorkerPool{pool})
+	return true
+}
@@ -0,0 +1,70 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lib
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFailover(t *testing.T) {
+	savedCfg := gAppConfig
+	defer func() { gAppConfig = savedCfg }()
+	gAppConfig = &Config{FailoverRestartWindow: 10}
+
+	started := time.Now().Add(-100 * time.Second)
+	pool := &WorkerPool{Type: wtypeRW, poolCond: sync.NewCond(&sync.Mutex{}), currentSize: 4}
+	pool.workers = make([]*WorkerClient, pool.currentSize)
+	for i := range pool.workers {
+		pool.workers[i] = &WorkerClient{ID: i, pid: 100 + i, startTime: started.Unix(), startTimeNs: started.UnixNano()}
+	}
+	if !pool.failover(pool.workers[0]) {
+		t.Fatal("failover not detected")
+	}
+	for i, worker := range pool.workers {
+		if (worker.exitTime == 0) || (worker.exitTime > time.Now().Unix()+10) {
+			t.Errorf("worker %d not recycled within failover_restart_window: %d", i, worker.exitTime)
+		}
+	}
+	// the other workers of the pool were recycled by this failover
+	if pool.failover(pool.workers[1]) {
+		t.Error("second failover for the same workers")
+	}
+	// a new worker, started in the same second, finding the database read-only during the backoff only restarts
+	newWorker := func() *WorkerClient {
+		now := time.Now()
+		return &WorkerClient{ID: 0, startTime: now.Unix(), startTimeNs: now.UnixNano()}
+	}
+	if pool.failover(newWorker()) || (pool.failoverBackoff != 10*time.Second) {
+		t.Errorf("failover during the backoff %s", pool.failoverBackoff)
+	}
+	// after the backoff, the backoff doubles while no database is writable
+	pool.failoverTime -= int64(11 * time.Second)
+	if !pool.failover(newWorker()) {
+		t.Fatal("failover after the backoff not detected")
+	}
+	if pool.failoverBackoff != 20*time.Second {
+		t.Errorf("backoff %s, expected 20s", pool.failoverBackoff)
+	}
+	pool.failoverTime -= int64(time.Hour)
+	pool.failoverBackoff = failoverMaxBackoff
+	if !pool.failover(newWorker()) || (pool.failoverBackoff != 10*time.Second) {
+		t.Errorf("backoff %s after a late failover, expected failover_restart_window", pool.failoverBackoff)
+	}
+}
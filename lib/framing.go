@@ -0,0 +1,148 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lib
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/paypal/hera/cal"
+	"github.com/paypal/hera/common"
+	"github.com/paypal/hera/utility/encoding/compression"
+	"github.com/paypal/hera/utility/encoding/netstring"
+	"github.com/paypal/hera/utility/logger"
+)
+
+// clientConn is the client connection once the client negotiated the binary framing or the compression.
+// The responses are compressed if large enough. With the binary framing the workers answer the binary
+// requests in binary frames, which are written as is, the text netstrings of the mux are converted.
+// The connection handler reads the requests with netstring.ReadFrame once the binary framing is negotiated
+type clientConn struct {
+	net.Conn
+	sync.Mutex
+	// set once the client switched to the binary framing
+	binary *netstring.BinaryWriter
+	// codec compressing the responses over "compression_threshold", empty if not compressed
+	codec string
+}
+
+// negotiatedConn returns the client connection, wrapping it the first time
+func (crd *Coordinator) negotiatedConn() *clientConn {
+	cc, ok := crd.conn.(*clientConn)
+	if !ok {
+		cc = &clientConn{Conn: crd.conn}
+		crd.conn = cc
+	}
+	return cc
+}
+
+func (cc *clientConn) Write(data []byte) (int, error) {
+	cc.Lock()
+	defer cc.Unlock()
+	n := len(data)
+	if len(cc.codec) > 0 {
+		data = cc.compress(data)
+	}
+	if cc.binary != nil {
+		_, err := cc.binary.Write(data)
+		return n, err
+	}
+	return n, WriteAll(cc.Conn, data)
+}
+
+// compress returns the data, which are complete netstrings, as a RcCompressed netstring if it is large
+// enough. Data which does not compress well is sent as is
+func (cc *clientConn) compress(data []byte) []byte {
+	if len(data) < GetConfig().CompressionThreshold {
+		return data
+	}
+	compressed, err := compression.Compress(cc.codec, nil, data)
+	if err != nil {
+		if logger.GetLogger().V(logger.Warning) {
+			logger.GetLogger().Log(logger.Warning, cc.RemoteAddr(), "compression failed:", err.Error())
+		}
+		return data
+	}
+	if len(compressed) > len(data)*9/10 {
+		return data
+	}
+	if len(data) >= 64*1024 {
+		evt := cal.NewCalEvent(EvtTypeMux, "compressed_payload_out", cal.TransOK, "")
+		evt.AddDataInt("len", int64(len(data)))
+		evt.AddDataInt("compressed", int64(len(compressed)))
+		evt.Completed()
+	}
+	return netstring.NewNetstringFrom(common.RcCompressed, compressed).Serialized
+}
+
+// processProtocolVersion answers the protocol version requested by the client with the version used
+// on this connection. The answer is the last text netstring sent to a client switching to binary frames
+func (crd *Coordinator) processProtocolVersion(version string) {
+	if cc, ok := crd.conn.(*clientConn); ok && (cc.binary != nil) {
+		crd.respond(netstring.NewNetstringFrom(common.CmdProtocolVersion, []byte(common.ProtocolVersionBinaryFraming)).Serialized)
+		return
+	}
+	if (version != common.ProtocolVersionBinaryFraming) || !GetConfig().EnableBinaryFraming {
+		crd.respond(netstring.NewNetstringFrom(common.CmdProtocolVersion, []byte(common.ProtocolVersionText)).Serialized)
+		return
+	}
+	// the client sends binary frames only after reading the answer, the connection handler accepts them
+	// once they are read
+	atomic.StoreInt32(&crd.binaryFraming, 1)
+	crd.respond(netstring.NewNetstringFrom(common.CmdProtocolVersion, []byte(common.ProtocolVersionBinaryFraming)).Serialized)
+	cc := crd.negotiatedConn()
+	cc.Lock()
+	cc.binary = netstring.NewBinaryWriter(cc.Conn)
+	cc.Unlock()
+	if logger.GetLogger().V(logger.Debug) {
+		logger.GetLogger().Log(logger.Debug, crd.id, "client switched to binary framing")
+	}
+	evt := cal.NewCalEvent(EvtTypeMux, "binary_framing", cal.TransOK, "")
+	evt.Completed()
+}
+
+// processCompression answers the codecs offered by the client with the codec used for the large responses,
+// empty if they are not compressed
+func (crd *Coordinator) processCompression(offered string) {
+	codec := ""
+	if GetConfig().CompressionThreshold > 0 {
+		codec = compression.Pick(offered)
+	}
+	crd.respond(netstring.NewNetstringFrom(common.CmdClientCompression, []byte(codec)).Serialized)
+	if (len(codec) == 0) && !crd.isNegotiated() {
+		return
+	}
+	cc := crd.negotiatedConn()
+	cc.Lock()
+	cc.codec = codec
+	cc.Unlock()
+	if logger.GetLogger().V(logger.Debug) {
+		logger.GetLogger().Log(logger.Debug, crd.id, "client compression:", offered, "using:", codec)
+	}
+}
+
+// isBinaryFraming tells if the client switched to the binary framing
+func (crd *Coordinator) isBinaryFraming() bool {
+	return atomic.LoadInt32(&crd.binaryFraming) == 1
+}
+
+func (crd *Coordinator) isNegotiated() bool {
+	_, ok := crd.conn.(*clientConn)
+	return ok
+}
@@ -0,0 +1,153 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lib
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/paypal/hera/common"
+	"github.com/paypal/hera/utility/encoding/compression"
+	"github.com/paypal/hera/utility/encoding/netstring"
+)
+
+func TestBinaryConn(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	conn := &clientConn{Conn: server, binary: netstring.NewBinaryWriter(server)}
+	go func() {
+		// a response from the worker, with the end of it in a second write
+		resp := netstring.NewNetstringEmbedded([]*netstring.Netstring{netstring.NewNetstringFrom(3, []byte("1")),
+			netstring.NewNetstringFrom(3, []byte("abc"))})
+		conn.Write(resp.Serialized[:5])
+		conn.Write(resp.Serialized[5:])
+		WriteAll(conn, []byte("1:5,"))
+		conn.Close()
+	}()
+	reader := netstring.NewFrameReader(client)
+	for _, expected := range []string{"1", "abc", ""} {
+		ns, err := reader.ReadNext()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ns.IsBinary() || (string(ns.Payload) != expected) {
+			t.Errorf("expected binary frame with %s, got %v", expected, ns)
+		}
+	}
+}
+
+func TestClientConnCompression(t *testing.T) {
+	savedCfg := gAppConfig
+	gAppConfig = &Config{CompressionThreshold: 1024}
+	defer func() { gAppConfig = savedCfg }()
+
+	client, server := net.Pipe()
+	defer client.Close()
+	conn := &clientConn{Conn: server, codec: compression.Deflate}
+	large := netstring.NewNetstringFrom(common.RcValue, bytes.Repeat([]byte("row value "), 1000)).Serialized
+	go func() {
+		conn.Write([]byte("1:5,"))
+		conn.Write(large)
+		conn.Close()
+	}()
+	reader := netstring.NewNetstringReader(client)
+	ns, err := reader.ReadNext()
+	if (err != nil) || (ns.Cmd != common.RcOK) {
+		t.Fatalf("expected the small response uncompressed, got %v %v", ns, err)
+	}
+	ns, err = reader.ReadNext()
+	if (err != nil) || (ns.Cmd != common.RcCompressed) {
+		t.Fatalf("expected a compressed response, got %v %v", ns, err)
+	}
+	if len(ns.Payload) >= len(large)/10 {
+		t.Errorf("compressed %d bytes to %d", len(large), len(ns.Payload))
+	}
+	data, err := compression.Decompress(compression.Deflate, ns.Payload, int64(len(large)))
+	if (err != nil) || !bytes.Equal(data, large) {
+		t.Errorf("decompressed response differs: %v", err)
+	}
+}
+
+func TestReadBinaryNegotiated(t *testing.T) {
+	frame, _ := netstring.AppendBinary(nil, netstring.NewNetstringFrom(common.CmdPrepare, []byte("select 1 from dual")))
+	for _, negotiated := range []bool{false, true} {
+		client, server := net.Pipe()
+		go client.Write(frame)
+		ns := <-wrapNewNetstring(server, func() bool { return negotiated })
+		if negotiated && ((ns == nil) || !ns.IsBinary() || (ns.Cmd != common.CmdPrepare)) {
+			t.Errorf("binary frame not read after negotiation: %v", ns)
+		}
+		if !negotiated && (ns != nil) {
+			t.Errorf("binary frame read without negotiation: %v", ns)
+		}
+		client.Close()
+		server.Close()
+	}
+}
+
+// benchmarkClientRoundTrip reads the requests and writes the responses of a client connection, like the mux
+// forwarding between the client and the worker
+func benchmarkClientRoundTrip(b *testing.B, binary bool) {
+	request := netstring.NewNetstringEmbedded([]*netstring.Netstring{
+		netstring.NewNetstringFrom(common.CmdPrepare, []byte("select id, int_val, str_val from test where id = :id")),
+		netstring.NewNetstringFrom(common.CmdBindName, []byte("id")), netstring.NewNetstringFrom(common.CmdBindValue, []byte("1234567890")),
+		netstring.NewNetstringFrom(common.CmdExecute, nil), netstring.NewNetstringFrom(common.CmdFetch, []byte("0"))})
+	values := make([]*netstring.Netstring, 30)
+	for i := range values {
+		values[i] = netstring.NewNetstringFrom(common.RcValue, []byte("row value 1234567890"))
+	}
+	response := netstring.NewNetstringEmbedded(values)
+	requestData, responseData := request.Serialized, response.Serialized
+	if binary {
+		// the worker answers binary requests in binary frames
+		requestData, _ = netstring.AppendBinary(nil, request)
+		responseData, _ = netstring.AppendBinary(nil, response)
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+	conn := &clientConn{Conn: server}
+	if binary {
+		conn.binary = netstring.NewBinaryWriter(server)
+	}
+	go func() {
+		reader := netstring.NewFrameReader(client)
+		for i := 0; i < b.N; i++ {
+			client.Write(requestData)
+			for j := 0; j < len(values); j++ {
+				reader.ReadNext()
+			}
+		}
+	}()
+	for i := 0; i < b.N; i++ {
+		ns := <-wrapNewNetstring(server, func() bool { return binary })
+		if ns == nil {
+			b.Fatal("read failed")
+		}
+		conn.Write(responseData)
+	}
+}
+
+func BenchmarkClientRoundTripText(b *testing.B) {
+	benchmarkClientRoundTrip(b, false)
+}
+
+func BenchmarkClientRoundTripBinary(b *testing.B) {
+	benchmarkClientRoundTrip(b, true)
+}
@@ -0,0 +1,69 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lib
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/paypal/hera/utility/logger"
+)
+
+// the introspection endpoints are served from their own mux, so that enabling them
+// does not expose the pprof handlers registered on http.DefaultServeMux
+var (
+	introspectMux     = http.NewServeMux()
+	introspectMuxOnce sync.Once
+)
+
+// RegisterIntrospectHandler adds a diagnostic handler, served on "introspect_http_port". The handlers are
+// read-only, except the ones enabled by their own config (e.g. maint_admin_enabled)
+func RegisterIntrospectHandler(path string, handler http.HandlerFunc) {
+	introspectMux.HandleFunc(path, handler)
+}
+
+// writeIntrospectJSON is a helper for the introspection handlers, dumping obj as json
+func writeIntrospectJSON(w http.ResponseWriter, obj interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(obj)
+	if (err != nil) && logger.GetLogger().V(logger.Warning) {
+		logger.GetLogger().Log(logger.Warning, "introspect: failed to encode response", err.Error())
+	}
+}
+
+// introspectHost is the interface of the introspection endpoints. They have no authentication, and
+// /hera/maint can schedule maintenances, so they are only served to the local host
+const introspectHost = "127.0.0.1"
+
+// StartIntrospection starts the http server for the introspection endpoints if "introspect_http_port" is configured
+func StartIntrospection() {
+	if GetConfig().IntrospectHTTPPort == "" {
+		return
+	}
+	introspectMuxOnce.Do(func() {
+		go func() {
+			err := http.ListenAndServe(introspectHost+":"+GetConfig().IntrospectHTTPPort, introspectMux)
+			if (err != nil) && logger.GetLogger().V(logger.Alert) {
+				logger.GetLogger().Log(logger.Alert, "Cannot listen on introspect port", GetConfig().IntrospectHTTPPort, err.Error())
+			}
+		}()
+	})
+}
@@ -38,13 +38,23 @@ import (
 func Run() {
 	signal.Ignore(syscall.SIGPIPE)
 	mux_process_id := syscall.Getpid()
+	// mux rotates the CAL localfile shared with the workers
+	cal.SetLogFileOwner(true)
 
 	// Defer release resource in case of any abnormal exit of for application
 	defer handlePanicAndReleaseResource(mux_process_id)
 
 	namePtr := flag.String("name", "", "module name in v$session table")
+	checkConfigPtr := flag.Bool("check-config", false, "validate hera.txt, print the effective configuration and exit")
 	flag.Parse()
 
+	if *checkConfigPtr {
+		if CheckConfig(os.Stdout) {
+			os.Exit(0)
+		}
+		os.Exit(1)
+	}
+
 	/* Don't log.
 	We haven't configured log level, so lots goes to stdout/err log. */
 	if len(*namePtr) == 0 {
@@ -99,8 +109,18 @@ func Run() {
 	//
 	// create singleton broker and start worker/pools
 	//
-	nameForTns := *namePtr
-	CfgFromTns(nameForTns)
+	if len(GetConfig().TopologyFile) > 0 {
+		err = InitTopology()
+		if err != nil {
+			if logger.GetLogger().V(logger.Alert) {
+				logger.GetLogger().Log(logger.Alert, "failed to load the topology:", err.Error())
+			}
+			FullShutdown()
+		}
+	} else {
+		nameForTns := *namePtr
+		CfgFromTns(nameForTns)
+	}
 	if (GetWorkerBrokerInstance() == nil) || (GetWorkerBrokerInstance().RestartWorkerPool(*namePtr) != nil) {
 		if logger.GetLogger().V(logger.Alert) {
 			logger.GetLogger().Log(logger.Alert, "failed to start hera worker")
@@ -136,6 +156,8 @@ func Run() {
 		for {
 			time.Sleep(time.Millisecond * sleep)
 			CheckOpsConfigChange()
+			CheckTopologyChange()
+			CheckMaintFile()
 		}
 	}()
 
@@ -154,11 +176,16 @@ func Run() {
 
 	CheckEnableProfiling()
 	GoStats()
+	InitPinStats()
+	InitSlowQueryLog()
+	InitSQLStats()
+	StartIntrospection()
 
 	RegisterLoopDriver(HandleConnection)
-	if GetConfig().EnableQueryBindBlocker {
+	if GetReloadableConfig().EnableQueryBindBlocker {
 		InitQueryBindBlocker(*namePtr)
 	}
+	InitSQLAllowlist(*namePtr)
 
 	if logger.GetLogger().V(logger.Info) {
 		logger.GetLogger().Log(logger.Info, "Waiting for at least one database connection")
@@ -175,7 +202,7 @@ func Run() {
 		if pool.GetHealthyWorkersCount() > 0 {
 			break
 		} else {
-			if GetConfig().EnableTAF {
+			if GetReloadableConfig().EnableTAF {
 				fallbackPool, err := GetWorkerBrokerInstance().GetWorkerPool(wtypeStdBy, 0, 0)
 				if (err == nil) && (fallbackPool.GetHealthyWorkersCount() > 0) {
 					break
@@ -184,6 +211,7 @@ func Run() {
 		}
 		time.Sleep(time.Millisecond * 100)
 	}
+	upgradeWarmUp()
 	var lsn Listener
 	if GetConfig().KeyFile != "" {
 		lsn = NewTLSListener(fmt.Sprintf("0.0.0.0:%d", GetConfig().Port))
@@ -200,11 +228,15 @@ func Run() {
 			FullShutdown()
 		}
 	}
+	InitMaint()
 	InitRacMaint(*namePtr)
 
 	srv := NewServer(lsn, HandleConnection)
 
 	go srv.Run()
+	upgradeSignalReady()
+	InitUpgrade()
+	InitDrain()
 
 	//
 	// calling releasectxresource right before exit only serves as an example on how
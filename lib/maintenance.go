@@ -0,0 +1,387 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/paypal/hera/cal"
+	"github.com/paypal/hera/utility/logger"
+)
+
+// maintenance actions
+const (
+	// MaintRecycle restarts the workers gradually over the window, so they reconnect to the database
+	MaintRecycle = "recycle"
+	// MaintPause rejects the requests needing a worker during the window
+	MaintPause = "pause"
+)
+
+// sources of the maintenance requests
+const (
+	maintSourceTable    = "table"
+	maintSourceFile     = "file"
+	maintSourceAdmin    = "admin"
+	maintSourceTopology = "topology"
+	maintSourceFailover = "failover"
+)
+
+// maintReportInterval is how often the progress of the maintenances is checked and sent to CAL
+const maintReportInterval = 10 * time.Second
+
+// maintHistorySize is the number of finished maintenances kept for /hera/maint
+const maintHistorySize = 50
+
+// errors of the maintenance requests
+var (
+	ErrMaintAction = errors.New("maintenance action must be recycle or pause")
+	ErrMaintType   = errors.New("maintenance pool type must be rw, ro or stdby")
+	ErrMaintWindow = errors.New("maintenance pause needs a positive window_sec")
+	ErrMaintNoPool = errors.New("no pool matches the maintenance")
+)
+
+// MaintRequest is a maintenance of the worker pools, either a gradual recycle of the workers or a pause.
+// The empty target fields match all the pools
+type MaintRequest struct {
+	ID     string `json:"id" yaml:"id"`
+	Action string `json:"action" yaml:"action"`
+	// start of the maintenance, now if not set
+	Time time.Time `json:"time" yaml:"time"`
+	// the recycle is spread over the window, rac_restart_window if 0. The pause lasts for the window
+	WindowSec int `json:"window_sec" yaml:"window_sec"`
+	// target pools
+	Shard *int   `json:"shard,omitempty" yaml:"shard"`
+	Type  string `json:"type,omitempty" yaml:"type"`
+	Inst  *int   `json:"inst,omitempty" yaml:"inst"`
+	// recycle only the workers connected to this RAC instance, 0 for all
+	RacID  int    `json:"rac_id,omitempty" yaml:"rac_id"`
+	Source string `json:"source" yaml:"-"`
+	// recycle all the workers at Time, for the "F" status of the maint table
+	immediate bool
+}
+
+// maintStatus is the progress of a scheduled maintenance
+type maintStatus struct {
+	MaintRequest
+	Pools     []string `json:"pools"`
+	Total     int      `json:"total"`
+	Remaining int      `json:"remaining"`
+	// scheduled, running or done
+	Status string `json:"status"`
+
+	pools []*WorkerPool
+	// the workers marked for recycle, per pool
+	workers [][]*WorkerClient
+}
+
+type maintScheduler struct {
+	sync.Mutex
+	seq     int
+	active  []*maintStatus
+	history []*maintStatus
+	// the entries of maint_file already scheduled, only the ones still in the file are kept, and the
+	// modification time of the file
+	fileEntries map[string]bool
+	fileModTime time.Time
+}
+
+var gMaintScheduler = &maintScheduler{fileEntries: make(map[string]bool)}
+
+// validate checks the request and sets its defaults
+func (req *MaintRequest) validate() error {
+	if (req.Action != MaintRecycle) && (req.Action != MaintPause) {
+		return ErrMaintAction
+	}
+	if req.Type != "" {
+		found := false
+		for _, name := range workerTypeNames {
+			if req.Type == name {
+				found = true
+			}
+		}
+		if !found {
+			return ErrMaintType
+		}
+	}
+	if (req.WindowSec < 0) || ((req.Action == MaintPause) && (req.WindowSec == 0)) {
+		return ErrMaintWindow
+	}
+	if req.Time.IsZero() {
+		req.Time = time.Now()
+	}
+	return nil
+}
+
+// matches returns true if the pool is a target of the request
+func (req *MaintRequest) matches(pool *WorkerPool) bool {
+	return ((req.Shard == nil) || (*req.Shard == pool.ShardID)) &&
+		((req.Type == "") || (req.Type == workerTypeNames[pool.Type])) &&
+		((req.Inst == nil) || (*req.Inst == pool.InstID))
+}
+
+// maintPools returns the pools targeted by the request
+func maintPools(req *MaintRequest) []*WorkerPool {
+	var pools []*WorkerPool
+	for _, shardPools := range GetWorkerBrokerInstance().workerpools {
+		for t := 0; t < int(wtypeTotalCount); t++ {
+			for _, pool := range shardPools[HeraWorkerType(t)] {
+				if (pool != nil) && req.matches(pool) {
+					pools = append(pools, pool)
+				}
+			}
+		}
+	}
+	return pools
+}
+
+// ScheduleMaint starts a maintenance. The recycle marks the workers of the pools for restart, which
+// checkWorkerLifespan does over the window. The pause rejects the requests of the pools during the window.
+// The progress is on /hera/maint and in CAL
+func ScheduleMaint(req MaintRequest) error {
+	err := req.validate()
+	if err != nil {
+		return err
+	}
+	pools := maintPools(&req)
+	if len(pools) == 0 {
+		return ErrMaintNoPool
+	}
+	startMaint(req, pools)
+	return nil
+}
+
+// startMaint applies the validated request to the pools and records it for the progress reports
+func startMaint(req MaintRequest, pools []*WorkerPool) {
+	gMaintScheduler.Lock()
+	defer gMaintScheduler.Unlock()
+	gMaintScheduler.seq++
+	if req.ID == "" {
+		req.ID = fmt.Sprintf("%s-%d", req.Source, gMaintScheduler.seq)
+	}
+	st := &maintStatus{MaintRequest: req, Status: "scheduled", pools: pools}
+	for _, pool := range pools {
+		st.Pools = append(st.Pools, fmt.Sprintf("%s%d_%d", poolNamePrefix[pool.Type], pool.InstID, pool.ShardID))
+		switch req.Action {
+		case MaintRecycle:
+			marked := pool.RacMaint(racAct{instID: req.RacID, tm: int(req.Time.Unix()), delay: !req.immediate, window: req.WindowSec})
+			st.workers = append(st.workers, marked)
+			st.Total += len(marked)
+		case MaintPause:
+			pool.pause(req.Time.Unix(), req.Time.Unix()+int64(req.WindowSec))
+		}
+	}
+	st.update(time.Now().Unix())
+	gMaintScheduler.active = append(gMaintScheduler.active, st)
+
+	if logger.GetLogger().V(logger.Info) {
+		logger.GetLogger().Log(logger.Info, "maint: scheduled", req.ID, req.Action, "from", req.Source, "at", req.Time, "window", req.WindowSec, "pools", st.Pools, "workers", st.Total)
+	}
+	evt := cal.NewCalEvent(EvtTypeMux, "maint_"+req.Action, cal.TransOK, "")
+	evt.AddDataStr("id", req.ID)
+	evt.AddDataStr("source", req.Source)
+	evt.AddDataStr("pools", strings.Join(st.Pools, ","))
+	evt.AddDataInt("time", req.Time.Unix())
+	evt.AddDataInt("window", int64(req.WindowSec))
+	evt.AddDataInt("workers", int64(st.Total))
+	evt.Completed()
+}
+
+// update refreshes the progress of the maintenance
+func (st *maintStatus) update(now int64) {
+	start := st.Time.Unix()
+	switch st.Action {
+	case MaintRecycle:
+		st.Remaining = 0
+		for i, pool := range st.pools {
+			st.Remaining += pool.remainingWorkers(st.workers[i])
+		}
+		if st.Remaining == 0 {
+			st.Status = "done"
+		} else if now >= start {
+			st.Status = "running"
+		}
+	case MaintPause:
+		if now >= start+int64(st.WindowSec) {
+			st.Status = "done"
+		} else if now >= start {
+			st.Status = "running"
+		}
+	}
+}
+
+// report sends the progress of the active maintenances to CAL, the finished ones go to the history
+func (sched *maintScheduler) report() {
+	sched.Lock()
+	defer sched.Unlock()
+	now := time.Now().Unix()
+	active := sched.active[:0]
+	for _, st := range sched.active {
+		status, remaining := st.Status, st.Remaining
+		st.update(now)
+		if (st.Status != status) || (st.Remaining != remaining) {
+			if logger.GetLogger().V(logger.Info) {
+				logger.GetLogger().Log(logger.Info, "maint:", st.ID, st.Status, st.Total-st.Remaining, "/", st.Total, "workers recycled")
+			}
+			evt := cal.NewCalEvent(EvtTypeMux, "maint_"+st.Status, cal.TransOK, "")
+			evt.AddDataStr("id", st.ID)
+			evt.AddDataInt("done", int64(st.Total-st.Remaining))
+			evt.AddDataInt("total", int64(st.Total))
+			evt.Completed()
+			if (st.Source == maintSourceFailover) && (st.Status != status) {
+				// failover_running then failover_done, after failover_detected
+				evt = cal.NewCalEvent(EvtTypeMux, "failover_"+st.Status, cal.TransOK, "")
+				evt.AddDataStr("id", st.ID)
+				evt.AddDataStr("pools", strings.Join(st.Pools, ","))
+				evt.Completed()
+			}
+		}
+		if st.Status == "done" {
+			// the workers are replaced, the pools don't need to be kept
+			st.pools, st.workers = nil, nil
+			sched.history = append(sched.history, st)
+		} else {
+			active = append(active, st)
+		}
+	}
+	sched.active = active
+	if len(sched.history) > maintHistorySize {
+		sched.history = sched.history[len(sched.history)-maintHistorySize:]
+	}
+}
+
+// loadMaintFile reads the list of maintenances of maint_file, in JSON if its extension is .json,
+// otherwise in YAML
+func loadMaintFile(filename string) ([]MaintRequest, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var reqs []MaintRequest
+	if filepath.Ext(filename) == ".json" {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&reqs)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&reqs)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err.Error())
+	}
+	return reqs, nil
+}
+
+// CheckMaintFile schedules the new entries of maint_file when the file changes. Each entry is scheduled once,
+// editing an entry, or removing it then adding it back, schedules it again
+func CheckMaintFile() {
+	filename := GetConfig().MaintFile
+	if filename == "" {
+		return
+	}
+	stat, err := os.Stat(filename)
+	if (err != nil) || stat.ModTime().Equal(gMaintScheduler.fileModTime) {
+		return
+	}
+	gMaintScheduler.fileModTime = stat.ModTime()
+	reqs, err := loadMaintFile(filename)
+	if err != nil {
+		maintRejected(maintSourceFile, err)
+		return
+	}
+	entries := make(map[string]bool, len(reqs))
+	for _, req := range reqs {
+		key, _ := json.Marshal(req)
+		scheduled := gMaintScheduler.fileEntries[string(key)] || entries[string(key)]
+		entries[string(key)] = true
+		if scheduled {
+			continue
+		}
+		req.Source = maintSourceFile
+		err = ScheduleMaint(req)
+		if err != nil {
+			maintRejected(maintSourceFile, fmt.Errorf("%s %s: %s", filename, req.ID, err.Error()))
+		}
+	}
+	gMaintScheduler.fileEntries = entries
+}
+
+func maintRejected(source string, err error) {
+	if logger.GetLogger().V(logger.Warning) {
+		logger.GetLogger().Log(logger.Warning, "maint: rejecting request from", source, err.Error())
+	}
+	evt := cal.NewCalEvent(EvtTypeMux, "maint_rejected", cal.TransWarning, err.Error())
+	evt.AddDataStr("source", source)
+	evt.Completed()
+}
+
+// handleMaint lists the maintenances on GET. If maint_admin_enabled is set, a POST of a MaintRequest in JSON
+// schedules it
+func handleMaint(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		if !GetConfig().MaintAdminEnabled {
+			http.Error(w, "maint_admin_enabled is false", http.StatusForbidden)
+			return
+		}
+		var req MaintRequest
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		err := dec.Decode(&req)
+		if err == nil {
+			req.Source = maintSourceAdmin
+			err = ScheduleMaint(req)
+		}
+		if err != nil {
+			maintRejected(maintSourceAdmin, err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	gMaintScheduler.Lock()
+	defer gMaintScheduler.Unlock()
+	now := time.Now().Unix()
+	for _, st := range gMaintScheduler.active {
+		st.update(now)
+	}
+	writeIntrospectJSON(w, map[string]interface{}{
+		"active":  gMaintScheduler.active,
+		"history": gMaintScheduler.history,
+	})
+}
+
+// InitMaint starts reporting the progress of the maintenances and registers /hera/maint
+func InitMaint() {
+	RegisterIntrospectHandler("/hera/maint", handleMaint)
+	go func() {
+		for {
+			time.Sleep(maintReportInterval)
+			gMaintScheduler.report()
+		}
+	}()
+}
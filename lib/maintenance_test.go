@@ -0,0 +1,175 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lib
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMaintRequestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		req      MaintRequest
+		expected error
+	}{
+		{MaintRequest{Action: MaintRecycle}, nil},
+		{MaintRequest{Action: MaintRecycle, Type: "ro", WindowSec: 60}, nil},
+		{MaintRequest{Action: MaintPause, WindowSec: 30}, nil},
+		{MaintRequest{Action: "restart"}, ErrMaintAction},
+		{MaintRequest{Action: MaintRecycle, Type: "read"}, ErrMaintType},
+		{MaintRequest{Action: MaintRecycle, WindowSec: -1}, ErrMaintWindow},
+		{MaintRequest{Action: MaintPause}, ErrMaintWindow},
+	} {
+		if err := tc.req.validate(); err != tc.expected {
+			t.Errorf("%+v: error %v, expected %v", tc.req, err, tc.expected)
+		}
+		if (tc.expected == nil) && tc.req.Time.IsZero() {
+			t.Errorf("%+v: time not set", tc.req)
+		}
+	}
+
+	shard := 1
+	req := MaintRequest{Shard: &shard, Type: "rw"}
+	if !req.matches(&WorkerPool{Type: wtypeRW, ShardID: 1}) || req.matches(&WorkerPool{Type: wtypeRO, ShardID: 1}) ||
+		req.matches(&WorkerPool{Type: wtypeRW, ShardID: 0}) {
+		t.Error("wrong pools matched")
+	}
+}
+
+func TestMaintRecycleProgress(t *testing.T) {
+	savedCfg := gAppConfig
+	defer func() { gAppConfig = savedCfg }()
+	gAppConfig = &Config{RacRestartWindow: 10}
+
+	pool := &WorkerPool{poolCond: sync.NewCond(&sync.Mutex{}), currentSize: 3}
+	pool.workers = make([]*WorkerClient, pool.currentSize)
+	for i := range pool.workers {
+		pool.workers[i] = &WorkerClient{ID: i, startTime: time.Now().Unix() - 100}
+	}
+	now := time.Now()
+	marked := pool.RacMaint(racAct{tm: int(now.Unix()), delay: true, window: 30})
+	if (len(marked) != 3) || (pool.workers[2].exitTime != now.Unix()+20) {
+		t.Fatalf("%d workers marked, exit time %d", len(marked), pool.workers[2].exitTime-now.Unix())
+	}
+
+	st := &maintStatus{MaintRequest: MaintRequest{Action: MaintRecycle, Time: now}, Total: 3,
+		pools: []*WorkerPool{pool}, workers: [][]*WorkerClient{marked}}
+	st.update(now.Unix())
+	if (st.Status != "running") || (st.Remaining != 3) {
+		t.Errorf("status %s, %d remaining", st.Status, st.Remaining)
+	}
+	// the restarted workers are replaced in the pool
+	for i := range pool.workers {
+		pool.workers[i] = &WorkerClient{ID: i, startTime: time.Now().Unix()}
+		st.update(now.Unix())
+		if st.Remaining != 2-i {
+			t.Errorf("%d remaining after %d restarts", st.Remaining, i+1)
+		}
+	}
+	if st.Status != "done" {
+		t.Errorf("status %s after the restarts", st.Status)
+	}
+}
+
+func TestMaintPause(t *testing.T) {
+	pool := &WorkerPool{}
+	if pool.paused() {
+		t.Error("new pool paused")
+	}
+	now := time.Now().Unix()
+	pool.pause(now+60, now+120)
+	if pool.paused() {
+		t.Error("paused before the start")
+	}
+	pool.pause(now-1, now+60)
+	if !pool.paused() {
+		t.Error("not paused")
+	}
+	// a new pause doesn't end the current one
+	pool.pause(now-60, now-1)
+	if !pool.paused() {
+		t.Error("pause replaced by an ended one")
+	}
+	pool = &WorkerPool{}
+	pool.pause(now-60, now-1)
+	if pool.paused() {
+		t.Error("paused after the end")
+	}
+	pool.pause(now+60, now+120)
+	if windows := pool.pauses.Load().([]pauseWindow); len(windows) != 1 {
+		t.Errorf("ended pause kept in %v", windows)
+	}
+
+	st := &maintStatus{MaintRequest: MaintRequest{Action: MaintPause, Time: time.Unix(now, 0), WindowSec: 60}, Status: "scheduled"}
+	for _, tc := range []struct {
+		now      int64
+		expected string
+	}{{now - 1, "scheduled"}, {now, "running"}, {now + 60, "done"}} {
+		st.update(tc.now)
+		if st.Status != tc.expected {
+			t.Errorf("status %s at %d, expected %s", st.Status, tc.now-now, tc.expected)
+		}
+	}
+}
+
+func TestLoadMaintFile(t *testing.T) {
+	reqs, err := loadMaintFile(writeTopology(t, "maint.yaml", `
+- id: failover
+  action: recycle
+  shard: 0
+  type: rw
+  window_sec: 60
+- action: pause
+  time: 2024-06-01T03:00:00Z
+  window_sec: 30
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (len(reqs) != 2) || (*reqs[0].Shard != 0) || (reqs[0].Inst != nil) || (reqs[1].Time.Hour() != 3) {
+		t.Errorf("unexpected requests %+v", reqs)
+	}
+	if _, err = loadMaintFile(writeTopology(t, "maint.json", `[{"action": "pause", "duration": 30}]`)); err == nil {
+		t.Error("unknown field accepted")
+	}
+}
+
+func TestCheckMaintFileEntries(t *testing.T) {
+	savedCfg, savedEntries, savedModTime := gAppConfig, gMaintScheduler.fileEntries, gMaintScheduler.fileModTime
+	defer func() {
+		gAppConfig, gMaintScheduler.fileEntries, gMaintScheduler.fileModTime = savedCfg, savedEntries, savedModTime
+	}()
+	// invalid actions, the entries are recorded without scheduling a maintenance
+	filename := writeTopology(t, "maint.yaml", "- id: a\n  action: stop\n- id: b\n  action: stop\n")
+	gAppConfig = &Config{MaintFile: filename}
+	gMaintScheduler.fileEntries = make(map[string]bool)
+	CheckMaintFile()
+	if len(gMaintScheduler.fileEntries) != 2 {
+		t.Fatalf("entries %v", gMaintScheduler.fileEntries)
+	}
+	// the removed entries are forgotten
+	os.WriteFile(filename, []byte("- id: b\n  action: stop\n"), 0644)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(filename, future, future)
+	CheckMaintFile()
+	if len(gMaintScheduler.fileEntries) != 1 {
+		t.Errorf("entries %v after removing one", gMaintScheduler.fileEntries)
+	}
+}
@@ -0,0 +1,272 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lib
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/paypal/hera/cal"
+	"github.com/paypal/hera/utility/logger"
+)
+
+// maxPinStatsKeys bounds the number of (client host, pool) pairs tracked in one interval
+const maxPinStatsKeys = 10000
+
+// connPinStats is the per coordinator accounting of a worker pinned across client requests,
+// i.e. while in transaction or with an open cursor
+type connPinStats struct {
+	// when the worker was attached, zero if no worker is pinned
+	start time.Time
+	// when the last reply was sent to the client, the worker waits for the client since then
+	lastReply time.Time
+	// when the current request was dispatched
+	dispatched time.Time
+	// time the worker spent waiting for the client while in transaction
+	idle  time.Duration
+	stmts int
+}
+
+// PinStatsEntry aggregates the pinning stats of the connections from the same client host and pool
+type PinStatsEntry struct {
+	Host        string `json:"host"`
+	Pool        string `json:"pool"`
+	Count       int64  `json:"count"`
+	PinnedMs    int64  `json:"pinned_ms"`
+	MaxPinnedMs int64  `json:"max_pinned_ms"`
+	IdleMs      int64  `json:"idle_in_txn_ms"`
+	MaxIdleMs   int64  `json:"max_idle_in_txn_ms"`
+	Stmts       int64  `json:"stmts"`
+	MaxStmts    int64  `json:"max_stmts"`
+}
+
+// PinnedConn describes a connection currently holding a worker
+type PinnedConn struct {
+	ID            string `json:"id"`
+	Host          string `json:"host"`
+	Pool          string `json:"pool"`
+	PinnedMs      int64  `json:"pinned_ms"`
+	IdleMs        int64  `json:"idle_ms"`
+	Stmts         int    `json:"stmts"`
+	InTransaction bool   `json:"in_transaction"`
+}
+
+type pinnedConnState struct {
+	id, host, pool string
+	start          time.Time
+	lastReply      time.Time
+	stmts          int
+	inTransaction  bool
+}
+
+type pinStats struct {
+	sync.Mutex
+	entries map[string]*PinStatsEntry
+	dropped int64
+	active  map[*Coordinator]*pinnedConnState
+	// top N from the last completed interval
+	last []PinStatsEntry
+}
+
+var gPinStats = &pinStats{entries: make(map[string]*PinStatsEntry), active: make(map[*Coordinator]*pinnedConnState)}
+
+// add records a pinning which just ended
+func (ps *pinStats) add(host string, pool string, pinned time.Duration, idle time.Duration, stmts int) {
+	key := host + "|" + pool
+	pinnedMs := int64(pinned / time.Millisecond)
+	idleMs := int64(idle / time.Millisecond)
+	ps.Lock()
+	defer ps.Unlock()
+	entry, ok := ps.entries[key]
+	if !ok {
+		if len(ps.entries) >= maxPinStatsKeys {
+			ps.dropped++
+			return
+		}
+		entry = &PinStatsEntry{Host: host, Pool: pool}
+		ps.entries[key] = entry
+	}
+	entry.Count++
+	entry.PinnedMs += pinnedMs
+	if pinnedMs > entry.MaxPinnedMs {
+		entry.MaxPinnedMs = pinnedMs
+	}
+	entry.IdleMs += idleMs
+	if idleMs > entry.MaxIdleMs {
+		entry.MaxIdleMs = idleMs
+	}
+	entry.Stmts += int64(stmts)
+	if int64(stmts) > entry.MaxStmts {
+		entry.MaxStmts = int64(stmts)
+	}
+}
+
+// rotate ends the current interval, returning its worst offenders by total pinned time
+func (ps *pinStats) rotate(topN int) ([]PinStatsEntry, int64) {
+	ps.Lock()
+	entries := ps.entries
+	dropped := ps.dropped
+	ps.entries = make(map[string]*PinStatsEntry)
+	ps.dropped = 0
+	ps.Unlock()
+
+	top := make([]PinStatsEntry, 0, len(entries))
+	for _, entry := range entries {
+		top = append(top, *entry)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].PinnedMs != top[j].PinnedMs {
+			return top[i].PinnedMs > top[j].PinnedMs
+		}
+		return top[i].IdleMs > top[j].IdleMs
+	})
+	if len(top) > topN {
+		top = top[:topN]
+	}
+
+	ps.Lock()
+	ps.last = top
+	ps.Unlock()
+	return top, dropped
+}
+
+// pinned returns the connections currently holding a worker, longest pinned first
+func (ps *pinStats) pinned(topN int) []PinnedConn {
+	now := time.Now()
+	ps.Lock()
+	out := make([]PinnedConn, 0, len(ps.active))
+	for _, st := range ps.active {
+		out = append(out, PinnedConn{ID: st.id, Host: st.host, Pool: st.pool,
+			PinnedMs:      int64(now.Sub(st.start) / time.Millisecond),
+			IdleMs:        int64(now.Sub(st.lastReply) / time.Millisecond),
+			Stmts:         st.stmts,
+			InTransaction: st.inTransaction})
+	}
+	ps.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].PinnedMs > out[j].PinnedMs })
+	if len(out) > topN {
+		out = out[:topN]
+	}
+	return out
+}
+
+// pinRequestStart is called before dispatching a client request
+func (crd *Coordinator) pinRequestStart() {
+	crd.pin.dispatched = time.Now()
+	if crd.pin.start.IsZero() {
+		return
+	}
+	crd.pin.stmts++
+	if crd.inTransaction {
+		crd.pin.idle += time.Since(crd.pin.lastReply)
+	}
+}
+
+// pinRequestDone is called after dispatching a client request, if the worker stays attached
+// it starts (or continues) to account the pinning
+func (crd *Coordinator) pinRequestDone() {
+	if !GetConfig().EnablePinStats || (crd.worker == nil) {
+		return
+	}
+	now := time.Now()
+	if crd.pin.start.IsZero() {
+		crd.pin.start = now
+		crd.pin.stmts = 1
+	}
+	// the worker waits for the client since its last response, or since now if the mux answered
+	crd.pin.lastReply = now
+	if crd.timing.lastResp.After(crd.pin.dispatched) {
+		crd.pin.lastReply = crd.timing.lastResp
+	}
+
+	gPinStats.Lock()
+	st, ok := gPinStats.active[crd]
+	if !ok {
+		st = &pinnedConnState{id: crd.id, host: crd.clientHostName, pool: crd.poolName, start: crd.pin.start}
+		gPinStats.active[crd] = st
+	}
+	st.lastReply = crd.pin.lastReply
+	st.stmts = crd.pin.stmts
+	st.inTransaction = crd.inTransaction
+	gPinStats.Unlock()
+}
+
+// pinRelease is called when the worker is detached from the coordinator
+func (crd *Coordinator) pinRelease() {
+	if crd.pin.start.IsZero() {
+		return
+	}
+	gPinStats.Lock()
+	delete(gPinStats.active, crd)
+	gPinStats.Unlock()
+	gPinStats.add(crd.clientHostName, crd.poolName, time.Since(crd.pin.start), crd.pin.idle, crd.pin.stmts)
+	crd.pin = connPinStats{}
+}
+
+func reportPinStats() {
+	top, dropped := gPinStats.rotate(GetConfig().PinStatsTopN)
+	for _, entry := range top {
+		evt := cal.NewCalEvent(EvtTypeMux, "pin_stats", cal.TransOK, "")
+		evt.AddDataStr("host", entry.Host)
+		evt.AddDataStr("pool", entry.Pool)
+		evt.AddDataInt("cnt", entry.Count)
+		evt.AddDataInt("pinned_ms", entry.PinnedMs)
+		evt.AddDataInt("max_pinned_ms", entry.MaxPinnedMs)
+		evt.AddDataInt("idle_in_txn_ms", entry.IdleMs)
+		evt.AddDataInt("max_idle_in_txn_ms", entry.MaxIdleMs)
+		evt.AddDataInt("stmts", entry.Stmts)
+		evt.AddDataInt("max_stmts", entry.MaxStmts)
+		evt.Completed()
+	}
+	if dropped > 0 {
+		evt := cal.NewCalEvent(cal.EventTypeWarning, "pin_stats_dropped", cal.TransOK, "")
+		evt.AddDataInt("cnt", dropped)
+		evt.Completed()
+	}
+}
+
+func handlePinStats(w http.ResponseWriter, r *http.Request) {
+	gPinStats.Lock()
+	last := gPinStats.last
+	gPinStats.Unlock()
+	writeIntrospectJSON(w, map[string]interface{}{
+		"interval_sec": GetConfig().PinStatsInterval,
+		"top":          last,
+		"pinned":       gPinStats.pinned(GetConfig().PinStatsTopN),
+	})
+}
+
+// InitPinStats starts the periodic pinning report, sent to CAL as "pin_stats" events. The
+// last report and the connections currently pinned are available on /hera/pinstats
+func InitPinStats() {
+	if !GetConfig().EnablePinStats {
+		return
+	}
+	RegisterIntrospectHandler("/hera/pinstats", handlePinStats)
+	go func() {
+		for {
+			time.Sleep(time.Duration(GetConfig().PinStatsInterval) * time.Second)
+			reportPinStats()
+			if logger.GetLogger().V(logger.Verbose) {
+				logger.GetLogger().Log(logger.Verbose, "pin stats reported")
+			}
+		}
+	}()
+}
@@ -0,0 +1,85 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lib
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPinStatsTopN(t *testing.T) {
+	ps := &pinStats{entries: make(map[string]*PinStatsEntry), active: make(map[*Coordinator]*pinnedConnState)}
+	ps.add("hostA", "poolA", 100*time.Millisecond, 50*time.Millisecond, 3)
+	ps.add("hostA", "poolA", 300*time.Millisecond, 10*time.Millisecond, 5)
+	ps.add("hostB", "poolA", 500*time.Millisecond, 400*time.Millisecond, 2)
+	ps.add("hostC", "poolB", 10*time.Millisecond, 0, 1)
+
+	top, dropped := ps.rotate(2)
+	if dropped != 0 {
+		t.Errorf("expected no drop, got %d", dropped)
+	}
+	if len(top) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(top))
+	}
+	if top[0].Host != "hostB" || top[1].Host != "hostA" {
+		t.Errorf("wrong order %+v", top)
+	}
+	a := top[1]
+	if a.Count != 2 || a.PinnedMs != 400 || a.MaxPinnedMs != 300 || a.IdleMs != 60 || a.MaxIdleMs != 50 || a.Stmts != 8 || a.MaxStmts != 5 {
+		t.Errorf("wrong aggregation %+v", a)
+	}
+	if len(ps.entries) != 0 {
+		t.Error("entries not reset after rotate")
+	}
+}
+
+func TestPinStatsMaxKeys(t *testing.T) {
+	ps := &pinStats{entries: make(map[string]*PinStatsEntry), active: make(map[*Coordinator]*pinnedConnState)}
+	for i := 0; i < maxPinStatsKeys+5; i++ {
+		ps.add(fmt.Sprintf("host%d", i), "pool", time.Millisecond, 0, 1)
+	}
+	_, dropped := ps.rotate(10)
+	if dropped != 5 {
+		t.Errorf("expected 5 dropped, got %d", dropped)
+	}
+}
+
+func TestPinRequestDoneLastReply(t *testing.T) {
+	savedCfg := gAppConfig
+	defer func() { gAppConfig = savedCfg }()
+	gAppConfig = &Config{EnablePinStats: true}
+
+	crd := &Coordinator{worker: &WorkerClient{}, inTransaction: true}
+	defer crd.pinRelease()
+	crd.pinRequestStart()
+	replied := time.Now()
+	crd.timing.lastResp = replied
+	time.Sleep(10 * time.Millisecond)
+	crd.pinRequestDone()
+	if !crd.pin.lastReply.Equal(replied) {
+		t.Errorf("last reply %v, expected the response time %v", crd.pin.lastReply, replied)
+	}
+
+	// a request answered by the mux, the response time is the one of the previous request
+	crd.pinRequestStart()
+	crd.pinRequestDone()
+	if !crd.pin.lastReply.After(replied) {
+		t.Error("last reply not updated")
+	}
+}
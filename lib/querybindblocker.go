@@ -23,102 +23,327 @@ import (
 	"fmt"
 	"math/rand"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/paypal/hera/utility"
 	"github.com/paypal/hera/utility/logger"
+	"github.com/paypal/hera/utility/slowlog"
+)
+
+// special Bindvarvalue and Herasqltext formats
+const (
+	qbbAllValues   = "BLOCKALLVALUES"
+	qbbRegexPrefix = "re:"    // "re:<regex>" matches the bind value, or the normalized sql for Herasqltext
+	qbbRangePrefix = "range:" // "range:<low>..<high>" numeric inclusive range, either end can be empty
 )
 
 type QueryBindBlockerEntry struct {
-	Herasqlhash  uint32
-	Herasqltext  string // prefix since some sql is too long
+	Herasqlhash  uint32 // when 0 the entry applies to all the sqls matching Herasqltext
+	Herasqltext  string // prefix since some sql is too long, or "re:<regex>" over the normalized sql
 	Bindvarname  string // prefix for in clause
-	Bindvarvalue string // when set to "BLOCKALLVALUES" should block all sqltext queries
+	Bindvarvalue string // when set to "BLOCKALLVALUES" should block all sqltext queries, also "re:" and "range:" formats
 	Blockperc    int
 	Heramodule   string
+	Endtime      int64  // expiry in epoch seconds, no expiry if 0
+	Remarks      string // the reason, reported in the error to the client
+
+	sqlRegex   *regexp.Regexp
+	valueRegex *regexp.Regexp
+	valueRange *qbbRange
+}
+
+type qbbRange struct {
+	low, high           string
+	hasLow, hasHigh     bool
+	lowNum, highNum     float64
+	lowInt, highInt     int64
+	lowIsInt, highIsInt bool
 }
 
 type QueryBindBlockerCfg struct {
 	// lookup by sqlhash
 	// then by bind name, then by bind value
 	BySqlHash map[uint32]map[string]map[string][]QueryBindBlockerEntry
-	// check by sqltext prefix (delay to end)
+	// lookup by sqlhash then by bind name, for the regex and range bind values
+	ValuePatternsBySqlHash map[uint32]map[string][]QueryBindBlockerEntry
+	// entries without sqlhash, checked by sqltext prefix or regex after the lookup by sqlhash
+	SqlPatterns []QueryBindBlockerEntry
 }
 
 var lastLoggingTime time.Time
 var defaultQBBTableMissingErrorLoggingInterval = 2 * time.Hour
+var qbbNumSuffixRegex = regexp.MustCompile("[_0-9]*$")
+
+func newQueryBindBlockerCfg() *QueryBindBlockerCfg {
+	return &QueryBindBlockerCfg{BySqlHash: make(map[uint32]map[string]map[string][]QueryBindBlockerEntry),
+		ValuePatternsBySqlHash: make(map[uint32]map[string][]QueryBindBlockerEntry)}
+}
 
-func (cfg *QueryBindBlockerCfg) IsBlocked(sqltext string, bindPairs []string) (bool, string) {
+func parseQbbRange(val string) (*qbbRange, error) {
+	bounds := strings.SplitN(val, "..", 2)
+	if len(bounds) != 2 {
+		return nil, fmt.Errorf("range must be <low>..<high>: %s", val)
+	}
+	rng := &qbbRange{low: strings.TrimSpace(bounds[0]), high: strings.TrimSpace(bounds[1])}
+	var err error
+	if len(rng.low) > 0 {
+		rng.hasLow = true
+		rng.lowInt, err = strconv.ParseInt(rng.low, 10, 64)
+		rng.lowIsInt = (err == nil)
+		rng.lowNum, err = strconv.ParseFloat(rng.low, 64)
+		if err != nil {
+			return nil, fmt.Errorf("range low is not a number: %s", val)
+		}
+	}
+	if len(rng.high) > 0 {
+		rng.hasHigh = true
+		rng.highInt, err = strconv.ParseInt(rng.high, 10, 64)
+		rng.highIsInt = (err == nil)
+		rng.highNum, err = strconv.ParseFloat(rng.high, 64)
+		if err != nil {
+			return nil, fmt.Errorf("range high is not a number: %s", val)
+		}
+	}
+	return rng, nil
+}
+
+// contains compares as integers when possible, so that large ids are compared exactly
+func (rng *qbbRange) contains(val string) bool {
+	valInt, err := strconv.ParseInt(val, 10, 64)
+	valIsInt := (err == nil)
+	valNum, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return false
+	}
+	if rng.hasLow {
+		if valIsInt && rng.lowIsInt {
+			if valInt < rng.lowInt {
+				return false
+			}
+		} else if valNum < rng.lowNum {
+			return false
+		}
+	}
+	if rng.hasHigh {
+		if valIsInt && rng.highIsInt {
+			if valInt > rng.highInt {
+				return false
+			}
+		} else if valNum > rng.highNum {
+			return false
+		}
+	}
+	return true
+}
+
+// compile parses the regex and range formats of the entry
+func (entry *QueryBindBlockerEntry) compile() error {
+	var err error
+	if strings.HasPrefix(entry.Herasqltext, qbbRegexPrefix) {
+		entry.sqlRegex, err = regexp.Compile(entry.Herasqltext[len(qbbRegexPrefix):])
+		if err != nil {
+			return err
+		}
+	}
+	if strings.HasPrefix(entry.Bindvarvalue, qbbRegexPrefix) {
+		entry.valueRegex, err = regexp.Compile(entry.Bindvarvalue[len(qbbRegexPrefix):])
+		if err != nil {
+			return err
+		}
+	} else if strings.HasPrefix(entry.Bindvarvalue, qbbRangePrefix) {
+		entry.valueRange, err = parseQbbRange(entry.Bindvarvalue[len(qbbRangePrefix):])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (entry *QueryBindBlockerEntry) isValuePattern() bool {
+	return (entry.valueRegex != nil) || (entry.valueRange != nil)
+}
+
+func (entry *QueryBindBlockerEntry) expired(now time.Time) bool {
+	return (entry.Endtime > 0) && (now.Unix() > entry.Endtime)
+}
+
+func (entry *QueryBindBlockerEntry) matchSQL(sqltext string, normalized func() string) bool {
+	if entry.sqlRegex != nil {
+		return entry.sqlRegex.MatchString(normalized())
+	}
+	return strings.HasPrefix(sqltext, entry.Herasqltext)
+}
+
+func (entry *QueryBindBlockerEntry) matchValue(val string) bool {
+	switch {
+	case entry.valueRegex != nil:
+		return entry.valueRegex.MatchString(val)
+	case entry.valueRange != nil:
+		return entry.valueRange.contains(val)
+	case entry.Bindvarvalue == qbbAllValues:
+		return true
+	}
+	return entry.Bindvarvalue == val
+}
+
+// matchName matches the bind name, also without its numeric suffix
+func (entry *QueryBindBlockerEntry) matchName(name string) bool {
+	return (entry.Bindvarname == name) || (entry.Bindvarname == qbbNumSuffixRegex.ReplaceAllString(name, ""))
+}
+
+// roll decides if the matching request is blocked, based on the block percentage
+func (entry *QueryBindBlockerEntry) roll(sqlhash uint32, name string, val string) bool {
+	dice := rand.Intn(100)
+	rv := true
+	rvStr := "blockRv=true"
+	var logLevel int32
+	logLevel = logger.Warning
+	if dice > entry.Blockperc-1 {
+		// got lucky, don't block
+		rv = false
+		rvStr = "blockRv=false"
+		logLevel = logger.Debug
+	}
+	if logger.GetLogger().V(logLevel) {
+		logger.GetLogger().Log(logLevel, fmt.Sprintf("query bind blocker on %d %s %s %d dice:%d %s", sqlhash, name, val, entry.Blockperc, dice, rvStr))
+	}
+	return rv
+}
+
+// IsBlocked returns if the request is blocked, with the bind value matched and the remarks of the entry
+func (cfg *QueryBindBlockerCfg) IsBlocked(sqltext string, bindPairs []string) (bool, string, string) {
 	sqlhash := uint32(utility.GetSQLHash(sqltext))
 	if logger.GetLogger().V(logger.Verbose) {
 		logger.GetLogger().Log(logger.Verbose, fmt.Sprintf("query bind blocker sqlhash and text %d %s", sqlhash, sqltext))
 	}
-	byBindName, ok := cfg.BySqlHash[sqlhash]
-	if !ok {
-		return false, ""
-	}
-	for i := range bindPairs {
-		if i%2 == 1 {
-			continue
-		}
+	now := time.Now()
+	for i := 0; i+1 < len(bindPairs); i += 2 {
 		if strings.HasPrefix(bindPairs[i], ":") {
 			bindPairs[i] = bindPairs[i][1:]
 		}
-		if logger.GetLogger().V(logger.Verbose) {
-			logger.GetLogger().Log(logger.Verbose, fmt.Sprintf("query bind blocker bind name and value %d %s %s", i, bindPairs[i], bindPairs[i+1]))
+	}
+	var normalizedSQL string
+	normalized := func() string {
+		if len(normalizedSQL) == 0 {
+			normalizedSQL = slowlog.NormalizeSQL(sqltext)
 		}
-		byBindValue, ok := byBindName[bindPairs[i]]
-		if !ok {
-			// strip numeric suffix to try to match
-			withoutNumSuffix := regexp.MustCompile("[_0-9]*$").ReplaceAllString(bindPairs[i], "")
-			byBindValue, ok = byBindName[withoutNumSuffix]
+		return normalizedSQL
+	}
+	byBindName, ok := cfg.BySqlHash[sqlhash]
+	if ok {
+		for i := 0; i+1 < len(bindPairs); i += 2 {
+			if logger.GetLogger().V(logger.Verbose) {
+				logger.GetLogger().Log(logger.Verbose, fmt.Sprintf("query bind blocker bind name and value %d %s %s", i, bindPairs[i], bindPairs[i+1]))
+			}
+			byBindValue, ok := byBindName[bindPairs[i]]
 			if !ok {
-				continue
+				// strip numeric suffix to try to match
+				withoutNumSuffix := qbbNumSuffixRegex.ReplaceAllString(bindPairs[i], "")
+				byBindValue, ok = byBindName[withoutNumSuffix]
+				if !ok {
+					continue
+				}
 			}
-		}
 
-		val := bindPairs[i+1]
-		list, ok := byBindValue[val]
-		if !ok {
-			val = "BLOCKALLVALUES"
-			list, ok = byBindValue[val]
+			val := bindPairs[i+1]
+			list, ok := byBindValue[val]
 			if !ok {
-				continue
+				val = qbbAllValues
+				list, ok = byBindValue[val]
+				if !ok {
+					continue
+				}
 			}
-		}
 
-		// found
-		for _, entry := range list {
-			if logger.GetLogger().V(logger.Verbose) {
-				logger.GetLogger().Log(logger.Verbose, fmt.Sprintf("query bind blocker checking prefix %s", entry.Herasqltext))
+			// found
+			for _, entry := range list {
+				if logger.GetLogger().V(logger.Verbose) {
+					logger.GetLogger().Log(logger.Verbose, fmt.Sprintf("query bind blocker checking prefix %s", entry.Herasqltext))
+				}
+				if entry.expired(now) || !entry.matchSQL(sqltext, normalized) {
+					continue
+				}
+				return entry.roll(sqlhash, bindPairs[i], val), val, entry.Remarks
 			}
-			if !strings.HasPrefix(sqltext, entry.Herasqltext) {
-				continue
-			}
-			dice := rand.Intn(100)
-			rv := true
-			rvStr := "blockRv=true"
-			var logLevel int32
-			logLevel = logger.Warning
-			if dice > entry.Blockperc-1 {
-				// got lucky, don't block
-				rv = false
-				rvStr = "blockRv=false"
-				logLevel = logger.Debug
+		} // end for each bind
+	}
+
+	// the entries which can't be looked up by bind value
+	check := func(entry *QueryBindBlockerEntry, checkSQL bool) (bool, string, bool) {
+		if entry.expired(now) {
+			return false, "", false
+		}
+		if checkSQL && !entry.matchSQL(sqltext, normalized) {
+			return false, "", false
+		}
+		for i := 0; i+1 < len(bindPairs); i += 2 {
+			if entry.matchName(bindPairs[i]) && entry.matchValue(bindPairs[i+1]) {
+				return true, bindPairs[i+1], entry.roll(sqlhash, bindPairs[i], bindPairs[i+1])
 			}
-			if logger.GetLogger().V(logLevel) {
-				logger.GetLogger().Log(logLevel, fmt.Sprintf("query bind blocker on %d %s %s %d dice:%d %s", sqlhash, bindPairs[i], val, entry.Blockperc, dice, rvStr))
+		}
+		return false, "", false
+	}
+	for _, list := range cfg.ValuePatternsBySqlHash[sqlhash] {
+		for i := range list {
+			// hash entries use the sqltext as a prefix guard
+			matched, val, rv := check(&list[i], true)
+			if matched {
+				return rv, val, list[i].Remarks
 			}
-			return rv, val
 		}
-	} // end for each bind
-	return false, ""
+	}
+	for i := range cfg.SqlPatterns {
+		matched, val, rv := check(&cfg.SqlPatterns[i], true)
+		if matched {
+			return rv, val, cfg.SqlPatterns[i].Remarks
+		}
+	}
+	return false, "", ""
+}
+
+// add validates and indexes an entry
+func (cfg *QueryBindBlockerCfg) add(entry QueryBindBlockerEntry) error {
+	err := entry.compile()
+	if err != nil {
+		return err
+	}
+	if (entry.sqlRegex == nil) && (len(entry.Herasqltext) < GetConfig().QueryBindBlockerMinSqlPrefix) {
+		return fmt.Errorf("sqltext must be %d bytes or more", GetConfig().QueryBindBlockerMinSqlPrefix)
+	}
+	if entry.Herasqlhash == 0 {
+		cfg.SqlPatterns = append(cfg.SqlPatterns, entry)
+		return nil
+	}
+	if entry.isValuePattern() {
+		byBindName, ok := cfg.ValuePatternsBySqlHash[entry.Herasqlhash]
+		if !ok {
+			byBindName = make(map[string][]QueryBindBlockerEntry)
+			cfg.ValuePatternsBySqlHash[entry.Herasqlhash] = byBindName
+		}
+		byBindName[entry.Bindvarname] = append(byBindName[entry.Bindvarname], entry)
+		return nil
+	}
+	sqlHash, ok := cfg.BySqlHash[entry.Herasqlhash]
+	if !ok {
+		sqlHash = make(map[string]map[string][]QueryBindBlockerEntry)
+		cfg.BySqlHash[entry.Herasqlhash] = sqlHash
+	}
+	bindName, ok := sqlHash[entry.Bindvarname]
+	if !ok {
+		bindName = make(map[string][]QueryBindBlockerEntry)
+		sqlHash[entry.Bindvarname] = bindName
+	}
+	bindName[entry.Bindvarvalue] = append(bindName[entry.Bindvarvalue], entry)
+	return nil
 }
 
 var g_module string
 var gQueryBindBlockerCfg atomic.Value
+var gQueryBindBlockerInit sync.Once
 
 func GetQueryBindBlockerCfg() *QueryBindBlockerCfg {
 	cfg := gQueryBindBlockerCfg.Load()
@@ -129,6 +354,11 @@ func GetQueryBindBlockerCfg() *QueryBindBlockerCfg {
 }
 
 func InitQueryBindBlocker(modName string) {
+	// enable_query_bind_blocker can be turned on and off in the ops config, the loader is started once
+	gQueryBindBlockerInit.Do(func() { initQueryBindBlocker(modName) })
+}
+
+func initQueryBindBlocker(modName string) {
 	g_module = modName
 
 	db, err := sql.Open("heraloop", fmt.Sprintf("0:0:0"))
@@ -160,7 +390,7 @@ func loadBlockQueryBind(db *sql.DB) {
 	}
 
 	defer conn.Close()
-	q := fmt.Sprintf("SELECT /*heraMgmt.QueryBindBlocker*/ %ssqlhash, %ssqltext, bindvarname, bindvarvalue, blockperc, %smodule FROM %s_rate_limiter where %smodule='%s'", GetConfig().StateLogPrefix, GetConfig().StateLogPrefix, GetConfig().StateLogPrefix, GetConfig().ManagementTablePrefix, GetConfig().StateLogPrefix, g_module)
+	q := fmt.Sprintf("SELECT /*heraMgmt.QueryBindBlocker*/ %ssqlhash, %ssqltext, bindvarname, bindvarvalue, blockperc, %smodule, end_time, remarks FROM %s_rate_limiter where %smodule='%s'", GetConfig().StateLogPrefix, GetConfig().StateLogPrefix, GetConfig().StateLogPrefix, GetConfig().ManagementTablePrefix, GetConfig().StateLogPrefix, g_module)
 	logger.GetLogger().Log(logger.Info, "Loading query bind blocker meta-sql "+q)
 	stmt, err := conn.PrepareContext(ctx, q)
 	if err != nil {
@@ -180,44 +410,36 @@ func loadBlockQueryBind(db *sql.DB) {
 	}
 	defer rows.Close()
 
-	cfgLoad := QueryBindBlockerCfg{BySqlHash: make(map[uint32]map[string]map[string][]QueryBindBlockerEntry)}
+	cfgLoad := newQueryBindBlockerCfg()
 
 	rowCount := 0
+	now := time.Now()
 	for rows.Next() {
 		var entry QueryBindBlockerEntry
-		err = rows.Scan(&(entry.Herasqlhash), &(entry.Herasqltext), &(entry.Bindvarname), &(entry.Bindvarvalue), &(entry.Blockperc), &(entry.Heramodule))
+		var endTime sql.NullInt64
+		var remarks sql.NullString
+		err = rows.Scan(&(entry.Herasqlhash), &(entry.Herasqltext), &(entry.Bindvarname), &(entry.Bindvarvalue), &(entry.Blockperc), &(entry.Heramodule), &endTime, &remarks)
 		if err != nil {
 			logger.GetLogger().Log(logger.Alert, "Error (row scan) loading query bind blocker:", err)
 			continue
 		}
-
-		if len(entry.Herasqltext) < GetConfig().QueryBindBlockerMinSqlPrefix {
-			logger.GetLogger().Log(logger.Alert, "Error (row scan) loading query bind blocker - sqltext must be ", GetConfig().QueryBindBlockerMinSqlPrefix, " bytes or more - sqlhash:", entry.Herasqlhash)
+		entry.Endtime = endTime.Int64
+		entry.Remarks = remarks.String
+		if entry.expired(now) {
 			continue
 		}
-		rowCount++
-		sqlHash, ok := cfgLoad.BySqlHash[entry.Herasqlhash]
-		if !ok {
-			sqlHash = make(map[string]map[string][]QueryBindBlockerEntry)
-			cfgLoad.BySqlHash[entry.Herasqlhash] = sqlHash
-		}
-		bindName, ok := sqlHash[entry.Bindvarname]
-		if !ok {
-			bindName = make(map[string][]QueryBindBlockerEntry)
-			sqlHash[entry.Bindvarname] = bindName
-		}
-		bindVal, ok := bindName[entry.Bindvarvalue]
-		if !ok {
-			bindVal = make([]QueryBindBlockerEntry, 0)
-			bindName[entry.Bindvarvalue] = bindVal
+		err = cfgLoad.add(entry)
+		if err != nil {
+			logger.GetLogger().Log(logger.Alert, "Error (row scan) loading query bind blocker -", err.Error(), "- sqlhash:", entry.Herasqlhash)
+			continue
 		}
-		bindName[entry.Bindvarvalue] = append(bindName[entry.Bindvarvalue], entry)
+		rowCount++
 		if logger.GetLogger().V(logger.Verbose) {
 			logger.GetLogger().Log(logger.Verbose, fmt.Sprintf("query bind blocker entry %d %s %s %s %d", entry.Herasqlhash, entry.Herasqltext, entry.Bindvarname, entry.Bindvarvalue, entry.Blockperc))
 		}
 	}
 	if logger.GetLogger().V(logger.Info) {
-		logger.GetLogger().Log(logger.Info, fmt.Sprintf("Loaded %d sqlhashes, %d entries, query bind blocker entries", len(cfgLoad.BySqlHash), rowCount))
+		logger.GetLogger().Log(logger.Info, fmt.Sprintf("Loaded %d sqlhashes, %d entries, query bind blocker entries", len(cfgLoad.BySqlHash)+len(cfgLoad.ValuePatternsBySqlHash), rowCount))
 	}
-	gQueryBindBlockerCfg.Store(&cfgLoad)
+	gQueryBindBlockerCfg.Store(cfgLoad)
 }
@@ -0,0 +1,71 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lib
+
+import (
+	"testing"
+	"time"
+
+	"github.com/paypal/hera/utility"
+)
+
+func TestQueryBindBlockerPatterns(t *testing.T) {
+	savedCfg := gAppConfig
+	gAppConfig = &Config{QueryBindBlockerMinSqlPrefix: 10}
+	defer func() { gAppConfig = savedCfg }()
+
+	sqlA := "/*acct.find*/select id from account where id=:id"
+	sqlB := "/*acct.find2*/select id, name from account where id = :id and x = 5"
+	entries := []QueryBindBlockerEntry{
+		{Herasqlhash: uint32(utility.GetSQLHash(sqlA)), Herasqltext: "/*acct.find*/", Bindvarname: "id", Bindvarvalue: "range:100..200", Blockperc: 100, Remarks: "range"},
+		{Herasqltext: "re:^/\\*acct\\.find2\\*/select .* where id = :id and x = \\?$", Bindvarname: "id", Bindvarvalue: "re:^9+$", Blockperc: 100, Remarks: "regex"},
+		{Herasqltext: "/*acct.find2*/", Bindvarname: "id", Bindvarvalue: "1", Blockperc: 100, Endtime: time.Now().Unix() - 10, Remarks: "expired"},
+	}
+	cfg := newQueryBindBlockerCfg()
+	for _, entry := range entries {
+		if err := cfg.add(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cfg.add(QueryBindBlockerEntry{Herasqltext: "/*x*/", Bindvarname: "id", Bindvarvalue: "1"}); err == nil {
+		t.Error("expected error for a short sqltext prefix")
+	}
+	if err := cfg.add(QueryBindBlockerEntry{Herasqltext: "/*acct.find*/", Bindvarname: "id", Bindvarvalue: "range:a..b"}); err == nil {
+		t.Error("expected error for a bad range")
+	}
+
+	tests := []struct {
+		sql     string
+		value   string
+		blocked bool
+		remarks string
+	}{
+		{sqlA, "150", true, "range"},
+		{sqlA, "99", false, ""},
+		{sqlA, "200", true, "range"},
+		{sqlB, "999", true, "regex"},
+		{sqlB, "998", false, ""},
+		{sqlB, "1", false, ""},
+	}
+	for _, tc := range tests {
+		blocked, _, remarks := cfg.IsBlocked(tc.sql, []string{":id", tc.value})
+		if (blocked != tc.blocked) || (remarks != tc.remarks) {
+			t.Errorf("%s %s: expected %v %q, got %v %q", tc.sql, tc.value, tc.blocked, tc.remarks, blocked, remarks)
+		}
+	}
+}
@@ -132,6 +132,9 @@ func (q *ringQueue)remove(pos int) {
 
 // Remove removes the element having the given value
 func (q *ringQueue)Remove(el interface{}) bool {
+	if !q.idmap[el] {
+		return false
+	}
 	pos := q.head
 	for pos != q.tail {
 		if el == q.data[pos] {
@@ -41,6 +41,8 @@ type racAct struct {
 	instID int
 	tm     int
 	delay  bool
+	// restart window in seconds, rac_restart_window if 0
+	window int
 }
 
 type racCfgKey struct {
@@ -112,7 +114,7 @@ func racMaintMain(shard int, interval int, cmdLineModuleName string) {
 
 /*
 racMaint is the main function for RAC maintenance processing, being called regularly.
-When maintenance is planned, it calls workerpool.RacMaint to start the actuall processing
+When maintenance is planned, it schedules a recycle of the pools of the shard with ScheduleMaint
 */
 func racMaint(ctx context.Context, shard int, db *sql.DB, racSQL string, cmdLineModuleName string, prev map[racCfgKey]racCfg) {
 	//
@@ -212,22 +214,24 @@ func racMaint(ctx context.Context, shard int, db *sql.DB, racSQL string, cmdLine
 					evt.Completed()
 				}
 
-				// Code flow will gets executed irrespective of valid or in-valid status but actual recycle of workers
-				//controlled by `racReq.tm`.
-				//`racReq.tm` value we are setting to "0" in-case of status "U" or "unknown" status.
-				var workerpool *WorkerPool
-				if strings.HasSuffix(row.module, "_TAF") {
-					workerpool, err = GetWorkerBrokerInstance().GetWorkerPool(wtypeStdBy, 0, shard)
-				} else {
-					workerpool, err = GetWorkerBrokerInstance().GetWorkerPool(wtypeRW, 0, shard)
-				}
-				if err == nil {
-					workerpool.RacMaint(racReq)
-				}
-				if GetConfig().ReadonlyPct > 0 {
-					workerpool, err = GetWorkerBrokerInstance().GetWorkerPool(wtypeRO, 0, shard)
-					if err == nil {
-						workerpool.RacMaint(racReq)
+				// the actual recycle of the workers is controlled by `racReq.tm`, set to "0" in-case of status "U"
+				// or unknown status
+				if racReq.tm != 0 {
+					types := []string{workerTypeNames[wtypeRW]}
+					if strings.HasSuffix(row.module, "_TAF") {
+						types[0] = workerTypeNames[wtypeStdBy]
+					}
+					if GetReloadableConfig().ReadonlyPct > 0 {
+						types = append(types, workerTypeNames[wtypeRO])
+					}
+					inst := 0
+					for _, t := range types {
+						err = ScheduleMaint(MaintRequest{Source: maintSourceTable, Action: MaintRecycle,
+							Time: time.Unix(int64(racReq.tm), 0), Shard: &shard, Type: t, Inst: &inst,
+							RacID: racReq.instID, immediate: !racReq.delay})
+						if err != nil {
+							maintRejected(maintSourceTable, err)
+						}
 					}
 				}
 				prev[cfgKey] = row
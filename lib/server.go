@@ -74,7 +74,6 @@ type server struct {
 	bouncerActivated  bool
 
 	bouncerStartupDelayDone bool
-	startShutdown           int64
 }
 
 // NewServer creates a server from the Lister and the function handling the connections accepted
@@ -83,8 +82,12 @@ func NewServer(lsn Listener, f HandlerFunc) Server {
 	return srv
 }
 
-// FullShutdown kills the parent process if it is named watchdog and exits the process
+// FullShutdown kills the parent process if it is named watchdog and exits the process. During an upgrade the
+// parent of the new mux is the old mux, which is not killed
 func FullShutdown() {
+	if isUpgradeChild() {
+		os.Exit(9)
+	}
 	fileh, err := os.Open(fmt.Sprintf("/proc/%d/status", os.Getppid()))
 	if err == nil {
 		linescan := bufio.NewScanner(fileh)
@@ -132,20 +135,18 @@ func (srv *server) Run() {
 			continue
 		}
 
-		if srv.startShutdown > 0 {
-			if GetStateLog().GetTotalConnections() > 0 && time.Now().Unix()-srv.startShutdown < 62 {
-				// still some connections, allow some time to finish
-				time.Sleep(time.Microsecond * 100)
-				continue
+		conn, err := srv.listener.Accept()
+		if isDraining() {
+			// the listener was handed off to the new mux
+			if conn != nil {
+				conn.Close()
 			}
-			if logger.GetLogger().V(logger.Alert) {
-				logger.GetLogger().Log(logger.Alert, "watchdog died, terminating")
+			if logger.GetLogger().V(logger.Info) {
+				logger.GetLogger().Log(logger.Info, "server: stopped accepting, upgrading")
 			}
 			return
 		}
 
-		conn, err := srv.listener.Accept()
-
 		if srv.bounceRequired(startTime, startupDelay, pollInterval) {
 			if logger.GetLogger().V(logger.Info) {
 				logger.GetLogger().Log(logger.Info, "bouncing", conn.RemoteAddr().String())
@@ -185,7 +186,11 @@ func (srv *server) Run() {
 func (srv *server) authAndHandle(c net.Conn, f HandlerFunc) {
 	conn, err := srv.listener.Init(c)
 	if err == nil {
-		f(conn)
+		if isDrainRequested() {
+			bounceDraining(conn)
+		} else {
+			f(conn)
+		}
 	}
 
 	e := cal.NewCalEvent("CLOSE", IPAddrStr(c.RemoteAddr()), cal.TransOK, "")
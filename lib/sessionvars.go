@@ -0,0 +1,138 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lib
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/paypal/hera/cal"
+	"github.com/paypal/hera/common"
+	"github.com/paypal/hera/utility/encoding/netstring"
+	"github.com/paypal/hera/utility/logger"
+)
+
+// maxSessionVars is the maximum number of session variables a client can set
+const maxSessionVars = 32
+
+// the session variable names are identifiers, optionally qualified like "myapp.tenant" for PostgreSQL
+var regexSessionVarName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// parseSessionVarsAllowed parses session_variables_allowed, a comma separated list of names. The names are
+// case insensitive, a name ending with "*" allows all the names starting with it
+func parseSessionVarsAllowed(list string) []string {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if len(name) > 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// sessionVarAllowed tells if a client can set the session variable. The other ones could change the
+// behavior of the worker for the next clients, e.g. autocommit would leave a transaction open on a free
+// worker, or the privileges of the session, e.g. role
+func sessionVarAllowed(name string, allowed []string) bool {
+	for _, allowedName := range allowed {
+		if strings.HasSuffix(allowedName, "*") {
+			if strings.HasPrefix(name, allowedName[:len(allowedName)-1]) {
+				return true
+			}
+		} else if name == allowedName {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSessionVars validates the payload of CmdSetSessionVars, one "name=value" per line, and returns it
+// sorted by name, so that two sets of variables can be compared as strings. The names are case insensitive,
+// the last value set wins. Only the names of session_variables_allowed are accepted
+func parseSessionVars(payload []byte, allowed []string) (string, error) {
+	vars := make(map[string]string)
+	for _, line := range strings.Split(string(payload), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+		pos := strings.Index(line, "=")
+		if pos == -1 {
+			return "", ErrBadSessionVar
+		}
+		name := strings.ToLower(strings.TrimSpace(line[:pos]))
+		if !regexSessionVarName.MatchString(name) || !sessionVarAllowed(name, allowed) {
+			return "", ErrBadSessionVar
+		}
+		vars[name] = strings.TrimSpace(line[pos+1:])
+	}
+	if len(vars) > maxSessionVars {
+		return "", ErrBadSessionVar
+	}
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lines := make([]string, len(names))
+	for i, name := range names {
+		lines[i] = name + "=" + vars[name]
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// processSetSessionVars remembers the session variables of the client, replacing the ones set before. They
+// are applied to the workers when they are allocated to this client
+func (crd *Coordinator) processSetSessionVars(payload []byte) error {
+	if !GetConfig().EnableSessionVariables {
+		return ErrSessionVarsDisabled
+	}
+	vars, err := parseSessionVars(payload, GetConfig().SessionVarsAllowed)
+	if err != nil {
+		evt := cal.NewCalEvent(cal.EventTypeWarning, "session_vars_rejected", cal.TransOK, "")
+		evt.AddDataStr("raddr", crd.id)
+		evt.Completed()
+		return err
+	}
+	if logger.GetLogger().V(logger.Debug) {
+		logger.GetLogger().Log(logger.Debug, crd.id, "session variables:", vars)
+	}
+	crd.sessionVars = vars
+	return nil
+}
+
+// prependSessionVars adds CmdSetSessionVars in front of the request to the worker, so that the worker
+// applies the session variables of the client before running the request. It returns the new request
+// and its number of netstrings
+func (crd *Coordinator) prependSessionVars(worker *WorkerClient, request *netstring.Netstring, cnt int) (*netstring.Netstring, int) {
+	setVars := netstring.NewNetstringFrom(common.CmdSetSessionVars, []byte(crd.sessionVars))
+	var ns []*netstring.Netstring
+	if request.IsComposite() {
+		nss, _ := netstring.SubNetstrings(request)
+		ns = append([]*netstring.Netstring{setVars}, nss...)
+	} else {
+		ns = []*netstring.Netstring{setVars, request}
+	}
+	if logger.GetLogger().V(logger.Debug) {
+		logger.GetLogger().Log(logger.Debug, crd.id, "applying session variables on worker pid", worker.pid)
+	}
+	worker.sessionVars = crd.sessionVars
+	return netstring.NewNetstringEmbedded(ns), cnt + 1
+}
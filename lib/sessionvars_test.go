@@ -0,0 +1,100 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lib
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/paypal/hera/common"
+	"github.com/paypal/hera/utility/encoding/netstring"
+)
+
+func TestParseSessionVars(t *testing.T) {
+	MkErr("HERA")
+	allowed := parseSessionVarsAllowed(" Time_Zone, sql_mode,search_path ,nls_*,,myapp.*")
+	for _, tc := range []struct {
+		payload  string
+		expected string
+		err      error
+	}{
+		{"", "", nil},
+		{"time_zone=UTC", "time_zone=UTC", nil},
+		{"SQL_MODE = ANSI\r\n\ntime_zone=+00:00\n", "sql_mode=ANSI\ntime_zone=+00:00", nil},
+		{"search_path=a,b\nmyapp.tenant=7\nsearch_path=c", "myapp.tenant=7\nsearch_path=c", nil},
+		{"nls_date_format=YYYY-MM-DD HH24:MI:SS", "nls_date_format=YYYY-MM-DD HH24:MI:SS", nil},
+		{"time_zone", "", ErrBadSessionVar},
+		{"time_zone; DROP TABLE t=1", "", ErrBadSessionVar},
+		{"1x=1", "", ErrBadSessionVar},
+		// the names not allowed could leave a transaction open or change the privileges of the session
+		{"time_zone=UTC\nautocommit=0", "", ErrBadSessionVar},
+		{"role=admin", "", ErrBadSessionVar},
+		{"session_authorization=postgres", "", ErrBadSessionVar},
+		{"statement_timeout=0", "", ErrBadSessionVar},
+		{"sql_log_bin=0", "", ErrBadSessionVar},
+		{"nls=1", "", ErrBadSessionVar},
+		{"time_zone_x=1", "", ErrBadSessionVar},
+	} {
+		vars, err := parseSessionVars([]byte(tc.payload), allowed)
+		if (vars != tc.expected) || (err != tc.err) {
+			t.Errorf("%q: %q, %v, expected %q, %v", tc.payload, vars, err, tc.expected, tc.err)
+		}
+	}
+
+	var lines []string
+	for i := 0; i <= maxSessionVars; i++ {
+		lines = append(lines, fmt.Sprintf("v%d=1", i))
+	}
+	if _, err := parseSessionVars([]byte(strings.Join(lines, "\n")), []string{"*"}); err != ErrBadSessionVar {
+		t.Errorf("%d variables accepted", len(lines))
+	}
+	if _, err := parseSessionVars([]byte("time_zone=UTC"), nil); err != ErrBadSessionVar {
+		t.Error("variable accepted with no name allowed")
+	}
+}
+
+func TestPrependSessionVars(t *testing.T) {
+	crd := &Coordinator{sessionVars: "time_zone=UTC"}
+	worker := &WorkerClient{}
+	prepare := netstring.NewNetstringFrom(common.CmdPrepare, []byte("select 1 from dual"))
+	execute := netstring.NewNetstringFrom(common.CmdExecute, nil)
+	for _, tc := range []struct {
+		request *netstring.Netstring
+		cnt     int
+	}{
+		{prepare, 1},
+		{netstring.NewNetstringEmbedded([]*netstring.Netstring{prepare, execute}), 2},
+	} {
+		worker.sessionVars = ""
+		request, cnt := crd.prependSessionVars(worker, tc.request, tc.cnt)
+		nss, err := netstring.SubNetstrings(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (cnt != tc.cnt+1) || (len(nss) != cnt) {
+			t.Errorf("%d netstrings, count %d, expected %d", len(nss), cnt, tc.cnt+1)
+		}
+		if (nss[0].Cmd != common.CmdSetSessionVars) || (string(nss[0].Payload) != crd.sessionVars) || (nss[1].Cmd != common.CmdPrepare) {
+			t.Errorf("unexpected request %q", request.Serialized)
+		}
+		if worker.sessionVars != crd.sessionVars {
+			t.Errorf("worker session variables %q", worker.sessionVars)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lib
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/paypal/hera/cal"
+	"github.com/paypal/hera/common"
+	"github.com/paypal/hera/config"
+	"github.com/paypal/hera/utility/encoding/netstring"
+	"github.com/paypal/hera/utility/logger"
+	"github.com/paypal/hera/utility/slowlog"
+)
+
+// names of the worker types, used in the slow query log, in the ops config keys and passed to the workers
+var workerTypeNames = [wtypeTotalCount]string{"rw", "ro", "stdby"}
+
+// rqTiming keeps the timestamps of the current request sent to the worker, for the slow query log
+// and the sql stats
+type rqTiming struct {
+	start     time.Time
+	firstResp time.Time
+	// when the last response was written to the client
+	lastResp time.Time
+	eorRqID  uint32
+	// the commands in the request whose responses are still expected, used to count the rows
+	pending []int
+	// rows changed (DML) or fetched (query), valid if rowsKnown
+	rows      int64
+	rowsKnown bool
+	// the worker replied with an error
+	failed bool
+}
+
+// InitSlowQueryLog opens the slow query log if "enable_slow_query_log" is set. Mux owns the file, i.e. it is the
+// only process rotating it, the workers append to the same file.
+func InitSlowQueryLog() {
+	if !GetConfig().EnableSlowQueryLog {
+		return
+	}
+	err := slowlog.Init(GetConfig().SlowQueryLogFile, GetConfig().SlowQueryLogMaxSizeMB, GetConfig().SlowQueryLogBackups, true)
+	if err != nil {
+		if logger.GetLogger().V(logger.Alert) {
+			logger.GetLogger().Log(logger.Alert, "Can't open slow query log", GetConfig().SlowQueryLogFile, err.Error())
+		}
+	}
+}
+
+// loadSlowQueryThresholds reads the slow query thresholds from ops config. "slow_query_threshold_ms" applies to
+// all the pool types, it can be overwritten by "slow_query_threshold_ms_<rw|ro|stdby>"
+func loadSlowQueryThresholds(cfg config.Config) {
+	def := cfg.GetOrDefaultInt("slow_query_threshold_ms", 1000)
+	for i, name := range workerTypeNames {
+		val := cfg.GetOrDefaultInt("slow_query_threshold_ms_"+name, def)
+		if val < 0 {
+			val = 0
+		}
+		atomic.StoreUint32(&(gOpsConfig.slowQueryThresholdMs[i]), uint32(val))
+	}
+}
+
+// GetSlowQueryThresholdMs returns the slow query threshold for the given pool type, 0 means disabled
+func GetSlowQueryThresholdMs(wType HeraWorkerType) uint32 {
+	if (wType < 0) || (wType >= wtypeTotalCount) {
+		return 0
+	}
+	return atomic.LoadUint32(&(gOpsConfig.slowQueryThresholdMs[wType]))
+}
+
+// requestCmds returns the commands in the request, crd.nss must have been parsed already
+func (crd *Coordinator) requestCmds(request *netstring.Netstring) []*netstring.Netstring {
+	if !request.IsComposite() {
+		return []*netstring.Netstring{request}
+	}
+	return crd.nss
+}
+
+// slowQuerySQL returns the normalized text of the SQL prepared in the request, if any
+func (crd *Coordinator) slowQuerySQL(request *netstring.Netstring) string {
+	for _, ns := range crd.requestCmds(request) {
+		if (ns.Cmd == common.CmdPrepare) || (ns.Cmd == common.CmdPrepareV2) || (ns.Cmd == common.CmdPrepareSpecial) {
+			return slowlog.NormalizeSQL(string(ns.Payload))
+		}
+	}
+	return ""
+}
+
+// logSlowQuery writes the mux side of the slow query log entry, if the request took longer than the threshold
+func (crd *Coordinator) logSlowQuery(worker *WorkerClient, request *netstring.Netstring, backlogWait time.Duration, err error) {
+	if !slowlog.Enabled() || (worker == nil) || (request == nil) {
+		return
+	}
+	threshold := GetSlowQueryThresholdMs(worker.Type)
+	if threshold == 0 {
+		return
+	}
+	now := time.Now()
+	var dbTime time.Duration
+	if !crd.timing.start.IsZero() {
+		dbTime = now.Sub(crd.timing.start)
+	}
+	total := backlogWait + dbTime
+	if total < time.Duration(threshold)*time.Millisecond {
+		return
+	}
+
+	entry := slowlog.NewEntry(slowlog.SourceMux)
+	entry.WorkerPid = worker.pid
+	entry.RqID = crd.timing.eorRqID
+	entry.SQLHash = uint32(crd.sqlhash)
+	entry.ShardID = worker.shardID
+	entry.PoolType = workerTypeNames[worker.Type]
+	entry.BacklogWaitMs = int64(backlogWait / time.Millisecond)
+	if !crd.timing.firstResp.IsZero() {
+		entry.ExecMs = int64(crd.timing.firstResp.Sub(crd.timing.start) / time.Millisecond)
+		entry.FetchMs = int64(now.Sub(crd.timing.firstResp) / time.Millisecond)
+	}
+	entry.TotalMs = int64(total / time.Millisecond)
+	if crd.timing.rowsKnown {
+		entry.Rows = crd.timing.rows
+	}
+	entry.Client = fmt.Sprintf("%s@%s/%s", crd.poolName, crd.clientHostName, crd.conn.RemoteAddr().String())
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	entry.SQL = crd.slowQuerySQL(request)
+	binds := parseBinds(request)
+	if len(binds) > 0 {
+		entry.Binds = make([]slowlog.Bind, 0, len(binds))
+		for name, value := range binds {
+			if !GetConfig().SlowQueryLogBinds {
+				value = slowlog.RedactedValue
+			}
+			entry.Binds = append(entry.Binds, slowlog.Bind{Name: name, Value: value})
+		}
+		sort.Slice(entry.Binds, func(i, j int) bool { return entry.Binds[i].Name < entry.Binds[j].Name })
+	}
+	slowlog.Log(entry)
+
+	evt := cal.NewCalEvent(EvtTypeMux, "slow_query", cal.TransOK, "")
+	evt.AddDataInt("sqlhash", int64(uint32(crd.sqlhash)))
+	evt.AddDataInt("total_ms", entry.TotalMs)
+	evt.AddDataInt("backlog_ms", entry.BacklogWaitMs)
+	evt.Completed()
+}
@@ -0,0 +1,279 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lib
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/paypal/hera/cal"
+	"github.com/paypal/hera/utility"
+	"github.com/paypal/hera/utility/logger"
+	"github.com/paypal/hera/utility/slowlog"
+)
+
+// SQL allowlist modes, set with "sql_allowlist_mode"
+const (
+	// SQLAllowlistOff disables the allowlist
+	SQLAllowlistOff = "off"
+	// SQLAllowlistEnforce rejects the statements not in the allowlist
+	SQLAllowlistEnforce = "enforce"
+	// SQLAllowlistDryRun only logs the statements not in the allowlist
+	SQLAllowlistDryRun = "dry_run"
+	// SQLAllowlistLearn records all the statements, to build the initial allowlist
+	SQLAllowlistLearn = "learn"
+)
+
+// how often the allowlist file or table is re-loaded
+const sqlAllowlistReloadInterval = 11 * time.Second
+
+// SQLAllowlist is the set of sqlhashes allowed to run
+type SQLAllowlist struct {
+	BySqlHash map[uint32]struct{}
+}
+
+// IsAllowed returns true if the sqlhash is in the allowlist
+func (al *SQLAllowlist) IsAllowed(sqlhash uint32) bool {
+	_, ok := al.BySqlHash[sqlhash]
+	return ok
+}
+
+var gSQLAllowlist atomic.Value
+
+// GetSQLAllowlist returns the last allowlist loaded, nil if none loaded yet
+func GetSQLAllowlist() *SQLAllowlist {
+	al := gSQLAllowlist.Load()
+	if al == nil {
+		return nil
+	}
+	return al.(*SQLAllowlist)
+}
+
+// parseSQLAllowlist reads the allowlist file format: one sqlhash per line, optionally followed by the
+// SQL text. Empty lines and lines starting with '#' are ignored. The learn mode writes the same format
+func parseSQLAllowlist(reader io.Reader) (*SQLAllowlist, error) {
+	al := &SQLAllowlist{BySqlHash: make(map[uint32]struct{})}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if (len(line) == 0) || (line[0] == '#') {
+			continue
+		}
+		fields := strings.Fields(line)
+		sqlhash, err := strconv.ParseUint(fields[0], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid sqlhash %s", lineNo, fields[0])
+		}
+		al.BySqlHash[uint32(sqlhash)] = struct{}{}
+	}
+	return al, scanner.Err()
+}
+
+// sqlAllowlistLearner appends the sqlhashes not seen before to the learn file
+type sqlAllowlistLearner struct {
+	sync.Mutex
+	seen map[uint32]struct{}
+	file *os.File
+}
+
+var gSQLAllowlistLearner *sqlAllowlistLearner
+
+func (learner *sqlAllowlistLearner) record(sqlhash uint32, sqltext string) {
+	learner.Lock()
+	defer learner.Unlock()
+	if _, ok := learner.seen[sqlhash]; ok {
+		return
+	}
+	learner.seen[sqlhash] = struct{}{}
+	_, err := fmt.Fprintf(learner.file, "%d %s\n", sqlhash, slowlog.NormalizeSQL(sqltext))
+	if (err != nil) && logger.GetLogger().V(logger.Warning) {
+		logger.GetLogger().Log(logger.Warning, "Error writing the sql allowlist learn file:", err.Error())
+	}
+}
+
+// checkSQLAllowlist returns false if the statement must be rejected. The statements of the mux itself
+// (allowlist, rac maintenance, shard map, bind blocker, etc) are not checked
+func (crd *Coordinator) checkSQLAllowlist(sqltext string) bool {
+	mode := GetConfig().SQLAllowlistMode
+	if (mode == SQLAllowlistOff) || crd.isInternal {
+		return true
+	}
+	sqlhash := uint32(utility.GetSQLHash(sqltext))
+	if mode == SQLAllowlistLearn {
+		if gSQLAllowlistLearner != nil {
+			gSQLAllowlistLearner.record(sqlhash, sqltext)
+		}
+		return true
+	}
+	al := GetSQLAllowlist()
+	if (al != nil) && al.IsAllowed(sqlhash) {
+		return true
+	}
+	evtName := "sql_not_allowed"
+	if al == nil {
+		// not loaded yet, enforce rejects everything until then
+		evtName = "sql_allowlist_not_loaded"
+	}
+	if mode == SQLAllowlistDryRun {
+		evtName += "_dry_run"
+	}
+	evt := cal.NewCalEvent(EvtTypeMux, evtName, cal.TransWarning, "")
+	evt.AddDataInt("sqlhash", int64(sqlhash))
+	evt.AddDataStr("raddr", crd.conn.RemoteAddr().String())
+	evt.Completed()
+	if logger.GetLogger().V(logger.Warning) {
+		logger.GetLogger().Log(logger.Warning, crd.id, evtName, "sqlhash:", sqlhash)
+	}
+	return mode != SQLAllowlistEnforce
+}
+
+// loadSQLAllowlistFile loads the allowlist file if it changed since the last load
+func loadSQLAllowlistFile(fileName string, lastMod time.Time) time.Time {
+	fi, err := os.Stat(fileName)
+	if err != nil {
+		logger.GetLogger().Log(logger.Alert, "Error loading sql allowlist:", err)
+		return lastMod
+	}
+	if !fi.ModTime().After(lastMod) {
+		return lastMod
+	}
+	file, err := os.Open(fileName)
+	if err != nil {
+		logger.GetLogger().Log(logger.Alert, "Error loading sql allowlist:", err)
+		return lastMod
+	}
+	defer file.Close()
+	al, err := parseSQLAllowlist(file)
+	if err != nil {
+		// keep the last good allowlist
+		logger.GetLogger().Log(logger.Alert, "Error loading sql allowlist", fileName, err)
+		return lastMod
+	}
+	if logger.GetLogger().V(logger.Info) {
+		logger.GetLogger().Log(logger.Info, fmt.Sprintf("Loaded %d sqlhashes in the sql allowlist from %s", len(al.BySqlHash), fileName))
+	}
+	gSQLAllowlist.Store(al)
+	return fi.ModTime()
+}
+
+// loadSQLAllowlistTable loads the allowlist from the <management_table_prefix>_sql_allowlist table
+func loadSQLAllowlistTable(db *sql.DB, module string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5000*time.Millisecond)
+	defer cancel()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		logger.GetLogger().Log(logger.Alert, "Error (conn) loading sql allowlist:", err)
+		return
+	}
+	defer conn.Close()
+	q := fmt.Sprintf("SELECT /*heraMgmt.SqlAllowlist*/ %ssqlhash FROM %s_sql_allowlist where %smodule='%s'", GetConfig().StateLogPrefix, GetConfig().ManagementTablePrefix, GetConfig().StateLogPrefix, module)
+	stmt, err := conn.PrepareContext(ctx, q)
+	if err != nil {
+		logger.GetLogger().Log(logger.Alert, "Error (stmt) loading sql allowlist:", err)
+		return
+	}
+	defer stmt.Close()
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		logger.GetLogger().Log(logger.Alert, "Error (query) loading sql allowlist:", err)
+		return
+	}
+	defer rows.Close()
+	al := &SQLAllowlist{BySqlHash: make(map[uint32]struct{})}
+	for rows.Next() {
+		var sqlhash uint32
+		err = rows.Scan(&sqlhash)
+		if err != nil {
+			logger.GetLogger().Log(logger.Alert, "Error (row scan) loading sql allowlist:", err)
+			return
+		}
+		al.BySqlHash[sqlhash] = struct{}{}
+	}
+	if err = rows.Err(); err != nil {
+		logger.GetLogger().Log(logger.Alert, "Error (rows) loading sql allowlist:", err)
+		return
+	}
+	if logger.GetLogger().V(logger.Info) {
+		logger.GetLogger().Log(logger.Info, fmt.Sprintf("Loaded %d sqlhashes in the sql allowlist", len(al.BySqlHash)))
+	}
+	gSQLAllowlist.Store(al)
+}
+
+// InitSQLAllowlist starts loading the allowlist, from "sql_allowlist_file" if set or else from the management
+// table. In learn mode it opens the file recording the statements seen. Until the allowlist is loaded the
+// enforce mode rejects the statements, after that a failed reload keeps the previous allowlist.
+func InitSQLAllowlist(modName string) {
+	switch GetConfig().SQLAllowlistMode {
+	case SQLAllowlistOff:
+		return
+	case SQLAllowlistLearn:
+		file, err := os.OpenFile(GetConfig().SQLAllowlistLearnFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			logger.GetLogger().Log(logger.Alert, "Can't open the sql allowlist learn file", GetConfig().SQLAllowlistLearnFile, err)
+			return
+		}
+		learner := &sqlAllowlistLearner{seen: make(map[uint32]struct{}), file: file}
+		// do not record again what was learned by a previous run
+		prev, err := os.Open(GetConfig().SQLAllowlistLearnFile)
+		if err == nil {
+			al, err := parseSQLAllowlist(prev)
+			prev.Close()
+			if err == nil {
+				learner.seen = al.BySqlHash
+			}
+		}
+		gSQLAllowlistLearner = learner
+		return
+	}
+
+	if len(GetConfig().SQLAllowlistFile) > 0 {
+		go func() {
+			var lastMod time.Time
+			for {
+				lastMod = loadSQLAllowlistFile(GetConfig().SQLAllowlistFile, lastMod)
+				time.Sleep(sqlAllowlistReloadInterval)
+			}
+		}()
+		return
+	}
+	db, err := sql.Open("heraloop", fmt.Sprintf("0:0:0"))
+	if err != nil {
+		logger.GetLogger().Log(logger.Alert, "Loading sql allowlist - conn err ", err)
+		return
+	}
+	db.SetMaxIdleConns(0)
+	go func() {
+		time.Sleep(4 * time.Second)
+		for {
+			loadSQLAllowlistTable(db, modName)
+			time.Sleep(sqlAllowlistReloadInterval)
+		}
+	}()
+}
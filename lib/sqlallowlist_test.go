@@ -0,0 +1,102 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lib
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/paypal/hera/utility"
+)
+
+func TestSQLAllowlistParse(t *testing.T) {
+	al, err := parseSQLAllowlist(strings.NewReader("# reviewed 2024-05\n12345 select 1 from dual\n\n  678\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !al.IsAllowed(12345) || !al.IsAllowed(678) || al.IsAllowed(1) {
+		t.Errorf("unexpected allowlist %v", al.BySqlHash)
+	}
+	_, err = parseSQLAllowlist(strings.NewReader("select 1 from dual\n"))
+	if err == nil {
+		t.Error("expected error for a line without sqlhash")
+	}
+}
+
+func TestSQLAllowlistLearn(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "learned.txt")
+	file, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	learner := &sqlAllowlistLearner{seen: make(map[uint32]struct{}), file: file}
+	learner.record(1, "select  a\n from t where b = 5")
+	learner.record(1, "select a from t where b = 5")
+	learner.record(2, "delete from t")
+	file.Close()
+
+	file, _ = os.Open(name)
+	defer file.Close()
+	al, err := parseSQLAllowlist(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (len(al.BySqlHash) != 2) || !al.IsAllowed(1) || !al.IsAllowed(2) {
+		t.Errorf("unexpected learned allowlist %v", al.BySqlHash)
+	}
+	data, _ := os.ReadFile(name)
+	if string(data) != "1 select a from t where b = ?\n2 delete from t\n" {
+		t.Errorf("unexpected learn file %q", data)
+	}
+}
+
+func TestCheckSQLAllowlist(t *testing.T) {
+	savedCfg := gAppConfig
+	savedAl := gSQLAllowlist
+	defer func() {
+		gAppConfig = savedCfg
+		gSQLAllowlist = savedAl
+	}()
+	gSQLAllowlist = atomic.Value{}
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	crd := &Coordinator{conn: server, id: "test"}
+	internal := &Coordinator{conn: server, id: "internal", isInternal: true}
+	sqltext := "select 1 from dual"
+
+	gAppConfig = &Config{SQLAllowlistMode: SQLAllowlistDryRun}
+	if !crd.checkSQLAllowlist(sqltext) {
+		t.Error("dry run rejected the statement")
+	}
+	gAppConfig = &Config{SQLAllowlistMode: SQLAllowlistEnforce}
+	if crd.checkSQLAllowlist(sqltext) {
+		t.Error("statement allowed before the allowlist is loaded")
+	}
+	if !internal.checkSQLAllowlist(sqltext) {
+		t.Error("statement of the mux rejected")
+	}
+	gSQLAllowlist.Store(&SQLAllowlist{BySqlHash: map[uint32]struct{}{uint32(utility.GetSQLHash(sqltext)): {}}})
+	if !crd.checkSQLAllowlist(sqltext) || crd.checkSQLAllowlist("delete from t") {
+		t.Error("allowlist not enforced")
+	}
+}
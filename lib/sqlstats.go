@@ -0,0 +1,306 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lib
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/paypal/hera/cal"
+	"github.com/paypal/hera/common"
+	"github.com/paypal/hera/utility/encoding/netstring"
+	"github.com/paypal/hera/utility/logger"
+	otellogger "github.com/paypal/hera/utility/logger/otel"
+	otelconfig "github.com/paypal/hera/utility/logger/otel/config"
+	"github.com/paypal/hera/utility/slowlog"
+)
+
+// upper bounds (in ms) of the latency buckets, the percentiles are approximated to the bucket bound
+var sqlStatsBuckets = []int64{1, 2, 3, 5, 7, 10, 15, 20, 30, 50, 70, 100, 150, 200, 300, 500, 700,
+	1000, 1500, 2000, 3000, 5000, 7000, 10000, 20000, 30000, 60000}
+
+// SQLStatsEntry is the summary of one sqlhash over an interval
+type SQLStatsEntry struct {
+	SQLHash   uint32 `json:"sqlhash"`
+	Count     int64  `json:"count"`
+	Errors    int64  `json:"errors"`
+	TotalMs   int64  `json:"total_db_ms"`
+	MaxMs     int64  `json:"max_ms"`
+	P50Ms     int64  `json:"p50_ms"`
+	P95Ms     int64  `json:"p95_ms"`
+	P99Ms     int64  `json:"p99_ms"`
+	Rows      int64  `json:"rows"`
+	BacklogMs int64  `json:"backlog_wait_ms"`
+}
+
+type sqlHashStats struct {
+	count     int64
+	errors    int64
+	totalMs   int64
+	maxMs     int64
+	rows      int64
+	backlogMs int64
+	// the last bucket is for the latencies over the largest bound
+	buckets []int64
+}
+
+type sqlStats struct {
+	sync.Mutex
+	hashes  map[uint32]*sqlHashStats
+	dropped int64
+	// top N from the last completed interval
+	last []SQLStatsEntry
+	// set of the sqlhashes in last, exported to OTEL with their own label. it is read on every request
+	top atomic.Value
+}
+
+var gSQLStats = newSQLStats()
+
+func newSQLStats() *sqlStats {
+	ss := &sqlStats{hashes: make(map[uint32]*sqlHashStats)}
+	ss.top.Store(map[uint32]struct{}{})
+	return ss
+}
+
+// add records one request
+func (ss *sqlStats) add(sqlhash uint32, latencyMs int64, backlogMs int64, rows int64, failed bool, maxHashes int) {
+	ss.Lock()
+	defer ss.Unlock()
+	st, ok := ss.hashes[sqlhash]
+	if !ok {
+		if len(ss.hashes) >= maxHashes {
+			ss.dropped++
+			return
+		}
+		st = &sqlHashStats{buckets: make([]int64, len(sqlStatsBuckets)+1)}
+		ss.hashes[sqlhash] = st
+	}
+	st.count++
+	if failed {
+		st.errors++
+	}
+	st.totalMs += latencyMs
+	if latencyMs > st.maxMs {
+		st.maxMs = latencyMs
+	}
+	st.rows += rows
+	st.backlogMs += backlogMs
+	st.buckets[sort.Search(len(sqlStatsBuckets), func(i int) bool { return sqlStatsBuckets[i] >= latencyMs })]++
+}
+
+// percentile returns the bound of the bucket containing the p-th percentile, capped by the max latency
+func (st *sqlHashStats) percentile(p float64) int64 {
+	target := int64(math.Ceil(p * float64(st.count)))
+	var cnt int64
+	for i, bucketCnt := range st.buckets {
+		cnt += bucketCnt
+		if cnt >= target {
+			if (i < len(sqlStatsBuckets)) && (sqlStatsBuckets[i] < st.maxMs) {
+				return sqlStatsBuckets[i]
+			}
+			return st.maxMs
+		}
+	}
+	return st.maxMs
+}
+
+// rotate ends the current interval, returning the sqlhashes with the most DB time
+func (ss *sqlStats) rotate(topN int) ([]SQLStatsEntry, int64) {
+	ss.Lock()
+	hashes := ss.hashes
+	dropped := ss.dropped
+	ss.hashes = make(map[uint32]*sqlHashStats)
+	ss.dropped = 0
+	ss.Unlock()
+
+	top := make([]SQLStatsEntry, 0, len(hashes))
+	for sqlhash, st := range hashes {
+		top = append(top, SQLStatsEntry{SQLHash: sqlhash, Count: st.count, Errors: st.errors, TotalMs: st.totalMs,
+			MaxMs: st.maxMs, P50Ms: st.percentile(0.5), P95Ms: st.percentile(0.95), P99Ms: st.percentile(0.99),
+			Rows: st.rows, BacklogMs: st.backlogMs})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].TotalMs != top[j].TotalMs {
+			return top[i].TotalMs > top[j].TotalMs
+		}
+		return top[i].Count > top[j].Count
+	})
+	if len(top) > topN {
+		top = top[:topN]
+	}
+
+	topSet := make(map[uint32]struct{}, len(top))
+	for _, entry := range top {
+		topSet[entry.SQLHash] = struct{}{}
+	}
+	ss.Lock()
+	ss.last = top
+	ss.Unlock()
+	ss.top.Store(topSet)
+	return top, dropped
+}
+
+// otelLabel returns the sqlhash label for OTEL, only the top sqlhashes have their own label to bound the cardinality
+func (ss *sqlStats) otelLabel(sqlhash uint32) string {
+	if _, ok := ss.top.Load().(map[uint32]struct{})[sqlhash]; ok {
+		return strconv.FormatUint(uint64(sqlhash), 10)
+	}
+	return otellogger.SQLHashOther
+}
+
+// initRowCount prepares counting the rows in the responses of the request being sent to the worker
+func (crd *Coordinator) initRowCount(request *netstring.Netstring) {
+	if !GetConfig().EnableSQLStats && !slowlog.Enabled() {
+		return
+	}
+	for _, ns := range crd.requestCmds(request) {
+		switch ns.Cmd {
+		case common.CmdExecute, common.CmdCols, common.CmdColsInfo, common.CmdFetch:
+			crd.timing.pending = append(crd.timing.pending, ns.Cmd)
+		}
+	}
+}
+
+// countRows inspects a worker response. The execute response has the number of columns, followed by
+// the number of rows for a DML. For a query the rows are counted from the values in the fetch responses.
+// Since the column info may not have a response, the count is best effort.
+func (crd *Coordinator) countRows(data []byte) {
+	if len(crd.timing.pending) == 0 {
+		return
+	}
+	ns, _, err := netstring.ParseAny(data)
+	if err != nil {
+		return
+	}
+	if (ns.Cmd == common.RcSQLError) || (ns.Cmd == common.RcError) {
+		crd.timing.failed = true
+		crd.timing.pending = nil
+		return
+	}
+	switch crd.timing.pending[0] {
+	case common.CmdExecute:
+		crd.timing.pending = crd.timing.pending[1:]
+		crd.resultCols = 0
+		if !ns.IsComposite() {
+			return
+		}
+		cols, rest, err := netstring.ParseAny(ns.Payload)
+		if err != nil {
+			return
+		}
+		crd.resultCols, _ = strconv.Atoi(string(cols.Payload))
+		if crd.resultCols == 0 {
+			rows, _, err := netstring.ParseAny(rest)
+			if err == nil {
+				crd.timing.rows, err = strconv.ParseInt(string(rows.Payload), 10, 64)
+				crd.timing.rowsKnown = (err == nil)
+			}
+		}
+	case common.CmdCols, common.CmdColsInfo:
+		crd.timing.pending = crd.timing.pending[1:]
+	case common.CmdFetch:
+		if !ns.IsComposite() || (crd.resultCols <= 0) {
+			return
+		}
+		values := 0
+		for rest := ns.Payload; len(rest) > 0; values++ {
+			_, rest, err = netstring.ParseAny(rest)
+			if err != nil {
+				return
+			}
+		}
+		crd.timing.rows += int64(values / crd.resultCols)
+		crd.timing.rowsKnown = true
+	}
+}
+
+// recordSQLStats accounts the request just completed in the sqlhash stats and in the OTEL histogram
+func (crd *Coordinator) recordSQLStats(worker *WorkerClient, backlogWait time.Duration, err error) {
+	if !GetConfig().EnableSQLStats || (worker == nil) || crd.timing.start.IsZero() {
+		return
+	}
+	dbTime := time.Since(crd.timing.start)
+	failed := (err != nil) || crd.timing.failed
+	sqlhash := uint32(crd.sqlhash)
+	gSQLStats.add(sqlhash, int64(dbTime/time.Millisecond), int64(backlogWait/time.Millisecond), crd.timing.rows,
+		failed, GetConfig().SQLStatsMaxHashes)
+	if otelconfig.OTelConfigData.Enabled {
+		otellogger.RecordSQLLatency(crd.ctx, gSQLStats.otelLabel(sqlhash), int(worker.Type), worker.shardID,
+			float64(dbTime)/float64(time.Millisecond), failed)
+	}
+}
+
+func reportSQLStats() {
+	top, dropped := gSQLStats.rotate(GetConfig().SQLStatsTopN)
+	for rank, entry := range top {
+		evt := cal.NewCalEvent(EvtTypeMux, "top_sql", cal.TransOK, "")
+		evt.AddDataInt("rank", int64(rank+1))
+		evt.AddDataInt("sqlhash", int64(entry.SQLHash))
+		evt.AddDataInt("cnt", entry.Count)
+		evt.AddDataInt("errors", entry.Errors)
+		evt.AddDataInt("total_db_ms", entry.TotalMs)
+		evt.AddDataInt("p50_ms", entry.P50Ms)
+		evt.AddDataInt("p95_ms", entry.P95Ms)
+		evt.AddDataInt("p99_ms", entry.P99Ms)
+		evt.AddDataInt("max_ms", entry.MaxMs)
+		evt.AddDataInt("rows", entry.Rows)
+		evt.AddDataInt("backlog_wait_ms", entry.BacklogMs)
+		evt.Completed()
+		if logger.GetLogger().V(logger.Info) {
+			logger.GetLogger().Log(logger.Info, fmt.Sprintf("top_sql rank=%d sqlhash=%d cnt=%d errors=%d total_db_ms=%d p50_ms=%d p95_ms=%d p99_ms=%d max_ms=%d rows=%d backlog_wait_ms=%d",
+				rank+1, entry.SQLHash, entry.Count, entry.Errors, entry.TotalMs, entry.P50Ms, entry.P95Ms, entry.P99Ms,
+				entry.MaxMs, entry.Rows, entry.BacklogMs))
+		}
+	}
+	if dropped > 0 {
+		evt := cal.NewCalEvent(cal.EventTypeWarning, "sql_stats_dropped", cal.TransOK, "")
+		evt.AddDataInt("cnt", dropped)
+		evt.Completed()
+	}
+}
+
+func handleTopSQL(w http.ResponseWriter, r *http.Request) {
+	gSQLStats.Lock()
+	last := gSQLStats.last
+	gSQLStats.Unlock()
+	writeIntrospectJSON(w, map[string]interface{}{
+		"interval_sec": GetConfig().SQLStatsInterval,
+		"top":          last,
+	})
+}
+
+// InitSQLStats starts the periodic top SQL report, ranked by the total DB time of each sqlhash. It is sent
+// to CAL as "top_sql" events and to the log, the last report is available on /hera/topsql
+func InitSQLStats() {
+	if !GetConfig().EnableSQLStats {
+		return
+	}
+	RegisterIntrospectHandler("/hera/topsql", handleTopSQL)
+	go func() {
+		for {
+			time.Sleep(time.Duration(GetConfig().SQLStatsInterval) * time.Second)
+			reportSQLStats()
+		}
+	}()
+}
@@ -0,0 +1,78 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lib
+
+import (
+	"testing"
+
+	"github.com/paypal/hera/common"
+	"github.com/paypal/hera/utility/encoding/netstring"
+	otellogger "github.com/paypal/hera/utility/logger/otel"
+)
+
+func TestSQLStatsTopN(t *testing.T) {
+	ss := newSQLStats()
+	for i := 0; i < 100; i++ {
+		ss.add(1, int64(i+1), 0, 1, false, 10)
+	}
+	ss.add(2, 20000, 5, 0, true, 10)
+	ss.add(3, 1, 0, 0, false, 10)
+	ss.add(4, 1, 0, 0, false, 3)
+
+	top, dropped := ss.rotate(2)
+	if dropped != 1 {
+		t.Errorf("expected 1 dropped, got %d", dropped)
+	}
+	if (len(top) != 2) || (top[0].SQLHash != 2) || (top[1].SQLHash != 1) {
+		t.Fatalf("unexpected top %+v", top)
+	}
+	if (top[0].Errors != 1) || (top[0].P99Ms != 20000) {
+		t.Errorf("unexpected stats %+v", top[0])
+	}
+	if (top[1].Count != 100) || (top[1].Rows != 100) || (top[1].P50Ms != 50) || (top[1].P95Ms != 100) || (top[1].MaxMs != 100) {
+		t.Errorf("unexpected stats %+v", top[1])
+	}
+	if (ss.otelLabel(2) != "2") || (ss.otelLabel(3) != otellogger.SQLHashOther) {
+		t.Error("unexpected otel labels")
+	}
+}
+
+func TestCountRows(t *testing.T) {
+	crd := &Coordinator{}
+	crd.timing.pending = []int{common.CmdExecute}
+	crd.countRows(netstring.NewNetstringEmbedded([]*netstring.Netstring{
+		netstring.NewNetstringFrom(common.RcValue, []byte("0")),
+		netstring.NewNetstringFrom(common.RcValue, []byte("7"))}).Serialized)
+	if !crd.timing.rowsKnown || (crd.timing.rows != 7) {
+		t.Errorf("expected 7 DML rows, got %d", crd.timing.rows)
+	}
+
+	crd.timing = rqTiming{pending: []int{common.CmdExecute, common.CmdFetch}}
+	crd.countRows(netstring.NewNetstringEmbedded([]*netstring.Netstring{
+		netstring.NewNetstringFrom(common.RcValue, []byte("2")),
+		netstring.NewNetstringFrom(common.RcValue, []byte("0"))}).Serialized)
+	values := make([]*netstring.Netstring, 6)
+	for i := range values {
+		values[i] = netstring.NewNetstringFrom(common.RcValue, []byte("v"))
+	}
+	crd.countRows(netstring.NewNetstringEmbedded(values).Serialized)
+	crd.countRows(netstring.NewNetstringFrom(common.RcNoMoreData, nil).Serialized)
+	if crd.timing.rows != 3 {
+		t.Errorf("expected 3 fetched rows, got %d", crd.timing.rows)
+	}
+}
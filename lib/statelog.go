@@ -232,7 +232,7 @@ func (sl *StateLog) HasActiveWorker() bool {
 			return true
 		}
 
-		if GetConfig().EnableTAF {
+		if GetReloadableConfig().EnableTAF {
 			stdbyPool, erb := GetWorkerBrokerInstance().GetWorkerPool(wtypeStdBy, 0, s)
 			if erb == nil {
 				return stdbyPool.GetHealthyWorkersCount() > 0
@@ -347,7 +347,7 @@ func (sl *StateLog) ProxyHasCapacity(_wlimit int, _rlimit int) (bool, int) {
 		for n := 0; n < instCnt; n++ {
 			wbacklog += sl.mConnStates[s][wtypeRW][n].perStateCnt[Backlog]
 		}
-		if GetConfig().EnableTAF {
+		if GetReloadableConfig().EnableTAF {
 			instCnt = len(sl.mWorkerStates[s][wtypeStdBy])
 			for n := 0; n < instCnt; n++ {
 				wbacklog += sl.mConnStates[s][wtypeStdBy][n].perStateCnt[Backlog]
@@ -411,7 +411,7 @@ func (sl *StateLog) hasFreeWorker(shdCnt int) bool {
 				}
 			}
 		}
-		if GetConfig().EnableTAF {
+		if GetReloadableConfig().EnableTAF {
 			instCnt = len(sl.mWorkerStates[s][wtypeStdBy])
 			for n := 0; n < instCnt; n++ {
 				sbwCnt := len(sl.mWorkerStates[s][wtypeStdBy][n])
@@ -509,15 +509,23 @@ func (sl *StateLog) init() error {
 			instCnt := sl.workerPoolCfg[s][HeraWorkerType(t)].instCnt
 			workerCnt := sl.workerPoolCfg[s][HeraWorkerType(t)].maxWorkerCnt
 			totalWorkersCount += workerCnt
-			sl.mWorkerStates[s][HeraWorkerType(t)] = make([][]*WorkerStateInfo, instCnt)
-			sl.mConnStates[s][HeraWorkerType(t)] = make([]*ConnStateInfo, instCnt)
-			sl.mTypeTitles[s][HeraWorkerType(t)] = make([]string, instCnt)
-			sl.mLastReqCnt[s][HeraWorkerType(t)] = make([]int64, instCnt)
-			sl.mLastRspCnt[s][HeraWorkerType(t)] = make([]int64, instCnt)
+			//
+			// a type without pool keeps an instance without workers, resized when the pool is added
+			//
+			slots := instCnt
+			if slots == 0 {
+				slots = 1
+				workerCnt = 0
+			}
+			sl.mWorkerStates[s][HeraWorkerType(t)] = make([][]*WorkerStateInfo, slots)
+			sl.mConnStates[s][HeraWorkerType(t)] = make([]*ConnStateInfo, slots)
+			sl.mTypeTitles[s][HeraWorkerType(t)] = make([]string, slots)
+			sl.mLastReqCnt[s][HeraWorkerType(t)] = make([]int64, slots)
+			sl.mLastRspCnt[s][HeraWorkerType(t)] = make([]int64, slots)
 			//
 			// for each "standby" instance, initialize array for each worker
 			//
-			for i := 0; i < instCnt; i++ {
+			for i := 0; i < slots; i++ {
 				sl.mWorkerStates[s][HeraWorkerType(t)][i] = make([]*WorkerStateInfo, workerCnt)
 				sl.mConnStates[s][HeraWorkerType(t)][i] = &ConnStateInfo{}
 				sl.mConnStates[s][HeraWorkerType(t)][i].perStateCnt = make([]int, MaxConnState)
@@ -550,13 +558,13 @@ func (sl *StateLog) init() error {
 	for idx, val := range typeTitlePrefix {
 		typeTitlePrefix[idx] = GetConfig().StateLogPrefix + val
 	}
-	if GetConfig().ReadonlyPct == 0 {
+	if GetReloadableConfig().ReadonlyPct == 0 {
 		typeTitlePrefix[wtypeRW] = GetConfig().StateLogPrefix
 	}
 	for s := 0; s < sl.maxShardSize; s++ {
 		for t := wtypeRW; t < wtypeTotalCount; t++ {
 			var suffix = ".sh" + strconv.Itoa(s)
-			instCnt := sl.workerPoolCfg[s][HeraWorkerType(t)].instCnt
+			instCnt := len(sl.mTypeTitles[s][t])
 
 			for i := 0; i < instCnt; i++ {
 				sl.mTypeTitles[s][t][i] = typeTitlePrefix[t]
@@ -33,7 +33,7 @@ type tcpListener struct {
 func NewTCPListener(service string) Listener {
 	var err error
 	lsn := &tcpListener{}
-	lsn.lsn, err = net.Listen("tcp", service)
+	lsn.lsn, err = listenTCP(service)
 	if err != nil {
 		if logger.GetLogger().V(logger.Alert) {
 			logger.GetLogger().Log(logger.Alert, "Cannot create listener: ", err.Error())
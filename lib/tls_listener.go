@@ -72,7 +72,7 @@ func NewTLSListener(service string) Listener {
 	}
 
 	lsn.cfg = &tls.Config{Certificates: []tls.Certificate{cert}, DynamicRecordSizingDisabled: true}
-	lsn.tcpListener, err = net.Listen("tcp", service)
+	lsn.tcpListener, err = listenTCP(service)
 	if err != nil {
 		if logger.GetLogger().V(logger.Alert) {
 			logger.GetLogger().Log(logger.Alert, "Cannot create listener: ", err.Error())
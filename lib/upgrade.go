@@ -0,0 +1,234 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lib
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/paypal/hera/cal"
+	"github.com/paypal/hera/utility/logger"
+	"github.com/paypal/hera/watchdoglib"
+)
+
+// Binary upgrade without downtime. On SIGUSR2 the mux starts the new mux binary passing it the listening socket.
+// The new mux warms up its worker pools and signals it is ready, then the old mux stops accepting, drains the
+// client connections and exits with watchdoglib.ExitUpgraded so that the watchdog adopts the new mux instead
+// of starting another one.
+
+// env variables passed to the new mux
+const (
+	envUpgradeListenerFD = "HERA_UPGRADE_LISTENER_FD"
+	envUpgradeReadyFD    = "HERA_UPGRADE_READY_FD"
+	envUpgradeFromPid    = "HERA_UPGRADE_FROM_PID"
+)
+
+// upgrade states
+const (
+	upgradeNone int32 = iota
+	upgradeStarting
+	upgradeDraining
+)
+
+var gUpgradeState int32
+
+// the listening socket, handed off to the new mux
+var gMuxListener net.Listener
+
+// ErrUpgradeNotReady is returned when the new mux did not signal it was ready
+var ErrUpgradeNotReady = errors.New("new mux not ready")
+
+// isUpgradeChild returns true if this mux was started by the previous mux, while it is still its parent
+func isUpgradeChild() bool {
+	return os.Getenv(envUpgradeFromPid) == strconv.Itoa(os.Getppid())
+}
+
+// isDraining returns true once the listening socket was handed off to the new mux
+func isDraining() bool {
+	return atomic.LoadInt32(&gUpgradeState) == upgradeDraining
+}
+
+// listenTCP creates the listening socket, or uses the one inherited from the previous mux during an upgrade
+func listenTCP(service string) (net.Listener, error) {
+	var lsn net.Listener
+	var err error
+	fdStr := os.Getenv(envUpgradeListenerFD)
+	if len(fdStr) > 0 {
+		os.Unsetenv(envUpgradeListenerFD)
+		var fd int
+		fd, err = strconv.Atoi(fdStr)
+		if err != nil {
+			return nil, err
+		}
+		file := os.NewFile(uintptr(fd), "listener")
+		lsn, err = net.FileListener(file)
+		file.Close()
+		if (err == nil) && logger.GetLogger().V(logger.Info) {
+			logger.GetLogger().Log(logger.Info, "using the listening socket of the previous mux")
+		}
+	} else {
+		lsn, err = net.Listen("tcp", service)
+	}
+	if err != nil {
+		return nil, err
+	}
+	gMuxListener = lsn
+	return lsn, nil
+}
+
+// upgradeWarmUp is called by the new mux before taking traffic, it waits until "upgrade_warm_pct" of the
+// workers of every pool are healthy or half of the upgrade timeout passed
+func upgradeWarmUp() {
+	if len(os.Getenv(envUpgradeReadyFD)) == 0 {
+		return
+	}
+	deadline := time.Now().Add(time.Duration(GetConfig().UpgradeTimeoutSec) * time.Second / 2)
+	for _, pool := range maintPools(&MaintRequest{}) {
+		target := int32(pool.desiredSize * GetConfig().UpgradeWarmPct / 100)
+		for (pool.GetHealthyWorkersCount() < target) && time.Now().Before(deadline) {
+			time.Sleep(100 * time.Millisecond)
+		}
+		if logger.GetLogger().V(logger.Info) {
+			logger.GetLogger().Log(logger.Info, "upgrade: pool", workerTypeNames[pool.Type], "shard", pool.ShardID, "inst", pool.InstID,
+				"healthy workers", pool.GetHealthyWorkersCount(), "target", target)
+		}
+	}
+}
+
+// upgradeSignalReady tells the previous mux that this mux accepts connections
+func upgradeSignalReady() {
+	fdStr := os.Getenv(envUpgradeReadyFD)
+	if len(fdStr) == 0 {
+		return
+	}
+	os.Unsetenv(envUpgradeReadyFD)
+	fd, err := strconv.Atoi(fdStr)
+	if err != nil {
+		return
+	}
+	file := os.NewFile(uintptr(fd), "upgrade_ready")
+	file.WriteString("ready\n")
+	file.Close()
+}
+
+// startUpgrade starts the new mux and hands off the listener to it. If the new mux is not ready in time
+// it is killed and this mux keeps running
+func startUpgrade() error {
+	if !atomic.CompareAndSwapInt32(&gUpgradeState, upgradeNone, upgradeStarting) {
+		return errors.New("upgrade already in progress")
+	}
+	tcpLsn, ok := gMuxListener.(*net.TCPListener)
+	if !ok {
+		atomic.StoreInt32(&gUpgradeState, upgradeNone)
+		return errors.New("listener can't be handed off")
+	}
+	lsnFile, err := tcpLsn.File()
+	if err != nil {
+		atomic.StoreInt32(&gUpgradeState, upgradeNone)
+		return err
+	}
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		lsnFile.Close()
+		atomic.StoreInt32(&gUpgradeState, upgradeNone)
+		return err
+	}
+	cmd := exec.Command(os.Args[0], os.Args[1:]...)
+	cmd.Env = append(os.Environ(),
+		envUpgradeListenerFD+"=3",
+		envUpgradeReadyFD+"=4",
+		fmt.Sprintf("%s=%d", envUpgradeFromPid, os.Getpid()))
+	cmd.ExtraFiles = []*os.File{lsnFile, readyW}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// own process group, the watchdog kills the group of the old mux when it exits
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	err = cmd.Start()
+	lsnFile.Close()
+	readyW.Close()
+	if err != nil {
+		readyR.Close()
+		atomic.StoreInt32(&gUpgradeState, upgradeNone)
+		return err
+	}
+	if logger.GetLogger().V(logger.Alert) {
+		logger.GetLogger().Log(logger.Alert, "upgrade: started new mux", cmd.Process.Pid)
+	}
+
+	ready := make(chan bool, 1)
+	go func() {
+		// EOF if the new mux exits before being ready
+		line, _ := bufio.NewReader(readyR).ReadString('\n')
+		ready <- (line == "ready\n")
+	}()
+	var isReady bool
+	select {
+	case isReady = <-ready:
+	case <-time.After(time.Duration(GetConfig().UpgradeTimeoutSec) * time.Second):
+	}
+	readyR.Close()
+	if !isReady {
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+		go cmd.Wait()
+		atomic.StoreInt32(&gUpgradeState, upgradeNone)
+		return ErrUpgradeNotReady
+	}
+
+	atomic.StoreInt32(&gUpgradeState, upgradeDraining)
+	// the new mux has its own copy of the socket
+	gMuxListener.Close()
+	startDrain()
+	go func() {
+		drainConnections(time.Duration(GetConfig().UpgradeDrainTimeoutSec) * time.Second)
+		exitMux(watchdoglib.ExitUpgraded)
+	}()
+	return nil
+}
+
+// InitUpgrade handles SIGUSR2 which starts the binary upgrade
+func InitUpgrade() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGUSR2)
+	go func() {
+		for range sigs {
+			if logger.GetLogger().V(logger.Alert) {
+				logger.GetLogger().Log(logger.Alert, "upgrade: requested")
+			}
+			err := startUpgrade()
+			if err != nil {
+				if logger.GetLogger().V(logger.Alert) {
+					logger.GetLogger().Log(logger.Alert, "upgrade: failed", err.Error())
+				}
+				evt := cal.NewCalEvent(EvtTypeMux, "upgrade_failed", cal.TransWarning, err.Error())
+				evt.Completed()
+				continue
+			}
+			evt := cal.NewCalEvent(EvtTypeMux, "upgrade_handoff", cal.TransOK, "")
+			evt.Completed()
+		}
+	}()
+}
@@ -128,7 +128,7 @@ func IPAddrStr(address net.Addr) string {
 // NetstringFromBytes creates a netstring containing data as payload.
 func NetstringFromBytes(data []byte) (*netstring.Netstring, error) {
 	reader := bytes.NewReader(data)
-	ns, err := netstring.NewNetstring(reader)
+	ns, err := netstring.ReadFrame(reader)
 	if err != nil {
 		return nil, err
 	}
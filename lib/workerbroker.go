@@ -19,6 +19,7 @@ package lib
 
 import (
 	"errors"
+	"github.com/paypal/hera/common"
 	"github.com/paypal/hera/utility/logger"
 	"os"
 	"os/signal"
@@ -63,6 +64,8 @@ type WorkerBroker struct {
 	//
 	pidworkermap map[int32]*WorkerClient
 	lock         sync.Mutex
+	// guards the slots of workerpools, filled at runtime by addWorkerPool
+	poolsLock sync.RWMutex
 
 	//
 	// loaded from cfg once and used later.
@@ -137,7 +140,7 @@ func (broker *WorkerBroker) init() error {
 		broker.poolCfgs[s][wtypeRW].instCnt = 1
 
 		broker.poolCfgs[s][wtypeStdBy] = new(WorkerPoolCfg)
-		if GetConfig().EnableTAF {
+		if GetReloadableConfig().EnableTAF {
 			broker.poolCfgs[s][wtypeStdBy].maxWorkerCnt = GetNumStdByWorkers(s)
 			broker.poolCfgs[s][wtypeStdBy].instCnt = 1
 		} else {
@@ -155,7 +158,12 @@ func (broker *WorkerBroker) init() error {
 				logger.GetLogger().Log(logger.Verbose, "init pool ", poolcfg)
 			}
 			workercnt += (poolcfg.instCnt * poolcfg.maxWorkerCnt)
-			broker.workerpools[s][HeraWorkerType(t)] = make([]*WorkerPool, poolcfg.instCnt)
+			// a type without pool keeps an empty slot, for addWorkerPool
+			slots := poolcfg.instCnt
+			if slots == 0 {
+				slots = 1
+			}
+			broker.workerpools[s][HeraWorkerType(t)] = make([]*WorkerPool, slots)
 			for i := 0; i < poolcfg.instCnt; i++ {
 				broker.workerpools[s][HeraWorkerType(t)][i] = &WorkerPool{}
 			}
@@ -195,6 +203,30 @@ func (broker *WorkerBroker) RestartWorkerPool(_moduleName string) error {
 	return err
 }
 
+// addWorkerPool starts the pool of a type which had none at startup, e.g. the RO pools when the topology
+// gets replicas. It fills the empty slot left by init, the statelog sizes its slot with WorkerResizeEvt
+func (broker *WorkerBroker) addWorkerPool(wType HeraWorkerType, shardID int, size int) error {
+	broker.lock.Lock()
+	defer broker.lock.Unlock()
+	if _, err := broker.GetWorkerPool(wType, 0, shardID); err == nil {
+		return nil
+	}
+	poolcfg := broker.poolCfgs[shardID][wType]
+	poolcfg.maxWorkerCnt = size
+	poolcfg.instCnt = 1
+	pool := &WorkerPool{}
+	// the workers look their pool up when they are ready, the requests are routed to it once the caller
+	// enables the R-W split or TAF
+	broker.poolsLock.Lock()
+	broker.workerpools[shardID][wType][0] = pool
+	broker.poolsLock.Unlock()
+	GetStateLog().PublishStateEvent(StateEvent{eType: WorkerResizeEvt, shardID: shardID, wType: wType, instID: 0, newWSize: size})
+	if logger.GetLogger().V(logger.Info) {
+		logger.GetLogger().Log(logger.Info, "adding the", workerTypeNames[wType], "pool of shard", shardID, "with", size, "workers")
+	}
+	return pool.Init(wType, size, 0, shardID, GetConfig().moduleName)
+}
+
 // GetWorkerPoolCfgs returns the worker pool configuration
 func (broker *WorkerBroker) GetWorkerPoolCfgs() (pCfgs []map[HeraWorkerType]*WorkerPoolCfg) {
 	return broker.poolCfgs
@@ -221,6 +253,8 @@ func (broker *WorkerBroker) GetWorkerPool(wType HeraWorkerType, ids ...int) (wor
 		sid = ids[1]
 	}
 
+	broker.poolsLock.RLock()
+	defer broker.poolsLock.RUnlock()
 	if broker.workerpools != nil {
 		if broker.workerpools[sid] != nil && len(broker.workerpools[sid]) > 0 {
 			if broker.workerpools[sid][wType] != nil && len(broker.workerpools[sid][wType]) > 0 {
@@ -274,6 +308,8 @@ func (broker *WorkerBroker) startWorkerMonitor() (err error) {
 					// get none-hera defunct processes. +1 in case racing casue mapsize=0.
 					//
 					defunctPids := make([]int32, 0)
+					// the workers which found their database read-only
+					readOnlyPids := make(map[int32]bool)
 					for {
 						var status syscall.WaitStatus
 
@@ -284,6 +320,9 @@ func (broker *WorkerBroker) startWorkerMonitor() (err error) {
 								logger.GetLogger().Log(logger.Verbose, "received worker exit signal for pid:", pid, " status: ", status)
 							}
 							defunctPids = append(defunctPids, int32(pid))
+							if status.Exited() && (status.ExitStatus() == common.WorkerExitReadOnly) {
+								readOnlyPids[int32(pid)] = true
+							}
 						} else if pid == 0 {
 							break
 						} else {
@@ -326,6 +365,9 @@ func (broker *WorkerBroker) startWorkerMonitor() (err error) {
 										logger.GetLogger().Log(logger.Debug, "worker (id=", workerclient.ID, "pid=", workerclient.pid, ") received signal. transits from state ", workerclient.Status, " to terminated.")
 									}
 									workerclient.setState(wsUnset) // Set the state to UNSET to make sure worker does not stay in FNSH state so long
+									if readOnlyPids[pid] {
+										pool.failover(workerclient)
+									}
 									pool.RestartWorker(workerclient)
 								}
 							} else {
@@ -405,7 +447,7 @@ func (broker *WorkerBroker) changeMaxWorkers() {
 		}
 
 		// if TAF enabled, handle stdby as well
-		if GetConfig().EnableTAF {
+		if GetReloadableConfig().EnableTAF {
 			broker.resizePool(wtypeStdBy, sW, i)
 		}
 
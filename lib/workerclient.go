@@ -129,14 +129,21 @@ type WorkerClient struct {
 	// for SQL eviction and throttle by host prefix
 	clientHostPrefix atomic.Value //  string
 	clientApp        atomic.Value // string
+	// the session variables applied on the worker session, in the CmdSetSessionVars format. it is only
+	// accessed by the coordinator holding the worker
+	sessionVars string
 	//
 	// time since hera_start in ms when the current prepare statement is sent to worker.
 	// reset to 0 after eor meaning no sql running (same as start_time_offset_ms in c++).
 	//
 	sqlStartTimeMs uint32
 
-	// time when the worker started
-	startTime int64
+	// set when the worker replied EORRestart, it is not returned to the pool
+	restarting int32
+
+	// time when the worker started, in seconds and in nanoseconds
+	startTime   int64
+	startTimeNs int64
 	// time when this worker must exit because of lifetime exceeded, randomized value of "max_lifespan_per_child" ops config value.
 	// it can be also set sooner when doing rac maintenance
 	exitTime int64
@@ -157,6 +164,9 @@ type WorkerClient struct {
 
 	//mutex lock to update state from single go-routine
 	stateLock sync.Mutex
+
+	// the sqlhashes of the last statements the worker ran, nil if the sqlhash affinity is disabled
+	recent *recentHashes
 }
 
 type strandedCalInfo struct {
@@ -190,11 +200,13 @@ func envUpsert(attr *syscall.ProcAttr, key string, val string) {
 // NewWorker creates a new workerclient instance (pointer)
 func NewWorker(wid int, wType HeraWorkerType, instID int, shardID int, moduleName string, thr Throttler) *WorkerClient {
 	worker := &WorkerClient{ID: wid, Type: wType, Status: wsUnset, instID: instID, shardID: shardID, moduleName: moduleName, thr: thr}
+	worker.recent = newRecentHashes()
 	maxReqs := GetMaxRequestsPerChild()
 	if maxReqs >= 4 {
 		worker.maxReqCount = maxReqs - uint32(rand.Intn(int(maxReqs/4)))
 	}
-	worker.startTime = time.Now().Unix()
+	worker.startTimeNs = time.Now().UnixNano()
+	worker.startTime = worker.startTimeNs / int64(time.Second)
 	lifespan := GetMaxLifespanPerChild()
 	if lifespan >= 4 {
 		worker.exitTime = worker.startTime + int64(lifespan) - int64(rand.Intn(int(lifespan/4)))
@@ -238,12 +250,15 @@ func (worker *WorkerClient) StartWorker() (err error) {
 		}
 	}
 
+	envUpsert(&attr, envWorkerType, workerTypeNames[worker.Type])
+	envUpsert(&attr, envShardID, fmt.Sprintf("%d", worker.shardID))
+
 	var twoTask string
 	switch worker.Type {
 	case wtypeStdBy:
 		if GetConfig().EnableSharding {
 			envUpsert(&attr, envCalClientSession, fmt.Sprintf("CLIENT_SESSION_TAF_%d", worker.shardID))
-			if GetConfig().EnableTAF {
+			if GetReloadableConfig().EnableTAF {
 				envUpsert(&attr, envDbHostName, fmt.Sprintf("%s_TAF_%d", dbHostName, worker.shardID))
 			} else {
 				envUpsert(&attr, envDbHostName, fmt.Sprintf("%s_R_%d", dbHostName, worker.shardID))
@@ -251,7 +266,7 @@ func (worker *WorkerClient) StartWorker() (err error) {
 			envUpsert(&attr, envLogPrefix, fmt.Sprintf("S0-WORKER shd%d %d", worker.shardID, worker.ID))
 		} else {
 			envUpsert(&attr, envCalClientSession, "CLIENT_SESSION_TAF")
-			if GetConfig().EnableTAF {
+			if GetReloadableConfig().EnableTAF {
 				envUpsert(&attr, envDbHostName, fmt.Sprintf("%s_TAF", dbHostName))
 			} else {
 				envUpsert(&attr, envDbHostName, fmt.Sprintf("%s_R", dbHostName))
@@ -872,7 +887,7 @@ func (worker *WorkerClient) doRead() {
 		// blocking call. if something goes wrong, recycle will close uds from worker
 		// side to unblock this call.
 		//
-		ns, err := netstring.NewNetstring(worker.workerConn)
+		ns, err := netstring.ReadFrame(worker.workerConn)
 		if err != nil {
 			if logger.GetLogger().V(logger.Warning) {
 				logger.GetLogger().Log(logger.Warning, "workerclient pid=", worker.pid, " read error:", err.Error())
@@ -905,7 +920,11 @@ func (worker *WorkerClient) doRead() {
 			if logger.GetLogger().V(logger.Verbose) {
 				logger.GetLogger().Log(logger.Verbose, "workerclient (<<< pid =", worker.pid, ",wrqId:", worker.rqId, "): EOR code:", eor, ", rqId: ", rqId, ", data:", DebugString(payload))
 			}
-			if eor == common.EORFree {
+			if eor == common.EORRestart {
+				// the worker exits after this request, e.g. its session could not be reset
+				atomic.StoreInt32(&(worker.restarting), 1)
+			}
+			if (eor == common.EORFree) || (eor == common.EORRestart) {
 				worker.setState(wsFnsh)
 				/*worker.sqlStartTimeMs = 0
 				if logger.GetLogger().V(logger.Verbose) {
@@ -915,7 +934,7 @@ func (worker *WorkerClient) doRead() {
 				worker.setState(wsWait)
 			}
 			if eor != common.EORMoreIncomingRequests {
-				worker.outCh <- &workerMsg{data: payload, eor: true, free: (eor == common.EORFree), inTransaction: ((eor == common.EORInTransaction) || (eor == common.EORInCursorInTransaction)), rqId: uint32(rqId)}
+				worker.outCh <- &workerMsg{data: payload, eor: true, free: (eor == common.EORFree) || (eor == common.EORRestart), inTransaction: ((eor == common.EORInTransaction) || (eor == common.EORInCursorInTransaction)), rqId: uint32(rqId)}
 				payload = nil
 			} else {
 				// buffer data to avoid race condition
@@ -85,6 +85,17 @@ type WorkerPool struct {
 	workers []*WorkerClient
 	// Throtle workers lifecycle
 	thr Throttler
+	// the worker selections by sqlhash affinity
+	affinity affinityStats
+	// the free workers by the sqlhashes they recently ran, see indexIdle
+	idleByHash map[int32][]*WorkerClient
+	// the maintenance pauses, a []pauseWindow replaced under pauseLock and read without lock by paused
+	pauses    atomic.Value
+	pauseLock sync.Mutex
+	// when the last failover recycle was scheduled (unix nanoseconds), and how long the failovers after it
+	// are ignored. Set by failover, under the pool lock
+	failoverTime    int64
+	failoverBackoff time.Duration
 }
 
 // Init creates the pool by creating the workers and making all the initializations
@@ -187,11 +198,15 @@ func (pool *WorkerPool) RestartWorker(worker *WorkerClient) (err error) {
 			//
 			GetStateLog().PublishStateEvent(StateEvent{eType: WorkerResizeEvt, shardID: pool.ShardID, wType: pool.Type, instID: pool.InstID, newWSize: pool.currentSize})
 		}
-		pool.activeQ.Remove(worker)
+		if pool.activeQ.Remove(worker) {
+			pool.unindexIdle(worker)
+		}
 		pool.poolCond.L.Unlock()
 		return
 	}
-	pool.activeQ.Remove(worker)
+	if pool.activeQ.Remove(worker) {
+		pool.unindexIdle(worker)
+	}
 	pool.poolCond.L.Unlock()
 	go pool.spawnWorker(worker.ID)
 	return nil
@@ -211,8 +226,12 @@ func (pool *WorkerPool) WorkerReady(worker *WorkerClient) (err error) {
 	if logger.GetLogger().V(logger.Debug) {
 		logger.GetLogger().Log(logger.Debug, "Pool::WorkerReady", worker.pid, worker.Type, worker.instID)
 	}
+	// a worker just started has an empty statement cache
+	worker.recent = newRecentHashes()
 
-	pool.activeQ.Push(worker)
+	if pool.activeQ.Push(worker) {
+		pool.indexIdle(worker)
+	}
 	if logger.GetLogger().V(logger.Debug) {
 		logger.GetLogger().Log(logger.Debug, "poolsize(ready)", pool.activeQ.Len(), " type ", pool.Type, " instance ", pool.InstID)
 	}
@@ -246,9 +265,16 @@ func (pool *WorkerPool) GetWorker(sqlhash int32, timeoutMs ...int) (worker *Work
 			logger.GetLogger().Log(logger.Debug, "Pool::GetWorker(end) type:", pool.Type, ", instance:", pool.InstID, ", active: ", pool.activeQ.Len(), "healthy:", pool.GetHealthyWorkersCount())
 		}
 	}()
+	if pool.paused() {
+		msg := fmt.Sprintf("REJECT_MAINT_PAUSE_%s%d", poolNamePrefix[pool.Type], pool.InstID)
+		e := cal.NewCalEvent(cal.EventTypeWarning, msg, cal.TransOK, "")
+		e.AddDataInt("sql_hash", int64(uint32(sqlhash)))
+		e.Completed()
+		return nil, "", ErrRejectDbDown
+	}
 	pool.poolCond.L.Lock()
 
-	var workerclient = pool.getActiveWorker()
+	var workerclient = pool.getActiveWorker(sqlhash)
 	for workerclient == nil {
 		if pool.GetHealthyWorkersCount() == 0 {
 			msg := fmt.Sprintf("REJECT_DB_DOWN_%s%d", poolNamePrefix[pool.Type], pool.InstID)
@@ -392,14 +418,14 @@ func (pool *WorkerPool) GetWorker(sqlhash int32, timeoutMs ...int) (worker *Work
 			} else {
 				etype += "_short"
 			}
-			ename := fmt.Sprintf("%d", (sleepingtime / GetConfig().BacklogTimeoutUnit))
+			ename := fmt.Sprintf("%d", (sleepingtime / GetReloadableConfig().BacklogTimeoutUnit))
 			e := cal.NewCalEvent(etype, ename, cal.TransOK, strconv.Itoa(int(sleepingtime)))
 			e.Completed()
 			if logger.GetLogger().V(logger.Debug) {
 				logger.GetLogger().Log(logger.Debug, "exiting backlog. type:", pool.Type, ", instance:", pool.InstID)
 			}
 
-			workerclient = pool.getActiveWorker()
+			workerclient = pool.getActiveWorker(sqlhash)
 			//
 			// we still have the lock. if there are other connections also woke up but lost the
 			// race to acquire the lock, backlog stats still have them counted.
@@ -494,6 +520,14 @@ func (pool *WorkerPool) ReturnWorker(worker *WorkerClient, ticket string) (err e
 		worker.DrainResponseChannel(time.Microsecond * 10)
 	}
 
+	if atomic.LoadInt32(&(worker.restarting)) != 0 {
+		// the worker exits, RestartWorker replaces it
+		if logger.GetLogger().V(logger.Info) {
+			logger.GetLogger().Log(logger.Info, "worker restarting, not returned to the pool: pid =", worker.pid, ", pool_type =", worker.Type, ", inst =", worker.instID)
+		}
+		pool.poolCond.L.Unlock()
+		return nil
+	}
 	worker.setState(wsAcpt)
 	if (pool.desiredSize < pool.currentSize) && (worker.ID >= pool.desiredSize) {
 		go func(w *WorkerClient) {
@@ -567,12 +601,19 @@ func (pool *WorkerPool) ReturnWorker(worker *WorkerClient, ticket string) (err e
 		evt.Completed()
 	}
 
+	if (worker.recent != nil) && worker.recent.reset {
+		// the worker reset its session, its statement cache is empty
+		worker.recent = newRecentHashes()
+	}
 	var pstatus = false
 	if GetConfig().LifoScheduler {
 		pstatus = pool.activeQ.PushFront(worker)
 	} else {
 		pstatus = pool.activeQ.Push(worker)
 	}
+	if pstatus {
+		pool.indexIdle(worker)
+	}
 
 	blgsize := atomic.LoadInt32(&(pool.backlogCnt))
 	if logger.GetLogger().V(logger.Debug) {
@@ -595,8 +636,15 @@ func (pool *WorkerPool) ReturnWorker(worker *WorkerClient, ticket string) (err e
 /**
  * caller has lock
  */
-func (pool *WorkerPool) getActiveWorker() (worker *WorkerClient) {
+func (pool *WorkerPool) getActiveWorker(sqlhash int32) (worker *WorkerClient) {
 	var workerclient *WorkerClient
+	if (sqlhash != 0) && affinityEnabled() {
+		// prefer a worker which recently ran the SQL
+		workerclient = pool.getAffinityWorker(sqlhash)
+		if workerclient != nil {
+			return workerclient
+		}
+	}
 	var cnt = pool.activeQ.Len()
 	for cnt > 0 {
 		if logger.GetLogger().V(logger.Debug) {
@@ -607,6 +655,7 @@ func (pool *WorkerPool) getActiveWorker() (worker *WorkerClient) {
 			if logger.GetLogger().V(logger.Debug) {
 				logger.GetLogger().Log(logger.Debug, "Pool::SelectWorker", workerclient.pid, workerclient.Type, pool.InstID)
 			}
+			pool.unindexIdle(workerclient)
 			return workerclient
 		}
 		cnt--
@@ -655,6 +704,7 @@ func (pool *WorkerPool) Resize(newSize int) {
 					}
 					w.Terminate()
 				}(worker)
+				pool.unindexIdle(worker)
 				return true
 			}
 			return false
@@ -691,16 +741,21 @@ func (pool *WorkerPool) GetHealthyWorkersCount() int32 {
 }
 
 // RacMaint is called when rac maintenance is needed. It marks the workers for restart, spreading
-// to an interval in order to avoid connection storm to the database
-func (pool *WorkerPool) RacMaint(racReq racAct) {
+// to an interval in order to avoid connection storm to the database. It returns the workers marked
+func (pool *WorkerPool) RacMaint(racReq racAct) []*WorkerClient {
 	if logger.GetLogger().V(logger.Info) {
 		logger.GetLogger().Log(logger.Info, "Rac maint processing, shard =", pool.ShardID, ", inst =", racReq.instID, ", time=", racReq.tm)
 	}
 	now := time.Now().Unix()
 	window := GetConfig().RacRestartWindow
+	if racReq.window > 0 {
+		window = racReq.window
+	}
+	var marked []*WorkerClient
 	pool.poolCond.L.Lock()
 	for i := 0; i < pool.currentSize; i++ {
 		if (pool.workers[i] != nil) && (racReq.instID == 0 || pool.workers[i].racID == racReq.instID) && (pool.workers[i].startTime < int64(racReq.tm)) {
+			marked = append(marked, pool.workers[i])
 			statusTime := now
 			// requested time is in the past, restart starts from now
 			// requested time is in the future, set restart time starting from it
@@ -732,6 +787,58 @@ func (pool *WorkerPool) RacMaint(racReq racAct) {
 		}
 	}
 	pool.poolCond.L.Unlock()
+	return marked
+}
+
+// remainingWorkers returns how many of the workers are still in the pool, i.e. not yet replaced by a new worker
+func (pool *WorkerPool) remainingWorkers(workers []*WorkerClient) int {
+	pool.poolCond.L.Lock()
+	defer pool.poolCond.L.Unlock()
+	cnt := 0
+	for _, worker := range workers {
+		if (worker.ID < len(pool.workers)) && (pool.workers[worker.ID] == worker) {
+			cnt++
+		}
+	}
+	return cnt
+}
+
+// pauseWindow is a maintenance pause of a pool, from start to end (unix seconds)
+type pauseWindow struct {
+	start int64
+	end   int64
+}
+
+// pause stops handing out workers between start and end (unix seconds), the requests are rejected. The
+// pauses already scheduled are kept, the ended ones are dropped
+func (pool *WorkerPool) pause(start int64, end int64) {
+	pool.pauseLock.Lock()
+	defer pool.pauseLock.Unlock()
+	now := time.Now().Unix()
+	windows := []pauseWindow{{start: start, end: end}}
+	if old, ok := pool.pauses.Load().([]pauseWindow); ok {
+		for _, window := range old {
+			if window.end > now {
+				windows = append(windows, window)
+			}
+		}
+	}
+	pool.pauses.Store(windows)
+}
+
+// paused returns true during a maintenance pause
+func (pool *WorkerPool) paused() bool {
+	windows, _ := pool.pauses.Load().([]pauseWindow)
+	if len(windows) == 0 {
+		return false
+	}
+	now := time.Now().Unix()
+	for _, window := range windows {
+		if (now >= window.start) && (now < window.end) {
+			return true
+		}
+	}
+	return false
 }
 
 // checkWorkerLifespan is called periodically to check if any worker lifetime has expired and terminates it
@@ -777,6 +884,7 @@ func (pool *WorkerPool) checkWorkerLifespan() {
 					break
 				}
 				if pool.activeQ.Remove(pool.workers[i]) {
+					pool.unindexIdle(pool.workers[i])
 					workers = append(workers, pool.workers[i])
 					//
 					// reset exit time to prevent return worker from terminating this worker again.
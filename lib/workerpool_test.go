@@ -18,8 +18,10 @@
 package lib
 
 import (
+	"container/list"
 	"encoding/hex"
 	otelconfig "github.com/paypal/hera/utility/logger/otel/config"
+	"math/rand"
 	"os"
 	"sync"
 	"testing"
@@ -36,7 +38,8 @@ func TestPoolDempotency(t *testing.T) {
 			return
 		}
 	} else {
-		gAppConfig = &Config{BacklogTimeoutMsec: 1, LifoScheduler: true, numWorkersCh: make(chan int, 1)}
+		gAppConfig = &Config{LifoScheduler: true, numWorkersCh: make(chan int, 1)}
+		gReloadableConfig.Store(&ReloadableConfig{BacklogTimeoutMsec: 1})
 		otelconfig.OTelConfigData = &otelconfig.OTelConfig{}
 		gOpsConfig = &OpsConfig{numWorkers: 3}
 		gAppConfig.numWorkersCh <- int(gOpsConfig.numWorkers)
@@ -45,7 +48,7 @@ func TestPoolDempotency(t *testing.T) {
 	// Create the state.log file
 	_, err = os.Create("state.log")
 	GetStateLog()
-	t.Log("--------config SatRecoverThresholdMs, SatRecoverThrottleRate, SatRecoverFreqMs, gAppConfig.SatRecoverThrottleCnt, gAppConfig.SoftEvictionEffectiveTimeMs, gAppConfig.SoftEvictionProbability", GetSatRecoverThresholdMs(), GetSatRecoverThrottleRate(), GetSatRecoverFreqMs(0), GetSatRecoverThrottleCnt(0), gAppConfig.SoftEvictionEffectiveTimeMs, gAppConfig.SoftEvictionProbability)
+	t.Log("--------config SatRecoverThresholdMs, SatRecoverThrottleRate, SatRecoverFreqMs, gAppConfig.SatRecoverThrottleCnt, SoftEvictionEffectiveTimeMs, SoftEvictionProbability", GetSatRecoverThresholdMs(), GetSatRecoverThrottleRate(), GetSatRecoverFreqMs(0), GetSatRecoverThrottleCnt(0), GetReloadableConfig().SoftEvictionEffectiveTimeMs, GetReloadableConfig().SoftEvictionProbability)
 
 	pool := &WorkerPool{}
 	pool.Type = wtypeRW
@@ -147,3 +150,48 @@ func TestPoolDempotency(t *testing.T) {
 	}
 
 }
+
+// benchmarkGetWorker selects workers for 1000 SQLs, the first ones being the most frequent, with about half of
+// the 64 workers busy and the requests completing in random order. It reports the percentage of the selections
+// where the worker recently ran the SQL
+func benchmarkGetWorker(b *testing.B, affinity bool) {
+	savedCfg := gAppConfig
+	gAppConfig = &Config{EnableSQLHashAffinity: affinity, SQLHashAffinitySize: 32, SQLHashAffinityMinIdlePct: 10, SQLHashAffinityReportInterval: 3600}
+	defer func() { gAppConfig = savedCfg }()
+
+	pool := &WorkerPool{activeQ: NewQueue(), currentSize: 64}
+	for i := 0; i < pool.currentSize; i++ {
+		worker := &WorkerClient{ID: i, Status: wsAcpt}
+		worker.recent = &recentHashes{size: 32, lru: list.New(), items: make(map[int32]*list.Element)}
+		pool.activeQ.Push(worker)
+	}
+	rnd := rand.New(rand.NewSource(1))
+	var inFlight []*WorkerClient
+	warm := 0
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		sqlhash := int32(1 + rnd.Intn(1+rnd.Intn(1000)))
+		worker := pool.getActiveWorker(sqlhash)
+		if worker.recent.has(sqlhash) {
+			warm++
+		}
+		worker.recent.add(sqlhash)
+		inFlight = append(inFlight, worker)
+		// the requests complete in random order, more often when more are in flight
+		for (len(inFlight) > 0) && (rnd.Intn(pool.currentSize) < len(inFlight)) {
+			done := rnd.Intn(len(inFlight))
+			// returned as ReturnWorker does with lifo_scheduler
+			pool.activeQ.PushFront(inFlight[done])
+			inFlight = append(inFlight[:done], inFlight[done+1:]...)
+		}
+	}
+	b.ReportMetric(100*float64(warm)/float64(b.N), "warm%")
+}
+
+func BenchmarkGetWorkerLIFO(b *testing.B) {
+	benchmarkGetWorker(b, false)
+}
+
+func BenchmarkGetWorkerAffinity(b *testing.B) {
+	benchmarkGetWorker(b, true)
+}
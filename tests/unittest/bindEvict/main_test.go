@@ -131,8 +131,8 @@ func fastAndSlowBinds() error {
 
 	// if we throttle down or stop, it restores
 	stop2 = 1 // stop bad clients
-	lib.GetConfig().BindEvictionDecrPerSec = 11500.1
-	defer func() { lib.GetConfig().BindEvictionDecrPerSec = 1.1 }()
+	lib.UpdateReloadableConfig(func(cfg *lib.ReloadableConfig) { cfg.BindEvictionDecrPerSec = 11500.1 })
+	defer lib.UpdateReloadableConfig(func(cfg *lib.ReloadableConfig) { cfg.BindEvictionDecrPerSec = 1.1 })
 	time.Sleep(1 * time.Second)
 	conn, err := db.Conn(context.Background())
 	if err != nil {
@@ -142,8 +142,8 @@ func partialBadLoad(fracBad float64) error {
 	// if we throttle down or stop, it restores
 	stop2 = 1 // stop bad clients
 	stop3 = 1
-	lib.GetConfig().BindEvictionDecrPerSec = 11333.1
-	defer func() { lib.GetConfig().BindEvictionDecrPerSec = 1.1 }()
+	lib.UpdateReloadableConfig(func(cfg *lib.ReloadableConfig) { cfg.BindEvictionDecrPerSec = 11333.1 })
+	defer lib.UpdateReloadableConfig(func(cfg *lib.ReloadableConfig) { cfg.BindEvictionDecrPerSec = 1.1 })
 	time.Sleep(1 * time.Second)
 	conn, err := db.Conn(context.Background())
 	if err != nil {
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/paypal/hera/client/gosqldriver"
+	"github.com/paypal/hera/tests/unittest/testutil"
+	"github.com/paypal/hera/utility/logger"
+)
+
+/*
+To run the test
+export DB_USER=x
+export DB_PASSWORD=x
+export DB_DATASOURCE=x
+export username=realU
+export password=realU-pwd
+export TWO_TASK='tcp(mysql.example.com:3306)/someSchema?timeout=60s&tls=preferred||tcp(failover.example.com:3306)/someSchema'
+export TWO_TASK_READ='tcp(mysqlr.example.com:3306)/someSchema?timeout=6s&tls=preferred||tcp(failover.example.com:3306)/someSchema'
+$GOROOT/bin/go install  .../worker/{mysql,oracle}worker
+ln -s $GOPATH/bin/{mysql,oracle}worker .
+$GOROOT/bin/go test -c .../tests/unittest/pipelining && ./pipelining.test
+*/
+
+var mx testutil.Mux
+var tableName string
+
+func cfg() (map[string]string, map[string]string, testutil.WorkerType) {
+
+	appcfg := make(map[string]string)
+	// best to chose an "unique" port in case golang runs tests in paralel
+	appcfg["bind_port"] = "31002"
+	appcfg["log_level"] = "5"
+	appcfg["log_file"] = "hera.log"
+	appcfg["sharding_cfg_reload_interval"] = "0"
+	appcfg["rac_sql_interval"] = "0"
+	appcfg["child.executable"] = "mysqlworker"
+
+	opscfg := make(map[string]string)
+	opscfg["opscfg.default.server.max_connections"] = "3"
+	opscfg["opscfg.default.server.log_level"] = "5"
+
+	return appcfg, opscfg, testutil.MySQLWorker
+}
+
+func before() error {
+	tableName = os.Getenv("TABLE_NAME")
+	if tableName == "" {
+		tableName = "jdbc_hera_test"
+	}
+	return testutil.RunDML("create table jdbc_hera_test ( ID BIGINT, INT_VAL BIGINT, STR_VAL VARCHAR(500))")
+}
+
+func TestMain(m *testing.M) {
+	os.Exit(testutil.UtilMain(m, cfg, before))
+}
+
+// TestPipelineInTransaction sends the statements of a transaction back to back, the mux runs them one after
+// the other like statements sent one by one
+func TestPipelineInTransaction(t *testing.T) {
+	logger.GetLogger().Log(logger.Debug, "TestPipelineInTransaction begin +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++\n")
+
+	db, err := sql.Open("heraloop", "0:0:0")
+	if err != nil {
+		t.Fatal("Error starting Mux:", err)
+	}
+	db.SetMaxIdleConns(0)
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Error getting connection %s\n", err.Error())
+	}
+	defer conn.Close()
+
+	p := gosqldriver.InnerConn(conn).NewPipeline()
+	p.Exec("/*cmd*/delete from " + tableName)
+	p.Exec("/*cmd*/insert into "+tableName+" (id, int_val, str_val) VALUES(?, ?, ?)", 1, 10, "val 1")
+	p.Exec("/*cmd*/insert into "+tableName+" (id, int_val, str_val) VALUES(?, ?, ?)", 2, 20, "val 2")
+	p.Query("/*cmd*/select id, int_val from " + tableName + " order by id")
+	results, err := p.Results()
+	if err != nil {
+		t.Fatalf("Error reading the pipeline results %s\n", err.Error())
+	}
+	if len(results) != 4 {
+		t.Fatalf("Expected 4 results, got %d", len(results))
+	}
+	for i, res := range results {
+		if res.Err != nil {
+			t.Fatalf("Statement %d failed: %s", i, res.Err.Error())
+		}
+	}
+	if (results[1].RowsAffected != 1) || (results[2].RowsAffected != 1) {
+		t.Errorf("Expected 1 row inserted, got %d and %d", results[1].RowsAffected, results[2].RowsAffected)
+	}
+	// the query runs after the inserts, in their transaction
+	if len(results[3].Rows) != 2 {
+		t.Errorf("Expected 2 rows, got %d", len(results[3].Rows))
+	}
+	// ends the transaction of the pipelined DMLs
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("Error starting the transaction %s\n", err.Error())
+	}
+	if err = tx.Rollback(); err != nil {
+		t.Fatalf("Error rollback %s\n", err.Error())
+	}
+
+	// the pipelined statements are processed as separate requests
+	if testutil.RegexCountFile("multiple_client_req", "cal.log") > 0 {
+		t.Error("pipelined statements forwarded as multiple client requests")
+	}
+	logger.GetLogger().Log(logger.Debug, "TestPipelineInTransaction done  -------------------------------------------------------------")
+}
@@ -0,0 +1,140 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package compression contains the codecs compressing the responses on the client connections
+package compression
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/golang/snappy"
+)
+
+const (
+	// Deflate is the codec name of DEFLATE (RFC 1951), at the fastest level
+	Deflate = "deflate"
+	// Snappy is the codec name of the snappy block format, faster than deflate but compressing less
+	Snappy = "snappy"
+)
+
+// ErrUnknownCodec is returned for a codec not supported
+var ErrUnknownCodec = errors.New("unknown compression codec")
+
+// the flate writers are large, they are reused
+var deflateWriters = sync.Pool{
+	New: func() interface{} {
+		w, _ := flate.NewWriter(nil, flate.BestSpeed)
+		return w
+	},
+}
+
+var deflateReaders = sync.Pool{
+	New: func() interface{} {
+		return flate.NewReader(nil)
+	},
+}
+
+// Pick returns the first codec supported in the comma separated list offered by the client, "" if none
+func Pick(offered string) string {
+	for _, codec := range strings.Split(offered, ",") {
+		codec = strings.TrimSpace(codec)
+		if (codec == Deflate) || (codec == Snappy) {
+			return codec
+		}
+	}
+	return ""
+}
+
+// Compress appends the compressed data to dst
+func Compress(codec string, dst []byte, data []byte) ([]byte, error) {
+	switch codec {
+	case Deflate:
+		return deflate(dst, data)
+	case Snappy:
+		maxLen := snappy.MaxEncodedLen(len(data))
+		if maxLen < 0 {
+			return nil, snappy.ErrTooLarge
+		}
+		start := len(dst)
+		if cap(dst)-start < maxLen {
+			dst = append(dst, make([]byte, maxLen)...)
+		}
+		encoded := snappy.Encode(dst[start:start+maxLen], data)
+		return dst[:start+len(encoded)], nil
+	}
+	return nil, ErrUnknownCodec
+}
+
+func deflate(dst []byte, data []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(dst)
+	w := deflateWriters.Get().(*flate.Writer)
+	defer deflateWriters.Put(w)
+	w.Reset(buf)
+	_, err := w.Write(data)
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress returns the decompressed data, up to maxLen bytes
+func Decompress(codec string, data []byte, maxLen int64) ([]byte, error) {
+	switch codec {
+	case Deflate:
+		return inflate(data, maxLen)
+	case Snappy:
+		// the block starts with the decompressed length
+		n, err := snappy.DecodedLen(data)
+		if err != nil {
+			return nil, err
+		}
+		if int64(n) > maxLen {
+			return nil, fmt.Errorf("decompressed data over %d bytes", maxLen)
+		}
+		return snappy.Decode(nil, data)
+	}
+	return nil, ErrUnknownCodec
+}
+
+func inflate(data []byte, maxLen int64) ([]byte, error) {
+	r := deflateReaders.Get().(io.ReadCloser)
+	defer deflateReaders.Put(r)
+	err := r.(flate.Resetter).Reset(bytes.NewReader(data), nil)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	out.Grow(4 * len(data))
+	n, err := io.Copy(&out, io.LimitReader(r, maxLen+1))
+	if err != nil {
+		return nil, err
+	}
+	if n > maxLen {
+		return nil, fmt.Errorf("decompressed data over %d bytes", maxLen)
+	}
+	return out.Bytes(), nil
+}
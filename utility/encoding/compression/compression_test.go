@@ -0,0 +1,43 @@
+package compression
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte("3:3 1,13:3 wide report,"), 1000)
+	for _, codec := range []string{Deflate, Snappy} {
+		compressed, err := Compress(codec, []byte("prefix"), data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(compressed, []byte("prefix")) || (len(compressed) > len(data)/10) {
+			t.Fatalf("%s: unexpected compressed data, %d bytes", codec, len(compressed))
+		}
+		plain, err := Decompress(codec, compressed[len("prefix"):], int64(len(data)))
+		if err != nil || !bytes.Equal(plain, data) {
+			t.Fatalf("%s: round trip failed %v", codec, err)
+		}
+		_, err = Decompress(codec, compressed[len("prefix"):], int64(len(data)-1))
+		if err == nil {
+			t.Errorf("%s: expected error over the max length", codec)
+		}
+	}
+	_, err := Compress("zip", nil, data)
+	if err != ErrUnknownCodec {
+		t.Errorf("expected %v, got %v", ErrUnknownCodec, err)
+	}
+}
+
+func TestPick(t *testing.T) {
+	if Pick("zstd, deflate") != Deflate {
+		t.Error("deflate not picked")
+	}
+	if Pick("zstd, snappy, deflate") != Snappy {
+		t.Error("snappy not picked")
+	}
+	if Pick("zstd") != "" {
+		t.Error("unsupported codec picked")
+	}
+}
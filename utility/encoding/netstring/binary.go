@@ -0,0 +1,254 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netstring
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Binary framing, negotiated with CmdProtocolVersion. A frame has a fixed header: the payload length as a
+// big endian uint32 with the highest bit set, followed by the command as a big endian uint32. The highest bit
+// makes the first byte of a frame different from the digit starting a text netstring, so that both formats
+// can be read from the same stream. The payload of a composite frame (command 0) is a sequence of frames.
+// A Netstring read from a binary frame has the frame in Serialized.
+
+// BinaryHeaderLen is the length of the binary frame header
+const BinaryHeaderLen = 8
+
+const binaryFlag = 0x80000000
+
+// MaxBinaryPayload is the largest payload of a binary frame
+const MaxBinaryPayload = binaryFlag - 1
+
+// ErrBinaryTooLarge is returned when the payload does not fit in a binary frame
+var ErrBinaryTooLarge = errors.New("payload too large for binary frame")
+
+func isBinaryStart(b byte) bool {
+	return (b & 0x80) != 0
+}
+
+// IsBinary returns true if the Netstring was read from a binary frame
+func (ns *Netstring) IsBinary() bool {
+	return (len(ns.Serialized) > 0) && isBinaryStart(ns.Serialized[0])
+}
+
+// readBinary reads the rest of a binary frame, after its first byte
+func readBinary(first byte, _reader io.Reader) (*Netstring, error) {
+	var header [BinaryHeaderLen]byte
+	header[0] = first
+	_, err := io.ReadFull(_reader, header[1:])
+	if err != nil {
+		return nil, err
+	}
+	length := int(binary.BigEndian.Uint32(header[:4]) &^ binaryFlag)
+	if length > MaxLength {
+		return nil, ErrTooLarge
+	}
+	ns := &Netstring{Cmd: int(binary.BigEndian.Uint32(header[4:]))}
+	ns.Serialized = make([]byte, BinaryHeaderLen+length)
+	copy(ns.Serialized, header[:])
+	_, err = io.ReadFull(_reader, ns.Serialized[BinaryHeaderLen:])
+	if err != nil {
+		return nil, err
+	}
+	ns.Payload = ns.Serialized[BinaryHeaderLen:]
+	return ns, nil
+}
+
+// scanBinary decodes the header of the first binary frame in data, returning the length of the frame
+func scanBinary(data []byte) (cmd int, totalLen int, err error) {
+	if len(data) < BinaryHeaderLen {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+	if !isBinaryStart(data[0]) {
+		return 0, 0, errors.New("Expected binary frame")
+	}
+	length := int(binary.BigEndian.Uint32(data[:4]) &^ binaryFlag)
+	if length > MaxLength {
+		return 0, 0, ErrTooLarge
+	}
+	totalLen = BinaryHeaderLen + length
+	if totalLen > len(data) {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+	return int(binary.BigEndian.Uint32(data[4:8])), totalLen, nil
+}
+
+// ParseBinary decodes the first binary frame in data without copying it. The returned Netstring points into data,
+// rest is what follows the frame
+func ParseBinary(data []byte) (ns *Netstring, rest []byte, err error) {
+	cmd, totalLen, err := scanBinary(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	ns = &Netstring{Cmd: cmd, Serialized: data[:totalLen], Payload: data[BinaryHeaderLen:totalLen]}
+	return ns, data[totalLen:], nil
+}
+
+// newBinaryEmbedded embeds the netstrings and binary frames as is in a binary frame
+func newBinaryEmbedded(_netstrings []*Netstring, payloadLen int) *Netstring {
+	data := make([]byte, BinaryHeaderLen, BinaryHeaderLen+payloadLen)
+	binary.BigEndian.PutUint32(data, uint32(payloadLen)|binaryFlag)
+	binary.BigEndian.PutUint32(data[4:], uint32(CodeSubCommand-'0'))
+	for _, i := range _netstrings {
+		data = append(data, i.Serialized...)
+	}
+	return &Netstring{Cmd: CodeSubCommand - '0', Serialized: data, Payload: data[BinaryHeaderLen:]}
+}
+
+// ParseAny decodes the first netstring or binary frame in data without copying it, like ParseNext
+func ParseAny(data []byte) (*Netstring, []byte, error) {
+	if (len(data) > 0) && isBinaryStart(data[0]) {
+		return ParseBinary(data)
+	}
+	return ParseNext(data)
+}
+
+// appendBinaryFrom appends the binary frame of the first netstring or binary frame in data, returning the rest of data
+func appendBinaryFrom(dst []byte, data []byte) ([]byte, []byte, error) {
+	if (len(data) > 0) && isBinaryStart(data[0]) {
+		_, totalLen, err := scanBinary(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		return append(dst, data[:totalLen]...), data[totalLen:], nil
+	}
+	cmd, payloadStart, totalLen, err := scanText(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	dst, err = appendBinary(dst, cmd, data[payloadStart:totalLen-1])
+	return dst, data[totalLen:], err
+}
+
+// AppendBinary appends the binary frame of ns to dst. The embedded netstrings of a composite are converted too
+func AppendBinary(dst []byte, ns *Netstring) ([]byte, error) {
+	if ns.IsBinary() {
+		return append(dst, ns.Serialized...), nil
+	}
+	return appendBinary(dst, ns.Cmd, ns.Payload)
+}
+
+func appendBinary(dst []byte, cmd int, payload []byte) ([]byte, error) {
+	start := len(dst)
+	dst = append(dst, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(dst[start+4:], uint32(cmd))
+	if cmd == (CodeSubCommand - '0') {
+		var err error
+		for rest := payload; len(rest) > 0; {
+			dst, rest, err = appendBinaryFrom(dst, rest)
+			if err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		dst = append(dst, payload...)
+	}
+	length := len(dst) - start - BinaryHeaderLen
+	if length > MaxBinaryPayload {
+		return nil, ErrBinaryTooLarge
+	}
+	binary.BigEndian.PutUint32(dst[start:], uint32(length)|binaryFlag)
+	return dst, nil
+}
+
+// ToText returns the text netstring of a Netstring read from a binary frame, ns itself if it is already text
+func ToText(ns *Netstring) (*Netstring, error) {
+	if !ns.IsBinary() {
+		return ns, nil
+	}
+	if !ns.IsComposite() {
+		return NewNetstringFrom(ns.Cmd, ns.Payload), nil
+	}
+	nss, err := SubNetstrings(ns)
+	if err != nil {
+		return nil, err
+	}
+	for i := range nss {
+		nss[i], err = ToText(nss[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return NewNetstringEmbedded(nss), nil
+}
+
+// BinaryWriter converts the text netstrings written to it to binary frames, the binary frames are written
+// as is. An incomplete netstring is kept until the rest of it is written
+type BinaryWriter struct {
+	writer  io.Writer
+	pending []byte
+}
+
+// NewBinaryWriter creates a BinaryWriter writing the frames to _writer
+func NewBinaryWriter(_writer io.Writer) *BinaryWriter {
+	return &BinaryWriter{writer: _writer}
+}
+
+// Write converts the complete netstrings in data and writes them
+func (bw *BinaryWriter) Write(data []byte) (int, error) {
+	if (len(bw.pending) == 0) && isBinaryFrames(data) {
+		return len(data), writeFull(bw.writer, data)
+	}
+	in := data
+	if len(bw.pending) > 0 {
+		in = append(bw.pending, data...)
+	}
+	var out []byte
+	for len(in) > 0 {
+		next, rest, err := appendBinaryFrom(out, in)
+		if err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		out = next
+		in = rest
+	}
+	bw.pending = append(bw.pending[:0], in...)
+	if err := writeFull(bw.writer, out); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// isBinaryFrames tells if data is a sequence of complete binary frames
+func isBinaryFrames(data []byte) bool {
+	for len(data) > 0 {
+		_, totalLen, err := scanBinary(data)
+		if err != nil {
+			return false
+		}
+		data = data[totalLen:]
+	}
+	return true
+}
+
+func writeFull(w io.Writer, data []byte) error {
+	for len(data) > 0 {
+		n, err := w.Write(data)
+		if err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package netstring
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+func testCommand() *Netstring {
+	nss := []*Netstring{NewNetstringFrom(25, []byte("select id from test where id = :id")),
+		NewNetstringFrom(4, []byte("id")), NewNetstringFrom(3, []byte("1234567890")), NewNetstringFrom(4, nil)}
+	return NewNetstringEmbedded(nss)
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	text := testCommand()
+	data, err := AppendBinary(nil, text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns, err := ReadFrame(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ns.IsBinary() || !ns.IsComposite() || !bytes.Equal(ns.Serialized, data) {
+		t.Fatalf("unexpected frame %v", ns)
+	}
+	nss, err := SubNetstrings(ns)
+	if err != nil || len(nss) != 4 {
+		t.Fatalf("unexpected sub frames %v %v", nss, err)
+	}
+	if (nss[0].Cmd != 25) || (string(nss[0].Payload) != "select id from test where id = :id") || (nss[3].Cmd != 4) || (len(nss[3].Payload) != 0) {
+		t.Errorf("unexpected sub frames %v", nss)
+	}
+	back, err := ToText(ns)
+	if err != nil || !bytes.Equal(back.Serialized, text.Serialized) {
+		t.Errorf("expected %s, got %s %v", text.Serialized, back.Serialized, err)
+	}
+	_, _, err = ParseBinary(data[:len(data)-1])
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("expected incomplete frame, got %v", err)
+	}
+}
+
+func TestReaderMixed(t *testing.T) {
+	// a text answer followed by binary frames, like the protocol version switch
+	data := []byte("6:2008 2,")
+	data, _ = AppendBinary(data, testCommand())
+	data, _ = AppendBinary(data, NewNetstringFrom(5, []byte("ok")))
+	reader := NewFrameReader(bytes.NewReader(data))
+	var cmds []int
+	for {
+		ns, err := reader.ReadNext()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		cmds = append(cmds, ns.Cmd)
+	}
+	expected := []int{2008, 25, 4, 3, 4, 5}
+	if len(cmds) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, cmds)
+	}
+	for i := range cmds {
+		if cmds[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, cmds)
+		}
+	}
+}
+
+func TestBinaryWriter(t *testing.T) {
+	var out bytes.Buffer
+	bw := NewBinaryWriter(&out)
+	text := append(append([]byte{}, testCommand().Serialized...), NewNetstringFrom(5, []byte("ok")).Serialized...)
+	// written in pieces, cutting the netstrings
+	for _, piece := range [][]byte{text[:3], text[3:20], text[20:]} {
+		n, err := bw.Write(piece)
+		if (err != nil) || (n != len(piece)) {
+			t.Fatalf("write %d %v", n, err)
+		}
+	}
+	expected, _ := AppendBinary(nil, testCommand())
+	expected, _ = AppendBinary(expected, NewNetstringFrom(5, []byte("ok")))
+	if !bytes.Equal(out.Bytes(), expected) {
+		t.Errorf("expected %v, got %v", expected, out.Bytes())
+	}
+	_, err := bw.Write([]byte("x:"))
+	if err == nil {
+		t.Error("Bad input should have failed")
+	}
+}
+
+func TestBinaryNotNegotiated(t *testing.T) {
+	data, _ := AppendBinary(nil, NewNetstringFrom(5, []byte("ok")))
+	if _, err := NewNetstring(bytes.NewReader(data)); err == nil {
+		t.Error("binary frame read as a netstring")
+	}
+	if _, err := NewNetstringReader(bytes.NewReader(data)).ReadNext(); err == nil {
+		t.Error("binary frame read by the netstring reader")
+	}
+}
+
+func TestSubNetstringsMixed(t *testing.T) {
+	prepare := NewNetstringFrom(25, []byte("select 1 from dual"))
+	bin, _ := AppendBinary(nil, NewNetstringFrom(4, nil))
+	execute := &Netstring{Cmd: 4, Serialized: bin, Payload: bin[BinaryHeaderLen:]}
+
+	// a binary frame embedded with a netstring makes a binary frame
+	ns := NewNetstringEmbedded([]*Netstring{prepare, execute})
+	if !ns.IsBinary() || !ns.IsComposite() {
+		t.Fatalf("expected a binary composite, got %v", ns.Serialized)
+	}
+	nss, err := SubNetstrings(ns)
+	if (err != nil) || (len(nss) != 2) || nss[0].IsBinary() || !nss[1].IsBinary() || (nss[1].Cmd != 4) {
+		t.Fatalf("unexpected sub frames %v %v", nss, err)
+	}
+
+	// a netstring cannot embed a binary frame
+	text := NewNetstringFrom(CodeSubCommand-'0', append(append([]byte{}, prepare.Serialized...), bin...))
+	if _, err = SubNetstrings(text); err == nil {
+		t.Error("binary frame embedded in a netstring")
+	}
+}
+
+func TestSubNetstringsTruncated(t *testing.T) {
+	text := testCommand()
+	payload := text.Payload[:len(text.Payload)-2]
+	if _, err := SubNetstrings(NewNetstringFrom(CodeSubCommand-'0', payload)); err != io.ErrUnexpectedEOF {
+		t.Errorf("truncated netstring: %v", err)
+	}
+	data, _ := AppendBinary(nil, text)
+	ns, _, _ := ParseBinary(data)
+	ns.Payload = ns.Payload[:len(ns.Payload)-2]
+	if _, err := SubNetstrings(ns); err != io.ErrUnexpectedEOF {
+		t.Errorf("truncated binary frame: %v", err)
+	}
+}
+
+func TestBinaryWriterFrames(t *testing.T) {
+	var out bytes.Buffer
+	bw := NewBinaryWriter(&out)
+	frames, _ := AppendBinary(nil, testCommand())
+	frames, _ = AppendBinary(frames, NewNetstringFrom(5, []byte("ok")))
+	// the binary frames are written as is, after a netstring which is converted
+	for _, piece := range [][]byte{[]byte("1:5,"), frames} {
+		if _, err := bw.Write(piece); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, _ := AppendBinary(nil, NewNetstringFrom(5, nil))
+	expected = append(expected, frames...)
+	if !bytes.Equal(out.Bytes(), expected) {
+		t.Errorf("expected %v, got %v", expected, out.Bytes())
+	}
+}
+
+func benchCommand() *Netstring {
+	nss := make([]*Netstring, 10)
+	nss[0] = NewNetstringFrom(25, []byte("select id, int_val, str_val from test where id = :account_id and name = :name and address = :address  /*12345-123456789012345678901234567890123456789012345678901234567890123456789012345678901234567890*/"))
+	nss[1] = NewNetstringFrom(4, []byte("account_id"))
+	nss[2] = NewNetstringFrom(3, []byte("1234567890"))
+	nss[3] = NewNetstringFrom(4, []byte("name"))
+	nss[4] = NewNetstringFrom(3, []byte("John Smith"))
+	nss[5] = NewNetstringFrom(4, []byte("address"))
+	nss[6] = NewNetstringFrom(3, []byte("2211 North First Street, San Jose"))
+	nss[7] = NewNetstringFrom(4, []byte(""))
+	nss[8] = NewNetstringFrom(22, []byte(""))
+	nss[9] = NewNetstringFrom(7, []byte("0"))
+	return NewNetstringEmbedded(nss)
+}
+
+// benchmarkRead reads from a connection, where each read has a cost unlike reading from memory
+func benchmarkRead(b *testing.B, data []byte) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		for i := 0; i < b.N; i++ {
+			server.Write(data)
+		}
+		server.Close()
+	}()
+	reader := NewFrameReader(client)
+	for {
+		ns, err := reader.ReadNext()
+		if err != nil {
+			break
+		}
+		result = ns
+	}
+}
+
+func BenchmarkReadText(b *testing.B) {
+	benchmarkRead(b, benchCommand().Serialized)
+}
+
+func BenchmarkReadBinary(b *testing.B) {
+	data, _ := AppendBinary(nil, benchCommand())
+	benchmarkRead(b, data)
+}
+
+func BenchmarkDecodeBinary(b *testing.B) {
+	data, _ := AppendBinary(nil, benchCommand())
+	ns, _ := ReadFrame(bytes.NewReader(data))
+	var nss []*Netstring
+	for i := 0; i < b.N; i++ {
+		nss, _ = SubNetstrings(ns)
+	}
+	results = nss
+}
+
+func BenchmarkEncodeBinary(b *testing.B) {
+	ns := benchCommand()
+	var data []byte
+	for i := 0; i < b.N; i++ {
+		data, _ = AppendBinary(data[:0], ns)
+	}
+	result = &Netstring{Serialized: data}
+}
+
+func BenchmarkDecodeOneBinary(b *testing.B) {
+	data, _ := AppendBinary(nil, NewNetstringFrom(25, []byte("select id, int_val, str_val from test where id = :account_id and name = :name and address = :address")))
+	var ns *Netstring
+	for i := 0; i < b.N; i++ {
+		ns, _ = ReadFrame(strings.NewReader(string(data)))
+	}
+	result = ns
+}
+
+/* go 1.27 linux amd64, the reads are over net.Pipe
+BenchmarkReadText        	  120795	      9268 ns/op	    1313 B/op	      16 allocs/op
+BenchmarkReadBinary      	  179312	      6535 ns/op	    1360 B/op	      17 allocs/op
+BenchmarkDecodeBinary    	 1400774	      1026 ns/op	     832 B/op	      12 allocs/op
+BenchmarkEncodeBinary    	 3700760	       318.1 ns/op	       0 B/op	       0 allocs/op
+BenchmarkDecodeOneBinary 	 2418476	       448.1 ns/op	     400 B/op	       7 allocs/op
+BenchmarkEncode          	  195394	      8098 ns/op	    3072 B/op	      62 allocs/op
+BenchmarkDecode          	  959302	      1133 ns/op	     832 B/op	      12 allocs/op
+BenchmarkDecodeOne       	 2651551	       439.7 ns/op	     385 B/op	       6 allocs/op
+*/
@@ -31,8 +31,14 @@ const (
 	space byte = ' '
 	// CodeSubCommand is a special command used to define that the payload contains multiple netstrings
 	CodeSubCommand = '0'
+	// MaxLength is the largest length of a netstring or binary frame decoded, a larger length is corrupted
+	// data or a hostile peer
+	MaxLength = 1 << 30
 )
 
+// ErrTooLarge is returned when the length of a netstring or binary frame is larger than MaxLength
+var ErrTooLarge = errors.New("netstring length too large")
+
 // Netstring is a netstring packed, which consists of a command plus a payload
 type Netstring struct {
 	Cmd        int
@@ -42,6 +48,16 @@ type Netstring struct {
 
 // NewNetstring creates a Netstring from the reader, reading exactly as many bytes as necessary
 func NewNetstring(_reader io.Reader) (*Netstring, error) {
+	return readNetstring(_reader, false)
+}
+
+// ReadFrame creates a Netstring from the reader like NewNetstring, reading binary frames too. It is used
+// once the peer negotiated the binary framing
+func ReadFrame(_reader io.Reader) (*Netstring, error) {
+	return readNetstring(_reader, true)
+}
+
+func readNetstring(_reader io.Reader, binary bool) (*Netstring, error) {
 	ns := &Netstring{}
 
 	var buff bytes.Buffer
@@ -56,6 +72,9 @@ func NewNetstring(_reader io.Reader) (*Netstring, error) {
 		if err != nil {
 			return nil, err
 		}
+		if binary && (buff.Len() == 0) && isBinaryStart(b) {
+			return readBinary(b, _reader)
+		}
 		buff.WriteByte(b)
 		if b == colon {
 			break
@@ -65,6 +84,9 @@ func NewNetstring(_reader io.Reader) (*Netstring, error) {
 				return nil, errors.New("Expected digit reading length")
 			}
 			length = length*10 + digit
+			if length > MaxLength {
+				return nil, ErrTooLarge
+			}
 		}
 	}
 	//read the rest
@@ -121,12 +143,18 @@ func NewNetstringFrom(_cmd int, _payload []byte) *Netstring {
 	return ns
 }
 
-// NewNetstringEmbedded embedds a set of Netstrings into a netstring
+// NewNetstringEmbedded embedds a set of Netstrings into a netstring. If one of them is a binary frame,
+// the result is a binary frame, since only binary frames can embed binary frames
 func NewNetstringEmbedded(_netstrings []*Netstring) *Netstring {
 	// TODO: optimize
 	payloadLen := 0
+	binary := false
 	for _, i := range _netstrings {
 		payloadLen += len(i.Serialized)
+		binary = binary || i.IsBinary()
+	}
+	if binary {
+		return newBinaryEmbedded(_netstrings, payloadLen)
 	}
 	lenStr := fmt.Sprintf("%d:", payloadLen+2 /*len("0 ")*/)
 	totalLen := len(lenStr) + payloadLen + 2 /*len("0 ")*/ + 1 /*ending comma*/
@@ -145,20 +173,21 @@ func NewNetstringEmbedded(_netstrings []*Netstring) *Netstring {
 	return ns
 }
 
-// SubNetstrings parses the embedded Netstrings
+// SubNetstrings parses the embedded Netstrings. They point into the payload of _ns. A binary frame can
+// embed both binary frames and text netstrings, a text netstring embeds only text netstrings. On error,
+// the Netstrings parsed before are returned with it
 func SubNetstrings(_ns *Netstring) ([]*Netstring, error) {
-	//  TODO: optimize for zero-copy
+	parse := ParseNext
+	if _ns.IsBinary() {
+		parse = ParseAny
+	}
 	var nss []*Netstring
-	reader := bytes.NewReader(_ns.Payload)
 	var ns *Netstring
 	var err error
-	for {
-		ns, err = NewNetstring(reader)
-		if err == io.EOF {
-			break
-		}
+	for rest := _ns.Payload; len(rest) > 0; {
+		ns, rest, err = parse(rest)
 		if err != nil {
-			return nil, err
+			return nss, err
 		}
 		nss = append(nss, ns)
 	}
@@ -171,6 +200,10 @@ type Reader struct {
 	ns     *Netstring
 	nss    []*Netstring
 	next   int
+	// the error parsing the embedded Netstrings, returned after the ones parsed
+	err error
+	// reads binary frames too
+	binary bool
 }
 
 // NewNetstringReader creates a Reader, that maintains the state for embedded Netstrings
@@ -180,6 +213,13 @@ func NewNetstringReader(_reader io.Reader) *Reader {
 	return nsr
 }
 
+// NewFrameReader creates a Reader like NewNetstringReader, which reads binary frames too
+func NewFrameReader(_reader io.Reader) *Reader {
+	nsr := NewNetstringReader(_reader)
+	nsr.binary = true
+	return nsr
+}
+
 // ReadNext returns the next Netstring from the stream. Note: in case of embedded netstrings,
 // the Reader will buffer some Netstrings
 func (reader *Reader) ReadNext() (ns *Netstring, err error) {
@@ -194,15 +234,15 @@ func (reader *Reader) ReadNext() (ns *Netstring, err error) {
 			reader.next++
 			return
 		}
-		reader.ns, err = NewNetstring(reader.reader)
+		if reader.err != nil {
+			return nil, reader.err
+		}
+		reader.ns, err = readNetstring(reader.reader, reader.binary)
 		if err != nil {
 			return nil, err
 		}
 		if reader.ns.Cmd == (CodeSubCommand - '0') {
-			reader.nss, err = SubNetstrings(reader.ns)
-			if err != nil {
-				return nil, err
-			}
+			reader.nss, reader.err = SubNetstrings(reader.ns)
 			reader.ns = nil
 			reader.next = 0
 		}
@@ -213,3 +253,55 @@ func (reader *Reader) ReadNext() (ns *Netstring, err error) {
 func (ns *Netstring) IsComposite() bool {
 	return ns.Cmd == (CodeSubCommand - '0')
 }
+
+// scanText decodes the first netstring in data, returning where its payload starts and its length
+func scanText(data []byte) (cmd int, payloadStart int, totalLen int, err error) {
+	length := 0
+	next := 0
+	for {
+		if next >= len(data) {
+			return 0, 0, 0, io.ErrUnexpectedEOF
+		}
+		b := data[next]
+		next++
+		if b == colon {
+			break
+		}
+		digit := int(b - '0')
+		if (digit < 0) || (digit > 9) {
+			return 0, 0, 0, errors.New("Expected digit reading length")
+		}
+		length = length*10 + digit
+		if length > MaxLength {
+			return 0, 0, 0, ErrTooLarge
+		}
+	}
+	totalLen = next + length + 1 /*comma*/
+	if totalLen > len(data) {
+		return 0, 0, 0, io.ErrUnexpectedEOF
+	}
+	for next < (totalLen - 1) {
+		if data[next] == space {
+			next++
+			break
+		}
+		digit := int(data[next] - '0')
+		if (digit < 0) || (digit > 9) {
+			return 0, 0, 0, errors.New("Expected digit reading command")
+		}
+		cmd = cmd*10 + digit
+		next++
+	}
+	return cmd, next, totalLen, nil
+}
+
+// ParseNext decodes the first netstring in data without copying it. The returned Netstring points into data,
+// rest is what follows the netstring
+func ParseNext(data []byte) (ns *Netstring, rest []byte, err error) {
+	cmd, payloadStart, totalLen, err := scanText(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	ns = &Netstring{Cmd: cmd, Serialized: data[:totalLen], Payload: data[payloadStart : totalLen-1]}
+	return ns, data[totalLen:], nil
+}
@@ -180,6 +180,22 @@ func TestBadInput(t *testing.T) {
 	}
 }
 
+func TestParseNext(t *testing.T) {
+	data := []byte("5:502 0,3:502,")
+	ns, rest, err := ParseNext(data)
+	if err != nil || ns.Cmd != 502 || string(ns.Payload) != "0" || string(ns.Serialized) != "5:502 0," {
+		t.Fatalf("unexpected first netstring %v %v", ns, err)
+	}
+	ns, rest, err = ParseNext(rest)
+	if err != nil || ns.Cmd != 502 || len(ns.Payload) != 0 || len(rest) != 0 {
+		t.Fatalf("unexpected second netstring %v %v", ns, err)
+	}
+	_, _, err = ParseNext([]byte("54:0 16:502 "))
+	if err == nil {
+		t.Error("Bad input should have failed - incomplete Netstring")
+	}
+}
+
 // per https://dave.cheney.net/2013/06/30/how-to-write-benchmarks-in-go, to avoid compiler optimizations
 var result *Netstring
 
@@ -273,3 +289,38 @@ BenchmarkEncodeOne-4   	 3000000	       548 ns/op
 BenchmarkDecode-4      	  500000	      2449 ns/op
 BenchmarkDecodeOne-4   	 5000000	       299 ns/op
 */
+
+func TestLengthTooLarge(t *testing.T) {
+	for _, data := range []string{"18446744073709551610:", "18446744073709551610:1 x,", "9999999999:0 ,", "2147483648:"} {
+		if _, _, err := ParseNext([]byte(data)); err != ErrTooLarge {
+			t.Errorf("%q: ParseNext returned %v", data, err)
+		}
+		if _, err := NewNetstring(strings.NewReader(data)); err != ErrTooLarge {
+			t.Errorf("%q: NewNetstring returned %v", data, err)
+		}
+		// the embedded netstring is not parsed past the payload
+		outer := NewNetstringFrom(int(CodeSubCommand-'0'), []byte(data))
+		if _, err := SubNetstrings(outer); err != ErrTooLarge {
+			t.Errorf("%q: SubNetstrings returned %v", data, err)
+		}
+	}
+	// a binary frame of 2 GB
+	frame := []byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 1}
+	if _, err := ReadFrame(strings.NewReader(string(frame))); err != ErrTooLarge {
+		t.Errorf("ReadFrame returned %v", err)
+	}
+	if _, _, err := ParseBinary(frame); err != ErrTooLarge {
+		t.Errorf("ParseBinary returned %v", err)
+	}
+}
+
+func FuzzSubNetstrings(f *testing.F) {
+	for _, seed := range []string{"6:2008 2,", "0 4:3 ab,1:5,", "18446744073709551610:", "5:1 ab,x", "3:", ":,"} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// must not panic
+		SubNetstrings(&Netstring{Cmd: int(CodeSubCommand - '0'), Payload: data})
+		SubNetstrings(&Netstring{Cmd: int(CodeSubCommand - '0'), Serialized: []byte{0x80}, Payload: data})
+	})
+}
@@ -0,0 +1,97 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the key of a value without key, when kv has an odd length
+const missingKey = "!BADKEY"
+
+// jsonEntry returns the JSON object of a message: the time, level, process, caller and message followed
+// by the fields
+func jsonEntry(severity int32, procName string, caller string, msg string, kv []interface{}) string {
+	var buf bytes.Buffer
+	buf.WriteString(`{"time":`)
+	appendJSON(&buf, time.Now().Format("2006-01-02T15:04:05.000000Z07:00"))
+	buf.WriteString(`,"level":`)
+	appendJSON(&buf, levelStr[severity])
+	buf.WriteString(`,"proc":`)
+	appendJSON(&buf, procName)
+	buf.WriteString(`,"caller":`)
+	appendJSON(&buf, caller)
+	buf.WriteString(`,"msg":`)
+	appendJSON(&buf, msg)
+	for i := 0; i < len(kv); i += 2 {
+		key, val := fieldAt(kv, i)
+		buf.WriteByte(',')
+		appendJSON(&buf, key)
+		buf.WriteByte(':')
+		appendJSON(&buf, val)
+	}
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+// textFields returns the fields as " key1=value1 key2=value2", quoting the values having spaces
+func textFields(kv []interface{}) string {
+	var buf strings.Builder
+	for i := 0; i < len(kv); i += 2 {
+		key, val := fieldAt(kv, i)
+		str := fmt.Sprint(val)
+		if strings.ContainsAny(str, " \t\n\"=") {
+			str = strconv.Quote(str)
+		}
+		buf.WriteString(" ")
+		buf.WriteString(key)
+		buf.WriteString("=")
+		buf.WriteString(str)
+	}
+	return buf.String()
+}
+
+// fieldAt returns the key and the value starting at kv[i]
+func fieldAt(kv []interface{}, i int) (string, interface{}) {
+	if i+1 >= len(kv) {
+		return missingKey, kv[i]
+	}
+	key, ok := kv[i].(string)
+	if !ok {
+		key = fmt.Sprint(kv[i])
+	}
+	return key, kv[i+1]
+}
+
+// appendJSON writes the JSON encoding of the value, errors and values which can't be encoded are written
+// as strings
+func appendJSON(buf *bytes.Buffer, val interface{}) {
+	if err, ok := val.(error); ok {
+		val = err.Error()
+	}
+	data, err := json.Marshal(val)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(val))
+	}
+	buf.Write(data)
+}
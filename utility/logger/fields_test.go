@@ -0,0 +1,100 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTextFields(t *testing.T) {
+	for _, tc := range []struct {
+		kv       []interface{}
+		expected string
+	}{
+		{nil, ""},
+		{[]interface{}{"pid", 12, "type", "write"}, " pid=12 type=write"},
+		{[]interface{}{"error", errors.New("bad conn"), "sql", `a="b"`}, ` error="bad conn" sql="a=\"b\""`},
+		{[]interface{}{1, 2, "odd"}, " 1=2 !BADKEY=odd"},
+	} {
+		if fields := textFields(tc.kv); fields != tc.expected {
+			t.Errorf("%v: %q, expected %q", tc.kv, fields, tc.expected)
+		}
+	}
+}
+
+func TestJSONEntry(t *testing.T) {
+	entry := jsonEntry(Info, "PROXY", "pool.go:12", "worker started", []interface{}{"pid", 12, "error", errors.New("bad conn"), "odd"})
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(entry), &obj); err != nil {
+		t.Fatalf("%q is not JSON: %s", entry, err.Error())
+	}
+	for key, val := range map[string]interface{}{"level": "info", "proc": "PROXY", "caller": "pool.go:12", "msg": "worker started",
+		"pid": float64(12), "error": "bad conn", missingKey: "odd"} {
+		if obj[key] != val {
+			t.Errorf("%s: %v, expected %v", key, obj[key], val)
+		}
+	}
+	if _, ok := obj["time"]; !ok {
+		t.Error("no time")
+	}
+}
+
+// fieldLogger returns the logger as a FieldLogger
+func fieldLogger(t *testing.T) FieldLogger {
+	fl, ok := GetLogger().(FieldLogger)
+	if !ok {
+		t.Fatal("the logger does not implement FieldLogger")
+	}
+	return fl
+}
+
+func TestLogFormats(t *testing.T) {
+	saved := sInstance
+	defer func() { sInstance = saved }()
+
+	var buf bytes.Buffer
+	createLogger(&buf, "PROXY", Info, FormatJSON)
+	fieldLogger(t).LogFields(Info, "worker started", "pid", 12)
+	GetLogger().Log(Info, "worker", "exited")
+	fieldLogger(t).LogFields(Debug, "not logged", "pid", 12)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", lines)
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &obj); (err != nil) || (obj["msg"] != "worker started") || (obj["pid"] != float64(12)) {
+		t.Errorf("unexpected entry %q", lines[0])
+	}
+	if !strings.HasPrefix(obj["caller"].(string), "fields_test.go:") {
+		t.Errorf("unexpected caller %v", obj["caller"])
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &obj); (err != nil) || (obj["msg"] != "worker exited") {
+		t.Errorf("unexpected entry %q", lines[1])
+	}
+
+	buf.Reset()
+	createLogger(&buf, "PROXY", Info, FormatText)
+	fieldLogger(t).LogFields(Info, "worker started", "pid", 12, "type", "write")
+	if line := buf.String(); !strings.Contains(line, "info: [PROXY fields_test.go:") || !strings.HasSuffix(line, "] worker started pid=12 type=write\n") {
+		t.Errorf("unexpected line %q", line)
+	}
+}
@@ -30,6 +30,8 @@ import (
 
 	// for LOG_ALERT
 	"github.com/paypal/hera/cal"
+	"github.com/paypal/hera/config"
+	"github.com/paypal/hera/utility/rotatefile"
 )
 
 // Logger is the interface for logging
@@ -40,10 +42,48 @@ type Logger interface {
 	V(severity int32) bool
 }
 
+// FieldLogger is the optional interface of the loggers writing key/value fields, the logger returned by
+// GetLogger implements it
+type FieldLogger interface {
+	// LogFields writes a message with key/value fields, kv being key1, value1, key2, value2, ...
+	LogFields(severity int32, msg string, kv ...interface{})
+}
+
 type logger struct {
 	fileLogger *log.Logger
 	severity   int32
 	procName   string
+	// the messages are written as JSON objects
+	json bool
+}
+
+// log formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Options are the optional settings of the log file
+type Options struct {
+	// Format is FormatText (default) or FormatJSON
+	Format string
+	// MaxSizeMB is the size over which the file is rotated, 0 to never rotate it
+	MaxSizeMB int
+	// MaxBackups is the number of rotated files kept
+	MaxBackups int
+	// Owner must be true for only one of the processes sharing the file, the one rotating it. The other
+	// processes re-open the file after a rotation
+	Owner bool
+}
+
+// OptionsFromConfig returns the options from the "log_format", "log_max_size_mb" and "log_max_backups" entries
+func OptionsFromConfig(cfg config.Config, owner bool) Options {
+	return Options{
+		Format:     strings.ToLower(cfg.GetOrDefaultString("log_format", FormatText)),
+		MaxSizeMB:  cfg.GetOrDefaultInt("log_max_size_mb", 0),
+		MaxBackups: cfg.GetOrDefaultInt("log_max_backups", 5),
+		Owner:      owner,
+	}
 }
 
 // logger severity
@@ -58,6 +98,7 @@ const (
 var (
 	sInstance *logger
 	prefixStr = [...]string{"alert:", "warn:", "info:", "debug:", "verbose:"}
+	levelStr  = [...]string{"alert", "warn", "info", "debug", "verbose"}
 )
 
 // GetLogger returns the logger instance
@@ -66,7 +107,7 @@ func GetLogger() Logger {
 }
 
 func init() {
-	createLogger(os.Stdout, "PROXY", Info)
+	createLogger(os.Stdout, "PROXY", Info, FormatText)
 }
 
 /* hera.log pipe stalls on multiple spawn/open with start.sh */
@@ -89,6 +130,24 @@ func openFileTimeout(name string, flag int, perm os.FileMode) (*os.File, error)
 // CreateLogger creates a logger which writes to the given fileName. procName is used to prefix the
 // messages, usefull when mutiple proceses share the same log file.
 func CreateLogger(fileName string, procName string, severity int32) error {
+	return CreateLoggerWithOptions(fileName, procName, severity, Options{})
+}
+
+// CreateLoggerWithOptions is CreateLogger with the format and the rotation of the file
+func CreateLoggerWithOptions(fileName string, procName string, severity int32, opts Options) error {
+	if opts.MaxSizeMB > 0 {
+		file, err := rotatefile.Open(fileName, int64(opts.MaxSizeMB)*1024*1024, opts.MaxBackups, opts.Owner)
+		if err != nil {
+			log.Println("Failed to open log file", err.Error())
+			return fmt.Errorf("Failed! open log file")
+		}
+		// redirect stdout and stderr to this file, again after each rotation
+		file.SetOnOpen(func(f *os.File) {
+			dup(int(f.Fd()))
+		})
+		createLogger(file, procName, severity, opts.Format)
+		return nil
+	}
 	var file *os.File
 	var err error
 	file, err = openFileTimeout(fileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
@@ -98,29 +157,30 @@ func CreateLogger(fileName string, procName string, severity int32) error {
 	}
 	// redirect stdout and stderr to this file
 	dup(int(file.Fd()))
-	createLogger(file, procName, severity)
+	createLogger(file, procName, severity, opts.Format)
 	return nil
 }
 
-func createLogger(file io.Writer, procName string, severity int32) {
+func createLogger(file io.Writer, procName string, severity int32, format string) {
+	if format == FormatJSON {
+		// the time is a field of the JSON object
+		sInstance = &logger{fileLogger: log.New(file, "", 0), severity: severity, procName: procName, json: true}
+		return
+	}
 	sInstance = &logger{fileLogger: log.New(file, "" /* no general prefix. a severity prefix is attached at the time of each log*/, log.Ltime|log.Lmicroseconds),
 		severity: severity, procName: procName}
 }
 
 func (logger *logger) Log(severity int32, a ...interface{}) {
 	if severity <= logger.getSeverity() {
-		_, file, line, ok := runtime.Caller(1)
-		if !ok {
-			file = "???"
-			line = 1
+		caller := callerOf(2)
+		a = append([]interface{}{fmt.Sprintf("%s [%s %s]", prefixStr[severity], logger.procName, caller)}, a...)
+		if logger.json {
+			msg := fmt.Sprintln(a[1:]...)
+			logger.fileLogger.Output(0, jsonEntry(severity, logger.procName, caller, msg[:len(msg)-1], nil))
 		} else {
-			slash := strings.LastIndex(file, "/")
-			if slash >= 0 {
-				file = file[slash+1:]
-			}
+			logger.fileLogger.Println(a...)
 		}
-		a = append([]interface{}{fmt.Sprintf("%s [%s %s:%d]", prefixStr[severity], logger.procName, file, line)}, a...)
-		logger.fileLogger.Println(a...)
 		if severity == Alert {
 			evt := cal.NewCalEvent("LOGGER", "ALERT", cal.TransOK, "")
 			aJoined := fmt.Sprintln(a...)
@@ -130,6 +190,35 @@ func (logger *logger) Log(severity int32, a ...interface{}) {
 	}
 }
 
+func (logger *logger) LogFields(severity int32, msg string, kv ...interface{}) {
+	if severity <= logger.getSeverity() {
+		caller := callerOf(2)
+		if logger.json {
+			logger.fileLogger.Output(0, jsonEntry(severity, logger.procName, caller, msg, kv))
+		} else {
+			logger.fileLogger.Println(fmt.Sprintf("%s [%s %s]", prefixStr[severity], logger.procName, caller), msg+textFields(kv))
+		}
+		if severity == Alert {
+			evt := cal.NewCalEvent("LOGGER", "ALERT", cal.TransOK, "")
+			evt.AddDataStr("Data", msg+textFields(kv))
+			evt.Completed()
+		}
+	}
+}
+
+// callerOf returns the file:line of the caller, skip being the number of frames to skip
+func callerOf(skip int) string {
+	_, file, line, ok := runtime.Caller(skip)
+	if !ok {
+		return "???:1"
+	}
+	slash := strings.LastIndex(file, "/")
+	if slash >= 0 {
+		file = file[slash+1:]
+	}
+	return fmt.Sprintf("%s:%d", file, line)
+}
+
 func (logger *logger) V(severity int32) bool {
 	return (severity <= logger.getSeverity())
 }
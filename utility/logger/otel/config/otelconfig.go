@@ -3,6 +3,7 @@ package config
 import (
 	"errors"
 	"fmt"
+	heraconfig "github.com/paypal/hera/config"
 	"github.com/paypal/hera/utility/logger"
 	"sync/atomic"
 )
@@ -32,6 +33,30 @@ type OTelConfig struct {
 	EnableRetry                bool
 }
 
+// InitFromConfig initializes the OTEL configuration from hera.txt, in mux and in the workers
+func InitFromConfig(cdb heraconfig.Config, poolName string, resourceType string) {
+	OTelConfigData = &OTelConfig{}
+	OTelConfigData.Enabled = cdb.GetOrDefaultBool("enable_otel", false)
+	OTelConfigData.SkipCalStateLog = cdb.GetOrDefaultBool("skip_cal_statelog", false)
+	OTelConfigData.MetricNamePrefix = cdb.GetOrDefaultString("otel_metric_prefix", "pp.occ")
+	OTelConfigData.Host = cdb.GetOrDefaultString("otel_agent_host", "localhost")
+	OTelConfigData.MetricsPort = cdb.GetOrDefaultInt("otel_agent_metrics_port", 4318)
+	OTelConfigData.TracePort = cdb.GetOrDefaultInt("otel_agent_trace_port", 4318)
+	OTelConfigData.OtelMetricGRPC = cdb.GetOrDefaultBool("otel_agent_use_grpc_metric", false)
+	OTelConfigData.OtelTraceGRPC = cdb.GetOrDefaultBool("otel_agent_use_grpc_trace", false)
+	OTelConfigData.MetricsURLPath = cdb.GetOrDefaultString("otel_agent_metrics_uri", "")
+	OTelConfigData.TraceURLPath = cdb.GetOrDefaultString("otel_agent_trace_uri", "")
+	OTelConfigData.PoolName = poolName
+	OTelConfigData.UseTls = cdb.GetOrDefaultBool("otel_use_tls", false)
+	OTelConfigData.TLSCertPath = cdb.GetOrDefaultString("otel_tls_cert_path", "")
+	OTelConfigData.ResolutionTimeInSec = cdb.GetOrDefaultInt("otel_resolution_time_in_sec", 1)
+	OTelConfigData.ExporterTimeout = cdb.GetOrDefaultInt("otel_exporter_time_in_sec", 30)
+	OTelConfigData.EnableRetry = cdb.GetOrDefaultBool("otel_enable_exporter_retry", false)
+	OTelConfigData.ResourceType = resourceType
+	OTelConfigData.OTelErrorReportingInterval = cdb.GetOrDefaultInt("otel_error_reporting_interval_in_sec", 60)
+	SetOTelIngestToken(cdb.GetOrDefaultString("otel_ingest_token", ""))
+}
+
 // Validation function to check whether pool name is configured or not
 func (config *OTelConfig) validate() error {
 	if len(config.PoolName) <= 0 {
@@ -67,6 +67,16 @@ func initializeOTelSDK(ctx context.Context) (shutdown func(ctx context.Context)
 	}
 	shutdownFuncs = append(shutdownFuncs, meterProvider.Shutdown)
 
+	//Setup the tracer provider exporting the CAL transactions
+	if TracesEnabled() {
+		tracesShutdown, tracesErr := InitTraces(ctx)
+		if tracesErr != nil {
+			handleErr(tracesErr)
+			return nil, err
+		}
+		shutdownFuncs = append(shutdownFuncs, tracesShutdown)
+	}
+
 	oTelErrorHandler := OTelErrorHandler{}
 	otel.SetErrorHandler(oTelErrorHandler)  //Register custom error handler
 	oTelErrorHandler.processOTelErrorsMap() //Spawn Go routine peridically process OTEL errors
@@ -0,0 +1,71 @@
+package otel
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	"github.com/paypal/hera/utility/logger"
+	otelconfig "github.com/paypal/hera/utility/logger/otel/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const SQLStatsMeterName = "occ-sql-stats"
+
+// SQL latency metric, the sqlhash dimension is bounded by mux to the top N sqlhashes, the rest are reported as SQLHashOther
+const (
+	SQLLatencyMetric = "sql_latency"
+	SQLHashDimName   = "sqlhash"
+	SQLStatusDimName = "status"
+	SQLHashOther     = "other"
+)
+
+var SQLLatencyBucket = []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000, 2000, 5000, 10000, 30000, 60000}
+
+type sqlMetrics struct {
+	hostname string
+	latency  metric.Float64Histogram
+}
+
+var registerSQLMetrics sync.Once
+var gSQLMetrics *sqlMetrics
+
+func initSQLMetrics() {
+	hostName, err := os.Hostname()
+	if err != nil {
+		logger.GetLogger().Log(logger.Alert, "Failed to fetch hostname for current container", err)
+	}
+	meter := otel.GetMeterProvider().Meter(SQLStatsMeterName, metric.WithInstrumentationVersion(OtelInstrumentationVersion))
+	latency, err := meter.Float64Histogram(
+		otelconfig.OTelConfigData.PopulateMetricNamePrefix(SQLLatencyMetric),
+		metric.WithDescription("SQL latency in milliseconds, per sqlhash"),
+		metric.WithUnit("ms"),
+		metric.WithExplicitBucketBoundaries(SQLLatencyBucket...),
+	)
+	if err != nil {
+		logger.GetLogger().Log(logger.Alert, "Failed to register histogram metric for sql latency", err)
+		return
+	}
+	gSQLMetrics = &sqlMetrics{hostname: hostName, latency: latency}
+}
+
+// RecordSQLLatency adds a SQL latency data point. The caller must bound the cardinality of sqlHash
+func RecordSQLLatency(ctx context.Context, sqlHash string, workerType int, shardId int, latencyMs float64, failed bool) {
+	registerSQLMetrics.Do(initSQLMetrics)
+	if gSQLMetrics == nil {
+		return
+	}
+	status := "ok"
+	if failed {
+		status = "error"
+	}
+	gSQLMetrics.latency.Record(ctx, latencyMs, metric.WithAttributes(
+		attribute.String(SQLHashDimName, sqlHash),
+		attribute.String(SQLStatusDimName, status),
+		attribute.String(WorkerType, WorkerTypeMap[workerType]),
+		attribute.Int(ShardId, shardId),
+		attribute.String(HostDimensionName, gSQLMetrics.hostname),
+	))
+}
@@ -0,0 +1,61 @@
+package otel
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	otellogger "github.com/paypal/hera/utility/logger/otel"
+	otelconfig "github.com/paypal/hera/utility/logger/otel/config"
+	"go.opentelemetry.io/otel"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestTraceExporterHTTP(t *testing.T) {
+	requests := make(chan *coltracepb.ExportTraceServiceRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != otellogger.DefaultTraceURLPath {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get(otellogger.IngestTokenHeader) != "token" {
+			t.Errorf("missing ingest token")
+		}
+		body, _ := io.ReadAll(r.Body)
+		request := &coltracepb.ExportTraceServiceRequest{}
+		if err := proto.Unmarshal(body, request); err != nil {
+			t.Error(err)
+		}
+		requests <- request
+	}))
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	tracePort, _ := strconv.Atoi(port)
+	otelconfig.OTelConfigData = &otelconfig.OTelConfig{Host: host, TracePort: tracePort, PoolName: "occ-testapp", ExporterTimeout: 5}
+	otelconfig.SetOTelIngestToken("token")
+
+	shutdown, err := otellogger.InitTraces(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "API CLIENT_SESSION")
+	_, child := otel.Tracer("test").Start(ctx, "EXEC sql")
+	child.End()
+	parent.End()
+	if err = shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	request := <-requests
+	spans := request.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if (spans[0].Name != "EXEC sql") || (string(spans[0].ParentSpanId) != string(spans[1].SpanId)) {
+		t.Errorf("unexpected spans %v", spans)
+	}
+}
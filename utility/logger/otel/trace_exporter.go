@@ -0,0 +1,103 @@
+package otel
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/paypal/hera/cal"
+	"github.com/paypal/hera/utility/logger"
+	"github.com/paypal/hera/utility/logger/otel/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// DefaultTraceURLPath is the OTLP HTTP path of the traces when "otel_agent_trace_uri" is not set
+const DefaultTraceURLPath = "/v1/traces"
+
+// InitTraces sets the tracer provider exporting the spans (the CAL transactions when "cal_handler" is otel)
+// to the agent at "otel_agent_host":"otel_agent_trace_port", using GRPC if "otel_agent_use_grpc_trace" is set
+func InitTraces(ctx context.Context) (shutdown func(ctx context.Context) error, err error) {
+	tracerProvider, err := newTracerProvider(ctx)
+	if err != nil {
+		logger.GetLogger().Log(logger.Alert, fmt.Sprintf("failed to initialize trace exporter, error %v", err))
+		return nil, err
+	}
+	otel.SetTracerProvider(tracerProvider)
+	return tracerProvider.Shutdown, nil
+}
+
+// TracesEnabled tells if the CAL transactions are exported as spans, i.e. "enable_otel" is set and "cal_handler"
+// is otel. Mux and the workers check it after the OTEL configuration is initialized
+func TracesEnabled() bool {
+	if (config.OTelConfigData == nil) || !config.OTelConfigData.Enabled {
+		return false
+	}
+	calClient := cal.GetCalClientInstance()
+	return (calClient != nil) && calClient.IsOTelHandler()
+}
+
+func newTracerProvider(ctx context.Context) (*sdktrace.TracerProvider, error) {
+	exporter, err := getTraceExporter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithResource(getResourceInfo(config.OTelConfigData.PoolName)),
+		sdktrace.WithBatcher(exporter),
+	), nil
+}
+
+func getTraceExporter(ctx context.Context) (sdktrace.SpanExporter, error) {
+	if config.OTelConfigData.OtelTraceGRPC {
+		return newGRPCTraceExporter(ctx)
+	}
+	return newHTTPTraceExporter(ctx)
+}
+
+// newHTTPTraceExporter initializes the "otlptracehttp" exporter, sending the spans using the OpenTelemetry
+// Protocol (OTLP) over HTTP
+func newHTTPTraceExporter(ctx context.Context) (sdktrace.SpanExporter, error) {
+	headers := make(map[string]string)
+	headers[IngestTokenHeader] = config.GetOTelIngestToken()
+
+	path := config.OTelConfigData.TraceURLPath
+	if len(path) == 0 {
+		path = DefaultTraceURLPath
+	}
+	options := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(fmt.Sprintf("%s:%d", config.OTelConfigData.Host, config.OTelConfigData.TracePort)),
+		otlptracehttp.WithTimeout(time.Duration(config.OTelConfigData.ExporterTimeout) * time.Second),
+		otlptracehttp.WithCompression(otlptracehttp.NoCompression),
+		otlptracehttp.WithHeaders(headers),
+		// the spans are not retried, same as the metrics
+		otlptracehttp.WithRetry(otlptracehttp.RetryConfig{Enabled: false}),
+		otlptracehttp.WithURLPath(path),
+	}
+	if !config.OTelConfigData.UseTls {
+		options = append(options, otlptracehttp.WithInsecure()) //Since agent is local
+	}
+	return otlptracehttp.New(ctx, options...)
+}
+
+// newGRPCTraceExporter initializes the "otlptracegrpc" exporter, sending the spans using the OpenTelemetry
+// Protocol (OTLP) over GRPC
+func newGRPCTraceExporter(ctx context.Context) (sdktrace.SpanExporter, error) {
+	headers := make(map[string]string)
+	headers[IngestTokenHeader] = config.GetOTelIngestToken()
+
+	options := []otlptracegrpc.Option{
+		otlptracegrpc.WithEndpoint(fmt.Sprintf("%s:%d", config.OTelConfigData.Host, config.OTelConfigData.TracePort)),
+		otlptracegrpc.WithTimeout(time.Duration(config.OTelConfigData.ExporterTimeout) * time.Second),
+		otlptracegrpc.WithHeaders(headers),
+		otlptracegrpc.WithReconnectionPeriod(time.Duration(5) * time.Second),
+		// the spans are not retried, same as the metrics
+		otlptracegrpc.WithRetry(otlptracegrpc.RetryConfig{Enabled: false}),
+	}
+	if !config.OTelConfigData.UseTls {
+		options = append(options, otlptracegrpc.WithInsecure()) //Since agent is local
+	}
+	return otlptracegrpc.New(ctx, options...)
+}
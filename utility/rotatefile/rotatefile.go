@@ -0,0 +1,164 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rotatefile implements an append-only file rotated by size. The file can be shared by
+// several processes (mux and workers): only the owner rotates it, the other processes re-open the
+// file when they detect it was renamed.
+package rotatefile
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// how often a non-owner checks if the file was rotated
+const reopenCheckInterval = time.Second
+
+// File is an io.Writer appending to a file, rotated when it grows over maxSize
+type File struct {
+	sync.Mutex
+	name       string
+	maxSize    int64
+	maxBackups int
+	owner      bool
+	file       *os.File
+	size       int64
+	lastCheck  time.Time
+	// called each time the file is (re)opened
+	onOpen func(*os.File)
+}
+
+// Open opens (or creates) the file for appending. If owner is true the file is rotated when its size goes
+// over maxSize bytes, keeping maxBackups old files named <name>.1 ... <name>.<maxBackups>. A maxSize <= 0
+// disables the rotation.
+func Open(name string, maxSize int64, maxBackups int, owner bool) (*File, error) {
+	f := &File{name: name, maxSize: maxSize, maxBackups: maxBackups, owner: owner}
+	err := f.open()
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *File) open() error {
+	file, err := os.OpenFile(f.name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	f.file = file
+	f.size = 0
+	stat, err := file.Stat()
+	if err == nil {
+		f.size = stat.Size()
+	}
+	f.lastCheck = time.Now()
+	if f.onOpen != nil {
+		f.onOpen(file)
+	}
+	return nil
+}
+
+// SetOnOpen sets a function called with the current file and then each time the file is re-opened, for
+// example to redirect stderr to it
+func (f *File) SetOnOpen(onOpen func(*os.File)) {
+	f.Lock()
+	defer f.Unlock()
+	f.onOpen = onOpen
+	if f.file != nil {
+		onOpen(f.file)
+	}
+}
+
+// Write appends p to the file. p should be a complete record (i.e. a line), so that records from
+// different processes do not interleave
+func (f *File) Write(p []byte) (int, error) {
+	f.Lock()
+	defer f.Unlock()
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.owner {
+		if f.maxSize > 0 {
+			// the other processes append to the file too
+			if stat, err := f.file.Stat(); err == nil {
+				f.size = stat.Size()
+			}
+		}
+		if (f.maxSize > 0) && (f.size+int64(len(p)) > f.maxSize) {
+			err := f.rotate()
+			if err != nil {
+				return 0, err
+			}
+		}
+	} else {
+		f.reopenIfMoved()
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate shifts the backups, renames the current file to <name>.1 and re-opens
+func (f *File) rotate() error {
+	f.file.Close()
+	f.file = nil
+	if f.maxBackups > 0 {
+		for i := f.maxBackups - 1; i > 0; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", f.name, i), fmt.Sprintf("%s.%d", f.name, i+1))
+		}
+		os.Rename(f.name, f.name+".1")
+	} else {
+		os.Remove(f.name)
+	}
+	return f.open()
+}
+
+// reopenIfMoved re-opens the file if the owner rotated it
+func (f *File) reopenIfMoved() {
+	now := time.Now()
+	if now.Sub(f.lastCheck) < reopenCheckInterval {
+		return
+	}
+	f.lastCheck = now
+	pathStat, err := os.Stat(f.name)
+	if err == nil {
+		fileStat, err := f.file.Stat()
+		if (err == nil) && os.SameFile(pathStat, fileStat) {
+			return
+		}
+	}
+	old := f.file
+	if f.open() == nil {
+		old.Close()
+	} else {
+		f.file = old
+	}
+}
+
+// Close closes the file
+func (f *File) Close() error {
+	f.Lock()
+	defer f.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
@@ -0,0 +1,105 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rotatefile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRotate(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "test.log")
+	owner, err := Open(name, 10, 2, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer owner.Close()
+	other, err := Open(name, 10, 2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+
+	for _, line := range []string{"aaaaaaa\n", "bbbbbbb\n", "ccccccc\n", "ddddddd\n"} {
+		owner.Write([]byte(line))
+	}
+	data, _ := os.ReadFile(name)
+	if string(data) != "ddddddd\n" {
+		t.Errorf("unexpected current file %q", data)
+	}
+	data, _ = os.ReadFile(name + ".2")
+	if string(data) != "bbbbbbb\n" {
+		t.Errorf("unexpected backup %q", data)
+	}
+	if _, err = os.Stat(name + ".3"); err == nil {
+		t.Error("too many backups")
+	}
+
+	// the non owner follows the rotation
+	other.lastCheck = time.Time{}
+	other.Write([]byte("x\n"))
+	data, _ = os.ReadFile(name)
+	if string(data) != "ddddddd\nx\n" {
+		t.Errorf("non owner did not re-open %q", data)
+	}
+}
+
+func TestOnOpen(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "test.log")
+	f, err := Open(name, 10, 1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	opened := 0
+	f.SetOnOpen(func(*os.File) { opened++ })
+	f.Write([]byte("aaaaaaa\n"))
+	f.Write([]byte("bbbbbbb\n"))
+	if opened != 2 {
+		t.Errorf("expected the current file and the one after the rotation, got %d", opened)
+	}
+}
+
+func TestRotateOtherWrites(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "test.log")
+	owner, err := Open(name, 20, 1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer owner.Close()
+	other, err := Open(name, 20, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+
+	// the file grows with the writes of the other process, the owner rotates it
+	other.Write([]byte("aaaaaaa\n"))
+	other.Write([]byte("bbbbbbb\n"))
+	owner.Write([]byte("ccccccc\n"))
+	data, _ := os.ReadFile(name)
+	if string(data) != "ccccccc\n" {
+		t.Errorf("unexpected current file %q", data)
+	}
+	data, _ = os.ReadFile(name + ".1")
+	if string(data) != "aaaaaaa\nbbbbbbb\n" {
+		t.Errorf("unexpected backup %q", data)
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package slowlog writes the slow query log, shared by mux and the workers. Mux logs the request side
+// (backlog wait, shard, pool type, client) and the workers log the database side (execute and fetch time,
+// rows, SQL text and binds). Both entries carry the worker pid and the request id so they can be joined.
+package slowlog
+
+import (
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/paypal/hera/utility/rotatefile"
+)
+
+// Sources of the entries
+const (
+	SourceMux    = "mux"
+	SourceWorker = "worker"
+)
+
+// RedactedValue replaces the bind values when the binds are not logged
+const RedactedValue = "<redacted>"
+
+// Bind is a bind variable name and its value
+type Bind struct {
+	Name  string
+	Value string
+}
+
+// Entry is a slow query log record. Numeric fields with negative value are unknown and not logged
+type Entry struct {
+	Source        string
+	WorkerPid     int
+	RqID          uint32
+	SQLHash       uint32
+	SQL           string
+	Binds         []Bind
+	ShardID       int
+	PoolType      string
+	BacklogWaitMs int64
+	ExecMs        int64
+	FetchMs       int64
+	TotalMs       int64
+	Rows          int64
+	Client        string
+	Error         string
+}
+
+// NewEntry creates an entry with all the numeric fields unknown
+func NewEntry(source string) *Entry {
+	return &Entry{Source: source, WorkerPid: -1, ShardID: -1, BacklogWaitMs: -1, ExecMs: -1, FetchMs: -1, TotalMs: -1, Rows: -1}
+}
+
+var gFile atomic.Value // *rotatefile.File
+
+// Init opens the slow query log. owner must be true for only one process (mux), the one rotating the file
+func Init(fileName string, maxSizeMB int, maxBackups int, owner bool) error {
+	file, err := rotatefile.Open(fileName, int64(maxSizeMB)*1024*1024, maxBackups, owner)
+	if err != nil {
+		return err
+	}
+	gFile.Store(file)
+	return nil
+}
+
+// Enabled tells if the slow query log was initialized
+func Enabled() bool {
+	return gFile.Load() != nil
+}
+
+// Log writes the entry to the slow query log, it is a no-op if the log was not initialized
+func Log(entry *Entry) error {
+	file, ok := gFile.Load().(*rotatefile.File)
+	if !ok {
+		return nil
+	}
+	_, err := file.Write(entry.Format(time.Now()))
+	return err
+}
+
+func appendInt(buf []byte, name string, val int64) []byte {
+	if val < 0 {
+		return buf
+	}
+	buf = append(buf, ' ')
+	buf = append(buf, name...)
+	buf = append(buf, '=')
+	return strconv.AppendInt(buf, val, 10)
+}
+
+func appendStr(buf []byte, name string, val string) []byte {
+	if len(val) == 0 {
+		return buf
+	}
+	buf = append(buf, ' ')
+	buf = append(buf, name...)
+	buf = append(buf, '=')
+	return strconv.AppendQuote(buf, val)
+}
+
+// Format serializes the entry as one line of name=value pairs, the SQL text last
+func (entry *Entry) Format(tm time.Time) []byte {
+	buf := make([]byte, 0, 256+len(entry.SQL))
+	buf = tm.AppendFormat(buf, "2006-01-02T15:04:05.000000Z07:00")
+	buf = appendStr(buf, "src", entry.Source)
+	buf = appendInt(buf, "wpid", int64(entry.WorkerPid))
+	buf = appendInt(buf, "rqid", int64(entry.RqID))
+	buf = appendInt(buf, "sqlhash", int64(entry.SQLHash))
+	buf = appendInt(buf, "shard", int64(entry.ShardID))
+	buf = appendStr(buf, "pool", entry.PoolType)
+	buf = appendInt(buf, "backlog_ms", entry.BacklogWaitMs)
+	buf = appendInt(buf, "exec_ms", entry.ExecMs)
+	buf = appendInt(buf, "fetch_ms", entry.FetchMs)
+	buf = appendInt(buf, "total_ms", entry.TotalMs)
+	buf = appendInt(buf, "rows", entry.Rows)
+	buf = appendStr(buf, "client", entry.Client)
+	buf = appendStr(buf, "err", entry.Error)
+	if len(entry.Binds) > 0 {
+		var sb strings.Builder
+		for i, bind := range entry.Binds {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			sb.WriteString(bind.Name)
+			sb.WriteByte('=')
+			sb.WriteString(bind.Value)
+		}
+		buf = appendStr(buf, "binds", sb.String())
+	}
+	buf = appendStr(buf, "sql", entry.SQL)
+	return append(buf, '\n')
+}
+
+func isIdentChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' || c == '$' || c == ':'
+}
+
+// NormalizeSQL collapses the white spaces and replaces the string and numeric literals with '?', so that
+// the same statement with different literals has the same text
+func NormalizeSQL(sql string) string {
+	var sb strings.Builder
+	sb.Grow(len(sql))
+	space := false
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			continue
+		case c == '\'':
+			// string literal, '' is an escaped quote
+			i++
+			for i < len(sql) {
+				if sql[i] == '\'' {
+					if (i+1 < len(sql)) && (sql[i+1] == '\'') {
+						i++
+					} else {
+						break
+					}
+				}
+				i++
+			}
+			c = '?'
+		case (c >= '0' && c <= '9') && ((i == 0) || !isIdentChar(sql[i-1])):
+			for (i+1 < len(sql)) && ((sql[i+1] >= '0' && sql[i+1] <= '9') || sql[i+1] == '.') {
+				i++
+			}
+			c = '?'
+		}
+		if space {
+			if sb.Len() > 0 {
+				sb.WriteByte(' ')
+			}
+			space = false
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
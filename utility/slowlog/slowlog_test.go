@@ -0,0 +1,75 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slowlog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalizeSQL(t *testing.T) {
+	tests := map[string]string{
+		"select  *\n from t where id=:id":                 "select * from t where id=:id",
+		"select * from t2 where a = 5 and b='x''y' ":      "select * from t2 where a = ? and b=?",
+		"  update t set c=12.5, d='abc' where col1 = :p1": "update t set c=?, d=? where col1 = :p1",
+		"insert into t(a) values (1,2)":                   "insert into t(a) values (?,?)",
+	}
+	for in, expected := range tests {
+		out := NormalizeSQL(in)
+		if out != expected {
+			t.Errorf("NormalizeSQL(%q): expected %q got %q", in, expected, out)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	entry := NewEntry(SourceWorker)
+	entry.WorkerPid = 1234
+	entry.RqID = 7
+	entry.SQLHash = 42
+	entry.ExecMs = 1500
+	entry.Rows = 3
+	entry.Binds = []Bind{{Name: ":id", Value: RedactedValue}}
+	entry.SQL = "select 1 from dual"
+	line := string(entry.Format(time.Unix(0, 0).UTC()))
+	expected := `1970-01-01T00:00:00.000000Z src="worker" wpid=1234 rqid=7 sqlhash=42 exec_ms=1500 rows=3 binds=":id=<redacted>" sql="select 1 from dual"` + "\n"
+	if line != expected {
+		t.Errorf("expected %s got %s", expected, line)
+	}
+}
+
+func TestLog(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "slow.log")
+	err := Init(name, 1, 1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := NewEntry(SourceMux)
+	entry.SQL = "select 2"
+	Log(entry)
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `sql="select 2"`) {
+		t.Errorf("entry not logged: %s", data)
+	}
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"crypto/tls"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
+	"net"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -12,7 +15,9 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/paypal/hera/common"
 	"github.com/paypal/hera/config"
+	"github.com/paypal/hera/utility/encoding/netstring"
 	"github.com/paypal/hera/utility/logger"
 	"github.com/paypal/hera/watchdoglib"
 )
@@ -49,7 +54,7 @@ func main() {
 	}
 
 	//Initialize Statelog in watchdog. watchdog will have reference for statelog's fd. So statelog won't get exit if mux dies.
-	stateLogErr := initializeStateLog()
+	stateLog, stateLogErr := initializeStateLog()
 	if stateLogErr != nil {
 		logger.GetLogger().Log(logger.Alert, "watchdog: failed to initialize statelog:", stateLogErr.Error())
 	}
@@ -70,6 +75,9 @@ func main() {
 	muxProcess := []string{muxpath, "--name", *namePtr}
 	logger.GetLogger().Log(logger.Alert, "Starting watchdog process.")
 	watcher := watchdoglib.NewWatchdog(muxProcess)
+	watcher.DrainTimeout = time.Duration(configData.GetOrDefaultInt("drain_timeout_sec", 60)) * time.Second
+	watcher.Policy = restartPolicy(configData)
+	watcher.StateLog = stateLog
 	//Start watcher
 	watcher.Start()
 	// give it a second to get started
@@ -81,6 +89,9 @@ func main() {
 		logger.GetLogger().Log(logger.Alert, "watchdog process got killed, sent stop signal to its children process as well.")
 	case <-watcher.Done:
 		logger.GetLogger().Log(logger.Alert, "watchdog exited.")
+		if watcher.GetErr() == watchdoglib.ErrRestartLimit {
+			os.Exit(2)
+		}
 	}
 }
 
@@ -108,7 +119,7 @@ func initializeConfig() (config.Config, error) {
 
 	filename := currentDir + "hera.txt"
 
-	cdb, err := config.NewTxtConfig(filename)
+	cdb, err := config.NewFileConfig(filename)
 	if err != nil {
 		return nil, err
 	}
@@ -117,15 +128,71 @@ func initializeConfig() (config.Config, error) {
 	logFile = currentDir + logFile
 	logLevel := cdb.GetOrDefaultInt("log_level", logger.Info)
 
-	err = logger.CreateLogger(logFile, "PROXY", int32(logLevel))
+	err = logger.CreateLoggerWithOptions(logFile, "PROXY", int32(logLevel), logger.OptionsFromConfig(cdb, false))
 	if err != nil {
 		return nil, err
 	}
 	return cdb, nil
 }
 
+// restartPolicy reads the mux restart policy from the config
+func restartPolicy(cdb config.Config) watchdoglib.RestartPolicy {
+	policy := watchdoglib.RestartPolicy{
+		BackoffMin:      time.Duration(cdb.GetOrDefaultInt("watchdog_backoff_min_ms", 1000)) * time.Millisecond,
+		BackoffMax:      time.Duration(cdb.GetOrDefaultInt("watchdog_backoff_max_ms", 60000)) * time.Millisecond,
+		MaxRestarts:     cdb.GetOrDefaultInt("watchdog_max_restarts", 0),
+		RestartWindow:   time.Duration(cdb.GetOrDefaultInt("watchdog_restart_window_sec", 600)) * time.Second,
+		ProbeInterval:   time.Duration(cdb.GetOrDefaultInt("watchdog_probe_interval_sec", 0)) * time.Second,
+		ProbeStartDelay: time.Duration(cdb.GetOrDefaultInt("watchdog_probe_start_delay_sec", 60)) * time.Second,
+		ProbeFailures:   cdb.GetOrDefaultInt("watchdog_probe_failures", 3),
+	}
+	port, err := cdb.GetInt("bind_port")
+	if (err == nil) && (policy.ProbeInterval > 0) {
+		addr := fmt.Sprintf("127.0.0.1:%d", port)
+		timeout := time.Duration(cdb.GetOrDefaultInt("watchdog_probe_timeout_ms", 2000)) * time.Millisecond
+		useTLS := len(cdb.GetOrDefaultString("key_file", "")) > 0
+		policy.Probe = func() error {
+			return pingMux(addr, useTLS, timeout)
+		}
+	}
+	return policy
+}
+
+// pingMux sends the ping command to mux, which answers it without a worker
+func pingMux(addr string, useTLS bool, timeout time.Duration) error {
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: timeout}
+	if useTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{InsecureSkipVerify: true})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+	_, err = conn.Write(netstring.NewNetstringFrom(common.CmdServerPingCommand, nil).Serialized)
+	if err != nil {
+		return err
+	}
+	ns, err := netstring.NewNetstring(conn)
+	if err == io.EOF {
+		// bounced by mux because it is at capacity, or draining
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if (ns.Cmd != common.CmdServerAlive) && (ns.Cmd != common.CmdServerDraining) {
+		return fmt.Errorf("unexpected ping response %d", ns.Cmd)
+	}
+	return nil
+}
+
 //Initializes state-log
-func initializeStateLog() error {
+func initializeStateLog() (*log.Logger, error) {
 	currentDir, absperr := filepath.Abs(filepath.Dir(os.Args[0]))
 	if absperr != nil {
 		currentDir = "./"
@@ -137,11 +204,10 @@ func initializeStateLog() error {
 
 	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	// format backward compatible with C++
-	log.New(file, "" /*log.Ldate|log.Ltime*/, 0)
-	return nil
+	return log.New(file, "" /*log.Ldate|log.Ltime*/, 0), nil
 }
 
 //Write watchdog process details to a file
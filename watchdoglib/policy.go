@@ -0,0 +1,107 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watchdoglib
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"syscall"
+	"time"
+
+	"github.com/paypal/hera/utility/logger"
+)
+
+// ErrRestartLimit is the watchdog error when the child restarted more than MaxRestarts times in RestartWindow
+var ErrRestartLimit = errors.New("child restart limit reached")
+
+// the backoff is reset when the child ran at least this long
+const stableUptime = time.Minute
+
+// RestartPolicy controls how the watchdog restarts its child. The zero value restarts right away, without
+// limit and without probing the child
+type RestartPolicy struct {
+	// the delay before restarting doubles after each crash, from BackoffMin up to BackoffMax, with jitter
+	BackoffMin time.Duration
+	BackoffMax time.Duration
+	// the watchdog exits with ErrRestartLimit when the child restarted MaxRestarts times in RestartWindow
+	MaxRestarts   int
+	RestartWindow time.Duration
+	// Probe is called every ProbeInterval, once the child runs for ProbeStartDelay. The child is killed
+	// after ProbeFailures consecutive errors
+	Probe           func() error
+	ProbeInterval   time.Duration
+	ProbeStartDelay time.Duration
+	ProbeFailures   int
+}
+
+// backoff returns the delay before the restart following n consecutive crashes. It is between half and
+// the full exponential delay
+func (p *RestartPolicy) backoff(n int) time.Duration {
+	if p.BackoffMin <= 0 {
+		return 0
+	}
+	delay := p.BackoffMax
+	if (n < 30) && (p.BackoffMin<<uint(n) < p.BackoffMax) {
+		delay = p.BackoffMin << uint(n)
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// allowRestart records a restart at now, returning false if it exceeds the restart budget
+func (w *Watchdog) allowRestart(now time.Time) bool {
+	if w.Policy.MaxRestarts <= 0 {
+		return true
+	}
+	kept := w.restarts[:0]
+	for _, t := range w.restarts {
+		if now.Sub(t) < w.Policy.RestartWindow {
+			kept = append(kept, t)
+		}
+	}
+	w.restarts = kept
+	if len(w.restarts) >= w.Policy.MaxRestarts {
+		return false
+	}
+	w.restarts = append(w.restarts, now)
+	return true
+}
+
+// exitReason describes how the child exited
+func exitReason(ws syscall.WaitStatus) string {
+	switch {
+	case ws.Exited():
+		return fmt.Sprintf("exit status %d", ws.ExitStatus())
+	case ws.Signaled() && ws.CoreDump():
+		return fmt.Sprintf("signal %v (core dumped)", ws.Signal())
+	case ws.Signaled():
+		return fmt.Sprintf("signal %v", ws.Signal())
+	}
+	return fmt.Sprintf("wait status %v", ws)
+}
+
+// recordState logs the child life cycle events, to the watchdog log and to the state log
+func (w *Watchdog) recordState(msg string) {
+	logger.GetLogger().Log(logger.Alert, "watchdog:", msg)
+	if w.StateLog != nil {
+		w.StateLog.Println(time.Now().Format("01/02/2006 15:04:05: ") + "watchdog: " + msg)
+	}
+}
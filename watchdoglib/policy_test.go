@@ -0,0 +1,73 @@
+package watchdoglib
+
+import (
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRestartBackoff(t *testing.T) {
+	policy := RestartPolicy{BackoffMin: 100 * time.Millisecond, BackoffMax: time.Second}
+	for n, max := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		max *= time.Millisecond
+		for i := 0; i < 20; i++ {
+			delay := policy.backoff(n)
+			if (delay < max/2) || (delay > max) {
+				t.Fatalf("backoff %d: %v not in [%v, %v]", n, delay, max/2, max)
+			}
+		}
+	}
+	if policy.backoff(100) > time.Second {
+		t.Error("backoff above the max")
+	}
+	if (&RestartPolicy{}).backoff(3) != 0 {
+		t.Error("no backoff expected by default")
+	}
+}
+
+func TestAllowRestart(t *testing.T) {
+	w := &Watchdog{Policy: RestartPolicy{MaxRestarts: 2, RestartWindow: time.Minute}}
+	now := time.Now()
+	if !w.allowRestart(now) || !w.allowRestart(now.Add(time.Second)) {
+		t.Fatal("restarts within the budget refused")
+	}
+	if w.allowRestart(now.Add(2 * time.Second)) {
+		t.Error("restart over the budget allowed")
+	}
+	// the first restarts are out of the window
+	if !w.allowRestart(now.Add(61 * time.Second)) {
+		t.Error("restart refused after the window")
+	}
+}
+
+func TestExitReason(t *testing.T) {
+	err := exec.Command("/bin/sh", "-c", "exit 3").Run()
+	ws := err.(*exec.ExitError).Sys().(syscall.WaitStatus)
+	if exitReason(ws) != "exit status 3" {
+		t.Errorf("unexpected reason %s", exitReason(ws))
+	}
+	err = exec.Command("/bin/sh", "-c", "kill -9 $$").Run()
+	ws = err.(*exec.ExitError).Sys().(syscall.WaitStatus)
+	if exitReason(ws) != "signal killed" {
+		t.Errorf("unexpected reason %s", exitReason(ws))
+	}
+}
+
+func TestRestartLimit(t *testing.T) {
+	watcher := NewWatchdog([]string{"/bin/false"})
+	watcher.Policy = RestartPolicy{BackoffMin: 10 * time.Millisecond, BackoffMax: 50 * time.Millisecond,
+		MaxRestarts: 3, RestartWindow: time.Minute}
+	watcher.Start()
+	select {
+	case <-watcher.Done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("watchdog did not give up")
+	}
+	if watcher.GetErr() != ErrRestartLimit {
+		t.Errorf("expected %v, got %v", ErrRestartLimit, watcher.GetErr())
+	}
+	if watcher.processData.startCount != 4 {
+		t.Errorf("expected 4 starts, got %d", watcher.processData.startCount)
+	}
+}
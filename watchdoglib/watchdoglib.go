@@ -4,6 +4,7 @@ package watchdoglib
 
 import (
 	"fmt"
+	"log"
 	"os"
 	"os/exec"
 	"os/signal"
@@ -14,6 +15,19 @@ import (
 	"github.com/paypal/hera/utility/logger"
 )
 
+// ExitUpgraded is the exit code of a mux which handed off its listener to a new mux. The watchdog adopts
+// the new mux instead of starting another one
+const ExitUpgraded = 98
+
+// ExitDrained is the exit code of a mux which drained its connections for maintenance. The watchdog exits
+const ExitDrained = 97
+
+// how long after the drain timeout the watchdog waits before terminating the mux
+const drainGracePeriod = 10 * time.Second
+
+// how often the watchdog logs the drain progress
+const drainReportInterval = 10 * time.Second
+
 type ProcessData struct {
 	currPid    int
 	startCount int64
@@ -23,6 +37,7 @@ type ProcessData struct {
 	err                   error
 	processStartFailed    bool
 	cmd                   *exec.Cmd
+	startTime             time.Time
 }
 
 type Watchdog struct {
@@ -35,6 +50,18 @@ type Watchdog struct {
 	processData      *ProcessData
 	shutdown         bool
 	exitAfterReaping bool
+	// DrainTimeout is the "drain_timeout_sec" of the mux
+	DrainTimeout time.Duration
+	// when the drain started, zero if not draining
+	drainStart time.Time
+	// Policy is set before Start
+	Policy RestartPolicy
+	// StateLog records the exits and restarts of the child, if set
+	StateLog *log.Logger
+	// restart times within the restart window
+	restarts []time.Time
+	// consecutive restarts, for the backoff
+	crashCount int
 }
 
 /*
@@ -141,23 +168,57 @@ func (processData *ProcessData) startProcess(parentProcessId int) error {
 	}
 	processData.cmd = processCommand
 	processData.currPid = processData.cmd.Process.Pid
+	processData.startTime = time.Now()
 	processData.startCount++
 	logger.GetLogger().Log(logger.Alert, fmt.Sprintf("start number %d: watchdog process: %d started new child process '%s' and pid: %d", processData.startCount, parentProcessId, processData.PathToChildExecutable, processData.currPid))
 	return nil
 }
 
+// adoptUpgraded makes the new mux, started by the old one and re-parented to the watchdog, the watched child
+func (w *Watchdog) adoptUpgraded(oldPid int) bool {
+	newPid := 0
+	for _, pid := range groupLeaderChildren(w.pid) {
+		if pid != oldPid {
+			newPid = pid
+			break
+		}
+	}
+	if newPid == 0 {
+		logger.GetLogger().Log(logger.Alert, fmt.Sprintf("watchdog: mux %d exited after upgrade but the new mux was not found", oldPid))
+		return false
+	}
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	// the workers of the old mux
+	syscall.Kill(-oldPid, syscall.SIGTERM)
+	w.processData.currPid = newPid
+	w.processData.startTime = time.Now()
+	logger.GetLogger().Log(logger.Alert, fmt.Sprintf("watchdog process: %d adopted upgraded mux process: %d, previous: %d", w.pid, newPid, oldPid))
+	return true
+}
+
 //Start watchdog process
 func (w *Watchdog) Start() {
 	signalChild := make(chan os.Signal, 1)
+	signalUpgrade := make(chan os.Signal, 1)
+	signalDrain := make(chan os.Signal, 1)
 
 	signal.Ignore(syscall.SIGPIPE)
 	signal.Notify(signalChild, syscall.SIGCHLD)
+	signal.Notify(signalUpgrade, syscall.SIGUSR2)
+	signal.Notify(signalDrain, syscall.SIGUSR1)
+	err := setChildSubreaper()
+	if err != nil {
+		logger.GetLogger().Log(logger.Warning, "watchdog can't be child subreaper, mux upgrade not supported:", err)
+	}
 
 	w.pid = os.Getpid()
 	//var ws syscall.WaitStatus
 	go func() {
 		defer func() {
 			signal.Stop(signalChild) // reverse the effect of the above Notify()
+			signal.Stop(signalUpgrade)
+			signal.Stop(signalDrain)
 			w.mut.Lock()
 			defer w.mut.Unlock()
 			if w.processData != nil {
@@ -185,6 +246,18 @@ func (w *Watchdog) Start() {
 		}
 
 		var ws syscall.WaitStatus
+		var drainTick <-chan time.Time
+		// set while waiting to restart the child
+		var restartTimer <-chan time.Time
+		var probeTick <-chan time.Time
+		probeResult := make(chan error, 1)
+		probeInFlight := false
+		probeFailures := 0
+		if (w.Policy.Probe != nil) && (w.Policy.ProbeInterval > 0) {
+			ticker := time.NewTicker(w.Policy.ProbeInterval)
+			defer ticker.Stop()
+			probeTick = ticker.C
+		}
 	reaploop:
 		for {
 			select {
@@ -192,18 +265,85 @@ func (w *Watchdog) Start() {
 				logger.GetLogger().Log(logger.Info, "request to stop watchdog noted, exiting watchdog.start() loop")
 				w.Done <- true
 				return
+			case <-signalUpgrade:
+				if w.processData.currPid == 0 {
+					logger.GetLogger().Log(logger.Alert, "watchdog: child not running, ignoring upgrade request")
+					continue reaploop
+				}
+				// the mux does the upgrade
+				logger.GetLogger().Log(logger.Alert, fmt.Sprintf("watchdog forwarding upgrade request to mux: %d", w.processData.currPid))
+				syscall.Kill(w.processData.currPid, syscall.SIGUSR2)
+			case <-signalDrain:
+				if !w.drainStart.IsZero() {
+					continue reaploop
+				}
+				if w.processData.currPid == 0 {
+					w.recordState("drain requested while the child is not running, exiting")
+					return
+				}
+				// the mux drains the connections and exits, then the watchdog exits
+				w.drainStart = time.Now()
+				ticker := time.NewTicker(drainReportInterval)
+				defer ticker.Stop()
+				drainTick = ticker.C
+				logger.GetLogger().Log(logger.Alert, fmt.Sprintf("watchdog forwarding drain request to mux: %d, drain timeout: %v", w.processData.currPid, w.DrainTimeout))
+				syscall.Kill(w.processData.currPid, syscall.SIGUSR1)
+			case <-drainTick:
+				elapsed := time.Since(w.drainStart).Round(time.Second)
+				if elapsed > w.DrainTimeout+drainGracePeriod {
+					logger.GetLogger().Log(logger.Alert, fmt.Sprintf("watchdog: mux %d did not drain in %v, terminating it", w.processData.currPid, elapsed))
+					syscall.Kill(-w.processData.currPid, syscall.SIGTERM)
+				} else {
+					logger.GetLogger().Log(logger.Alert, fmt.Sprintf("watchdog: drain in progress for %v, mux: %d", elapsed, w.processData.currPid))
+				}
+			case <-restartTimer:
+				restartTimer = nil
+				probeFailures = 0
+				w.mut.Lock()
+				startError := w.processData.startProcess(w.pid)
+				w.mut.Unlock()
+				if startError != nil {
+					return
+				}
+			case <-probeTick:
+				if probeInFlight || (w.processData.currPid == 0) || !w.drainStart.IsZero() ||
+					(time.Since(w.processData.startTime) < w.Policy.ProbeStartDelay) {
+					continue reaploop
+				}
+				probeInFlight = true
+				go func() {
+					probeResult <- w.Policy.Probe()
+				}()
+			case err := <-probeResult:
+				probeInFlight = false
+				if (err == nil) || (w.processData.currPid == 0) {
+					probeFailures = 0
+					continue reaploop
+				}
+				probeFailures++
+				logger.GetLogger().Log(logger.Warning, fmt.Sprintf("watchdog: liveness probe %d of child %d failed: %v", probeFailures, w.processData.currPid, err))
+				if probeFailures >= w.Policy.ProbeFailures {
+					w.recordState(fmt.Sprintf("child %d failed %d liveness probes (%v), killing it", w.processData.currPid, probeFailures, err))
+					syscall.Kill(-w.processData.currPid, syscall.SIGKILL)
+					probeFailures = 0
+				}
 			case <-signalChild:
+				// while waiting to restart the child, the orphans are still reaped
 				logger.GetLogger().Log(logger.Debug, "got signal <-signalChild")
 				for i := 0; i < 1000; i++ {
-					pid, err := syscall.Wait4(-w.processData.currPid, &ws, syscall.WNOHANG, nil)
+					// as child subreaper, the watchdog also reaps the processes orphaned by the mux, e.g. its
+					// workers after a crash or an upgrade
+					pid, err := syscall.Wait4(-1, &ws, syscall.WNOHANG, nil)
 					// pid > 0 => pid is the ID of the child that died, but
 					//  there could be other children that are signalling us
 					//  and not the one we in particular are waiting for.
-					// pid -1 && errno == ECHILD => no new status children
+					// pid -1 && errno == ECHILD => no children left
 					// pid -1 && errno != ECHILD => syscall interupped by signal
 					// pid == 0 => no more children to wait for.
 					logger.GetLogger().Log(logger.Info, fmt.Sprintf(" pid=%v  ws=%v and err == %v", pid, ws, err))
 					switch {
+					case err == syscall.ECHILD:
+						continue reaploop
 					case err != nil:
 						err = fmt.Errorf("wait4() got error back: '%s' and ws:%v", err, ws)
 						logger.GetLogger().Log(logger.Alert, fmt.Sprintf("warning in reaploop, wait4 returned error: '%s'. ws=%v", err, ws))
@@ -211,26 +351,57 @@ func (w *Watchdog) Start() {
 						continue reaploop
 					case pid > 0:
 						if pid == w.processData.currPid {
+							if ws.Exited() && (ws.ExitStatus() == ExitUpgraded) && w.adoptUpgraded(pid) {
+								continue reaploop
+							}
+							if !w.drainStart.IsZero() || (ws.Exited() && (ws.ExitStatus() == ExitDrained)) {
+								w.mut.Lock()
+								syscall.Kill(-pid, syscall.SIGTERM)
+								w.recordState(fmt.Sprintf("child %d drained (%s), exiting", pid, exitReason(ws)))
+								w.processData.currPid = 0
+								w.processData.cmd = nil
+								w.mut.Unlock()
+								return
+							}
 							w.mut.Lock()
 							//Sending kill
 							syscall.Kill(-pid, syscall.SIGTERM)
 							logger.GetLogger().Log(logger.Alert, fmt.Sprintf("watchdog saw its child pid: %d, process '%s' finish with waitstatus: %v.", pid, w.processData.PathToChildExecutable, ws))
 							w.processData.currPid = 0
+							w.processData.cmd = nil
+							w.mut.Unlock()
+							uptime := time.Since(w.processData.startTime).Round(time.Millisecond)
+							if !w.allowRestart(time.Now()) {
+								w.recordState(fmt.Sprintf("child %d exited (%s) after %v, %d restarts in %v, giving up",
+									pid, exitReason(ws), uptime, w.Policy.MaxRestarts, w.Policy.RestartWindow))
+								w.SetErr(ErrRestartLimit)
+								return
+							}
+							if uptime >= stableUptime {
+								w.crashCount = 0
+							}
+							delay := w.Policy.backoff(w.crashCount)
+							w.crashCount++
+							w.recordState(fmt.Sprintf("child %d exited (%s) after %v, restarting in %v", pid, exitReason(ws), uptime, delay.Round(time.Millisecond)))
+							if delay > 0 {
+								restartTimer = time.After(delay)
+								continue reaploop
+							}
 							//Kill mux childs before spawning new processes
+							w.mut.Lock()
 							startError := w.processData.startProcess(w.pid)
 							w.mut.Unlock()
 							if startError != nil {
 								return
 							}
 							continue reaploop
-						} else {
-							logger.GetLogger().Log(logger.Alert, fmt.Sprintf("watchdog saw different child pid: %d, finish with waitstatus: %v.", pid, ws))
 						}
+						logger.GetLogger().Log(logger.Info, fmt.Sprintf("watchdog reaped orphan pid: %d, finish with waitstatus: %v.", pid, ws))
 						if w.exitAfterReaping {
 							logger.GetLogger().Log(logger.Alert, "watchdog sees exitAfterReaping. exiting now.")
 							return
 						}
-						continue reaploop
+						// reap the other children which exited
 					case pid == 0:
 						// this is what we get when SIGSTOP is sent on OSX. ws == 0 in this case.
 						// Note that on OSX we never get a SIGCONT signal.
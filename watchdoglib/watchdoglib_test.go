@@ -3,6 +3,12 @@ package watchdoglib
 import (
 	"context"
 	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 	"syscall"
 	"testing"
 	"time"
@@ -116,3 +122,62 @@ func TestWatchDogChildProcessAndPgId(t *testing.T) {
 		fmt.Println("Timed out program.")
 	}
 }
+
+func TestGroupLeaderChildren(t *testing.T) {
+	cmd := exec.Command("/bin/sleep", "5")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+
+	for _, pid := range groupLeaderChildren(os.Getpid()) {
+		if pid == cmd.Process.Pid {
+			return
+		}
+	}
+	t.Errorf("child %d not found", cmd.Process.Pid)
+}
+
+func TestReapOrphans(t *testing.T) {
+	// the orphan is in another process group and is re-parented to the watchdog when the subshell exits
+	testReapOrphan(t, "exec sleep 50")
+}
+
+func TestReapOrphansDuringBackoff(t *testing.T) {
+	// the child exits, the orphan exits while the watchdog waits to restart the child
+	testReapOrphan(t, "exit 1")
+}
+
+func testReapOrphan(t *testing.T, childEnd string) {
+	if runtime.GOOS != "linux" {
+		t.Skip("child subreaper is linux only")
+	}
+	pidFile := filepath.Join(t.TempDir(), "orphan.pid")
+	watcher := NewWatchdog([]string{"/bin/sh", "-c", "(setsid sleep 0.2 & echo $! > " + pidFile + "); " + childEnd})
+	watcher.Policy = RestartPolicy{BackoffMin: time.Minute, BackoffMax: time.Minute}
+	watcher.Start()
+	defer func() {
+		watcher.ReqStopWatchdog <- true
+		<-watcher.Done
+	}()
+
+	var orphan int
+	for i := 0; (i < 100) && (orphan == 0); i++ {
+		time.Sleep(20 * time.Millisecond)
+		data, _ := os.ReadFile(pidFile)
+		orphan, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+	}
+	if orphan == 0 {
+		t.Fatal("orphan not started")
+	}
+	for i := 0; i < 100; i++ {
+		time.Sleep(20 * time.Millisecond)
+		if _, err := os.Stat(fmt.Sprintf("/proc/%d", orphan)); os.IsNotExist(err) {
+			return
+		}
+	}
+	stat, _ := os.ReadFile(fmt.Sprintf("/proc/%d/stat", orphan))
+	t.Errorf("orphan %d not reaped: %s", orphan, stat)
+}
@@ -20,6 +20,7 @@ package main
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"math/rand"
@@ -35,6 +36,8 @@ import (
 )
 
 type mysqlAdapter struct {
+	// the last Heartbeat found the database read-only
+	readOnly bool
 }
 
 func (adapter *mysqlAdapter) MakeSqlParser() (common.SQLParser, error) {
@@ -136,6 +139,7 @@ func (adapter *mysqlAdapter) InitDB() (*sql.DB, error) {
 func (adapter *mysqlAdapter) Heartbeat(db *sql.DB) bool {
 	ctx, _ /*cancel*/ := context.WithTimeout(context.Background(), 10*time.Second)
 	writable := false
+	adapter.readOnly = false
 	conn, err := db.Conn(ctx)
 	if err != nil {
 		if logger.GetLogger().V(logger.Warning) {
@@ -173,6 +177,8 @@ func (adapter *mysqlAdapter) Heartbeat(db *sql.DB) bool {
 			rows.Scan(&readOnly)
 			if readOnly == 0 {
 				writable = true
+			} else {
+				adapter.readOnly = true
 			}
 		}
 
@@ -184,6 +190,43 @@ func (adapter *mysqlAdapter) Heartbeat(db *sql.DB) bool {
 	return writable
 }
 
+// ResetSession clears the session state left by a client. The driver does not send COM_RESET_CONNECTION,
+// the connection is closed and a new one is opened, so the variables, the temporary tables, the prepared
+// statements, the locks and the transaction of the session are gone
+func (adapter *mysqlAdapter) ResetSession(db *sql.DB) error {
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		return err
+	}
+	err = conn.Raw(func(driverConn interface{}) error {
+		return driver.ErrBadConn
+	})
+	conn.Close()
+	if err != driver.ErrBadConn {
+		return err
+	}
+	// the worker exits if it can't reconnect, instead of failing the next client
+	return db.Ping()
+}
+
+// SetSessionVar sets a session variable, e.g. time_zone or sql_mode. The mux checked that the name
+// is in session_variables_allowed
+func (adapter *mysqlAdapter) SetSessionVar(db *sql.DB, name string, value string) error {
+	_, err := db.Exec("SET SESSION "+name+" = ?", value)
+	return err
+}
+
+// ResetSessionVar sets a session variable back to the global value
+func (adapter *mysqlAdapter) ResetSessionVar(db *sql.DB, name string) error {
+	_, err := db.Exec("SET SESSION " + name + " = DEFAULT")
+	return err
+}
+
+// HeartbeatReadOnly returns true if the last Heartbeat found the database read-only, e.g. after a failover
+func (adapter *mysqlAdapter) HeartbeatReadOnly() bool {
+	return adapter.readOnly
+}
+
 // UseBindNames return false because the SQL string uses ? for bind parameters
 func (adapter *mysqlAdapter) UseBindNames() bool {
 	return false
@@ -193,6 +236,22 @@ func (adapter *mysqlAdapter) UseBindQuestionMark() bool {
 	return true
 }
 
+// UseArrayDML returns false, the driver has no array binds
+func (adapter *mysqlAdapter) UseArrayDML() bool {
+	return false
+}
+
+// UseMultiRowInsert returns true, a batch INSERT runs as one INSERT with the values of all the rows, the
+// other batches run one row at a time
+func (adapter *mysqlAdapter) UseMultiRowInsert() bool {
+	return true
+}
+
+// OutBind returns a session variable, selected after the execute
+func (adapter *mysqlAdapter) OutBind(name string) (string, bool) {
+	return "@hera_out_" + strings.Trim(name, "`"), true
+}
+
 /**
  * @TODO infra.hera.jdbc.HeraResultSetMetaData mysql type to java type map.
  */
@@ -271,6 +330,10 @@ func (adapter *mysqlAdapter) ProcessError(errToProcess error, workerScope *share
 	case 1878: // temp file write fail
 		(*workerScope).Child_shutdown_flag = true
 	}
+	if (errno == 1290) || (errno == 1836) {
+		// the database is not the primary anymore
+		(*workerScope).Db_read_only = true
+	}
 }
 
 func (adapter *mysqlAdapter) ProcessResult(colType string, res string) string {
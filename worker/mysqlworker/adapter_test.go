@@ -18,8 +18,13 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"log"
 	"regexp"
+	"sync/atomic"
 	"testing"
 )
 
@@ -46,3 +51,57 @@ func TestExtractAndReplaceBindVar(t *testing.T) {
 	}
 	log.Println(re.ReplaceAllString(query, "?$1"))
 }
+
+// countingDriver opens connections which only answer the pings, it counts the opens and the closes
+type countingDriver struct {
+	opens  int32
+	closes int32
+}
+
+type countingConn struct {
+	drv *countingDriver
+}
+
+func (drv *countingDriver) Open(name string) (driver.Conn, error) {
+	atomic.AddInt32(&drv.opens, 1)
+	return &countingConn{drv: drv}, nil
+}
+
+func (conn *countingConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (conn *countingConn) Close() error {
+	atomic.AddInt32(&conn.drv.closes, 1)
+	return nil
+}
+
+func (conn *countingConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+func (conn *countingConn) Ping(ctx context.Context) error {
+	return nil
+}
+
+func TestResetSession(t *testing.T) {
+	drv := &countingDriver{}
+	sql.Register("counting", drv)
+	db, err := sql.Open("counting", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err = db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the session is reset by a new connection
+	adapter := &mysqlAdapter{}
+	if err = adapter.ResetSession(db); err != nil {
+		t.Fatal(err)
+	}
+	if opens, closes := atomic.LoadInt32(&drv.opens), atomic.LoadInt32(&drv.closes); (opens != 2) || (closes != 1) {
+		t.Errorf("expected the connection closed and a new one opened, %d opens and %d closes", opens, closes)
+	}
+}
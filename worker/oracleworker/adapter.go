@@ -31,6 +31,8 @@ import (
 )
 
 type oracleAdapter struct {
+	// the values of the session parameters before the first SetSessionVar, to reset them
+	initialVars map[string]string
 }
 
 func (adapter *oracleAdapter) MakeSqlParser() (common.SQLParser, error) {
@@ -63,6 +65,55 @@ func (adapter *oracleAdapter) UseBindQuestionMark() bool {
 	return true
 }
 
+// UseArrayDML returns true, godror runs the batch in one call when the binds are slices
+func (adapter *oracleAdapter) UseArrayDML() bool {
+	return true
+}
+
+// OutBind is not used, the out binds are named binds
+func (adapter *oracleAdapter) OutBind(name string) (string, bool) {
+	return "", false
+}
+
+// ResetSession clears the state of the PL/SQL packages left by a client
+func (adapter *oracleAdapter) ResetSession(db *sql.DB) error {
+	_, err := db.Exec("BEGIN DBMS_SESSION.RESET_PACKAGE; END;")
+	return err
+}
+
+// SetSessionVar changes a session parameter, e.g. time_zone or nls_date_format. The name was validated by
+// the mux. The initial value of the time zone and the NLS parameters is kept to reset them
+func (adapter *oracleAdapter) SetSessionVar(db *sql.DB, name string, value string) error {
+	if _, ok := adapter.initialVars[name]; !ok {
+		var initial string
+		var err error
+		if name == "time_zone" {
+			err = db.QueryRow("SELECT SESSIONTIMEZONE FROM DUAL").Scan(&initial)
+		} else if strings.HasPrefix(name, "nls_") {
+			err = db.QueryRow("SELECT value FROM nls_session_parameters WHERE parameter = :1", strings.ToUpper(name)).Scan(&initial)
+		}
+		if err == nil && len(initial) > 0 {
+			if adapter.initialVars == nil {
+				adapter.initialVars = make(map[string]string)
+			}
+			adapter.initialVars[name] = initial
+		}
+	}
+	_, err := db.Exec("ALTER SESSION SET " + name + " = '" + strings.Replace(value, "'", "''", -1) + "'")
+	return err
+}
+
+// ResetSessionVar sets a session parameter back to its initial value, only the time zone and the NLS
+// parameters can be reset
+func (adapter *oracleAdapter) ResetSessionVar(db *sql.DB, name string) error {
+	initial, ok := adapter.initialVars[name]
+	if !ok {
+		return fmt.Errorf("%s can't be reset", name)
+	}
+	_, err := db.Exec("ALTER SESSION SET " + name + " = '" + strings.Replace(initial, "'", "''", -1) + "'")
+	return err
+}
+
 /**
  * @TODO
  */
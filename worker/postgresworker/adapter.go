@@ -36,6 +36,8 @@ import (
 )
 
 type postgresAdapter struct {
+	// the last Heartbeat found the database in recovery, i.e. read-only
+	readOnly bool
 }
 
 func (adapter *postgresAdapter) MakeSqlParser() (common.SQLParser, error) {
@@ -157,6 +159,7 @@ func (adapter *postgresAdapter) InitDB() (*sql.DB, error) {
 func (adapter *postgresAdapter) Heartbeat(db *sql.DB) bool {
 	ctx, _ /*cancel*/ := context.WithTimeout(context.Background(), 10*time.Second)
 	writable := false
+	adapter.readOnly = false
 	conn, err := db.Conn(ctx)
 	if err != nil {
 		if logger.GetLogger().V(logger.Warning) {
@@ -189,6 +192,8 @@ func (adapter *postgresAdapter) Heartbeat(db *sql.DB) bool {
 			logger.GetLogger().Log(logger.Debug,"Scanned value", readOnly)
 			if !readOnly {
 				writable = true
+			} else {
+				adapter.readOnly = true
 			}
 		}
 
@@ -199,6 +204,32 @@ func (adapter *postgresAdapter) Heartbeat(db *sql.DB) bool {
 	}
 	return writable
 }
+
+// ResetSession clears the session state left by a client, DISCARD ALL deallocates the prepared statements,
+// drops the temporary tables, resets the variables and releases the advisory locks
+func (adapter *postgresAdapter) ResetSession(db *sql.DB) error {
+	_, err := db.Exec("DISCARD ALL")
+	return err
+}
+
+// SetSessionVar sets a run-time parameter for the session, e.g. TimeZone or search_path
+func (adapter *postgresAdapter) SetSessionVar(db *sql.DB, name string, value string) error {
+	_, err := db.Exec("SELECT set_config($1, $2, false)", name, value)
+	return err
+}
+
+// ResetSessionVar sets a run-time parameter back to its default. The mux checked that the name is in
+// session_variables_allowed
+func (adapter *postgresAdapter) ResetSessionVar(db *sql.DB, name string) error {
+	_, err := db.Exec("RESET " + name)
+	return err
+}
+
+// HeartbeatReadOnly returns true if the last Heartbeat found the database read-only, e.g. after a failover
+func (adapter *postgresAdapter) HeartbeatReadOnly() bool {
+	return adapter.readOnly
+}
+
 // UseBindNames return false because the SQL string uses $1 $2 for bind parameters
 func (adapter *postgresAdapter) UseBindNames() bool {
 	return false
@@ -207,6 +238,16 @@ func (adapter *postgresAdapter) UseBindQuestionMark() bool {
 	return false
 }
 
+// UseArrayDML returns false, the rows of a batch run one by one in the transaction
+func (adapter *postgresAdapter) UseArrayDML() bool {
+	return false
+}
+
+// OutBind returns NULL, the OUT parameters of a procedure and the values of RETURNING come back as a row
+func (adapter *postgresAdapter) OutBind(name string) (string, bool) {
+	return "NULL", false
+}
+
 /**
  * @TODO infra.hera.jdbc.HeraResultSetMetaData mysql type to java type map.
  */
@@ -278,6 +319,12 @@ func (adapter *postgresAdapter) ProcessError(errToProcess error, workerScope *sh
 			logger.GetLogger().Log(logger.Warning, pgErr.Code.Class().Name()+"=errClass postgres ProcessError setting child shutdown flag "+errStr+" sqlHash:"+(*queryScope).SqlHash+" Cmd:"+(*queryScope).NsCmd)
 		}
 	}
+	if pgErr.Code == "25006" {
+		// read_only_sql_transaction, the database is not the primary anymore. The class switch above
+		// compares the class names, it does not catch it
+		(*workerScope).Child_shutdown_flag = true
+		(*workerScope).Db_read_only = true
+	}
 
 	if strings.HasPrefix(errStr, "driver: bad connection") {
 		if logger.GetLogger().V(logger.Warning) {
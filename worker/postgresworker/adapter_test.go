@@ -17,7 +17,12 @@
 
 package main
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/paypal/hera/worker/shared"
+)
 
 func TestProcessResult(t *testing.T) {
 	adapter := &postgresAdapter{}
@@ -76,3 +81,14 @@ func TestProcessResult(t *testing.T) {
 	}
 	t.Log("----Done TestProcessResult for postgres")
 }
+
+func TestProcessErrorReadOnly(t *testing.T) {
+	adapter := &postgresAdapter{}
+	for code, readOnly := range map[pq.ErrorCode]bool{"25006": true, "23505": false} {
+		workerScope := &shared.WorkerScopeType{}
+		adapter.ProcessError(&pq.Error{Code: code, Message: "error"}, workerScope, &shared.QueryScopeType{})
+		if (workerScope.Db_read_only != readOnly) || (workerScope.Child_shutdown_flag != readOnly) {
+			t.Errorf("%s: read-only %v, shutdown %v, expected %v", code, workerScope.Db_read_only, workerScope.Child_shutdown_flag, readOnly)
+		}
+	}
+}
@@ -0,0 +1,394 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paypal/hera/cal"
+	"github.com/paypal/hera/common"
+	"github.com/paypal/hera/utility/encoding/netstring"
+	"github.com/paypal/hera/utility/logger"
+)
+
+// Batch DML. A bind name followed by CmdBindNum n and CmdBindValueMaxSize has n values, one per row of
+// the batch. All the array binds of a statement have the same number of rows, the other binds have the same
+// value for all the rows. The response has the number of columns (0), the total number of rows changed, then
+// the number of rows changed by each row of binds, -1 if unknown. If a row fails, the response ends with the
+// SQL error after the counts of the rows before it, the rows after it are not executed.
+// The batch runs in the transaction of the client. Sent with CmdBatchCommit, it runs outside of a transaction
+// of the client: it is committed if all the rows succeed, else rolled back, and the worker is freed.
+
+// MaxArrayBindRows is the largest number of rows in a batch, the same as in the C++ worker
+const MaxArrayBindRows = 100
+
+// ErrBatchQuery is returned for array binds in a query
+var ErrBatchQuery = errors.New("array binds are only supported for DML")
+
+// MultiRowInserter is implemented by the adapters which run a batch INSERT as one INSERT with the values of
+// all the rows, like MySQL
+type MultiRowInserter interface {
+	UseMultiRowInsert() bool
+}
+
+// matches the start of the values of an INSERT, up to the opening parenthesis
+var regexInsertValues = regexp.MustCompile(`(?is)^\s*insert\s.*?\bvalues\s*\(`)
+
+// bindNum starts the values of an array bind
+func (cp *CmdProcessor) bindNum(payload []byte) error {
+	num, err := strconv.Atoi(string(payload))
+	if err != nil {
+		return err
+	}
+	if (num < 1) || (num > MaxArrayBindRows) {
+		return fmt.Errorf("Can't array bind %d rows (>%d) at one time", num, MaxArrayBindRows)
+	}
+	if (cp.batchRows > 0) && (num != cp.batchRows) {
+		return fmt.Errorf("array bind %s has %d rows, other binds have %d", cp.currentBindName, num, cp.batchRows)
+	}
+	cp.batchRows = num
+	cp.bindVars[cp.currentBindName].values = make([]interface{}, 0, num)
+	return nil
+}
+
+// batchInput returns the bind values of a row of the batch
+func (cp *CmdProcessor) batchInput(row int) ([]interface{}, error) {
+	bindinput := make([]interface{}, 0, len(cp.bindPos))
+	for _, key := range cp.bindPos {
+		val := cp.bindVars[key]
+		if val.btype != btIn {
+			return nil, errors.New("outbind not supported in a batch")
+		}
+		if !val.valid {
+			return nil, fmt.Errorf("bindname undefined: %s", key)
+		}
+		value := val.value
+		if val.values != nil {
+			if len(val.values) != cp.batchRows {
+				return nil, fmt.Errorf("array bind %s has %d values instead of %d", key, len(val.values), cp.batchRows)
+			}
+			value = val.values[row]
+		}
+		if cp.adapter.UseBindNames() {
+			bindinput = append(bindinput, sql.Named(key[1:], value))
+		} else {
+			bindinput = append(bindinput, value)
+		}
+	}
+	return bindinput, nil
+}
+
+// arrayColumn returns the values of a bind for all the rows as a typed slice, for array DML. It returns
+// false if the values have different types
+func arrayColumn(values []interface{}) (interface{}, bool) {
+	switch values[0].(type) {
+	case sql.NullString, []byte:
+		col := make([][]byte, len(values))
+		for i, value := range values {
+			switch value := value.(type) {
+			case sql.NullString:
+				if value.Valid {
+					col[i] = []byte(value.String)
+				}
+			case []byte:
+				col[i] = value
+			default:
+				return nil, false
+			}
+		}
+		return col, true
+	case int:
+		col := make([]int, len(values))
+		for i, value := range values {
+			v, ok := value.(int)
+			if !ok {
+				return nil, false
+			}
+			col[i] = v
+		}
+		return col, true
+	case bool:
+		col := make([]bool, len(values))
+		for i, value := range values {
+			v, ok := value.(bool)
+			if !ok {
+				return nil, false
+			}
+			col[i] = v
+		}
+		return col, true
+	case time.Time:
+		col := make([]time.Time, len(values))
+		for i, value := range values {
+			v, ok := value.(time.Time)
+			if !ok {
+				return nil, false
+			}
+			col[i] = v
+		}
+		return col, true
+	}
+	return nil, false
+}
+
+// arrayInput returns the bind values for executing the batch in one array DML call, false if a bind
+// can't be sent as an array
+func (cp *CmdProcessor) arrayInput() ([]interface{}, bool) {
+	bindinput := make([]interface{}, 0, len(cp.bindPos))
+	for _, key := range cp.bindPos {
+		val := cp.bindVars[key]
+		values := val.values
+		if values == nil {
+			values = make([]interface{}, cp.batchRows)
+			for i := range values {
+				values[i] = val.value
+			}
+		}
+		col, ok := arrayColumn(values)
+		if !ok {
+			return nil, false
+		}
+		bindinput = append(bindinput, sql.Named(key[1:], col))
+	}
+	return bindinput, true
+}
+
+// closingParen returns the position of the parenthesis closing the one at start, skipping the quoted strings
+// and identifiers, -1 if there is none
+func closingParen(query string, start int) int {
+	depth := 0
+	var quote byte
+	for i := start; i < len(query); i++ {
+		c := query[i]
+		if quote != 0 {
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '\'', '"', '`':
+			quote = c
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// multiRowInsert rewrites "INSERT ... VALUES (?, ?)" to insert rows rows in one statement, repeating the
+// values. It returns false if the statement is not a plain INSERT of one row of values
+func multiRowInsert(query string, rows int) (string, bool) {
+	loc := regexInsertValues.FindStringIndex(query)
+	if loc == nil {
+		return "", false
+	}
+	start := loc[1] - 1
+	end := closingParen(query, start)
+	if (end == -1) || (len(strings.TrimSpace(query[end+1:])) > 0) || strings.Contains(query[:start], "?") {
+		// like ON DUPLICATE KEY UPDATE, or binds outside of the values
+		return "", false
+	}
+	values := query[start : end+1]
+	var sb strings.Builder
+	sb.Grow(len(query) + (rows-1)*(len(values)+1))
+	sb.WriteString(query[:end+1])
+	for i := 1; i < rows; i++ {
+		sb.WriteByte(',')
+		sb.WriteString(values)
+	}
+	return sb.String(), true
+}
+
+// unknownCounts returns the counts of a batch run in one call, the number of rows changed by each row
+// is unknown
+func unknownCounts(rows int) []int64 {
+	counts := make([]int64, rows)
+	for i := range counts {
+		counts[i] = -1
+	}
+	return counts
+}
+
+// execBatch runs the statement for each row of binds, in the transaction. It stops at the first row failing,
+// returning its error in rowErr. The adapters supporting array DML or multi-row INSERT run all the rows in
+// one call, the number of rows changed by each row is then unknown and an error fails the whole batch
+func (cp *CmdProcessor) execBatch() (counts []int64, total int64, rowErr error, err error) {
+	if cp.tx == nil {
+		cp.tx, err = cp.db.Begin()
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		cp.stmt = cp.tx.Stmt(cp.stmt)
+		cp.stmtCached = false
+	}
+	rowsInput := make([][]interface{}, cp.batchRows)
+	for row := range rowsInput {
+		rowsInput[row], err = cp.batchInput(row)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+	}
+	if cp.adapter.UseArrayDML() {
+		if input, ok := cp.arrayInput(); ok {
+			cp.result, err = cp.stmt.Exec(input...)
+			if err != nil {
+				return nil, 0, nil, err
+			}
+			total, _ = cp.result.RowsAffected()
+			return unknownCounts(cp.batchRows), total, nil, nil
+		}
+	}
+	if inserter, ok := cp.adapter.(MultiRowInserter); ok && inserter.UseMultiRowInsert() {
+		if query, ok := multiRowInsert(cp.preparedSQL, cp.batchRows); ok {
+			input := make([]interface{}, 0, cp.batchRows*len(cp.bindPos))
+			for _, row := range rowsInput {
+				input = append(input, row...)
+			}
+			cp.result, err = cp.tx.Exec(query, input...)
+			if err != nil {
+				return nil, 0, nil, err
+			}
+			total, _ = cp.result.RowsAffected()
+			return unknownCounts(cp.batchRows), total, nil, nil
+		}
+	}
+	counts = make([]int64, 0, cp.batchRows)
+	for _, input := range rowsInput {
+		cp.result, err = cp.stmt.Exec(input...)
+		if err != nil {
+			return counts, total, err, nil
+		}
+		cnt, err := cp.result.RowsAffected()
+		if err != nil {
+			cnt = -1
+		} else {
+			total += cnt
+		}
+		counts = append(counts, cnt)
+	}
+	return counts, total, nil, nil
+}
+
+// endBatchTrans commits or rolls back the transaction of a batch run outside of a transaction of the client
+func (cp *CmdProcessor) endBatchTrans(commit bool) error {
+	var err error
+	var calevt cal.Event
+	if commit {
+		calevt = cal.NewCalEvent("COMMIT", "Local", cal.TransOK, "")
+		err = cp.tx.Commit()
+	} else {
+		calevt = cal.NewCalEvent("ROLLBACK", "Local", cal.TransOK, "")
+		err = cp.tx.Rollback()
+	}
+	// the transaction is done even if it failed
+	cp.tx = nil
+	cp.inTrans = false
+	if err != nil {
+		cp.adapter.ProcessError(err, &cp.WorkerScope, &cp.queryScope)
+		if logger.GetLogger().V(logger.Warning) {
+			logger.GetLogger().Log(logger.Warning, "Batch commit:", commit, "error:", err.Error())
+		}
+		calevt.AddDataStr("RC", err.Error())
+		calevt.SetStatus(cal.TransError)
+	}
+	calevt.Completed()
+	return err
+}
+
+// executeBatch runs the batch and sends the response
+func (cp *CmdProcessor) executeBatch() {
+	if logger.GetLogger().V(logger.Debug) {
+		logger.GetLogger().Log(logger.Debug, "Executing batch of", cp.batchRows, "rows", cp.inTrans)
+	}
+	var counts []int64
+	var total int64
+	var rowErr error
+	err := ErrBatchQuery
+	execStart := time.Now()
+	if !cp.hasResult {
+		counts, total, rowErr, err = cp.execBatch()
+	}
+	if cp.batchCommit && (cp.tx != nil) {
+		commit := (err == nil) && (rowErr == nil)
+		if endErr := cp.endBatchTrans(commit); commit && (endErr != nil) {
+			// none of the rows is changed
+			counts, total, err = nil, 0, endErr
+		}
+	}
+	cp.stmtTiming.execDur = time.Since(execStart)
+	if err == nil {
+		err = rowErr
+	}
+	if err != nil {
+		cp.stmtTiming.err = err.Error()
+		cp.adapter.ProcessError(err, &cp.WorkerScope, &cp.queryScope)
+		cp.invalidateStmt(err)
+		cp.calExecTxn.AddDataInt("batch", int64(cp.batchRows))
+		cp.calExecErr("RC", err.Error())
+		if logger.GetLogger().V(logger.Warning) {
+			logger.GetLogger().Log(logger.Warning, "Batch execute error at row", len(counts), ":", err.Error())
+		}
+		cp.lastErr = err
+	} else {
+		cp.calExecTxn.AddDataInt("batch", int64(cp.batchRows))
+		cp.calExecTxn.Completed()
+		cp.calExecTxn = nil
+	}
+	if cp.tx != nil {
+		cp.inTrans = true
+	}
+	code := common.EORInTransaction
+	if !cp.inTrans {
+		code = common.EORFree
+		defer func() {
+			if cp.stmtCache != nil {
+				cp.stmtCache.preparePending(cp.db)
+			}
+		}()
+	}
+	if (rowErr == nil) && (err != nil) {
+		// the whole batch failed
+		cp.eor(code, netstring.NewNetstringFrom(common.RcSQLError, []byte(err.Error())))
+		return
+	}
+	nss := make([]*netstring.Netstring, 0, len(counts)+3)
+	nss = append(nss, netstring.NewNetstringFrom(common.RcValue, []byte("0")))
+	nss = append(nss, netstring.NewNetstringFrom(common.RcValue, []byte(strconv.FormatInt(total, 10))))
+	for _, cnt := range counts {
+		nss = append(nss, netstring.NewNetstringFrom(common.RcValue, []byte(strconv.FormatInt(cnt, 10))))
+	}
+	if rowErr != nil {
+		nss = append(nss, netstring.NewNetstringFrom(common.RcSQLError, []byte(rowErr.Error())))
+	}
+	cp.stmtTiming.rows = total
+	cp.eor(code, netstring.NewNetstringEmbedded(nss))
+}
@@ -0,0 +1,149 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/paypal/hera/common"
+	"github.com/paypal/hera/utility/encoding/netstring"
+)
+
+// batchRequest returns the commands of a batch inserting the values, with CmdBatchCommit if commit
+func batchRequest(query string, values []string, commit bool) []*netstring.Netstring {
+	nss := []*netstring.Netstring{
+		netstring.NewNetstringFrom(common.CmdPrepareV2, []byte(query)),
+		netstring.NewNetstringFrom(common.CmdBindName, []byte("a")),
+		netstring.NewNetstringFrom(common.CmdBindNum, []byte(strconv.Itoa(len(values)))),
+	}
+	for _, value := range values {
+		nss = append(nss, netstring.NewNetstringFrom(common.CmdBindValue, []byte(value)))
+	}
+	nss = append(nss, netstring.NewNetstringFrom(common.CmdBindName, []byte("b")),
+		netstring.NewNetstringFrom(common.CmdBindValue, []byte("x")))
+	if commit {
+		nss = append(nss, netstring.NewNetstringFrom(common.CmdBatchCommit, nil))
+	}
+	return append(nss, netstring.NewNetstringFrom(common.CmdExecute, nil))
+}
+
+// batchResponse returns the counts and the error of the response of a batch
+func batchResponse(nss []*netstring.Netstring) ([]string, string) {
+	var counts []string
+	var rowErr string
+	for _, ns := range nss[1:] {
+		if ns.Cmd == common.RcSQLError {
+			rowErr = string(ns.Payload)
+		} else {
+			counts = append(counts, string(ns.Payload))
+		}
+	}
+	return counts, rowErr
+}
+
+func TestBatchTransaction(t *testing.T) {
+	errRow := errors.New("duplicate key")
+	db := &fakeDB{fail: func(query string, args []driver.Value) error {
+		if (len(args) > 0) && (args[0] == "dup") {
+			return errRow
+		}
+		return nil
+	}}
+	cp, reader := newTestCmdProcessor(t, &fakeAdapter{db: db})
+	query := "insert into t (a, b) values (:a, :b)"
+	for _, tc := range []struct {
+		name   string
+		values []string
+		commit bool
+		code   int
+		counts []string
+		rowErr string
+		log    []string
+	}{
+		{"caller transaction", []string{"1", "2"}, false, common.EORInTransaction, []string{"2", "1", "1"}, "",
+			[]string{"begin", "exec insert into t (a, b) values (?, ?) [1 x]", "exec insert into t (a, b) values (?, ?) [2 x]"}},
+		{"commit", []string{"1", "2"}, true, common.EORFree, []string{"2", "1", "1"}, "",
+			[]string{"begin", "exec insert into t (a, b) values (?, ?) [1 x]", "exec insert into t (a, b) values (?, ?) [2 x]", "commit"}},
+		{"rollback", []string{"1", "dup", "3"}, true, common.EORFree, []string{"1", "1"}, errRow.Error(),
+			[]string{"begin", "exec insert into t (a, b) values (?, ?) [1 x]", "exec insert into t (a, b) values (?, ?) [dup x]", "rollback"}},
+	} {
+		process(t, cp, batchRequest(query, tc.values, tc.commit)...)
+		code, nss := readEOR(t, reader)
+		counts, rowErr := batchResponse(nss)
+		if (code != tc.code) || !reflect.DeepEqual(counts, tc.counts) || (rowErr != tc.rowErr) {
+			t.Errorf("%s: EOR %d, counts %v, error %q, expected %d, %v, %q", tc.name, code, counts, rowErr, tc.code, tc.counts, tc.rowErr)
+		}
+		if log := db.logged(); !reflect.DeepEqual(log, tc.log) {
+			t.Errorf("%s: ran %q, expected %q", tc.name, log, tc.log)
+		}
+		if (code == common.EORFree) && (cp.tx != nil || cp.inTrans) {
+			t.Errorf("%s: transaction still open", tc.name)
+		}
+		if tc.code == common.EORInTransaction {
+			// the caller ends its transaction
+			process(t, cp, netstring.NewNetstringFrom(common.CmdCommit, nil))
+			readEOR(t, reader)
+			db.logged()
+		}
+	}
+}
+
+func TestBatchMultiRowInsert(t *testing.T) {
+	db := &fakeDB{}
+	cp, reader := newTestCmdProcessor(t, &fakeAdapter{db: db, useMultiInsert: true})
+	process(t, cp, batchRequest("insert into t (a, b) values (:a, :b)", []string{"1", "2", "3"}, true)...)
+	code, nss := readEOR(t, reader)
+	counts, rowErr := batchResponse(nss)
+	if (code != common.EORFree) || !reflect.DeepEqual(counts, []string{"3", "-1", "-1", "-1"}) || (rowErr != "") {
+		t.Errorf("EOR %d, counts %v, error %q", code, counts, rowErr)
+	}
+	expected := []string{"begin", "exec insert into t (a, b) values (?, ?),(?, ?),(?, ?) [1 x 2 x 3 x]", "commit"}
+	if log := db.logged(); !reflect.DeepEqual(log, expected) {
+		t.Errorf("ran %q, expected %q", log, expected)
+	}
+
+	// not a plain insert, row by row
+	process(t, cp, batchRequest("insert into t (a, b) values (:a, :b) on duplicate key update b = values(b)", []string{"1", "2"}, true)...)
+	readEOR(t, reader)
+	if log := db.logged(); len(log) != 4 {
+		t.Errorf("ran %q, expected one insert per row", log)
+	}
+}
+
+func TestMultiRowInsert(t *testing.T) {
+	for _, tc := range []struct {
+		query    string
+		expected string
+	}{
+		{"insert into t (a, b) values (?, ?)", "insert into t (a, b) values (?, ?),(?, ?)"},
+		{"INSERT INTO t VALUES(?, 'a)', now())", "INSERT INTO t VALUES(?, 'a)', now()),(?, 'a)', now())"},
+		{"insert into t (a) values (?) on duplicate key update a = a + 1", ""},
+		{"insert into t (a) select ? from dual", ""},
+		{"update t set a = ?", ""},
+		{"insert into t (a) values (?), (?)", ""},
+	} {
+		query, ok := multiRowInsert(tc.query, 2)
+		if (query != tc.expected) || (ok != (tc.expected != "")) {
+			t.Errorf("%q: %q, %v, expected %q", tc.query, query, ok, tc.expected)
+		}
+	}
+}
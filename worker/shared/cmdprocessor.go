@@ -50,6 +50,17 @@ type CmdProcessorAdapter interface {
 	ProcessResult(colType string, res string) string
 	UseBindNames() bool
 	UseBindQuestionMark() bool // true for mysql, false for postgres $1 $2 binds
+	// UseArrayDML is true if the driver runs a batch in one call when the binds are slices
+	UseArrayDML() bool
+	// OutBind returns what replaces an out bind in the SQL when the bind names are not used, and true if
+	// it is a session variable to select after the execute
+	OutBind(name string) (string, bool)
+}
+
+// ReadOnlyReporter is implemented by the adapters which can tell, after a failed Heartbeat, that the database
+// is up but read-only, i.e. it is not the primary anymore
+type ReadOnlyReporter interface {
+	HeartbeatReadOnly() bool
 }
 
 // bindType defines types of bind variables
@@ -77,6 +88,8 @@ type BindValue struct {
 	btype bindType
 	// the data type
 	dataType common.DataType
+	// the values of an array bind, one per row of the batch
+	values []interface{}
 }
 
 // CmdProcessor holds the data needed to process the client commmands
@@ -171,6 +184,34 @@ type CmdProcessor struct {
 	WorkerScope          WorkerScopeType
 	// tells if the worker is dedicated: either in cursor or in transaction
 	dedicated bool
+	// client info sent by mux, for the slow query log
+	clientInfo string
+	// timing of the current statement, for the slow query log
+	stmtTiming stmtTiming
+	// number of rows of the array binds, 0 if the statement is not a batch
+	batchRows int
+	// the batch runs outside of a transaction of the client, it is committed or rolled back
+	batchCommit bool
+	// the SQL prepared, after the bind names are replaced
+	preparedSQL string
+	// the SQL as sent by the client, rewritten at execute when it has out binds
+	sqlText string
+	// tells if the SQL calls a stored procedure, which can return result sets
+	isCall bool
+	// the prepared statements, nil if "enable_cache" is not set
+	stmtCache *stmtCache
+	// tells if stmt belongs to stmtCache, it is not closed at the next prepare
+	stmtCached bool
+	// resets the session altered by a client, nil if "enable_session_reset" is not set
+	sessionReset *sessionReset
+	// the session variables set by CmdSetSessionVars
+	sessionVars map[string]string
+	// the error setting the session variables, returned by the next statement
+	sessionVarsErr error
+	// tells if setting the session variables failed, the worker exits when freed
+	sessionVarsFailed bool
+	// tells if the client sent the request in binary frames, the responses are sent as binary frames too
+	binaryResponses bool
 }
 
 type QueryScopeType struct {
@@ -179,6 +220,9 @@ type QueryScopeType struct {
 }
 type WorkerScopeType struct {
 	Child_shutdown_flag bool
+	// set with Child_shutdown_flag when the error says the database is read-only, the worker exits with
+	// common.WorkerExitReadOnly
+	Db_read_only bool
 }
 
 const LAST_INSERT_ID_BIND_OUT_NAME = ":p5000"
@@ -206,6 +250,12 @@ func (cp *CmdProcessor) ProcessCmd(ns *netstring.Netstring) error {
 	var err error
 
 	cp.queryScope.NsCmd = fmt.Sprintf("%d", ns.Cmd)
+	switch ns.Cmd {
+	case common.CmdClientCalCorrelationID, common.CmdClientInfo, common.CmdSetSessionVars:
+		// added by the mux
+	default:
+		cp.binaryResponses = ns.IsBinary()
+	}
 outloop:
 	switch ns.Cmd {
 	case common.CmdClientCalCorrelationID:
@@ -238,6 +288,7 @@ outloop:
 		if logger.GetLogger().V(logger.Verbose) {
 			logger.GetLogger().Log(logger.Verbose, "CmdClientInfo:", string(ns.Payload), string(ns.Serialized))
 		}
+		cp.clientInfo = string(ns.Payload)
 		if len(string(ns.Payload)) > 0 {
 			logger.GetLogger().Log(logger.Verbose, "len clientApplication:", len(string(ns.Payload)))
 			logger.GetLogger().Log(logger.Verbose, "clientApplication:", string(ns.Payload))
@@ -260,12 +311,34 @@ outloop:
 		} else {
 			logger.GetLogger().Log(logger.Verbose, "clientApplication: unknown")
 		}
+	case common.CmdSetSessionVars:
+		cp.setSessionVars(ns.Payload)
 	case common.CmdPrepare, common.CmdPrepareV2, common.CmdPrepareSpecial:
 		cp.dedicated = true
 		cp.queryScope = QueryScopeType{}
 		cp.lastErr = nil
 		cp.sqlHash = 0
 		cp.heartbeat = false // for hb
+		cp.stmtTiming = stmtTiming{}
+		cp.batchRows = 0
+		cp.batchCommit = false
+		cp.sqlText = string(ns.Payload)
+		cp.isCall = !cp.adapter.UseBindNames() && regexCall.MatchString(cp.sqlText)
+		if cp.sessionVarsErr != nil {
+			// the statement is not run with the wrong session variables
+			cp.lastErr = cp.sessionVarsErr
+			cp.sessionVarsErr = nil
+			if (cp.stmt != nil) && !cp.stmtCached {
+				cp.stmt.Close()
+			}
+			cp.stmt = nil
+			cp.didExecAtPrepare = false
+			break outloop
+		}
+		if gSlowQueryCfg != nil {
+			cp.stmtTiming.active = true
+			cp.stmtTiming.sql = string(ns.Payload)
+		}
 		//
 		// need to turn "select * from table where ca=:a and cb=:b"
 		// to "select * from table where ca=? and cb=?"
@@ -274,6 +347,7 @@ outloop:
 		// BindName and BindValue
 		//
 		sqlQuery := cp.preprocess(string(ns.Payload))
+		cp.preparedSQL = sqlQuery
 		if logger.GetLogger().V(logger.Verbose) {
 			logger.GetLogger().Log(logger.Verbose, "Preparing:", sqlQuery)
 		}
@@ -290,14 +364,25 @@ outloop:
 		cp.calSessionTxn.SendSQLData(string(ns.Payload))
 		cp.sqlHash = utility.GetSQLHash(string(ns.Payload))
 		cp.queryScope.SqlHash = fmt.Sprintf("%d", cp.sqlHash)
+		cp.checkSessionAltered(sqlQuery)
 		cp.calExecTxn = cal.NewCalTransaction(cal.TransTypeExec, fmt.Sprintf("%d", cp.sqlHash), cal.TransOK, "", cal.DefaultTGName)
-		if (cp.tx == nil) && (startTrans) {
-			cp.tx, err = cp.db.Begin()
-		}
 		if cp.stmt != nil {
-			cp.stmt.Close()
+			if !cp.stmtCached {
+				cp.stmt.Close()
+			}
 			cp.stmt = nil
 		}
+		cp.stmtCached = false
+		// the cached statements are prepared on the connection, before starting the transaction
+		var cached *sql.Stmt
+		if (cp.stmtCache != nil) && !cp.sqlParser.MustExecInsteadOfPrepare(sqlQuery) {
+			var status string
+			cached, status = cp.cachedStmt(sqlQuery)
+			cp.calExecTxn.AddDataStr("stmt_cache", status)
+		}
+		if (cp.tx == nil) && (startTrans) {
+			cp.tx, err = cp.db.Begin()
+		}
 		cp.didExecAtPrepare = false
 		if cp.sqlParser.MustExecInsteadOfPrepare(sqlQuery) {
 			cp.didExecAtPrepare = true
@@ -310,6 +395,11 @@ outloop:
 			cp.calExecTxn = nil
 			// keep cp.stmt nil so we don't exec
 			cp.stmt = nil
+		} else if (cached != nil) && (cp.tx != nil) {
+			cp.stmt = cp.tx.Stmt(cached)
+		} else if cached != nil {
+			cp.stmt = cached
+			cp.stmtCached = true
 		} else if cp.tx != nil {
 			cp.stmt, err = cp.tx.Prepare(sqlQuery)
 		} else {
@@ -359,6 +449,7 @@ outloop:
 				cp.numBindOuts++
 			}
 			cp.bindVars[cp.currentBindName].dataType = common.DataTypeString
+			cp.bindVars[cp.currentBindName].values = nil
 		}
 	case common.CmdBindType:
 		if cp.stmt != nil {
@@ -423,16 +514,32 @@ outloop:
 					}
 				}
 				cp.bindVars[cp.currentBindName].valid = true
+				if cp.bindVars[cp.currentBindName].values != nil {
+					cp.bindVars[cp.currentBindName].values = append(cp.bindVars[cp.currentBindName].values, cp.bindVars[cp.currentBindName].value)
+				}
 			}
 		}
 	case common.CmdBindNum:
 		if cp.stmt != nil {
-			err = fmt.Errorf("Batch not supported")
-			cp.calExecErr("Batch", err.Error())
-			break
+			if cp.bindVars[cp.currentBindName] == nil {
+				err = fmt.Errorf("bindname not found in query: %s", cp.currentBindName)
+				cp.calExecErr("BindNumNF", cp.currentBindName)
+				break
+			}
+			err = cp.bindNum(ns.Payload)
+			if err != nil {
+				cp.calExecErr("Batch", err.Error())
+				break
+			}
 		}
+	case common.CmdBatchCommit:
+		cp.batchCommit = true
 	case common.CmdExecute:
-		if cp.stmt != nil {
+		if (cp.stmt != nil) && (cp.batchRows > 0) {
+			cp.executeBatch()
+		} else if (cp.stmt != nil) && !cp.adapter.UseBindNames() && cp.hasOutBinds() {
+			cp.executeOutBinds()
+		} else if cp.stmt != nil {
 			//
 			// step through bindvar at each location to build bindinput.
 			//
@@ -481,24 +588,28 @@ outloop:
 				logger.GetLogger().Log(logger.Debug, "Executing ", cp.inTrans)
 				logger.GetLogger().Log(logger.Debug, "BINDS", bindinput)
 			}
+			execStart := time.Now()
 			if len(bindinput) == 0 {
 				//
 				// @TODO: do we keep a flag for curent statement.
 				//
-				if cp.hasResult {
+				if cp.hasResult || cp.isCall {
 					cp.rows, err = cp.stmt.Query()
 				} else {
 					cp.result, err = cp.stmt.Exec()
 				}
 			} else {
-				if cp.hasResult {
+				if cp.hasResult || cp.isCall {
 					cp.rows, err = cp.stmt.Query(bindinput...)
 				} else {
 					cp.result, err = cp.stmt.Exec(bindinput...)
 				}
 			}
+			cp.stmtTiming.execDur = time.Since(execStart)
 			if err != nil {
+				cp.stmtTiming.err = err.Error()
 				cp.adapter.ProcessError(err, &cp.WorkerScope, &cp.queryScope)
+				cp.invalidateStmt(err)
 				cp.calExecErr("RC", err.Error())
 				if logger.GetLogger().V(logger.Warning) {
 					logger.GetLogger().Log(logger.Warning, "Execute error:", err.Error())
@@ -535,6 +646,7 @@ outloop:
 				if logger.GetLogger().V(logger.Debug) {
 					logger.GetLogger().Log(logger.Debug, "exe row", rowcnt)
 				}
+				cp.stmtTiming.rows = rowcnt
 
 				lastId, err := cp.result.LastInsertId()
 				if err != nil {
@@ -588,6 +700,15 @@ outloop:
 				if logger.GetLogger().V(logger.Debug) {
 					logger.GetLogger().Log(logger.Debug, "exe col", cols, len(cols))
 				}
+				if cp.isCall {
+					// a procedure returning result sets is read like a query, else like a DML
+					if len(cols) > 0 {
+						cp.hasResult = true
+					} else {
+						cp.rows.Close()
+						cp.rows = nil
+					}
+				}
 				// TODO: what is there are rows?
 				sz := 2
 				if len(cp.bindOuts) > 0 {
@@ -610,7 +731,7 @@ outloop:
 							cp.eor(EOR_IN_CURSOR_NOT_IN_TRANSACTION, resns)
 						}
 					*/
-					WriteAll(cp.SocketOut, resns)
+					cp.writeResponse(resns)
 				} else {
 					if cp.inTrans {
 						cp.eor(common.EORInTransaction, resns)
@@ -661,6 +782,7 @@ outloop:
 				readCols[i] = &writeCols[i]
 			}
 			fetchBufferLen := 0
+			fetchStart := time.Now()
 			for cp.rows.Next() {
 				err = cp.rows.Scan(readCols...)
 				if err != nil {
@@ -673,6 +795,7 @@ outloop:
 					calt.Completed()
 					break
 				}
+				cp.stmtTiming.rows++
 				for i := range writeCols {
 					var outstr string
 					if writeCols[i].Valid {
@@ -685,10 +808,11 @@ outloop:
 					fetchBufferLen += len(outstr)
 				}
 			}
+			cp.stmtTiming.fetchDur += time.Since(fetchStart)
 			calt.AddDataInt("psize", int64(fetchBufferLen))
 			if len(nss) > 0 {
 				resns := netstring.NewNetstringEmbedded(nss)
-				err = WriteAll(cp.SocketOut, resns)
+				err = cp.writeResponse(resns)
 				if err != nil {
 					if logger.GetLogger().V(logger.Warning) {
 						logger.GetLogger().Log(logger.Warning, "Error writing to mux", err.Error())
@@ -700,6 +824,19 @@ outloop:
 				}
 			}
 			calt.Completed()
+			if cp.rows.NextResultSet() {
+				// the client fetches the next result set, the cursor stays open
+				cols, err = cp.rows.Columns()
+				if err == nil {
+					err = cp.writeResponse(netstring.NewNetstringFrom(common.RcMoreResults, []byte(strconv.Itoa(len(cols)))))
+				}
+				if err != nil {
+					if logger.GetLogger().V(logger.Warning) {
+						logger.GetLogger().Log(logger.Warning, "next result set:", err.Error())
+					}
+				}
+				break
+			}
 			if cp.inTrans {
 				cp.eor(common.EORInTransaction, netstring.NewNetstringFrom(common.RcNoMoreData, nil))
 			} else {
@@ -736,7 +873,7 @@ outloop:
 		}
 		if cts == nil {
 			ns := netstring.NewNetstringFrom(common.RcValue, []byte("0"))
-			err = WriteAll(cp.SocketOut, ns)
+			err = cp.writeResponse(ns)
 		} else {
 			nss := make([]*netstring.Netstring, len(cts)*5+1)
 			nss[0] = netstring.NewNetstringFrom(common.RcValue, []byte(strconv.Itoa(len(cts))))
@@ -786,7 +923,7 @@ outloop:
 				cnt++
 			}
 			resns := netstring.NewNetstringEmbedded(nss)
-			err = WriteAll(cp.SocketOut, resns)
+			err = cp.writeResponse(resns)
 		}
 	case common.CmdCommit:
 		if logger.GetLogger().V(logger.Debug) {
@@ -824,6 +961,9 @@ outloop:
 		if err == nil {
 			cp.inTrans = false
 			cp.eor(common.EORFree, netstring.NewNetstringFrom(common.RcOK, nil))
+			if (cp.stmtCache != nil) && (cp.tx == nil) {
+				cp.stmtCache.preparePending(cp.db)
+			}
 		} else {
 			cp.eor(common.EORInTransaction, netstring.NewNetstringFrom(common.RcSQLError, []byte(err.Error())))
 			err = nil
@@ -854,6 +994,9 @@ outloop:
 		if err == nil {
 			cp.inTrans = false
 			cp.eor(common.EORFree, netstring.NewNetstringFrom(common.RcOK, nil))
+			if (cp.stmtCache != nil) && (cp.tx == nil) {
+				cp.stmtCache.preparePending(cp.db)
+			}
 		} else {
 			cp.eor(common.EORInTransaction, netstring.NewNetstringFrom(common.RcSQLError, []byte(err.Error())))
 			err = nil
@@ -908,6 +1051,7 @@ func (cp *CmdProcessor) InitDB() error {
 }
 
 func (cp *CmdProcessor) eor(code int, ns *netstring.Netstring) error {
+	cp.logSlowQuery()
 	if code == common.EORFree {
 		if cp.moreIncomingRequests() {
 			code = common.EORMoreIncomingRequests
@@ -926,12 +1070,26 @@ func (cp *CmdProcessor) eor(code int, ns *netstring.Netstring) error {
 			}
 			cp.rqIdEORFree = cp.rqId
 			cp.dedicated = false
+			// the next request can be from another client, the session is reset before the mux frees the
+			// worker. If the worker has to exit, EORRestart tells the mux not to give it to another client
+			cp.resetSession()
+			cp.checkSessionVarsFailed()
+			if cp.WorkerScope.Child_shutdown_flag {
+				code = common.EORRestart
+			}
 		}
 
 	}
 
 	datalen := 0
 	if ns != nil {
+		if cp.binaryResponses {
+			var err error
+			ns, err = toBinary(ns)
+			if err != nil {
+				return err
+			}
+		}
 		datalen = len(ns.Serialized)
 	}
 	payload := make([]byte, 1 /*code*/ +4 /*rqId*/ +datalen)
@@ -947,6 +1105,27 @@ func (cp *CmdProcessor) eor(code int, ns *netstring.Netstring) error {
 	return WriteAll(cp.SocketOut, netstring.NewNetstringFrom(common.CmdEOR, payload))
 }
 
+// writeResponse writes a response to the client, as a binary frame if the client sent its request in binary
+// frames. The mux forwards it as is
+func (cp *CmdProcessor) writeResponse(ns *netstring.Netstring) error {
+	if cp.binaryResponses {
+		var err error
+		ns, err = toBinary(ns)
+		if err != nil {
+			return err
+		}
+	}
+	return WriteAll(cp.SocketOut, ns)
+}
+
+func toBinary(ns *netstring.Netstring) (*netstring.Netstring, error) {
+	data, err := netstring.AppendBinary(nil, ns)
+	if err != nil {
+		return nil, err
+	}
+	return &netstring.Netstring{Cmd: ns.Cmd, Serialized: data, Payload: data[netstring.BinaryHeaderLen:]}, nil
+}
+
 func (cp *CmdProcessor) calExecErr(field string, err string) {
 	cp.calExecTxn.AddDataStr(field, err)
 	cp.calExecTxn.SetStatus(cal.TransError)
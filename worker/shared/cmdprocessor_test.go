@@ -0,0 +1,60 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/paypal/hera/common"
+	"github.com/paypal/hera/utility/encoding/netstring"
+)
+
+func TestBinaryResponses(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	cp := &CmdProcessor{SocketOut: w, binaryResponses: true}
+	value := netstring.NewNetstringEmbedded([]*netstring.Netstring{netstring.NewNetstringFrom(common.RcValue, []byte("1"))})
+	go func() {
+		cp.writeResponse(value)
+		cp.eor(common.EORInTransaction, netstring.NewNetstringFrom(common.RcOK, nil))
+	}()
+
+	ns, err := netstring.ReadFrame(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nss, err := netstring.SubNetstrings(ns)
+	if !ns.IsBinary() || (err != nil) || (len(nss) != 1) || !nss[0].IsBinary() || (string(nss[0].Payload) != "1") {
+		t.Errorf("expected a binary response, got %v %v", ns.Serialized, err)
+	}
+	// the EOR is read by the mux, it embeds the binary response to the client
+	ns, err = netstring.ReadFrame(r)
+	if (err != nil) || ns.IsBinary() || (ns.Cmd != common.CmdEOR) {
+		t.Fatalf("expected a text EOR, got %v %v", ns, err)
+	}
+	ns, err = netstring.ReadFrame(bytes.NewReader(ns.Payload[5:]))
+	if (err != nil) || !ns.IsBinary() || (ns.Cmd != common.RcOK) {
+		t.Errorf("expected a binary response in the EOR, got %v %v", ns, err)
+	}
+}
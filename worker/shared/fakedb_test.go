@@ -0,0 +1,293 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/paypal/hera/common"
+	"github.com/paypal/hera/utility/encoding/netstring"
+)
+
+// fakeDB is a database/sql driver recording the statements run, for testing the CmdProcessor without a
+// database
+type fakeDB struct {
+	// "begin", "commit", "rollback" and "exec <query> <args>" or "query <query> <args>"
+	log []string
+	// returns the error of a statement, nil to succeed
+	fail func(query string, args []driver.Value) error
+	// returns the result sets of a query
+	results func(query string, args []driver.Value) []fakeResult
+	// number of connections opened and of statements prepared
+	connects int
+	prepares int
+}
+
+// fakeResult is a result set returned by the fake driver
+type fakeResult struct {
+	cols []string
+	rows [][]driver.Value
+}
+
+func (db *fakeDB) Connect(context.Context) (driver.Conn, error) {
+	db.connects++
+	return &fakeConn{db: db}, nil
+}
+
+func (db *fakeDB) Driver() driver.Driver {
+	return db
+}
+
+func (db *fakeDB) Open(string) (driver.Conn, error) {
+	return db.Connect(context.Background())
+}
+
+func (db *fakeDB) run(op string, query string, args []driver.Value) error {
+	db.log = append(db.log, fmt.Sprintf("%s %s %v", op, query, args))
+	if db.fail != nil {
+		return db.fail(query, args)
+	}
+	return nil
+}
+
+// logged returns the log and clears it
+func (db *fakeDB) logged() []string {
+	log := db.log
+	db.log = nil
+	return log
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	c.db.prepares++
+	return &fakeStmt{db: c.db, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.db.log = append(c.db.log, "begin")
+	return &fakeTx{db: c.db}, nil
+}
+
+type fakeTx struct {
+	db *fakeDB
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.db.log = append(tx.db.log, "commit")
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.db.log = append(tx.db.log, "rollback")
+	return nil
+}
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (st *fakeStmt) Close() error {
+	return nil
+}
+
+func (st *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (st *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if err := st.db.run("exec", st.query, args); err != nil {
+		return nil, err
+	}
+	// one row per row of values
+	return driver.RowsAffected(strings.Count(st.query, "),(") + 1), nil
+}
+
+func (st *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if err := st.db.run("query", st.query, args); err != nil {
+		return nil, err
+	}
+	rows := &fakeRows{}
+	if st.db.results != nil {
+		rows.results = st.db.results(st.query, args)
+	}
+	if len(rows.results) == 0 {
+		rows.results = []fakeResult{{}}
+	}
+	return rows, nil
+}
+
+type fakeRows struct {
+	results []fakeResult
+	row     int
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.results[0].cols
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.row >= len(r.results[0].rows) {
+		return io.EOF
+	}
+	copy(dest, r.results[0].rows[r.row])
+	r.row++
+	return nil
+}
+
+func (r *fakeRows) HasNextResultSet() bool {
+	return len(r.results) > 1
+}
+
+func (r *fakeRows) NextResultSet() error {
+	if len(r.results) < 2 {
+		return io.EOF
+	}
+	r.results = r.results[1:]
+	r.row = 0
+	return nil
+}
+
+// fakeAdapter is a CmdProcessorAdapter on a fakeDB, with the binds of MySQL unless useBindNames or
+// useDollarBinds (Postgres) is set
+type fakeAdapter struct {
+	db             *fakeDB
+	useBindNames   bool
+	useDollarBinds bool
+	useArrayDML    bool
+	useMultiInsert bool
+}
+
+func (adapter *fakeAdapter) MakeSqlParser() (common.SQLParser, error) {
+	return common.NewRegexSQLParser()
+}
+
+func (adapter *fakeAdapter) GetColTypeMap() map[string]int {
+	return map[string]int{}
+}
+
+func (adapter *fakeAdapter) Heartbeat(*sql.DB) bool {
+	return true
+}
+
+func (adapter *fakeAdapter) InitDB() (*sql.DB, error) {
+	return sql.OpenDB(adapter.db), nil
+}
+
+func (adapter *fakeAdapter) ProcessError(errToProcess error, workerScope *WorkerScopeType, queryScope *QueryScopeType) {
+}
+
+func (adapter *fakeAdapter) ProcessResult(colType string, res string) string {
+	return res
+}
+
+func (adapter *fakeAdapter) UseBindNames() bool {
+	return adapter.useBindNames
+}
+
+func (adapter *fakeAdapter) UseBindQuestionMark() bool {
+	return !adapter.useBindNames && !adapter.useDollarBinds
+}
+
+func (adapter *fakeAdapter) UseArrayDML() bool {
+	return adapter.useArrayDML
+}
+
+func (adapter *fakeAdapter) UseMultiRowInsert() bool {
+	return adapter.useMultiInsert
+}
+
+func (adapter *fakeAdapter) OutBind(name string) (string, bool) {
+	if adapter.useDollarBinds {
+		return "NULL", false
+	}
+	return "@hera_out_" + name, true
+}
+
+// newTestCmdProcessor returns a CmdProcessor on the adapter, and the reader of its responses
+func newTestCmdProcessor(t *testing.T, adapter CmdProcessorAdapter) (*CmdProcessor, *netstring.Reader) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		r.Close()
+		w.Close()
+	})
+	cp := NewCmdProcessor(adapter, w, nil)
+	cp.moreIncomingRequests = func() bool { return false }
+	if err = cp.InitDB(); err != nil {
+		t.Fatal(err)
+	}
+	return cp, netstring.NewNetstringReader(r)
+}
+
+// process runs the commands of a request, failing the test if one returns an error
+func process(t *testing.T, cp *CmdProcessor, nss ...*netstring.Netstring) {
+	cp.rqId++
+	for _, ns := range nss {
+		if err := cp.ProcessCmd(ns); err != nil {
+			t.Fatalf("command %d: %s", ns.Cmd, err.Error())
+		}
+	}
+}
+
+// readEOR reads the next response, which must be an EOR, and returns its code and the responses in it
+func readEOR(t *testing.T, reader *netstring.Reader) (int, []*netstring.Netstring) {
+	ns, err := reader.ReadNext()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns.Cmd != common.CmdEOR {
+		t.Fatalf("expected an EOR, got %d %q", ns.Cmd, ns.Payload)
+	}
+	code := int(ns.Payload[0] - '0')
+	if len(ns.Payload) == 5 {
+		return code, nil
+	}
+	resp, err := netstring.NewNetstring(strings.NewReader(string(ns.Payload[5:])))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsComposite() {
+		return code, []*netstring.Netstring{resp}
+	}
+	nss, err := netstring.SubNetstrings(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code, nss
+}
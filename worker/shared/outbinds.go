@@ -0,0 +1,235 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paypal/hera/common"
+	"github.com/paypal/hera/utility/encoding/netstring"
+	"github.com/paypal/hera/utility/logger"
+)
+
+// Out binds for the adapters not using bind names (MySQL, Postgres). The SQL is rewritten: the in binds
+// become the placeholders of the driver, each out bind becomes what the adapter's OutBind returns. On
+// MySQL it is a session variable, selected after the execute. On Postgres it is NULL, the procedure returns
+// its OUT parameters as a row. "RETURNING ... INTO :a, :b" becomes "RETURNING ...", the out binds get the
+// values of the returned row.
+
+// regexCall matches the stored procedure calls
+var regexCall = regexp.MustCompile(`(?i)^\s*CALL\s`)
+
+// regexReturningInto matches the INTO clause of a DML returning values
+var regexReturningInto = regexp.MustCompile(`(?is)\bRETURNING\b.+?(\s+INTO\s+:\w+(?:\s*,\s*:\w+)*)\s*;?\s*$`)
+
+// hasOutBinds tells if the client bound out binds in the statement
+func (cp *CmdProcessor) hasOutBinds() bool {
+	for _, key := range cp.bindPos {
+		if cp.bindVars[key].btype == btOut {
+			return true
+		}
+	}
+	return false
+}
+
+// outBindQuery rewrites the SQL for the out binds, returning it with the bind values and the session
+// variables to select after the execute
+func (cp *CmdProcessor) outBindQuery() (query string, bindinput []interface{}, sessionVars []string, err error) {
+	sqlText := cp.sqlText
+	if loc := regexReturningInto.FindStringSubmatchIndex(sqlText); loc != nil {
+		sqlText = sqlText[:loc[2]] + sqlText[loc[3]:]
+	}
+	var buf strings.Builder
+	// the number of the $n placeholder of each in bind
+	dollars := make(map[string]int)
+	curIdx := 0
+	for _, matchIdx := range cp.regexBindName.FindAllStringIndex(sqlText, -1) {
+		key := sqlText[matchIdx[0]:matchIdx[1]]
+		val := cp.bindVars[key]
+		if val == nil {
+			return "", nil, nil, fmt.Errorf("bindname not found in query: %s", key)
+		}
+		buf.WriteString(sqlText[curIdx:matchIdx[0]])
+		curIdx = matchIdx[1]
+		switch val.btype {
+		case btIn:
+			if !val.valid {
+				return "", nil, nil, fmt.Errorf("bindname undefined: %s", key)
+			}
+			if cp.adapter.UseBindQuestionMark() {
+				buf.WriteString("?")
+				bindinput = append(bindinput, val.value)
+			} else {
+				num, ok := dollars[key]
+				if !ok {
+					bindinput = append(bindinput, val.value)
+					num = len(bindinput)
+					dollars[key] = num
+				}
+				buf.WriteString("$" + strconv.Itoa(num))
+			}
+		case btOut:
+			placeholder, sessionVar := cp.adapter.OutBind(key[1:])
+			buf.WriteString(placeholder)
+			if sessionVar {
+				sessionVars = append(sessionVars, placeholder)
+			}
+		default:
+			return "", nil, nil, fmt.Errorf("bindname undefined: %s", key)
+		}
+	}
+	buf.WriteString(sqlText[curIdx:])
+	return buf.String(), bindinput, sessionVars, nil
+}
+
+// execOutBinds runs the statement and returns the number of rows changed and the values of the out binds
+func (cp *CmdProcessor) execOutBinds() (rowcnt int64, outs []string, err error) {
+	query, bindinput, sessionVars, err := cp.outBindQuery()
+	if err != nil {
+		return 0, nil, err
+	}
+	if logger.GetLogger().V(logger.Debug) {
+		logger.GetLogger().Log(logger.Debug, "Executing with out binds:", query)
+		logger.GetLogger().Log(logger.Debug, "BINDS", bindinput)
+	}
+	// the statement runs in the transaction started at prepare, if any. The worker has one connection, the
+	// session variables are selected on the connection which ran the statement
+	exec := cp.db.Exec
+	queryRows := cp.db.Query
+	if cp.tx != nil {
+		exec = cp.tx.Exec
+		queryRows = cp.tx.Query
+	}
+	var values []sql.NullString
+	if len(sessionVars) > 0 {
+		cp.result, err = exec(query, bindinput...)
+		if err != nil {
+			return 0, nil, err
+		}
+		rowcnt, _ = cp.result.RowsAffected()
+		rows, err := queryRows("SELECT " + strings.Join(sessionVars, ", "))
+		if err != nil {
+			return 0, nil, err
+		}
+		values, _, err = scanOutRows(rows)
+		if err != nil {
+			return 0, nil, err
+		}
+	} else {
+		rows, err := queryRows(query, bindinput...)
+		if err != nil {
+			return 0, nil, err
+		}
+		values, rowcnt, err = scanOutRows(rows)
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+	if cp.sendLastInsertId {
+		var lastId string
+		if cp.result != nil {
+			if id, err := cp.result.LastInsertId(); err == nil {
+				lastId = strconv.FormatInt(id, 10)
+			}
+		}
+		outs = append(outs, lastId)
+	}
+	// one value per out bind, empty for NULL and for the out binds the statement did not return
+	numOuts := cp.numBindOuts - len(outs)
+	for i := 0; i < numOuts; i++ {
+		if i < len(values) {
+			outs = append(outs, values[i].String)
+		} else {
+			outs = append(outs, "")
+		}
+	}
+	return rowcnt, outs, nil
+}
+
+// scanOutRows returns the values of the first row and the number of rows
+func scanOutRows(rows *sql.Rows) ([]sql.NullString, int64, error) {
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, 0, err
+	}
+	values := make([]sql.NullString, len(cols))
+	var cnt int64
+	for rows.Next() {
+		if cnt == 0 {
+			dest := make([]interface{}, len(values))
+			for i := range values {
+				dest[i] = &values[i]
+			}
+			err = rows.Scan(dest...)
+			if err != nil {
+				return nil, 0, err
+			}
+		}
+		cnt++
+	}
+	return values, cnt, rows.Err()
+}
+
+// executeOutBinds runs a statement having out binds and sends the response, the same as for a DML
+// with out binds on Oracle
+func (cp *CmdProcessor) executeOutBinds() {
+	if _, ok := cp.bindVars[LAST_INSERT_ID_BIND_OUT_NAME]; ok {
+		cp.sendLastInsertId = true
+	}
+	execStart := time.Now()
+	rowcnt, outs, err := cp.execOutBinds()
+	cp.stmtTiming.execDur = time.Since(execStart)
+	if err != nil {
+		cp.stmtTiming.err = err.Error()
+		cp.adapter.ProcessError(err, &cp.WorkerScope, &cp.queryScope)
+		cp.calExecErr("RC", err.Error())
+		if logger.GetLogger().V(logger.Warning) {
+			logger.GetLogger().Log(logger.Warning, "Execute error:", err.Error())
+		}
+		if cp.inTrans || cp.tx != nil {
+			cp.eor(common.EORInTransaction, netstring.NewNetstringFrom(common.RcSQLError, []byte(err.Error())))
+		} else {
+			cp.eor(common.EORFree, netstring.NewNetstringFrom(common.RcSQLError, []byte(err.Error())))
+		}
+		cp.lastErr = err
+		return
+	}
+	if cp.tx != nil {
+		cp.inTrans = true
+	}
+	cp.calExecTxn.Completed()
+	cp.calExecTxn = nil
+	cp.stmtTiming.rows = rowcnt
+	if logger.GetLogger().V(logger.Verbose) {
+		logger.GetLogger().Log(logger.Verbose, "BINDOUTS", len(outs), outs)
+	}
+	nss := make([]*netstring.Netstring, 0, len(outs)+3)
+	nss = append(nss, netstring.NewNetstringFrom(common.RcValue, []byte("0")))
+	nss = append(nss, netstring.NewNetstringFrom(common.RcValue, []byte(strconv.FormatInt(rowcnt, 10))))
+	nss = append(nss, netstring.NewNetstringFrom(common.RcValue, []byte("1")))
+	for _, out := range outs {
+		nss = append(nss, netstring.NewNetstringFrom(common.RcValue, []byte(out)))
+	}
+	cp.eor(common.EORInTransaction, netstring.NewNetstringEmbedded(nss))
+}
@@ -0,0 +1,155 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/paypal/hera/common"
+	"github.com/paypal/hera/utility/encoding/netstring"
+)
+
+// payloads returns the payloads of the responses
+func payloads(nss []*netstring.Netstring) []string {
+	values := make([]string, len(nss))
+	for i, ns := range nss {
+		values[i] = string(ns.Payload)
+	}
+	return values
+}
+
+func TestOutBinds(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		adapter *fakeAdapter
+		query   string
+		outs    []string
+		row     []driver.Value
+		run     []string
+		values  []string
+	}{
+		{"mysql session variables", &fakeAdapter{}, "call get_user(:id, :name, :age)", []string{"name", "age"},
+			[]driver.Value{"alice", "42"},
+			[]string{"exec call get_user(?, @hera_out_name, @hera_out_age) [7]", "query SELECT @hera_out_name, @hera_out_age []"},
+			[]string{"0", "1", "1", "alice", "42"}},
+		{"postgres placeholders", &fakeAdapter{useDollarBinds: true}, "call get_user(:id, :name, :id)", []string{"name"},
+			[]driver.Value{"alice"},
+			[]string{"query call get_user($1, NULL, $1) [7]"},
+			[]string{"0", "1", "1", "alice"}},
+		{"returning into", &fakeAdapter{useDollarBinds: true}, "insert into t (a) values (:id) returning id, name into :oid, :name",
+			[]string{"oid", "name"}, []driver.Value{"11", nil},
+			[]string{"query insert into t (a) values ($1) returning id, name [7]"},
+			[]string{"0", "1", "1", "11", ""}},
+	} {
+		db := &fakeDB{results: func(query string, args []driver.Value) []fakeResult {
+			return []fakeResult{{cols: make([]string, len(tc.row)), rows: [][]driver.Value{tc.row}}}
+		}}
+		tc.adapter.db = db
+		cp, reader := newTestCmdProcessor(t, tc.adapter)
+		nss := []*netstring.Netstring{
+			netstring.NewNetstringFrom(common.CmdPrepareV2, []byte(tc.query)),
+			netstring.NewNetstringFrom(common.CmdBindName, []byte("id")),
+			netstring.NewNetstringFrom(common.CmdBindValue, []byte("7")),
+		}
+		for _, out := range tc.outs {
+			nss = append(nss, netstring.NewNetstringFrom(common.CmdBindOutName, []byte(out)))
+		}
+		process(t, cp, append(nss, netstring.NewNetstringFrom(common.CmdExecute, nil))...)
+		_, resp := readEOR(t, reader)
+		if values := payloads(resp); !reflect.DeepEqual(values, tc.values) {
+			t.Errorf("%s: response %q, expected %q", tc.name, values, tc.values)
+		}
+		var run []string
+		for _, line := range db.logged() {
+			if (line != "begin") && (line != "commit") {
+				run = append(run, line)
+			}
+		}
+		if !reflect.DeepEqual(run, tc.run) {
+			t.Errorf("%s: ran %q, expected %q", tc.name, run, tc.run)
+		}
+	}
+}
+
+func TestMoreResults(t *testing.T) {
+	db := &fakeDB{results: func(query string, args []driver.Value) []fakeResult {
+		return []fakeResult{
+			{cols: []string{"name"}, rows: [][]driver.Value{{"a"}, {"b"}}},
+			{cols: []string{"id", "name"}, rows: [][]driver.Value{{"1", "c"}}},
+		}
+	}}
+	cp, reader := newTestCmdProcessor(t, &fakeAdapter{db: db})
+	process(t, cp, netstring.NewNetstringFrom(common.CmdPrepareV2, []byte("call list_users()")),
+		netstring.NewNetstringFrom(common.CmdExecute, nil))
+	// the number of columns and of rows, the reader returns the values of the composite one by one
+	var values []string
+	for i := 0; i < 2; i++ {
+		ns, err := reader.ReadNext()
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, string(ns.Payload))
+	}
+	if strings.Join(values, ",") != "1,0" {
+		t.Fatalf("unexpected execute response %q", values)
+	}
+
+	// the first result set ends with the number of columns of the next one, the worker stays allocated
+	process(t, cp, netstring.NewNetstringFrom(common.CmdFetch, []byte("0")))
+	var got []string
+	for {
+		ns, err := reader.ReadNext()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ns.Cmd == common.RcMoreResults {
+			got = append(got, "more:"+string(ns.Payload))
+			break
+		}
+		got = append(got, string(ns.Payload))
+	}
+	if !reflect.DeepEqual(got, []string{"a", "b", "more:2"}) {
+		t.Errorf("first result set %q", got)
+	}
+	if cp.rows == nil {
+		t.Fatal("the cursor is closed before the next result set is fetched")
+	}
+
+	process(t, cp, netstring.NewNetstringFrom(common.CmdFetch, []byte("0")))
+	values = values[:0]
+	for i := 0; i < 2; i++ {
+		ns, err := reader.ReadNext()
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, string(ns.Payload))
+	}
+	if strings.Join(values, ",") != "1,c" {
+		t.Errorf("second result set %q", values)
+	}
+	code, resp := readEOR(t, reader)
+	if (len(resp) != 1) || (resp[0].Cmd != common.RcNoMoreData) || (code == common.EORMoreIncomingRequests) {
+		t.Errorf("expected the end of the rows, got %d %v", code, resp)
+	}
+	if cp.rows != nil {
+		t.Error("the cursor is still open")
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/paypal/hera/cal"
+	"github.com/paypal/hera/config"
+	"github.com/paypal/hera/utility/logger"
+)
+
+// SessionResetter is implemented by the adapters which can clear the state of the session (variables,
+// temporary tables, prepared statements, locks) left by a client, before the worker serves another client
+type SessionResetter interface {
+	ResetSession(db *sql.DB) error
+}
+
+// sessionReset tracks the statements altering the session. The session is reset when the worker is freed
+// after such a statement
+type sessionReset struct {
+	// the statement resetting the session, the adapter's ResetSession if empty
+	sql string
+	// tells if the session was altered since the last reset
+	altered bool
+	// the sql hash of the first statement which altered the session, for CAL
+	alteredBy uint32
+}
+
+// newSessionReset returns the session reset if "enable_session_reset" is set, nil otherwise
+func newSessionReset(cfg config.Config) *sessionReset {
+	if !cfg.GetOrDefaultBool("enable_session_reset", false) {
+		return nil
+	}
+	return &sessionReset{sql: strings.TrimSpace(cfg.GetOrDefaultString("session_reset_sql", ""))}
+}
+
+// checkSessionAltered remembers that the current statement alters the session
+func (cp *CmdProcessor) checkSessionAltered(sqlQuery string) {
+	sr := cp.sessionReset
+	if (sr != nil) && !sr.altered && cp.sqlParser.AltersSession(sqlQuery) {
+		sr.altered = true
+		sr.alteredBy = cp.sqlHash
+		if logger.GetLogger().V(logger.Debug) {
+			logger.GetLogger().Log(logger.Debug, "session altered by", cp.sqlHash)
+		}
+	}
+}
+
+// resetSession resets the session if a statement altered it, it is called before the worker is freed. If the
+// reset fails the worker exits, the mux does not give it to the next client
+func (cp *CmdProcessor) resetSession() {
+	sr := cp.sessionReset
+	if (sr == nil) || !sr.altered {
+		return
+	}
+	sr.altered = false
+	var err error
+	if len(sr.sql) > 0 {
+		_, err = cp.db.Exec(sr.sql)
+	} else if resetter, ok := cp.adapter.(SessionResetter); ok {
+		err = resetter.ResetSession(cp.db)
+	} else {
+		return
+	}
+	// the statements prepared on the connection may be gone with the session
+	if cp.stmtCache != nil {
+		cp.stmtCache.clear()
+	}
+	if err == nil {
+		// the variables set by the client are kept, as the mux expects
+		err = cp.applySessionVars(nil, cp.sessionVars)
+	}
+	evt := cal.NewCalEvent("SESSION_RESET", fmt.Sprintf("%d", sr.alteredBy), cal.TransOK, "")
+	if err != nil {
+		if logger.GetLogger().V(logger.Warning) {
+			logger.GetLogger().Log(logger.Warning, "session reset failed, worker exiting:", err.Error())
+		}
+		evt.SetStatus(cal.TransError)
+		evt.AddDataStr("err", err.Error())
+		cp.WorkerScope.Child_shutdown_flag = true
+	}
+	evt.Completed()
+}
@@ -0,0 +1,133 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/paypal/hera/common"
+	"github.com/paypal/hera/utility/encoding/netstring"
+)
+
+// resetAdapter is a fakeAdapter implementing SessionResetter and SessionVarSetter
+type resetAdapter struct {
+	fakeAdapter
+	resets int
+	err    error
+	// the variables set, "name=value" or "name" when reset
+	set    []string
+	setErr error
+}
+
+func (adapter *resetAdapter) ResetSession(db *sql.DB) error {
+	adapter.resets++
+	return adapter.err
+}
+
+func (adapter *resetAdapter) SetSessionVar(db *sql.DB, name string, value string) error {
+	adapter.set = append(adapter.set, name+"="+value)
+	return adapter.setErr
+}
+
+func (adapter *resetAdapter) ResetSessionVar(db *sql.DB, name string) error {
+	adapter.set = append(adapter.set, name)
+	return adapter.setErr
+}
+
+// execFree runs a statement and commits, it returns the code of the EOR of the commit
+func execFree(t *testing.T, cp *CmdProcessor, reader *netstring.Reader, sql string) int {
+	process(t, cp, netstring.NewNetstringFrom(common.CmdPrepareV2, []byte(sql)),
+		netstring.NewNetstringFrom(common.CmdExecute, nil))
+	readEOR(t, reader)
+	process(t, cp, netstring.NewNetstringFrom(common.CmdCommit, nil))
+	code, _ := readEOR(t, reader)
+	return code
+}
+
+func TestResetSessionSQL(t *testing.T) {
+	db := &fakeDB{}
+	cp, reader := newTestCmdProcessor(t, &fakeAdapter{db: db})
+	cp.sessionReset = &sessionReset{sql: "reset session"}
+	cp.stmtCache = newTestStmtCache(2)
+
+	if code := execFree(t, cp, reader, "update t set a = 1"); code != common.EORFree {
+		t.Errorf("expected EORFree, got %d", code)
+	}
+	for _, op := range db.logged() {
+		if op == "exec reset session []" {
+			t.Error("the session was reset but not altered")
+		}
+	}
+
+	if code := execFree(t, cp, reader, "set @a = 1"); code != common.EORFree {
+		t.Errorf("expected EORFree, got %d", code)
+	}
+	log := db.logged()
+	if (len(log) == 0) || (log[len(log)-1] != "exec reset session []") {
+		t.Errorf("expected the session reset after the commit, ran %v", log)
+	}
+	if cp.sessionReset.altered || (cp.stmtCache.lru.Len() != 0) || cp.WorkerScope.Child_shutdown_flag {
+		t.Error("expected the session reset, the statement cache cleared and the worker kept")
+	}
+}
+
+func TestResetSessionAdapter(t *testing.T) {
+	adapter := &resetAdapter{fakeAdapter: fakeAdapter{db: &fakeDB{}}}
+	cp, reader := newTestCmdProcessor(t, adapter)
+	cp.sessionReset = &sessionReset{}
+
+	if code := execFree(t, cp, reader, "set @a = 1"); (code != common.EORFree) || (adapter.resets != 1) {
+		t.Errorf("expected EORFree after one reset, got %d after %d resets", code, adapter.resets)
+	}
+	if code := execFree(t, cp, reader, "update t set a = 1"); (code != common.EORFree) || (adapter.resets != 1) {
+		t.Errorf("expected no reset for a session not altered, got %d after %d resets", code, adapter.resets)
+	}
+
+	// the worker exits if the reset fails, the EOR tells the mux not to give it to another client
+	adapter.err = driver.ErrBadConn
+	if code := execFree(t, cp, reader, "create temporary table tmp (a int)"); code != common.EORRestart {
+		t.Errorf("expected EORRestart after a failed reset, got %d", code)
+	}
+	if !cp.WorkerScope.Child_shutdown_flag {
+		t.Error("expected the worker to exit after a failed reset")
+	}
+}
+
+func TestResetSessionVars(t *testing.T) {
+	adapter := &resetAdapter{fakeAdapter: fakeAdapter{db: &fakeDB{}}}
+	cp, reader := newTestCmdProcessor(t, adapter)
+	cp.sessionReset = &sessionReset{}
+	cp.sessionVars = map[string]string{"time_zone": "'+00:00'"}
+
+	// the variables set by the client are set again after the reset
+	if code := execFree(t, cp, reader, "set @a = 1"); code != common.EORFree {
+		t.Errorf("expected EORFree, got %d", code)
+	}
+	if !reflect.DeepEqual(adapter.set, []string{"time_zone='+00:00'"}) {
+		t.Errorf("expected the session variables set again after the reset, set %v", adapter.set)
+	}
+
+	adapter.setErr = errors.New("unknown time zone")
+	if code := execFree(t, cp, reader, "set @a = 1"); code != common.EORRestart {
+		t.Errorf("expected EORRestart when the session variables can't be set again, got %d", code)
+	}
+}
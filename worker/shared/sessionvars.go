@@ -0,0 +1,119 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/paypal/hera/cal"
+	"github.com/paypal/hera/utility/logger"
+)
+
+// SessionVarSetter is implemented by the adapters which can set the session variables registered by the
+// clients, like the time zone or the NLS settings
+type SessionVarSetter interface {
+	SetSessionVar(db *sql.DB, name string, value string) error
+	// ResetSessionVar sets the variable back to its default
+	ResetSessionVar(db *sql.DB, name string) error
+}
+
+var (
+	errSessionVarsUnsupported   = errors.New("session variables not supported by the worker")
+	errSessionVarsInTransaction = errors.New("session variables set in transaction")
+)
+
+// parseSessionVars parses the payload of CmdSetSessionVars, one "name=value" per line. The mux already
+// checked the names against session_variables_allowed
+func parseSessionVars(payload []byte) map[string]string {
+	vars := make(map[string]string)
+	for _, line := range strings.Split(string(payload), "\n") {
+		pos := strings.Index(line, "=")
+		if pos > 0 {
+			vars[line[:pos]] = line[pos+1:]
+		}
+	}
+	return vars
+}
+
+// setSessionVars applies the session variables of the client, only the ones different from what is set on
+// the session. The variables set before and not in the payload are reset. If it fails the error is returned
+// by the next statement and the worker exits when freed, since its session is partly changed
+func (cp *CmdProcessor) setSessionVars(payload []byte) {
+	vars := parseSessionVars(payload)
+	cp.sessionVarsErr = cp.applySessionVars(cp.sessionVars, vars)
+	evt := cal.NewCalEvent("SESSION_VARIABLES", "set", cal.TransOK, "")
+	evt.AddDataInt("count", int64(len(vars)))
+	if cp.sessionVarsErr != nil {
+		if logger.GetLogger().V(logger.Warning) {
+			logger.GetLogger().Log(logger.Warning, "setting the session variables failed:", cp.sessionVarsErr.Error())
+		}
+		evt.SetStatus(cal.TransError)
+		evt.AddDataStr("err", cp.sessionVarsErr.Error())
+		cp.sessionVarsFailed = true
+	}
+	evt.Completed()
+	cp.sessionVars = vars
+}
+
+// applySessionVars changes the session variables from prev to vars
+func (cp *CmdProcessor) applySessionVars(prev map[string]string, vars map[string]string) error {
+	setter, ok := cp.adapter.(SessionVarSetter)
+	if !ok {
+		if (len(prev) == 0) && (len(vars) == 0) {
+			return nil
+		}
+		return errSessionVarsUnsupported
+	}
+	if cp.tx != nil {
+		// the connection is used by the transaction
+		return errSessionVarsInTransaction
+	}
+	for name := range prev {
+		if _, ok := vars[name]; !ok {
+			if err := setter.ResetSessionVar(cp.db, name); err != nil {
+				return fmt.Errorf("reset %s: %s", name, err.Error())
+			}
+		}
+	}
+	for name, value := range vars {
+		if prevValue, ok := prev[name]; ok && (prevValue == value) {
+			continue
+		}
+		if logger.GetLogger().V(logger.Debug) {
+			logger.GetLogger().Log(logger.Debug, "set session variable", name, "=", value)
+		}
+		if err := setter.SetSessionVar(cp.db, name, value); err != nil {
+			return fmt.Errorf("set %s: %s", name, err.Error())
+		}
+	}
+	return nil
+}
+
+// checkSessionVarsFailed makes the worker exit if setting the session variables failed, it is called before
+// the worker is freed
+func (cp *CmdProcessor) checkSessionVarsFailed() {
+	if cp.sessionVarsFailed {
+		if logger.GetLogger().V(logger.Warning) {
+			logger.GetLogger().Log(logger.Warning, "session variables partly set, worker exiting")
+		}
+		cp.WorkerScope.Child_shutdown_flag = true
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/paypal/hera/common"
+	"github.com/paypal/hera/utility/encoding/netstring"
+)
+
+func TestParseSessionVarsWorker(t *testing.T) {
+	vars := parseSessionVars([]byte("nls_date_format=YYYY-MM-DD\ntime_zone=+00:00\n\nsql_mode\nsearch_path=a=b"))
+	expected := map[string]string{"nls_date_format": "YYYY-MM-DD", "time_zone": "+00:00", "search_path": "a=b"}
+	if !reflect.DeepEqual(vars, expected) {
+		t.Errorf("parsed %v, expected %v", vars, expected)
+	}
+	if vars = parseSessionVars(nil); len(vars) != 0 {
+		t.Errorf("parsed %v from an empty payload", vars)
+	}
+}
+
+// setVars runs a request after setting the session variables, it returns the variables set and reset by
+// the adapter, sorted
+func setVars(t *testing.T, cp *CmdProcessor, reader *netstring.Reader, adapter *resetAdapter, payload string) []string {
+	adapter.set = nil
+	process(t, cp, netstring.NewNetstringFrom(common.CmdSetSessionVars, []byte(payload)))
+	if code := execFree(t, cp, reader, "update t set a = 1"); code != common.EORFree {
+		t.Errorf("%q: expected EORFree, got %d", payload, code)
+	}
+	sort.Strings(adapter.set)
+	return adapter.set
+}
+
+func TestSetSessionVars(t *testing.T) {
+	adapter := &resetAdapter{fakeAdapter: fakeAdapter{db: &fakeDB{}}}
+	cp, reader := newTestCmdProcessor(t, adapter)
+
+	set := setVars(t, cp, reader, adapter, "time_zone=UTC\nsql_mode=ANSI")
+	if !reflect.DeepEqual(set, []string{"sql_mode=ANSI", "time_zone=UTC"}) {
+		t.Errorf("set %v", set)
+	}
+	// only the changes are applied, the variables not in the payload are reset
+	set = setVars(t, cp, reader, adapter, "time_zone=UTC\nnls_sort=BINARY")
+	if !reflect.DeepEqual(set, []string{"nls_sort=BINARY", "sql_mode"}) {
+		t.Errorf("set %v, expected sql_mode reset and nls_sort set", set)
+	}
+	if expected := map[string]string{"time_zone": "UTC", "nls_sort": "BINARY"}; !reflect.DeepEqual(cp.sessionVars, expected) {
+		t.Errorf("session variables %v, expected %v", cp.sessionVars, expected)
+	}
+	set = setVars(t, cp, reader, adapter, "")
+	if !reflect.DeepEqual(set, []string{"nls_sort", "time_zone"}) || (len(cp.sessionVars) != 0) {
+		t.Errorf("set %v, expected all the variables reset", set)
+	}
+	if cp.sessionVarsFailed || cp.WorkerScope.Child_shutdown_flag {
+		t.Error("expected the worker kept")
+	}
+}
+
+func TestSetSessionVarsFailed(t *testing.T) {
+	adapter := &resetAdapter{fakeAdapter: fakeAdapter{db: &fakeDB{}}, setErr: errors.New("unknown time zone")}
+	cp, reader := newTestCmdProcessor(t, adapter)
+
+	// the statement fails instead of running with the wrong variables, the worker exits when freed
+	process(t, cp, netstring.NewNetstringFrom(common.CmdSetSessionVars, []byte("time_zone=Mars/Olympus")),
+		netstring.NewNetstringFrom(common.CmdPrepareV2, []byte("update t set a = 1")),
+		netstring.NewNetstringFrom(common.CmdExecute, nil))
+	code, nss := readEOR(t, reader)
+	if (len(nss) != 1) || !strings.Contains(string(nss[0].Payload), "unknown time zone") {
+		t.Errorf("expected the statement to fail with the error of the variable, got %v", payloads(nss))
+	}
+	if len(adapter.db.logged()) != 0 {
+		t.Error("expected the statement not run")
+	}
+	if (code != common.EORRestart) || !cp.WorkerScope.Child_shutdown_flag {
+		t.Errorf("expected EORRestart, got %d", code)
+	}
+}
+
+func TestSetSessionVarsUnsupported(t *testing.T) {
+	cp, _ := newTestCmdProcessor(t, &fakeAdapter{db: &fakeDB{}})
+	cp.setSessionVars(nil)
+	if cp.sessionVarsErr != nil {
+		t.Errorf("no variable set, got %v", cp.sessionVarsErr)
+	}
+	cp.setSessionVars([]byte("time_zone=UTC"))
+	if (cp.sessionVarsErr != errSessionVarsUnsupported) || !cp.sessionVarsFailed {
+		t.Errorf("expected %v, got %v", errSessionVarsUnsupported, cp.sessionVarsErr)
+	}
+}
+
+func TestSetSessionVarsInTransaction(t *testing.T) {
+	adapter := &resetAdapter{fakeAdapter: fakeAdapter{db: &fakeDB{}}}
+	cp, reader := newTestCmdProcessor(t, adapter)
+	process(t, cp, netstring.NewNetstringFrom(common.CmdPrepareV2, []byte("update t set a = 1")),
+		netstring.NewNetstringFrom(common.CmdExecute, nil))
+	if code, _ := readEOR(t, reader); code != common.EORInTransaction {
+		t.Fatalf("expected a transaction, got %d", code)
+	}
+	cp.setSessionVars([]byte("time_zone=UTC"))
+	if (cp.sessionVarsErr != errSessionVarsInTransaction) || (len(adapter.set) != 0) {
+		t.Errorf("expected %v, got %v", errSessionVarsInTransaction, cp.sessionVarsErr)
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/paypal/hera/config"
+	"github.com/paypal/hera/utility/logger"
+	"github.com/paypal/hera/utility/slowlog"
+)
+
+const envWorkerType string = "HERA_WORKER_TYPE"
+const envShardID string = "HERA_SHARD_ID"
+
+// how often the ops config is checked for threshold changes
+const slowQueryCfgCheckInterval = 10 * time.Second
+
+// stmtTiming holds the database side timing of the current statement
+type stmtTiming struct {
+	active   bool
+	sql      string
+	execDur  time.Duration
+	fetchDur time.Duration
+	rows     int64
+	err      string
+}
+
+type slowQueryConfig struct {
+	opsCfg      config.OpsConfig
+	poolType    string
+	shardID     int
+	logBinds    bool
+	thresholdMs int
+	lastCheck   time.Time
+}
+
+var gSlowQueryCfg *slowQueryConfig
+
+// initSlowQueryLog opens the slow query log shared with mux, if "enable_slow_query_log" is set in hera.txt
+func initSlowQueryLog(cfg config.Config, currentDir string) {
+	if !cfg.GetOrDefaultBool("enable_slow_query_log", false) {
+		return
+	}
+	fileName := currentDir + cfg.GetOrDefaultString("slow_query_log_file", "slow_query.log")
+	// mux owns the file, it rotates it
+	err := slowlog.Init(fileName, 0, 0, false)
+	if err != nil {
+		if logger.GetLogger().V(logger.Warning) {
+			logger.GetLogger().Log(logger.Warning, "Can't open slow query log", fileName, err.Error())
+		}
+		return
+	}
+	sqCfg := &slowQueryConfig{poolType: os.Getenv(envWorkerType), shardID: -1, thresholdMs: 1000}
+	sqCfg.logBinds = cfg.GetOrDefaultBool("slow_query_log_binds", false)
+	shardID, err := strconv.Atoi(os.Getenv(envShardID))
+	if err == nil {
+		sqCfg.shardID = shardID
+	}
+	err = config.InitOpsConfig()
+	if err == nil {
+		sqCfg.opsCfg = config.GetOpsConfig()
+	} else if logger.GetLogger().V(logger.Warning) {
+		logger.GetLogger().Log(logger.Warning, "Error initializing ops config, using default slow query threshold:", err.Error())
+	}
+	gSlowQueryCfg = sqCfg
+}
+
+// threshold returns the slow query threshold from ops config, same keys as mux
+func (sqCfg *slowQueryConfig) threshold() int {
+	if sqCfg.opsCfg == nil {
+		return sqCfg.thresholdMs
+	}
+	now := time.Now()
+	if now.Sub(sqCfg.lastCheck) >= slowQueryCfgCheckInterval {
+		sqCfg.lastCheck = now
+		if sqCfg.opsCfg.Changed() && (sqCfg.opsCfg.Load() == nil) {
+			def := sqCfg.opsCfg.GetOrDefaultInt("slow_query_threshold_ms", 1000)
+			sqCfg.thresholdMs = sqCfg.opsCfg.GetOrDefaultInt("slow_query_threshold_ms_"+sqCfg.poolType, def)
+		}
+	}
+	return sqCfg.thresholdMs
+}
+
+func formatBindValue(value interface{}) string {
+	switch val := value.(type) {
+	case sql.NullString:
+		if !val.Valid {
+			return "NULL"
+		}
+		return val.String
+	case []byte:
+		return fmt.Sprintf("<%d bytes>", len(val))
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// logSlowQuery writes the worker side of the slow query log entry if the current statement is over the threshold.
+// it is called when the worker sends EOR, i.e. after the execute for DMLs and after the fetch for queries
+func (cp *CmdProcessor) logSlowQuery() {
+	if !cp.stmtTiming.active || (gSlowQueryCfg == nil) {
+		return
+	}
+	timing := cp.stmtTiming
+	cp.stmtTiming = stmtTiming{}
+	threshold := gSlowQueryCfg.threshold()
+	if (threshold <= 0) || (timing.execDur+timing.fetchDur < time.Duration(threshold)*time.Millisecond) {
+		return
+	}
+
+	entry := slowlog.NewEntry(slowlog.SourceWorker)
+	entry.WorkerPid = os.Getpid()
+	entry.RqID = cp.rqId
+	entry.SQLHash = cp.sqlHash
+	entry.SQL = slowlog.NormalizeSQL(timing.sql)
+	entry.ShardID = gSlowQueryCfg.shardID
+	entry.PoolType = gSlowQueryCfg.poolType
+	entry.ExecMs = int64(timing.execDur / time.Millisecond)
+	entry.FetchMs = int64(timing.fetchDur / time.Millisecond)
+	entry.TotalMs = entry.ExecMs + entry.FetchMs
+	entry.Rows = timing.rows
+	entry.Client = cp.clientInfo
+	entry.Error = timing.err
+	for _, name := range cp.bindPos {
+		bind := cp.bindVars[name]
+		if (bind == nil) || (bind.btype != btIn) {
+			continue
+		}
+		value := slowlog.RedactedValue
+		if gSlowQueryCfg.logBinds {
+			value = formatBindValue(bind.value)
+		}
+		entry.Binds = append(entry.Binds, slowlog.Bind{Name: name, Value: value})
+	}
+	slowlog.Log(entry)
+}
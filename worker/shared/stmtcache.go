@@ -0,0 +1,213 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"container/list"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/paypal/hera/cal"
+	"github.com/paypal/hera/config"
+	"github.com/paypal/hera/utility/logger"
+)
+
+// stmtCacheReportInterval is the number of lookups between two hit rate CAL events
+const stmtCacheReportInterval = 1000
+
+// staleStmtErrors are the errors telling that a prepared statement must be prepared again, usually after a DDL
+var staleStmtErrors = []string{
+	"Error 1615", // MySQL: Prepared statement needs to be re-prepared
+	"cached plan must not change result type", // Postgres
+	"ORA-04068", // existing state of packages has been discarded
+	"ORA-04061", // existing state of package has been invalidated
+	"ORA-01003", // no statement parsed
+}
+
+// isStaleStmtError tells if the statement failed because it must be prepared again
+func isStaleStmtError(err error) bool {
+	msg := err.Error()
+	for _, stale := range staleStmtErrors {
+		if strings.Contains(msg, stale) {
+			return true
+		}
+	}
+	return false
+}
+
+type stmtCacheEntry struct {
+	sqlHash uint32
+	sql     string
+	stmt    *sql.Stmt
+}
+
+// stmtCache keeps the statements prepared on the worker's connection, the least recently used one is closed
+// when the cache is full. Statements are prepared on the connection, not on a transaction, and used in a
+// transaction with tx.Stmt. A statement missing when a transaction is open can't be prepared on the connection
+// (the worker has only one), it is prepared on the transaction and cached after the transaction ends
+type stmtCache struct {
+	size  int
+	lru   *list.List // front is the most recently used
+	items map[uint32]*list.Element
+	// the statements to prepare when the transaction ends
+	pending map[uint32]string
+	hits    int64
+	misses  int64
+}
+
+// newStmtCache returns the cache if "enable_cache" is set, with "max_cache_size" statements, the same
+// settings as the C++ worker
+func newStmtCache(cfg config.Config) *stmtCache {
+	if !cfg.GetOrDefaultBool("enable_cache", false) {
+		return nil
+	}
+	size := cfg.GetOrDefaultInt("max_cache_size", 0)
+	if size < 1 {
+		if logger.GetLogger().V(logger.Alert) {
+			logger.GetLogger().Log(logger.Alert, "max_cache_size undefined or invalid, statement cache disabled")
+		}
+		return nil
+	}
+	return &stmtCache{size: size, lru: list.New(), items: make(map[uint32]*list.Element), pending: make(map[uint32]string)}
+}
+
+// get returns the cached statement, nil if it is not cached
+func (sc *stmtCache) get(sqlHash uint32, sqlQuery string) *sql.Stmt {
+	el, ok := sc.items[sqlHash]
+	if ok && (el.Value.(*stmtCacheEntry).sql == sqlQuery) {
+		sc.hits++
+		sc.lru.MoveToFront(el)
+		sc.report()
+		return el.Value.(*stmtCacheEntry).stmt
+	}
+	sc.misses++
+	sc.report()
+	return nil
+}
+
+// put caches a statement prepared on the connection, closing the least recently used statement if the cache is full
+func (sc *stmtCache) put(sqlHash uint32, sqlQuery string, stmt *sql.Stmt) {
+	sc.remove(sqlHash)
+	sc.items[sqlHash] = sc.lru.PushFront(&stmtCacheEntry{sqlHash: sqlHash, sql: sqlQuery, stmt: stmt})
+	for sc.lru.Len() > sc.size {
+		oldest := sc.lru.Back().Value.(*stmtCacheEntry)
+		if logger.GetLogger().V(logger.Debug) {
+			logger.GetLogger().Log(logger.Debug, "stmt cache: closing", oldest.sqlHash)
+		}
+		sc.remove(oldest.sqlHash)
+	}
+}
+
+// remove closes and removes a statement from the cache
+func (sc *stmtCache) remove(sqlHash uint32) {
+	el, ok := sc.items[sqlHash]
+	if !ok {
+		return
+	}
+	sc.lru.Remove(el)
+	delete(sc.items, sqlHash)
+	el.Value.(*stmtCacheEntry).stmt.Close()
+}
+
+// clear closes all the statements, after a session reset
+func (sc *stmtCache) clear() {
+	for sqlHash := range sc.items {
+		sc.remove(sqlHash)
+	}
+	sc.pending = make(map[uint32]string)
+}
+
+// deferPrepare remembers a statement to prepare on the connection when the transaction ends
+func (sc *stmtCache) deferPrepare(sqlHash uint32, sqlQuery string) {
+	if len(sc.pending) < sc.size {
+		sc.pending[sqlHash] = sqlQuery
+	}
+}
+
+// preparePending prepares on the connection the statements missed during the transaction
+func (sc *stmtCache) preparePending(db *sql.DB) {
+	for sqlHash, sqlQuery := range sc.pending {
+		delete(sc.pending, sqlHash)
+		if _, ok := sc.items[sqlHash]; ok {
+			continue
+		}
+		stmt, err := db.Prepare(sqlQuery)
+		if err != nil {
+			if logger.GetLogger().V(logger.Debug) {
+				logger.GetLogger().Log(logger.Debug, "stmt cache: prepare failed", sqlHash, err.Error())
+			}
+			continue
+		}
+		sc.put(sqlHash, sqlQuery, stmt)
+	}
+}
+
+// cachedStmt returns the statement from the cache, preparing and caching it if no transaction is open. It
+// returns nil when the statement is to be prepared as without cache, along with "hit" or "miss" for CAL
+func (cp *CmdProcessor) cachedStmt(sqlQuery string) (*sql.Stmt, string) {
+	stmt := cp.stmtCache.get(cp.sqlHash, sqlQuery)
+	if stmt != nil {
+		return stmt, "hit"
+	}
+	if cp.tx != nil {
+		cp.stmtCache.deferPrepare(cp.sqlHash, sqlQuery)
+		return nil, "miss"
+	}
+	stmt, err := cp.db.Prepare(sqlQuery)
+	if err != nil {
+		// prepared again without cache, reporting the error
+		return nil, "miss"
+	}
+	cp.stmtCache.put(cp.sqlHash, sqlQuery, stmt)
+	return stmt, "miss"
+}
+
+// invalidateStmt removes the current statement from the cache if it failed because it must be prepared again
+func (cp *CmdProcessor) invalidateStmt(err error) {
+	if (cp.stmtCache == nil) || !isStaleStmtError(err) {
+		return
+	}
+	if logger.GetLogger().V(logger.Info) {
+		logger.GetLogger().Log(logger.Info, "stmt cache: removing stale statement", cp.sqlHash, err.Error())
+	}
+	evt := cal.NewCalEvent("STMT_CACHE", "stale", cal.TransOK, fmt.Sprintf("%d", cp.sqlHash))
+	evt.Completed()
+	cp.stmtCache.remove(cp.sqlHash)
+	cp.stmtCached = false
+}
+
+// report sends the hit rate to CAL every stmtCacheReportInterval lookups
+func (sc *stmtCache) report() {
+	total := sc.hits + sc.misses
+	if total < stmtCacheReportInterval {
+		return
+	}
+	rate := fmt.Sprintf("%.2f", 100*float64(sc.hits)/float64(total))
+	if logger.GetLogger().V(logger.Info) {
+		logger.GetLogger().Log(logger.Info, "stmt cache stats: hits =", sc.hits, ", misses =", sc.misses, ", hit ratio =", rate, "%, size =", sc.lru.Len())
+	}
+	evt := cal.NewCalEvent("STMT_CACHE", "hit_rate", cal.TransOK, "")
+	evt.AddDataInt("hits", sc.hits)
+	evt.AddDataInt("misses", sc.misses)
+	evt.AddDataStr("pct", rate)
+	evt.AddDataInt("size", int64(sc.lru.Len()))
+	evt.Completed()
+	sc.hits = 0
+	sc.misses = 0
+}
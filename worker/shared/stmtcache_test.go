@@ -0,0 +1,114 @@
+// Copyright 2024 PayPal Inc.
+//
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"container/list"
+	"errors"
+	"testing"
+
+	"github.com/paypal/hera/common"
+	"github.com/paypal/hera/utility/encoding/netstring"
+)
+
+func newTestStmtCache(size int) *stmtCache {
+	return &stmtCache{size: size, lru: list.New(), items: make(map[uint32]*list.Element), pending: make(map[uint32]string)}
+}
+
+func TestStmtCacheLRU(t *testing.T) {
+	db := &fakeDB{}
+	cp, _ := newTestCmdProcessor(t, &fakeAdapter{db: db})
+	sc := newTestStmtCache(2)
+	for hash, query := range []string{"select 0", "select 1", "select 2"} {
+		stmt, err := cp.db.Prepare(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sc.put(uint32(hash), query, stmt)
+		if hash == 1 {
+			// 0 becomes the most recently used
+			sc.get(0, "select 0")
+		}
+	}
+	if (sc.get(1, "select 1") != nil) || (sc.get(0, "select 0") == nil) || (sc.get(2, "select 2") == nil) {
+		t.Error("expected the least recently used statement to be closed")
+	}
+	// a hash collision is a miss
+	if sc.get(2, "select 3") != nil {
+		t.Error("expected a miss for another SQL having the same hash")
+	}
+	if (sc.hits != 3) || (sc.misses != 2) {
+		t.Errorf("expected 3 hits and 2 misses, got %d, %d", sc.hits, sc.misses)
+	}
+	sc.clear()
+	if (sc.lru.Len() != 0) || (len(sc.items) != 0) {
+		t.Error("expected an empty cache")
+	}
+}
+
+func TestStmtCachePrepare(t *testing.T) {
+	db := &fakeDB{}
+	cp, reader := newTestCmdProcessor(t, &fakeAdapter{db: db})
+	cp.stmtCache = newTestStmtCache(2)
+	query := func(sql string) {
+		process(t, cp, netstring.NewNetstringFrom(common.CmdPrepareV2, []byte(sql)),
+			netstring.NewNetstringFrom(common.CmdExecute, nil),
+			netstring.NewNetstringFrom(common.CmdFetch, []byte("0")))
+		for {
+			ns, err := reader.ReadNext()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ns.Cmd == common.CmdEOR {
+				return
+			}
+		}
+	}
+	query("select a from t")
+	query("select a from t")
+	if (db.prepares != 1) || !cp.stmtCached {
+		t.Errorf("expected the statement prepared once and cached, prepared %d times", db.prepares)
+	}
+
+	// the first DML is prepared on the connection before the transaction starts, the next one is prepared
+	// on the transaction and cached when it ends
+	for _, dml := range []string{"update t set a = 1", "update t set b = 1"} {
+		process(t, cp, netstring.NewNetstringFrom(common.CmdPrepareV2, []byte(dml)),
+			netstring.NewNetstringFrom(common.CmdExecute, nil))
+		readEOR(t, reader)
+	}
+	if cp.stmtCached || (len(cp.stmtCache.pending) != 1) || (cp.stmtCache.lru.Len() != 2) {
+		t.Errorf("expected the second DML to be prepared after the transaction, pending %v", cp.stmtCache.pending)
+	}
+	process(t, cp, netstring.NewNetstringFrom(common.CmdCommit, nil))
+	readEOR(t, reader)
+	if (len(cp.stmtCache.pending) != 0) || (cp.stmtCache.lru.Len() != 2) || (cp.stmtCache.lru.Front().Value.(*stmtCacheEntry).sql != "update t set b = 1") {
+		t.Errorf("expected the second DML cached after the commit, %d cached", cp.stmtCache.lru.Len())
+	}
+
+	// a stale statement is removed, the others are kept
+	cp.sqlHash = cp.stmtCache.lru.Front().Value.(*stmtCacheEntry).sqlHash
+	cp.invalidateStmt(errors.New("Error 1062: Duplicate entry"))
+	if cp.stmtCache.lru.Len() != 2 {
+		t.Error("expected the statement kept after an error which is not stale")
+	}
+	cp.invalidateStmt(errors.New("Error 1615: Prepared statement needs to be re-prepared"))
+	if _, ok := cp.stmtCache.items[cp.sqlHash]; ok || (cp.stmtCache.lru.Len() != 1) {
+		t.Error("expected the stale statement removed")
+	}
+}
@@ -18,6 +18,7 @@
 package shared
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"path/filepath"
@@ -32,6 +33,8 @@ import (
 	"github.com/paypal/hera/config"
 	"github.com/paypal/hera/utility/encoding/netstring"
 	"github.com/paypal/hera/utility/logger"
+	otellogger "github.com/paypal/hera/utility/logger/otel"
+	otelconfig "github.com/paypal/hera/utility/logger/otel/config"
 )
 
 const (
@@ -67,7 +70,7 @@ func Start(adapter CmdProcessorAdapter) {
 	}
 
 	cfgfile := currentDir + "hera.txt"
-	cfg, err := config.NewTxtConfig(cfgfile)
+	cfg, err := config.NewFileConfig(cfgfile)
 	if err != nil {
 		fmt.Printf("Can't open config hera.txt")
 		return
@@ -83,7 +86,7 @@ func Start(adapter CmdProcessorAdapter) {
 	logPrefix += fmt.Sprintf(" %d", os.Getpid())
 	
 	logfilename := currentDir + cfg.GetOrDefaultString("log_file", "hera.log")
-	err = logger.CreateLogger(logfilename, logPrefix, int32(logLevel))
+	err = logger.CreateLoggerWithOptions(logfilename, logPrefix, int32(logLevel), logger.OptionsFromConfig(cfg, false))
 	if err != nil {
 		return
 	}
@@ -116,6 +119,19 @@ func Start(adapter CmdProcessorAdapter) {
 
 	logger.GetLogger().Log(logger.Info, "DB heartbeat interval:", wconfig.hbInterval)
 
+	initSlowQueryLog(cfg, currentDir)
+
+	if calClient := cal.GetCalClientInstance(); (calClient != nil) && calClient.IsOTelHandler() {
+		// the CAL transactions are exported as spans, as in mux
+		otelconfig.InitFromConfig(cfg, calClient.GetPoolName(), cfg.GetOrDefaultString("state_log_prefix", "hera"))
+	}
+	if otellogger.TracesEnabled() {
+		shutdown, err := otellogger.InitTraces(context.Background())
+		if err == nil {
+			defer shutdown(context.Background())
+		}
+	}
+
 	evt := cal.NewCalEvent(cal.EventTypeServerInfo, "worker-go-start", cal.TransOK, "")
 	evt.Completed()
 	//
@@ -125,6 +141,8 @@ func Start(adapter CmdProcessorAdapter) {
 	sockMuxCtrl := os.NewFile(uintptr(4), fmt.Sprintf("workerc_sp%d", 0))
 
 	cmdprocessor := NewCmdProcessor(adapter, sockMux, sockMuxCtrl)
+	cmdprocessor.stmtCache = newStmtCache(cfg)
+	cmdprocessor.sessionReset = newSessionReset(cfg)
 
 	err = cmdprocessor.InitDB()
 	if err != nil {
@@ -160,6 +178,8 @@ func runworker(cmdprocessor *CmdProcessor, cfg *workerConfig) {
 	sigchannel := waitForSignal()
 	ctrlchannel := waitForCtrl(cmdprocessor.SocketCtrl)
 
+	// how the database was found read-only, when WorkerScope.Db_read_only is set
+	readOnlyBy := "write"
 outerloop:
 	for {
 		select {
@@ -172,8 +192,12 @@ outerloop:
 
 				ok := cmdprocessor.SendDbHeartbeat()
 				if !ok {
+					if reporter, isReporter := cmdprocessor.adapter.(ReadOnlyReporter); isReporter && reporter.HeartbeatReadOnly() {
+						cmdprocessor.WorkerScope.Db_read_only = true
+						readOnlyBy = "heartbeat"
+					}
 					if logger.GetLogger().V(logger.Warning) {
-						logger.GetLogger().Log(logger.Warning, "master db is unavailable, worker exiting")
+						logger.GetLogger().Log(logger.Warning, "master db is unavailable, worker exiting, read-only:", cmdprocessor.WorkerScope.Db_read_only)
 					}
 					break outerloop
 				}
@@ -269,6 +293,17 @@ outerloop:
 	}
 	sockMux.Close()
 	sockMux = nil
+	if cmdprocessor.WorkerScope.Db_read_only {
+		// the mux recycles the pool onto the new primary
+		if logger.GetLogger().V(logger.Warning) {
+			logger.GetLogger().Log(logger.Warning, "database is read-only, found by the", readOnlyBy, ", worker exits with", common.WorkerExitReadOnly)
+		}
+		// the role change seen by the worker, the mux then logs failover_detected
+		evt := cal.NewCalEvent("FAILOVER", "db_read_only", cal.TransWarning, "")
+		evt.AddDataStr("detected_by", readOnlyBy)
+		evt.Completed()
+		os.Exit(common.WorkerExitReadOnly)
+	}
 }
 
 /**
@@ -280,7 +315,8 @@ func readNextNetstring(sockMux *os.File) <-chan *netstring.Netstring {
 	// up to 10 ns substrings will be queued up in the buffer.
 	//
 	commandch := make(chan *netstring.Netstring, 10)
-	nsreader := netstring.NewNetstringReader(sockMux)
+	// the requests of the clients using the binary framing are forwarded as is
+	nsreader := netstring.NewFrameReader(sockMux)
 	go func() {
 		for {
 			ns, err := nsreader.ReadNext()